@@ -0,0 +1,49 @@
+package main
+
+import "unicode"
+
+// Accented Latin letters by the letter they are written on, the upper case
+// ones are added by init
+var accented = map[rune]string{
+	'a': "àáâãäåāăąǎ",
+	'c': "çćĉċč",
+	'd': "ďđ",
+	'e': "èéêëēĕėęě",
+	'g': "ĝğġģ",
+	'h': "ĥħ",
+	'i': "ìíîïĩīĭįǐı",
+	'j': "ĵ",
+	'k': "ķ",
+	'l': "ĺļľŀł",
+	'n': "ñńņňŉ",
+	'o': "òóôõöøōŏőǒ",
+	'r': "ŕŗř",
+	's': "śŝşšș",
+	't': "ţťŧț",
+	'u': "ùúûüũūŭůűųǔ",
+	'w': "ŵ",
+	'y': "ýÿŷ",
+	'z': "źżž",
+}
+
+// The letter each accented one is written on. Eg:- é --> e, Ö --> O
+var unaccented = make(map[rune]rune)
+
+func init() {
+	for base, letters := range accented {
+		for _, r := range letters {
+			unaccented[r] = base
+			if upper := unicode.ToUpper(r); upper != r {
+				unaccented[upper] = unicode.ToUpper(base)
+			}
+		}
+	}
+}
+
+// r without its accent, r itself when it has none. Eg:- é --> e
+func stripAccent(r rune) rune {
+	if base, ok := unaccented[r]; ok {
+		return base
+	}
+	return r
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Copies of the dictionary taken by /admin/snapshot, in the data directory
+const backupsDir = "backups"
+
+// Rejects admin requests without an admin token, or from other machines when none are configured
+func adminOnly(cfg ServeConfig, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(cfg.AdminTokens) > 0 {
+			if !validToken(cfg.AdminTokens, bearerToken(r)) {
+				writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "missing or invalid admin token"})
+				return
+			}
+		} else if host, _, _ := net.SplitHostPort(r.RemoteAddr); !net.ParseIP(host).IsLoopback() {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "admin endpoints are local only"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Admin endpoints, all POST with an optional JSON body
+//
+//	/admin/reload   {"dictionary": "words-v2.txt"}   loads a new dictionary, the current one again when empty
+//	/admin/snapshot                                   copies the current dictionary into backups/
+//	/admin/restore  {"backup": "words.20261014-1200.txt"} reloads from a backup
+func (s *server) handleAdmin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "use POST"})
+		return
+	}
+	var req struct {
+		Dictionary string `json:"dictionary"`
+		Backup     string `json:"backup"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	var result map[string]any
+	var err error
+	switch strings.TrimPrefix(r.URL.Path, "/admin/") {
+	case "reload":
+		dictionary := s.shared.Load().dictionary
+		if req.Dictionary != "" {
+			dictionary = inDataDir(req.Dictionary)
+		}
+		result, err = s.reload(dictionary)
+	case "snapshot":
+		var name string
+		if name, err = s.snapshot(); err == nil {
+			result = map[string]any{"backup": name}
+		}
+	case "restore":
+		if req.Backup == "" || req.Backup != filepath.Base(req.Backup) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "expected the name of a backup"})
+			return
+		}
+		result, err = s.reload(filepath.Join(dataDir(), backupsDir, req.Backup))
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown admin endpoint"})
+		return
+	}
+	if os.IsNotExist(err) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	} else if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// Builds everything from dictionary next to the running state and swaps it in.
+// Lookups keep using the old words until the swap, which only waits for the
+// lookups already running on each client. The words each client learned, also
+// during the reload, and those of its session stay as they are. Nothing is
+// swapped when one of the dictionaries, packs included, cannot be loaded
+func (s *server) reload(dictionary string) (map[string]any, error) {
+	if _, err := os.Stat(dictionary); err != nil {
+		return nil, err
+	}
+	verifier := NewVerifier(s.cfg)
+	if !verifier.Allow(dictionary) {
+		return nil, fmt.Errorf("refused: %s", verifier.Problems())
+	}
+	d, problems := loadDictionaries(s.cfg, dictionary, verifier)
+	var baseProblems []string
+	d.base, _, baseProblems = loadBase(s.cfg.dictSpecs(dictionary), s.cfg.Dawg, s.cfg.BuiltinWords, verifier)
+	if problems = append(problems, baseProblems...); len(problems) > 0 {
+		return nil, fmt.Errorf("nothing swapped: %s", problemStatus(problems))
+	}
+
+	// Clients loaded from here on use the new words, those loaded or loading
+	// before get them below their own layers
+	s.shared.Store(d)
+	s.mu.Lock()
+	tenants := make([]*tenant, 0, len(s.clients))
+	for _, t := range s.clients {
+		t.users++ // not unloaded meanwhile
+		tenants = append(tenants, t)
+	}
+	configured := s.clients[""]
+	s.mu.Unlock()
+
+	for _, t := range tenants {
+		<-t.ready
+		t.mu.Lock()
+		t.trie.SetLayer(baseLayer, d.base)
+		t.mu.Unlock()
+	}
+	s.mu.Lock()
+	for _, t := range tenants {
+		t.users--
+	}
+	s.mu.Unlock()
+	result := map[string]any{"dictionary": dictionary, "clients": len(tenants)}
+	if p := verifier.Problems(); p != "" {
+		result["integrity"] = p // allowed, but not as signed
+	}
+	if configured != nil {
+		configured.mu.RLock()
+		result["words"] = configured.trie.Stats().Words
+		configured.mu.RUnlock()
+	}
+	return result, nil
+}
+
+// Copies the current dictionary to the backups directory and returns the name of the copy
+func (s *server) snapshot() (string, error) {
+	dictionary := s.shared.Load().dictionary
+	ext := filepath.Ext(dictionary)
+	name := strings.TrimSuffix(filepath.Base(dictionary), ext) + "." + time.Now().Format("20060102-150405") + ext
+	return name, copyFile(dictionary, filepath.Join(dataDir(), backupsDir, name))
+}
+
+// autocomplete admin reload [dictionary] | snapshot | restore <backup>
+// Asks the running serve mode daemon to swap dictionaries
+func adminCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: admin reload [dictionary] | snapshot | restore <backup>")
+	}
+	body := map[string]string{}
+	switch {
+	case args[0] == "reload" && len(args) == 2:
+		body["dictionary"], _ = filepath.Abs(args[1])
+	case args[0] == "restore" && len(args) == 2:
+		body["backup"] = args[1]
+	case args[0] == "restore":
+		return fmt.Errorf("usage: admin restore <backup>")
+	}
+
+	cfg, _ := LoadConfig(configPath())
+	data, _ := json.Marshal(body)
+	req, err := http.NewRequest(http.MethodPost, "http://"+cfg.Serve.Listen+"/admin/"+args[0], bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if len(cfg.Serve.AdminTokens) > 0 {
+		req.Header.Set("Authorization", "Bearer "+cfg.Serve.AdminTokens[0])
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	out, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(out)))
+	}
+	fmt.Print(string(out))
+	return nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// A reloaded dictionary goes below the words the clients learned and those of their session
+func TestAdminReload(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", dir)
+	old := paths
+	paths = pathsIn(filepath.Join(dir, "profile"))
+	t.Cleanup(func() { paths = old })
+	before, after := filepath.Join(dir, "before.txt"), filepath.Join(dir, "after.txt")
+	os.WriteFile(before, []byte("quokka\n"), 0644)
+	os.WriteFile(after, []byte("wombat\n"), 0644)
+	cfg := defaultConfig()
+	cfg.Dictionary = before
+	s, _ := newServer(cfg)
+	defer s.Close()
+
+	c, _, _ := s.tenant("")
+	c.trie.Insert(userLayer, "gopher")
+	c.prof.learn("gopher", time.Now())
+	c.trie.Insert(sessionLayer, "session")
+	s.release(c)
+	if _, err := s.reload(after); err != nil {
+		t.Fatal(err)
+	}
+	for word, want := range map[string]int{"quokka": 0, "wombat": 1, "gopher": 1, "session": 1} {
+		if got := c.trie.Count(word); got != want {
+			t.Errorf("%s counts %d after the reload, want %d", word, got, want)
+		}
+	}
+
+	s.cfg.Packs = []string{"no-such-pack"}
+	if _, err := s.reload(before); err == nil || !strings.Contains(err.Error(), "no-such-pack") {
+		t.Errorf("reload with a missing pack: %v", err)
+	}
+	if c.trie.Count("wombat") != 1 || c.trie.Count("quokka") != 0 {
+		t.Error("a reload missing a pack was swapped in")
+	}
+}
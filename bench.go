@@ -0,0 +1,231 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"time"
+
+	"github.com/b0tShaman/autocomplete-cli/trie"
+)
+
+// Words used for the throughput and memory measurements
+const benchWords = 100000
+
+// Result of autocomplete bench, meant to be compared between machines and versions
+type BenchReport struct {
+	Time     time.Time      `json:"time"`
+	Version  string         `json:"version"` // vcs revision of the binary, when known
+	Go       string         `json:"go"`
+	Platform string         `json:"platform"`
+	CPUs     int            `json:"cpus"`
+	Backends []BackendBench `json:"backends"`
+}
+
+type BackendBench struct {
+	Name            string           `json:"name"`
+	Words           int              `json:"words"`
+	InsertPerSecond float64          `json:"insert_per_second"`
+	BytesPer100k    uint64           `json:"bytes_per_100k_words"`
+	Autofill        []AutofillTiming `json:"autofill"`
+	StartupMillis   float64          `json:"startup_ms"` // loading the configured dictionary and profile
+}
+
+type AutofillTiming struct {
+	PrefixLength int     `json:"prefix_length"`
+	Micros       float64 `json:"mean_us"`
+	Completions  float64 `json:"mean_completions"`
+}
+
+// Deterministic pseudo words with a letter distribution close enough to English
+// that the trie gets a realistic shape
+func benchCorpus(n int) []string {
+	const letters = "eeeeeeeeeeeettttttttaaaaaaaooooooiiiiiinnnnnnsssssshhhhhrrrrrddddllllcccuuummwwffggyyppbbvkjxqz"
+	r := rand.New(rand.NewSource(1))
+	words := make([]string, n)
+	for i := range words {
+		word := make([]byte, 3+r.Intn(10))
+		for j := range word {
+			word[j] = letters[r.Intn(len(letters))]
+		}
+		words[i] = string(word)
+	}
+	return words
+}
+
+// A trie holding words, minimized with dawg
+func benchBuild(words []string, dawg bool) *trie.Trie {
+	t := trie.New()
+	for _, word := range words {
+		t.Insert(word)
+	}
+	if dawg {
+		t = t.Minimize()
+	}
+	return t
+}
+
+// Heap used by a trie holding words
+func trieBytes(words []string, dawg bool) uint64 {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	t := benchBuild(words, dawg)
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	runtime.KeepAlive(t)
+	if after.HeapAlloc < before.HeapAlloc {
+		return 0
+	}
+	return after.HeapAlloc - before.HeapAlloc
+}
+
+// Measures the trie backend, or the dawg one with dawg. Autofill is timed for
+// every prefix length up to maxPrefix, over prefixes of random corpus words
+func benchTrie(cfg Config, words []string, maxPrefix int, dawg bool) BackendBench {
+	b := BackendBench{Name: "trie", Words: len(words)}
+	if dawg {
+		b.Name = "dawg"
+	}
+
+	start := time.Now()
+	t := benchBuild(words, dawg)
+	b.InsertPerSecond = float64(len(words)) / time.Since(start).Seconds()
+	b.BytesPer100k = trieBytes(words, dawg) * benchWords / uint64(len(words))
+
+	r := rand.New(rand.NewSource(2))
+	for length := 1; length <= maxPrefix; length++ {
+		var prefixes []string
+		for len(prefixes) < 1000 {
+			if word := words[r.Intn(len(words))]; len(word) >= length {
+				prefixes = append(prefixes, word[:length])
+			}
+		}
+		completions := 0
+		start := time.Now()
+		for _, prefix := range prefixes {
+			completions += len(t.AutofillScored(prefix, (&profile{}).score))
+		}
+		b.Autofill = append(b.Autofill, AutofillTiming{
+			PrefixLength: length,
+			Micros:       float64(time.Since(start).Nanoseconds()) / 1000 / float64(len(prefixes)),
+			Completions:  float64(completions) / float64(len(prefixes)),
+		})
+	}
+
+	start = time.Now()
+	prof, _ := openProfile(paths)
+	loadTrie(cfg.dictSpecs(cfg.Dictionary), dawg, cfg.BuiltinWords, NewVerifier(cfg), paths.snapshot, prof.tombstones, prof.history, cfg.Tokens)
+	prof.Close()
+	b.StartupMillis = float64(time.Since(start).Microseconds()) / 1000
+	return b
+}
+
+// A metric counts as regressed when it got this much worse
+const benchTolerance = 0.10
+
+// autocomplete bench [words] [max prefix length] | compare <old.json> <new.json>
+// Prints a JSON report of insert throughput, Autofill latency, memory and startup time
+func benchCommand(args []string) error {
+	if len(args) > 0 && args[0] == "compare" {
+		if len(args) != 3 {
+			return fmt.Errorf("usage: bench compare <old.json> <new.json>")
+		}
+		return compareBench(args[1], args[2])
+	}
+	n, maxPrefix := benchWords, 5
+	var err error
+	if len(args) > 0 {
+		if n, err = strconv.Atoi(args[0]); err != nil || n < 1 {
+			return fmt.Errorf("usage: bench [words] [max prefix length]")
+		}
+	}
+	if len(args) > 1 {
+		if maxPrefix, err = strconv.Atoi(args[1]); err != nil || maxPrefix < 1 {
+			return fmt.Errorf("usage: bench [words] [max prefix length]")
+		}
+	}
+
+	corpus := benchCorpus(n)
+	cfg, _ := LoadConfig(configPath())
+	report := BenchReport{
+		Time:     time.Now().UTC(),
+		Go:       runtime.Version(),
+		Platform: runtime.GOOS + "/" + runtime.GOARCH,
+		CPUs:     runtime.NumCPU(),
+		Backends: []BackendBench{benchTrie(cfg, corpus, maxPrefix, false), benchTrie(cfg, corpus, maxPrefix, true)},
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		report.Version = info.Main.Version
+		for _, s := range info.Settings {
+			if s.Key == "vcs.revision" {
+				report.Version = s.Value
+			}
+		}
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+func readBench(path string) (BenchReport, error) {
+	var report BenchReport
+	data, err := os.ReadFile(path)
+	if err == nil {
+		err = json.Unmarshal(data, &report)
+	}
+	return report, err
+}
+
+// Prints how every metric of the backends in both reports changed and fails
+// when one of them regressed by more than benchTolerance
+func compareBench(oldPath, newPath string) error {
+	old, err := readBench(oldPath)
+	if err != nil {
+		return err
+	}
+	cur, err := readBench(newPath)
+	if err != nil {
+		return err
+	}
+
+	regressed := 0
+	// higher tells whether bigger is better for the metric
+	compare := func(backend, metric string, before, after float64, higher bool) {
+		if before == 0 {
+			return
+		}
+		change := (after - before) / before
+		mark := ""
+		if (higher && change < -benchTolerance) || (!higher && change > benchTolerance) {
+			mark = "  REGRESSED"
+			regressed++
+		}
+		fmt.Printf("%-6s %-22s %14.2f -> %14.2f  %+6.1f%%%s\n", backend, metric, before, after, change*100, mark)
+	}
+	for _, b := range cur.Backends {
+		for _, a := range old.Backends {
+			if a.Name != b.Name {
+				continue
+			}
+			compare(b.Name, "insert/s", a.InsertPerSecond, b.InsertPerSecond, true)
+			compare(b.Name, "bytes/100k words", float64(a.BytesPer100k), float64(b.BytesPer100k), false)
+			compare(b.Name, "startup ms", a.StartupMillis, b.StartupMillis, false)
+			for _, tb := range b.Autofill {
+				for _, ta := range a.Autofill {
+					if ta.PrefixLength == tb.PrefixLength {
+						compare(b.Name, fmt.Sprintf("autofill us, prefix %d", tb.PrefixLength), ta.Micros, tb.Micros, false)
+					}
+				}
+			}
+		}
+	}
+	if regressed > 0 {
+		return fmt.Errorf("%d metrics regressed by more than %.0f%%", regressed, benchTolerance*100)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/b0tShaman/autocomplete-cli/trie"
+)
+
+// Same measurements as the bench command, for go test -bench
+
+func BenchmarkInsert(b *testing.B) {
+	words := benchCorpus(benchWords)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		t := trie.New()
+		for _, word := range words {
+			t.Insert(word)
+		}
+	}
+	b.ReportMetric(float64(len(words)*b.N)/b.Elapsed().Seconds(), "words/s")
+}
+
+func BenchmarkAutofill(b *testing.B) {
+	words := benchCorpus(benchWords)
+	t := trie.New()
+	for _, word := range words {
+		t.Insert(word)
+	}
+	for length := 1; length <= 5; length++ {
+		var prefixes []string
+		for _, word := range words {
+			if len(word) >= length {
+				prefixes = append(prefixes, word[:length])
+			}
+		}
+		b.Run(fmt.Sprintf("prefix%d", length), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				t.AutofillScored(prefixes[i%len(prefixes)], (&profile{}).score)
+			}
+		})
+	}
+}
+
+// The k best completions the editor looks up, against all of them above, and
+// from a mapped dictionary
+func BenchmarkAutofillTop(b *testing.B) {
+	words := benchCorpus(benchWords)
+	t := trie.New()
+	for _, word := range words {
+		t.Insert(word)
+	}
+	var encoded bytes.Buffer
+	t.EncodeMapped(&encoded)
+	mapped, _ := trie.Map(encoded.Bytes())
+	for length := 1; length <= 5; length++ {
+		var prefixes []string
+		for _, word := range words {
+			if len(word) >= length {
+				prefixes = append(prefixes, word[:length])
+			}
+		}
+		b.Run(fmt.Sprintf("prefix%d", length), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				t.AutofillTop(prefixes[i%len(prefixes)], maxCompletion, (&profile{}).score)
+			}
+		})
+		b.Run(fmt.Sprintf("mapped/prefix%d", length), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				mapped.AutofillTop(prefixes[i%len(prefixes)], maxCompletion, (&profile{}).score)
+			}
+		})
+	}
+}
+
+func BenchmarkMemory(b *testing.B) {
+	words := benchCorpus(benchWords)
+	for _, dawg := range []bool{false, true} {
+		b.Run(fmt.Sprintf("dawg=%v", dawg), func(b *testing.B) {
+			var bytes uint64
+			for i := 0; i < b.N; i++ {
+				bytes = trieBytes(words, dawg)
+			}
+			b.ReportMetric(float64(bytes), "bytes/100k-words")
+		})
+	}
+}
+
+func BenchmarkLoadTrie(b *testing.B) {
+	dir := b.TempDir()
+	dictionary := filepath.Join(dir, "words.txt")
+	if err := os.WriteFile(dictionary, []byte(strings.Join(benchCorpus(benchWords), "\n")), 0644); err != nil {
+		b.Fatal(err)
+	}
+	if err := compileCommand([]string{dictionary}); err != nil {
+		b.Fatal(err)
+	}
+	if err := compileCommand([]string{"--mapped", dictionary, "-o", filepath.Join(dir, "mapped.dict")}); err != nil {
+		b.Fatal(err)
+	}
+	snapshot := filepath.Join(dir, snapshotFile)
+	for _, name := range []string{"words.txt", "words.dict", "mapped.dict"} {
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				loadTrie([]DictSpec{{path: filepath.Join(dir, name), weight: 1}}, false, false, nil, snapshot, nil, nil, TokensConfig{})
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Aligned bilingual word lists, one file per language named translations.<lang>.txt
+// Each line holds a word and its translation separated by a TAB
+// Eg:- translations.es.txt:  house	casa
+const translationsGlob = "translations.*.txt"
+
+// Maps a word to its translations, ready to be offered as secondary suggestions
+type Bilingual map[string][]Candidate
+
+// Loads every translation list matching pattern. Without any list the mode stays off
+func LoadBilingual(pattern string, v *Verifier) Bilingual {
+	bi := make(Bilingual)
+
+	files, _ := filepath.Glob(pattern)
+	for _, file := range files {
+		// translations.es.txt --> es
+		lang := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(file), "translations."), ".txt")
+
+		data, err := os.ReadFile(file)
+		if err != nil || !v.Allow(file) {
+			continue
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			word, translation, ok := strings.Cut(strings.TrimSpace(line), "\t")
+			word, translation = strings.TrimSpace(word), strings.TrimSpace(translation)
+			if !ok || word == "" || translation == "" {
+				continue
+			}
+			bi[word] = append(bi[word], Candidate{
+				word:   translation,
+				source: "translation",
+				label:  "[" + lang + "] " + word + " → " + translation,
+			})
+		}
+	}
+	return bi
+}
+
+// Returns the translations of the given words, skipping duplicates
+func (bi Bilingual) Translate(words []string) []Candidate {
+	var result []Candidate
+	seen := make(map[string]bool)
+	for _, word := range words {
+		for _, c := range bi[word] {
+			if !seen[c.word] {
+				seen[c.word] = true
+				result = append(result, c)
+			}
+		}
+	}
+	return result
+}
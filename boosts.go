@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Per-word ranking adjustments learned from ignored suggestions,
+// one "word<TAB>level<TAB>ignored<TAB>chosen" per line
+const boostsFile = "boosts.txt"
+
+const (
+	feedbackThreshold = 3    // times a word is passed over (or picked from below) before its level moves
+	boostFactor       = 1.25 // score multiplier per level
+	maxBoost          = 4    // levels range from -maxBoost to maxBoost
+)
+
+// Feedback collected for a word
+type Boost struct {
+	level   int // the score is multiplied by boostFactor^level
+	ignored int // shown first while a lower suggestion was accepted, since the last level change
+	chosen  int // accepted although it was not shown first, since the last level change
+}
+
+type Boosts map[string]*Boost
+
+// Multiplier applied to the score of word
+func (b Boosts) Factor(word string) float64 {
+	if fb := b[word]; fb != nil {
+		return math.Pow(boostFactor, float64(fb.level))
+	}
+	return 1
+}
+
+// Returns a copy of b which changes independently of it
+func (b Boosts) Clone() Boosts {
+	clone := make(Boosts, len(b))
+	for word, fb := range b {
+		copied := *fb
+		clone[word] = &copied
+	}
+	return clone
+}
+
+// Records that accepted was picked while top was suggested first. A word
+// passed over feedbackThreshold times drops a level, one picked from below that
+// often rises one
+func (b Boosts) Feedback(top, accepted string) {
+	get := func(word string) *Boost {
+		if b[word] == nil {
+			b[word] = &Boost{}
+		}
+		return b[word]
+	}
+	if top == accepted {
+		if fb := b[top]; fb != nil {
+			fb.ignored = 0 // it was wanted after all
+		}
+		return
+	}
+
+	passed, picked := get(top), get(accepted)
+	if passed.ignored++; passed.ignored >= feedbackThreshold {
+		passed.ignored = 0
+		passed.level = max(passed.level-1, -maxBoost)
+	}
+	if picked.chosen++; picked.chosen >= feedbackThreshold {
+		picked.chosen = 0
+		picked.level = min(picked.level+1, maxBoost)
+	}
+}
+
+// Reads the boosts. A missing file means no adjustments
+func LoadBoosts(path string) (Boosts, error) {
+	b := make(Boosts)
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return b, nil
+	} else if err != nil {
+		return b, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) != 4 || fields[0] == "" {
+			continue
+		}
+		var fb Boost
+		var errs [3]error
+		fb.level, errs[0] = strconv.Atoi(fields[1])
+		fb.ignored, errs[1] = strconv.Atoi(fields[2])
+		fb.chosen, errs[2] = strconv.Atoi(fields[3])
+		if errs[0] == nil && errs[1] == nil && errs[2] == nil {
+			b[fields[0]] = &fb
+		}
+	}
+	return b, scanner.Err()
+}
+
+func (b Boosts) Save(path string) error {
+	var out strings.Builder
+	for _, word := range b.sorted() {
+		fb := b[word]
+		fmt.Fprintf(&out, "%s\t%d\t%d\t%d\n", word, fb.level, fb.ignored, fb.chosen)
+	}
+	return os.WriteFile(path, []byte(out.String()), 0644)
+}
+
+func (b Boosts) sorted() []string {
+	words := make([]string, 0, len(b))
+	for word := range b {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	return words
+}
+
+// Number of words whose ranking was adjusted
+func (b Boosts) Adjusted() int {
+	var n int
+	for _, fb := range b {
+		if fb.level != 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// Drops the adjustments of words, of all words when none are given.
+// Returns how many were dropped
+func (b Boosts) Reset(words []string) int {
+	if len(words) == 0 {
+		words = b.sorted()
+	}
+	var n int
+	for _, word := range words {
+		if b[word] != nil {
+			delete(b, word)
+			n++
+		}
+	}
+	return n
+}
+
+// Human readable list of the adjusted words
+func (b Boosts) String() string {
+	var out strings.Builder
+	for _, word := range b.sorted() {
+		if fb := b[word]; fb.level != 0 {
+			fmt.Fprintf(&out, "%-20s %+d (x%.2f)\n", word, fb.level, b.Factor(word))
+		}
+	}
+	return out.String()
+}
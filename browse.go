@@ -0,0 +1,306 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unicode"
+
+	"golang.org/x/term"
+)
+
+// Keys of the browser sent as escape sequences
+const (
+	keyUp = -10 - iota
+	keyDown
+	keyPageUp
+	keyPageDown
+)
+
+var browseKeys = map[string]rune{
+	"\x1b[A": keyUp, "\x1bOA": keyUp,
+	"\x1b[B": keyDown, "\x1bOB": keyDown,
+	"\x1b[5~": keyPageUp,
+	"\x1b[6~": keyPageDown,
+}
+
+// A learned word as the browser lists it
+type browseEntry struct {
+	word string
+	Usage
+}
+
+// Lists the learned words of a profile for searching through them and editing,
+// boosting or deleting them. Every change is saved right away
+type Browser struct {
+	paths      DataPaths
+	counts     Snapshot
+	boosts     Boosts
+	tombstones Tombstones
+
+	filter   []rune        // typed search, matching anywhere in a word
+	shown    []browseEntry // the words matching it, most used first
+	selected int
+	top      int    // first shown row
+	prompt   []rune // the edited "word count" when editing, nil otherwise
+	status   string
+}
+
+// Loads the learned words at paths. The learn log should be compacted first,
+// words only in the log are not listed
+func NewBrowser(paths DataPaths) (*Browser, error) {
+	b := &Browser{paths: paths}
+	var err error
+	if b.counts, err = LoadSnapshot(paths.snapshot); err != nil {
+		return nil, err
+	}
+	if b.boosts, err = LoadBoosts(paths.boosts); err != nil {
+		return nil, err
+	}
+	if b.tombstones, err = LoadTombstones(paths.tombstones); err != nil {
+		return nil, err
+	}
+	b.refresh()
+	return b, nil
+}
+
+// Lists the words matching the filter again, keeping the selection in range
+func (b *Browser) refresh() {
+	filter := strings.ToLower(string(b.filter))
+	b.shown = b.shown[:0]
+	for word, u := range b.counts {
+		if strings.Contains(strings.ToLower(word), filter) {
+			b.shown = append(b.shown, browseEntry{word, u})
+		}
+	}
+	sort.Slice(b.shown, func(i, j int) bool {
+		if b.shown[i].count != b.shown[j].count {
+			return b.shown[i].count > b.shown[j].count
+		}
+		return b.shown[i].word < b.shown[j].word
+	})
+	b.selected = max(0, min(b.selected, len(b.shown)-1))
+}
+
+// Handles a read from the terminal and reports whether the browser keeps running
+func (b *Browser) Feed(chunk []byte) bool {
+	if chunk[0] == ESCAPE {
+		if len(chunk) > 1 {
+			if key, ok := browseKeys[string(chunk)]; ok {
+				b.Key(key)
+			}
+			return true
+		} else if b.prompt == nil {
+			return false
+		}
+	}
+	for _, r := range string(chunk) {
+		if r == CTRL_C {
+			return false
+		}
+		b.Key(r)
+	}
+	return true
+}
+
+// Handles one key
+func (b *Browser) Key(key rune) {
+	b.status = ""
+	if b.prompt != nil {
+		switch {
+		case key == ESCAPE:
+			b.prompt = nil
+		case key == '\r' || key == '\n':
+			b.status = b.edit(string(b.prompt))
+			b.prompt = nil
+		case key == BACKSPACE || key == DELETE:
+			b.prompt = b.prompt[:max(0, len(b.prompt)-1)]
+		case unicode.IsPrint(key):
+			b.prompt = append(b.prompt, key)
+		}
+		return
+	}
+
+	switch key {
+	case keyUp, keyDown, keyPageUp, keyPageDown:
+		step := map[rune]int{keyUp: -1, keyDown: 1, keyPageUp: -10, keyPageDown: 10}[key]
+		b.selected = max(0, min(b.selected+step, len(b.shown)-1))
+		return
+	case BACKSPACE, DELETE:
+		b.filter = b.filter[:max(0, len(b.filter)-1)]
+		b.refresh()
+		return
+	}
+	if key > 0 && unicode.IsPrint(key) {
+		b.filter = append(b.filter, key)
+		b.selected = 0
+		b.refresh()
+		return
+	}
+
+	if len(b.shown) == 0 {
+		return
+	}
+	entry := b.shown[b.selected]
+	switch key {
+	case CTRL_E:
+		b.prompt = []rune(fmt.Sprintf("%s %d", entry.word, entry.count))
+	case CTRL_B, CTRL_U:
+		if key == CTRL_B {
+			if b.boosts[entry.word] == nil {
+				b.boosts[entry.word] = &Boost{}
+			}
+			b.boosts[entry.word].level = min(b.boosts[entry.word].level+1, maxBoost)
+		} else {
+			b.boosts.Reset([]string{entry.word})
+		}
+		b.status = fmt.Sprintf("%s ranks x%.2f", entry.word, b.boosts.Factor(entry.word))
+		b.save(b.boosts.Save(b.paths.boosts))
+	case CTRL_D:
+		delete(b.counts, entry.word)
+		b.boosts.Reset([]string{entry.word})
+		b.tombstones[entry.word] = time.Now()
+		b.status = "deleted " + entry.word
+		b.save(SaveSnapshot(b.paths.snapshot, b.counts), b.tombstones.Save(b.paths.tombstones), b.boosts.Save(b.paths.boosts))
+		b.refresh()
+	}
+}
+
+// Applies the edited "word count" of the selected word: a new spelling moves
+// its counts, a new count replaces it. Returns the status to show
+func (b *Browser) edit(line string) string {
+	entry := b.shown[b.selected]
+	fields := strings.Fields(line)
+	if len(fields) == 0 || len(fields) > 2 {
+		return "expected a word and a count"
+	}
+	word, u := fields[0], entry.Usage
+	if len(fields) == 2 {
+		count, err := strconv.Atoi(fields[1])
+		if err != nil || count <= 0 {
+			return fmt.Sprintf("invalid count %q", fields[1])
+		}
+		u.count = count
+	}
+	if word != entry.word {
+		// The old spelling is forgotten, the new one is not any more
+		delete(b.counts, entry.word)
+		b.tombstones[entry.word] = time.Now()
+		delete(b.tombstones, word)
+		if existing, ok := b.counts[word]; ok {
+			u.count += existing.count
+			if existing.last.After(u.last) {
+				u.last = existing.last
+			}
+		}
+	}
+	b.counts[word] = u
+	b.save(SaveSnapshot(b.paths.snapshot, b.counts), b.tombstones.Save(b.paths.tombstones))
+	b.refresh()
+	for i, e := range b.shown {
+		if e.word == word {
+			b.selected = i
+		}
+	}
+	return fmt.Sprintf("%s used %d times", word, u.count)
+}
+
+// Shows the first error of a save, if any
+func (b *Browser) save(errs ...error) {
+	for _, err := range errs {
+		if err != nil {
+			b.status = "saving failed: " + err.Error()
+			return
+		}
+	}
+}
+
+// Draws the browser on a screen of width x height
+func (b *Browser) View(width, height int) string {
+	rows := max(1, height-3)
+	if b.selected < b.top {
+		b.top = b.selected
+	} else if b.selected >= b.top+rows {
+		b.top = b.selected - rows + 1
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "search: %s\033[2m  %d of %d words  ↑↓ move  ^E edit  ^B boost  ^U unboost  ^D delete  ESC quit\033[0m\r\n",
+		string(b.filter), len(b.shown), len(b.counts))
+	for i := b.top; i < min(len(b.shown), b.top+rows); i++ {
+		e := b.shown[i]
+		last := "-"
+		if !e.last.IsZero() {
+			last = e.last.Format("2006-01-02")
+		}
+		boost := ""
+		if fb := b.boosts[e.word]; fb != nil && fb.level != 0 {
+			boost = fmt.Sprintf("%+d", fb.level)
+		}
+		line := fitTo(fmt.Sprintf("%-24s %8d  %-10s  %s", e.word, e.count, last, boost), width)
+		if i == b.selected {
+			line = "\033[7m" + line + "\033[0m"
+		}
+		out.WriteString(line + "\r\n")
+	}
+	if b.prompt != nil {
+		fmt.Fprintf(&out, "\r\nedit (word count, ENTER saves, ESC cancels): %s", string(b.prompt))
+	} else if b.status != "" {
+		fmt.Fprintf(&out, "\r\n\033[2m%s\033[0m", b.status)
+	}
+	return out.String()
+}
+
+// Cuts s to width runes
+func fitTo(s string, width int) string {
+	if r := []rune(s); width > 0 && len(r) > width {
+		return string(r[:width])
+	}
+	return s
+}
+
+// autocomplete browse
+// Searchable list of the learned words of the active profile, see Browser
+func browseCommand(args []string) error {
+	// Everything learned goes into the snapshot first, so every word is listed
+	if fileSize(paths.learnLog) > 0 {
+		tombstones, err := LoadTombstones(paths.tombstones)
+		if err != nil {
+			return err
+		}
+		if _, err := Compact(paths.learnLog, paths.snapshot, paths.phrases, tombstones, PrunePolicy{}, time.Now()); err != nil {
+			return err
+		}
+	}
+	b, err := NewBrowser(paths)
+	if err != nil {
+		return err
+	}
+
+	restore, err := makeRaw()
+	if err != nil {
+		return err
+	}
+	defer restore()
+	defer fmt.Print("\033[H\033[2J")
+
+	var buf [64]byte
+	for {
+		width, height, err := term.GetSize(int(syscall.Stdout))
+		if err != nil || height == 0 {
+			width, height = 80, 24
+		}
+		fmt.Print("\033[H\033[2J" + b.View(width, height))
+		n, err := os.Stdin.Read(buf[:])
+		if err != nil {
+			return err
+		}
+		if n > 0 && !b.Feed(buf[:n]) {
+			return nil
+		}
+	}
+}
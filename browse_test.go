@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// The browser searches the learned words and saves what is changed right away
+func TestBrowser(t *testing.T) {
+	p := pathsIn(t.TempDir())
+	counts := Snapshot{}
+	counts.Add("hello", 5, time.Now())
+	counts.Add("helo", 2, time.Now())
+	counts.Add("world", 9, time.Now())
+	if err := SaveSnapshot(p.snapshot, counts); err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewBrowser(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Feed([]byte("hel"))
+	if len(b.shown) != 2 || b.shown[0].word != "hello" {
+		t.Fatalf("search listed %v", b.shown)
+	}
+	b.Feed([]byte("\x1b[B"))
+	b.Feed([]byte{CTRL_E})
+	b.Feed([]byte{BACKSPACE, BACKSPACE, BACKSPACE, BACKSPACE})
+	b.Feed([]byte("llo 1\r"))
+	b.Feed([]byte{CTRL_B})
+	if running := b.Feed([]byte{ESCAPE}); running {
+		t.Fatal("ESC kept the browser running")
+	}
+
+	saved, _ := LoadSnapshot(p.snapshot)
+	tombstones, _ := LoadTombstones(p.tombstones)
+	boosted, _ := LoadBoosts(p.boosts)
+	if saved["hello"].count != 6 || saved["helo"].count != 0 || tombstones["helo"].IsZero() || boosted.Factor("hello") <= 1 {
+		t.Fatalf("saved %v, tombstones %v, boosts %v", saved, tombstones, boosted)
+	}
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	_ "embed"
+	"strings"
+
+	"github.com/b0tShaman/autocomplete-cli/trie"
+)
+
+// Common English words shipped with the binary, so suggestions work before any
+// dictionary is set up. They go below the dictionary, each used once, so the
+// dictionary and the learned words outrank them
+//
+//go:embed english.txt
+var builtinWords string
+
+// Inserts the built-in words into t
+func addBuiltinWords(t *trie.Trie) {
+	for _, word := range strings.Fields(builtinWords) {
+		t.Insert(word)
+	}
+}
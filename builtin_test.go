@@ -0,0 +1,29 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// The built-in words go below the dictionary, and make up for a missing words.txt
+func TestBuiltinWords(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", dir)
+	v := NewVerifier(defaultConfig())
+	if base, _, problems := loadBase(defaultConfig().dictSpecs(defaultConfig().Dictionary), false, true, v); len(problems) > 0 || base.Count("people") != 1 {
+		t.Errorf("without words.txt: people %d, problems %q", base.Count("people"), problems)
+	}
+	if base, _, problems := loadBase(defaultConfig().dictSpecs(filepath.Join(dir, "missing.txt")), false, true, v); len(problems) != 1 || base.Count("people") != 1 {
+		t.Errorf("missing dictionary: people %d, problems %q", base.Count("people"), problems)
+	}
+
+	words := filepath.Join(dir, "words.txt")
+	os.WriteFile(words, []byte("people peoples\n"), 0644)
+	if base, _, _ := loadBase(defaultConfig().dictSpecs(words), false, true, v); base.Count("people") != 2 || base.Count("peoples") != 1 {
+		t.Errorf("people %d, peoples %d", base.Count("people"), base.Count("peoples"))
+	}
+	if base, _, _ := loadBase(defaultConfig().dictSpecs(words), false, false, v); base.Count("people") != 1 || base.Count("world") != 0 {
+		t.Errorf("builtin_words = false: people %d, world %d", base.Count("people"), base.Count("world"))
+	}
+}
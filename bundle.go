@@ -0,0 +1,194 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	bundleHeader  = "bundle.json" // first entry of every bundle
+	bundleFormat  = "autocomplete-cli/bundle"
+	bundleVersion = 1
+)
+
+// Describes the contents of a bundle. Dictionaries are stored under dictionaries/,
+// the learned data of the exported profile under profile/
+type BundleHeader struct {
+	Format  string    `json:"format"`
+	Version int       `json:"version"`
+	Created time.Time `json:"created"`
+	Profile string    `json:"profile,omitempty"`
+	Files   []string  `json:"files"`
+}
+
+// Files of a bundle and where they come from on this machine, keyed by their name in the bundle
+func bundleFiles(cfg Config) map[string]string {
+	files := make(map[string]string)
+	add := func(dir, file string) {
+		if _, err := os.Stat(file); err == nil {
+			files[path.Join(dir, filepath.Base(file))] = file
+		}
+	}
+
+	for _, dict := range cfg.dictionaryFiles() {
+		add("dictionaries", dict)
+		add("dictionaries", filepath.Join(filepath.Dir(dict), manifestFile))
+		add("dictionaries", filepath.Join(filepath.Dir(dict), manifestFile+".sig"))
+	}
+	for _, file := range []string{paths.snapshot, paths.phrases, paths.learnLog, paths.snippets, paths.tombstones, paths.ranker, paths.model, paths.pins, paths.typos, paths.temporary, paths.files} {
+		add("profile", file)
+	}
+	return files
+}
+
+// Writes the dictionaries and learned data into a gzipped tar archive at dest
+func ExportBundle(cfg Config, dest string) (*BundleHeader, error) {
+	files := bundleFiles(cfg)
+	header := &BundleHeader{Format: bundleFormat, Version: bundleVersion, Created: time.Now(), Profile: cfg.Profile}
+	for name := range files {
+		header.Files = append(header.Files, name)
+	}
+	sort.Strings(header.Files)
+
+	f, err := os.Create(dest)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	data, _ := json.MarshalIndent(header, "", "  ")
+	if err := writeTarFile(tw, bundleHeader, data); err != nil {
+		return nil, err
+	}
+	for _, name := range header.Files {
+		data, err := os.ReadFile(files[name])
+		if err != nil {
+			return nil, err
+		}
+		if err := writeTarFile(tw, name, data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return header, f.Close()
+}
+
+func writeTarFile(tw *tar.Writer, name string, data []byte) error {
+	hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: time.Now()}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// Unpacks a bundle into the data directory and the active profile. Files which
+// already exist are kept with a .bak suffix
+func ImportBundle(src string) (*BundleHeader, error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: not a bundle: %w", src, err)
+	}
+	tr := tar.NewReader(gz)
+
+	hdr, err := tr.Next()
+	if err != nil || hdr.Name != bundleHeader {
+		return nil, fmt.Errorf("%s: not a bundle, %s missing", src, bundleHeader)
+	}
+	var header BundleHeader
+	if err := json.NewDecoder(tr).Decode(&header); err != nil || header.Format != bundleFormat {
+		return nil, fmt.Errorf("%s: not a bundle", src)
+	}
+	if header.Version > bundleVersion {
+		return nil, fmt.Errorf("%s: bundle version %d is newer than the supported %d", src, header.Version, bundleVersion)
+	}
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		dir, name := path.Split(hdr.Name)
+		var dest string
+		switch dir {
+		case "dictionaries/":
+			dest = filepath.Join(dataDir(), name)
+		case "profile/":
+			dest = filepath.Join(paths.dir, name)
+		}
+		// Nothing is written outside those two directories
+		if dest == "" || name == "" || name != filepath.Base(name) || name == ".." {
+			return nil, fmt.Errorf("%s: unexpected file %q", src, hdr.Name)
+		}
+
+		if _, err := os.Stat(dest); err == nil {
+			if err := os.Rename(dest, dest+".bak"); err != nil {
+				return nil, err
+			}
+		}
+		out, err := os.Create(dest)
+		if err != nil {
+			return nil, err
+		}
+		_, err = io.Copy(out, tr)
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &header, nil
+}
+
+// autocomplete export-bundle <file>
+// Bundles the dictionaries and learned data of the active profile into file
+func exportBundleCommand(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: autocomplete export-bundle <file>")
+	}
+	cfg, _ := LoadConfig(configPath())
+	header, err := ExportBundle(cfg, args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Printf("exported %d files to %s\n", len(header.Files), args[0])
+	return nil
+}
+
+// autocomplete import-bundle <file>
+// Restores a bundle made by export-bundle into the active profile
+func importBundleCommand(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: autocomplete import-bundle <file>")
+	}
+	header, err := ImportBundle(args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Printf("imported %d files made %s (bundle version %d)\n", len(header.Files), header.Created.Format(time.DateTime), header.Version)
+	return nil
+}
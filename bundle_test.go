@@ -0,0 +1,76 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// A bundle brings back the dictionary and the learned data, keeping what it
+// replaces with a .bak suffix
+func TestBundleRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", dir)
+	old := paths
+	paths = pathsIn(filepath.Join(dir, "profile"))
+	t.Cleanup(func() { paths = old })
+	os.MkdirAll(paths.dir, 0755)
+	os.MkdirAll(dataDir(), 0755)
+	cfg := defaultConfig()
+	cfg.Dictionary = filepath.Join(dataDir(), "words.txt")
+	os.WriteFile(cfg.Dictionary, []byte("quokka\n"), 0644)
+	os.WriteFile(paths.snapshot, []byte("gopher\t3\n"), 0644)
+
+	bundle := filepath.Join(dir, "backup.tar.gz")
+	header, err := ExportBundle(cfg, bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"dictionaries/words.txt", "profile/" + snapshotFile}; strings.Join(header.Files, " ") != strings.Join(want, " ") {
+		t.Fatalf("exported %v, want %v", header.Files, want)
+	}
+
+	os.WriteFile(cfg.Dictionary, []byte("wombat\n"), 0644)
+	os.Remove(paths.snapshot)
+	if _, err := ImportBundle(bundle); err != nil {
+		t.Fatal(err)
+	}
+	for file, want := range map[string]string{cfg.Dictionary: "quokka\n", cfg.Dictionary + ".bak": "wombat\n", paths.snapshot: "gopher\t3\n"} {
+		if got, err := os.ReadFile(file); err != nil || string(got) != want {
+			t.Errorf("%s: %q, %v, want %q", filepath.Base(file), got, err, want)
+		}
+	}
+}
+
+// Nothing of a bundle is written outside the data directory and the profile
+func TestBundleEscape(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", filepath.Join(dir, "data"))
+	old := paths
+	paths = pathsIn(filepath.Join(dir, "data", "profile"))
+	t.Cleanup(func() { paths = old })
+	os.MkdirAll(paths.dir, 0755)
+	os.MkdirAll(dataDir(), 0755)
+
+	for _, name := range []string{"profile/../../evil", "dictionaries/..", "../evil", "/tmp/evil", "profile/sub/evil", "evil"} {
+		bundle := filepath.Join(dir, "bad.tar.gz")
+		f, _ := os.Create(bundle)
+		gz := gzip.NewWriter(f)
+		tw := tar.NewWriter(gz)
+		writeTarFile(tw, bundleHeader, []byte(`{"format": "`+bundleFormat+`", "version": 1}`))
+		writeTarFile(tw, name, []byte("gotcha"))
+		tw.Close()
+		gz.Close()
+		f.Close()
+
+		if _, err := ImportBundle(bundle); err == nil {
+			t.Errorf("imported %q", name)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "evil")); err == nil {
+			t.Fatalf("%q written outside the data directory", name)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"slices"
+
+	"github.com/b0tShaman/autocomplete-cli/trie"
+)
+
+const (
+	maxTranslated = 3   // top suggestions whose translations are offered in bilingual mode
+	contextWords  = 5   // words typed before the current one which are passed to the sources
+	maxTypos      = 2   // most typos the fuzzy option tolerates
+	maxCompletion = 100 // best trie completions of a word offered, nobody cycles through more
+)
+
+// A suggestion for the word being typed
+type Candidate struct {
+	word   string // full word which replaces the word being typed on accept
+	label  string // optional text for the status line, Eg:- the source of a translation
+	source string // where the candidate came from, Eg:- trie, snippet or plugin:emoji
+}
+
+// Collects suggestions for the word being typed after the previous words (oldest
+// first, see prompt.PreviousWords). Snippets whose abbreviation starts with the
+// word come first, then the predictions of the model and the trie completions,
+// followed by the translations of the best completions (and of the word itself).
+// Plugins go before or after all of them depending on their priority. Trie
+// completions and predictions are reranked by the tag rules. Without any, warmed
+// completions are used when cached
+func buildCandidates(t *trie.Stack, score trie.Scorer, warm *PrefixCache, bi Bilingual, snippets Snippets, plugins []*Plugin, model *Model, tags TagRanking, previous []string, word string) []Candidate {
+	var result []Candidate
+	if len(word) == 0 {
+		return result
+	}
+
+	// plugins are sorted by priority
+	split := len(plugins)
+	for i, p := range plugins {
+		if p.Priority <= 0 {
+			split = i
+			break
+		}
+	}
+
+	answers := pluginCandidates(plugins, previous, word)
+	result = append(result, slices.Concat(answers[:split]...)...)
+	result = append(result, snippets.Candidates(word)...)
+
+	predicted := slices.DeleteFunc(model.Candidates(previous, word), func(c Candidate) bool {
+		return tags.Factor(previous, c.word) == 0
+	})
+	result = append(result, predicted...)
+
+	translated := []string{word}
+	tagged := func(w string, count int) float64 { return score(w, count) * tags.Factor(previous, w) }
+	var completions []string
+	ok := false
+	if len(tags.rules) == 0 {
+		completions, ok = warm.Get(word)
+	}
+	if !ok {
+		completions = t.AutofillTop(word, maxCompletion, tagged)
+	}
+	for _, suffix := range completions {
+		if slices.ContainsFunc(predicted, func(c Candidate) bool { return c.word == word+suffix }) {
+			continue
+		}
+		result = append(result, Candidate{word: word + suffix, source: "trie"})
+		if len(translated) <= maxTranslated {
+			translated = append(translated, word+suffix)
+		}
+	}
+
+	result = append(result, bi.Translate(translated)...)
+	return append(result, slices.Concat(answers[split:]...)...)
+}
+
+// Words up to fuzzy typos away from word which are not among candidates, fewest
+// typos first. Short words tolerate fewer typos, one per two letters after the
+// first, or everything would be a match. Eg:- teh --> the
+func fuzzyCandidates(t *trie.Stack, score trie.Scorer, fuzzy int, word string, candidates []Candidate) []Candidate {
+	if fuzzy = min(fuzzy, (len([]rune(word))-1)/2); fuzzy <= 0 {
+		return nil
+	}
+	var result []Candidate
+	for _, m := range t.AutofillFuzzy(word, fuzzy, score) {
+		if m.Edits > 0 && !slices.ContainsFunc(candidates, func(c Candidate) bool { return c.word == m.Value }) {
+			result = append(result, Candidate{word: m.Value, source: "fuzzy"})
+		}
+	}
+	return result
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/b0tShaman/autocomplete-cli/trie"
+)
+
+// How the typed word matches the words it completes to, see matchCase
+const (
+	matchExact = "exact" // only the words starting with it as typed
+	matchTyped = "typed" // in any case, keeping the letters as typed
+	matchWord  = "word"  // in any case, spelled as learned
+)
+
+// How a suggested word is cased, see caseMode
+const (
+	caseLower = iota
+	caseTitle
+	caseUpper
+)
+
+// Merges the case variants among the trie completions of word, Eg:- The, the
+// and THE, into one suggestion ranked by the uses of all of them together.
+// It is cased for where it goes, see caseMode, except words spelled in keep,
+// which keep that spelling, and words never learned in lower case, which keep
+// the one learned most (Eg:- NASA, Paris). Words mixing cases some other way,
+// Eg:- iPhone, are no variants of anything
+func foldCase(t *trie.Stack, score trie.Scorer, keep []string, previous []string, word string, candidates []Candidate) []Candidate {
+	if word == "" {
+		return candidates
+	}
+	// The completions of the word as typed and of its other casings
+	words, rest, at := splitTrie(candidates)
+	words = append(words, t.AutofillFold(word, score)...)
+
+	type group struct {
+		key      string
+		variants []string // most used first
+		score    float64
+	}
+	groups := make(map[string]*group)
+	var keys []string
+	for _, w := range words {
+		key := w
+		if plainCase(w) {
+			key = strings.ToLower(w)
+		}
+		g := groups[key]
+		if g == nil {
+			g = &group{key: key}
+			groups[key] = g
+			keys = append(keys, key)
+		}
+		if !slices.Contains(g.variants, w) {
+			g.variants = append(g.variants, w)
+			g.score += score(w, t.Count(w))
+		}
+	}
+	sort.SliceStable(keys, func(i, j int) bool { return groups[keys[i]].score > groups[keys[j]].score })
+	for _, g := range groups {
+		sort.SliceStable(g.variants, func(i, j int) bool { return t.Count(g.variants[i]) > t.Count(g.variants[j]) })
+	}
+
+	mode := caseMode(word, previous)
+	var folded []Candidate
+	for _, key := range keys {
+		g := groups[key]
+		w := g.variants[0]
+		switch i := slices.IndexFunc(keep, func(k string) bool { return strings.EqualFold(k, key) }); {
+		case i >= 0:
+			w = keep[i]
+		case !plainCase(w):
+		case mode == caseUpper:
+			w = strings.ToUpper(key)
+		case !slices.Contains(g.variants, key):
+			// A name or an acronym, as it was learned
+		case mode == caseTitle:
+			w = titleCase(key)
+		default:
+			w = key
+		}
+		if same := func(c Candidate) bool { return c.word == w }; !slices.ContainsFunc(folded, same) && !slices.ContainsFunc(rest, same) {
+			folded = append(folded, Candidate{word: w, source: "trie"})
+		}
+	}
+	return slices.Concat(rest[:at], folded, rest[at:])
+}
+
+// Adds the words which start with word in another case, or with other accents
+// when accents is set, to the trie completions among candidates, ranked with
+// them. matchTyped keeps the case of the letters as typed, Eg:- Hel --> Hello
+// from hello, matchWord spells them as learned, Eg:- hel --> Hello. Accents are
+// always as learned, Eg:- cafe --> café
+func matchCase(t *trie.Stack, score trie.Scorer, mode string, accents bool, word string, candidates []Candidate) []Candidate {
+	if (mode == matchExact && !accents) || word == "" {
+		return candidates
+	}
+	fold := func(r rune) rune { return r }
+	if accents {
+		fold = stripAccent
+	}
+	if mode != matchExact {
+		plain := fold
+		fold = func(r rune) rune { return unicode.ToLower(plain(r)) }
+	}
+
+	words, rest, at := splitTrie(candidates)
+	scores := make(map[string]float64)
+	for _, w := range words {
+		scores[w] = score(w, t.Count(w))
+	}
+	for _, w := range t.AutofillFunc(word, fold, score) {
+		learned := w
+		if mode == matchTyped {
+			w = keepTyped(word, learned)
+		}
+		if _, ok := scores[w]; !ok {
+			words = append(words, w)
+		}
+		scores[w] = max(scores[w], score(learned, t.Count(learned)))
+	}
+	sort.SliceStable(words, func(i, j int) bool { return scores[words[i]] > scores[words[j]] })
+
+	var matched []Candidate
+	for _, w := range words {
+		if !slices.ContainsFunc(rest, func(c Candidate) bool { return c.word == w }) {
+			matched = append(matched, Candidate{word: w, source: "trie"})
+		}
+	}
+	return slices.Concat(rest[:at], matched, rest[at:])
+}
+
+// learned with its letters cased like those of typed, which it starts with.
+// Eg:- CAFe, café --> CAFé
+func keepTyped(typed, learned string) string {
+	var letters []rune
+	for _, r := range typed {
+		if !unicode.Is(unicode.Mn, r) {
+			letters = append(letters, r)
+		}
+	}
+	var b strings.Builder
+	for _, r := range learned {
+		if len(letters) > 0 && !unicode.Is(unicode.Mn, r) {
+			if unicode.IsUpper(letters[0]) {
+				r = unicode.ToUpper(r)
+			} else if unicode.IsLower(letters[0]) {
+				r = unicode.ToLower(r)
+			}
+			letters = letters[1:]
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Splits the trie completions off candidates. Returns their words, the other
+// candidates and where among those the completions go
+func splitTrie(candidates []Candidate) (words []string, rest []Candidate, at int) {
+	at = slices.IndexFunc(candidates, func(c Candidate) bool { return c.source == "trie" })
+	if at < 0 {
+		if at = slices.IndexFunc(candidates, func(c Candidate) bool { return c.source == "translation" }); at < 0 {
+			at = len(candidates)
+		}
+	}
+	for _, c := range candidates {
+		if c.source == "trie" {
+			words = append(words, c.word)
+		} else {
+			rest = append(rest, c)
+		}
+	}
+	return words, rest, min(at, len(rest))
+}
+
+// How the completion of word goes: upper case after an upper case word of two
+// letters or more, capitalised after a capitalised one or at the start of a
+// sentence, lower case otherwise. Eg:- TH --> THE, Th --> The, "Hi. th" --> The
+func caseMode(word string, previous []string) int {
+	r := []rune(word)
+	if len(r) > 1 && strings.ToUpper(word) == word && strings.ToLower(word) != word {
+		return caseUpper
+	}
+	if unicode.IsUpper(r[0]) || len(previous) == 0 {
+		return caseTitle
+	}
+	if last := previous[len(previous)-1]; strings.ContainsAny(last[len(last)-1:], ".!?") {
+		return caseTitle
+	}
+	return caseLower
+}
+
+// Reports whether word is in lower case, capitalised or in upper case
+func plainCase(word string) bool {
+	lower := strings.ToLower(word)
+	return word == lower || word == strings.ToUpper(word) || word == titleCase(lower)
+}
+
+// word with its first letter in upper case and the others in lower case.
+// Eg:- hELLO --> Hello
+func titleCase(word string) string {
+	r := []rune(strings.ToLower(word))
+	if len(r) > 0 {
+		r[0] = unicode.ToUpper(r[0])
+	}
+	return string(r)
+}
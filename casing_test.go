@@ -0,0 +1,87 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/b0tShaman/autocomplete-cli/trie"
+)
+
+// The, the and THE are suggested once, cased for where the word goes
+func TestFoldCase(t *testing.T) {
+	tr := newWords(trie.New())
+	tr.Insert(baseLayer, "the")
+	tr.Insert(baseLayer, "then")
+	tr.Insert(baseLayer, "paris")
+	tr.InsertCount(userLayer, "The", 3)
+	tr.Insert(userLayer, "THE")
+	tr.Insert(userLayer, "NASA")
+	keep := []string{"Paris"}
+	score := (&profile{}).score
+	suggest := func(previous []string, word string) []string {
+		var candidates []Candidate
+		for _, suffix := range tr.AutofillScored(word, score) {
+			candidates = append(candidates, Candidate{word: word + suffix, source: "trie"})
+		}
+		var words []string
+		for _, c := range foldCase(tr, score, keep, previous, word, candidates) {
+			words = append(words, c.word)
+		}
+		return words
+	}
+	for _, c := range []struct {
+		previous []string
+		word     string
+		want     []string
+	}{
+		{[]string{"so"}, "th", []string{"the", "then"}},
+		{nil, "th", []string{"The", "Then"}},
+		{[]string{"Hi."}, "th", []string{"The", "Then"}},
+		{[]string{"so"}, "Th", []string{"The", "Then"}},
+		{[]string{"so"}, "TH", []string{"THE", "THEN"}},
+		{[]string{"so"}, "na", []string{"NASA"}},
+		{[]string{"so"}, "par", []string{"Paris"}},
+	} {
+		if got := suggest(c.previous, c.word); !slices.Equal(got, c.want) {
+			t.Errorf("%v %s: %v, want %v", c.previous, c.word, got, c.want)
+		}
+	}
+}
+
+// match_case completes the typed word in any case, keeping the typed letters
+// or spelling the words as learned, match_accents with any accents
+func TestEditorMatchCase(t *testing.T) {
+	for _, c := range []struct {
+		mode        string
+		accents     bool
+		typed, want string // want is what is accepted, "" for no suggestion
+	}{
+		{matchExact, false, "Hel", ""},
+		{matchTyped, false, "Hel", "Hello "},
+		{matchTyped, false, "zü", "zürich "},
+		{matchWord, false, "zü", "Zürich "},
+		{matchWord, false, "HELm", "helmet "},
+		{matchExact, false, "cafe", ""},
+		{matchExact, true, "cafe", "café "},
+		{matchExact, true, "nai", "naïve "},
+		{matchExact, true, "zu", ""},
+		{matchTyped, true, "ZU", "ZÜrich "},
+		{matchWord, true, "zu", "Zürich "},
+	} {
+		h := newHarness(t, t.TempDir())
+		h.e.trie.Insert(baseLayer, "Zürich")
+		h.e.cfg.MatchCase, h.e.cfg.MatchAccents = c.mode, c.accents
+		h.feed([]byte(c.typed))
+		h.pause()
+		if !h.e.triggered {
+			if c.want != "" {
+				t.Errorf("%s %v %s: no suggestion", c.mode, c.accents, c.typed)
+			}
+			continue
+		}
+		h.feed([]byte("\r"))
+		if got := string(h.e.input); got != c.want {
+			t.Errorf("%s %v %s: accepted %q, want %q", c.mode, c.accents, c.typed, got, c.want)
+		}
+	}
+}
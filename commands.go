@@ -0,0 +1,150 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Subcommands run instead of the editor. Eg:- autocomplete snippets list
+var commands = map[string]func(args []string) error{
+	"snippets":      snippetsCommand,
+	"rebalance":     rebalanceCommand,
+	"compact":       compactCommand,
+	"forget":        forgetCommand,
+	"vacuum":        vacuumCommand,
+	"setup":         setupCommand,
+	"manifest":      manifestCommand,
+	"verify":        verifyCommand,
+	"report":        reportCommand,
+	"ranker":        rankerCommand,
+	"train":         trainCommand,
+	"model":         modelCommand,
+	"export-bundle": exportBundleCommand,
+	"import-bundle": importBundleCommand,
+	"ignores":       ignoresCommand,
+	"pins":          pinsCommand,
+	"typos":         typosCommand,
+	"files":         filesCommand,
+	"temporary":     temporaryCommand,
+	"packs":         packsCommand,
+	"serve":         serveCommand,
+	"suggest":       suggestCommand,
+	"explain":       explainCommand,
+	"team":          teamCommand,
+	"admin":         adminCommand,
+	"doctor":        doctorCommand,
+	"diff":          diffCommand,
+	"bench":         benchCommand,
+	"compile":       compileCommand,
+	"browse":        browseCommand,
+	"control":       controlPipeCommand,
+}
+
+// Flags given before the command. They override the config by setting its
+// AUTOCOMPLETE_* variables, so config reloads and every command see them too
+var globalFlags = []struct {
+	name, env, usage string
+	check            func(string) error
+}{
+	{"config", envPrefix + "_CONFIG", "config file to read instead of config.toml", nil},
+	{"dict", envPrefix + "_DICTIONARY", "word list to load, again for more of them, Eg:- --dict words.txt --dict medical.txt:weight=2", func(v string) error {
+		_, err := parseDictSpec(v)
+		return err
+	}},
+	{"delay", envPrefix + "_DEBOUNCE", "pause in typing before suggestions show up, Eg:- 100ms, 0 to suggest on every keystroke", func(v string) error {
+		_, err := time.ParseDuration(v)
+		return err
+	}},
+	{"max-suggestions", envPrefix + "_MAX_SUGGESTIONS", "suggestions TAB cycles through, 0 for all of them", func(v string) error {
+		_, err := strconv.Atoi(v)
+		return err
+	}},
+	{"profile", envPrefix + "_PROFILE", "learned data to use", nil},
+	{"recap", envPrefix + "_RECAP", "session recap printed on exit: short, full or off", nil},
+}
+
+// Parses the flags in front of the command and returns the command with its arguments
+func parseFlags(args []string) ([]string, error) {
+	fs := flag.NewFlagSet("autocomplete", flag.ContinueOnError)
+	var dicts []string // the --dict flags, those after the first one are dictionaries
+	for _, f := range globalFlags {
+		fs.Func(f.name, f.usage, func(v string) error {
+			if f.check != nil {
+				if err := f.check(v); err != nil {
+					return err
+				}
+			}
+			if f.name == "config" || f.name == "dict" {
+				// Relative to where the command runs, not to the data directory
+				abs, err := filepath.Abs(v)
+				if err != nil {
+					return err
+				}
+				v = abs
+			}
+			if f.name == "dict" {
+				if dicts = append(dicts, v); len(dicts) > 1 {
+					return os.Setenv(envPrefix+"_DICTIONARIES", strings.Join(dicts[1:], ","))
+				}
+			}
+			return os.Setenv(f.env, v)
+		})
+	}
+	fs.BoolFunc("no-learn", "use the learned data without adding to it", func(v string) error {
+		return os.Setenv(envPrefix+"_NO_LEARN", v)
+	})
+	fs.BoolFunc("low-power", "redraw less often, for slow SSH connections and serial consoles", func(v string) error {
+		return os.Setenv(envPrefix+"_LOW_POWER", v)
+	})
+	fs.BoolFunc("perf", "show the suggestion latency and cache hit rate in the status line", func(v string) error {
+		return os.Setenv(envPrefix+"_PERF", v)
+	})
+	fs.Usage = func() {
+		names := []string{"run"}
+		for name := range commands {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		fmt.Fprintf(fs.Output(), "usage: autocomplete [flags] [command] [arguments]\n\ncommands (run, the editor, when none is given):\n")
+		for _, name := range names {
+			fmt.Fprintf(fs.Output(), "  %s\n", name)
+		}
+		fmt.Fprintf(fs.Output(), "\nflags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	return fs.Args(), nil
+}
+
+// Runs the subcommand named by args[0] and returns the process exit code
+func runCommand(args []string) int {
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", args[0])
+		return 2
+	}
+	cfg, err := LoadConfig(configPath())
+	if err != nil && args[0] != "doctor" { // doctor reports it itself
+		fmt.Fprintln(os.Stderr, "Error: config:", err)
+		return 1
+	}
+	cfg.apply()
+	if err := paths.mkdir(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return 1
+	}
+
+	if err := cmd(args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return 1
+	}
+	return 0
+}
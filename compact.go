@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Snapshot of phrase counts merged out of the learn log, one "count<TAB>phrase" per line
+const phrasesFile = "phrases.txt"
+
+// The editor compacts once the learn log grows past this size and the user is idle, see maintenance.go
+const compactLogSize = 1 << 20
+
+// Which learned words compaction drops
+type PrunePolicy struct {
+	minCount int           // words used fewer times than this ...
+	maxAge   time.Duration // ... and not used within this period are pruned
+}
+
+var defaultPrune = PrunePolicy{minCount: 2, maxAge: 180 * 24 * time.Hour}
+
+// Reports whether u is rare and old enough to be pruned
+func (p PrunePolicy) Prune(u Usage, now time.Time) bool {
+	return u.count < p.minCount && now.Sub(u.last) > p.maxAge
+}
+
+// What a compaction did
+type CompactReport struct {
+	merged int   // learn log entries merged into the snapshots
+	pruned int   // words dropped from the snapshot
+	before int64 // bytes used by the learned data before compacting
+	after  int64 // and after
+}
+
+func (r CompactReport) String() string {
+	return fmt.Sprintf("merged %d log entries, pruned %d words, reclaimed %d bytes (%d -> %d)",
+		r.merged, r.pruned, r.before-r.after, r.before, r.after)
+}
+
+// Merges the learn log into the count and phrase snapshots, drops forgotten words,
+// prunes words according to policy and empties the log
+func Compact(logPath, snapshotPath, phrasesPath string, tombstones Tombstones, policy PrunePolicy, now time.Time) (CompactReport, error) {
+	var report CompactReport
+	report.before = fileSize(logPath) + fileSize(snapshotPath) + fileSize(phrasesPath)
+
+	history, err := ReadLearnLog(logPath)
+	if err != nil {
+		return report, err
+	}
+	counts, err := LoadSnapshot(snapshotPath)
+	if err != nil {
+		return report, err
+	}
+	phraseCounts, err := LoadPhraseCounts(phrasesPath)
+	if err != nil {
+		return report, err
+	}
+
+	// Usage from before a word was forgotten goes away, later usage is merged
+	for word, u := range counts {
+		if tombstones.Buried(word, u.last) {
+			delete(counts, word)
+			report.pruned++
+		}
+	}
+	for _, lw := range history {
+		if !tombstones.Buried(lw.word, lw.at) {
+			counts.Add(lw.word, 1, lw.at)
+		}
+	}
+	report.merged = len(history)
+
+	for word, u := range counts {
+		if policy.Prune(u, now) {
+			delete(counts, word)
+			report.pruned++
+		}
+	}
+
+	phrases := NewPhrases(phraseCounts, history)
+	if err := SavePhraseCounts(phrasesPath, phrases.counts); err != nil {
+		return report, err
+	}
+	if err := SaveSnapshot(snapshotPath, counts); err != nil {
+		return report, err
+	}
+	// Only now that everything is safely in the snapshots
+	if err := os.Truncate(logPath, 0); err != nil && !os.IsNotExist(err) {
+		return report, err
+	}
+
+	report.after = fileSize(logPath) + fileSize(snapshotPath) + fileSize(phrasesPath)
+	return report, nil
+}
+
+// Compacts while holding the log, so words learned meanwhile are not lost
+func (l *LearnLog) Compact(snapshotPath, phrasesPath string, tombstones Tombstones, policy PrunePolicy) (CompactReport, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return CompactReport{}, fmt.Errorf("the learn log is closed")
+	}
+	return Compact(l.f.Name(), snapshotPath, phrasesPath, tombstones, policy, time.Now())
+}
+
+// Reads the phrase snapshot. A missing snapshot is the same as an empty one
+func LoadPhraseCounts(path string) (map[string]int, error) {
+	counts := make(map[string]int)
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return counts, nil
+	} else if err != nil {
+		return counts, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		n, phrase, ok := strings.Cut(scanner.Text(), "\t")
+		count, err := strconv.Atoi(n)
+		if ok && err == nil && phrase != "" {
+			counts[phrase] += count
+		}
+	}
+	return counts, scanner.Err()
+}
+
+// Writes the phrases typed more than once, most used first
+func SavePhraseCounts(path string, counts map[string]int) error {
+	var phrases []string
+	for phrase, count := range counts {
+		if count > 1 {
+			phrases = append(phrases, phrase)
+		}
+	}
+	sort.Slice(phrases, func(i, j int) bool {
+		if counts[phrases[i]] != counts[phrases[j]] {
+			return counts[phrases[i]] > counts[phrases[j]]
+		}
+		return phrases[i] < phrases[j]
+	})
+
+	var b strings.Builder
+	for _, phrase := range phrases {
+		fmt.Fprintf(&b, "%d\t%s\n", counts[phrase], phrase)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(b.String()), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Size of the file at path, 0 if it does not exist
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+// autocomplete compact [min count] [max age in days]
+func compactCommand(args []string) error {
+	policy := defaultPrune
+	if len(args) > 0 {
+		if _, err := fmt.Sscan(args[0], &policy.minCount); err != nil {
+			return fmt.Errorf("invalid min count %q", args[0])
+		}
+	}
+	if len(args) > 1 {
+		var days int
+		if _, err := fmt.Sscan(args[1], &days); err != nil {
+			return fmt.Errorf("invalid max age %q", args[1])
+		}
+		policy.maxAge = time.Duration(days) * 24 * time.Hour
+	}
+
+	tombstones, err := LoadTombstones(paths.tombstones)
+	if err != nil {
+		return err
+	}
+	report, err := Compact(paths.learnLog, paths.snapshot, paths.phrases, tombstones, policy, time.Now())
+	if err != nil {
+		return err
+	}
+	fmt.Println(report)
+	return nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Writes a learn log with words typed at the given times
+func writeLearnLog(t *testing.T, path string, words []LearnedWord) {
+	var b strings.Builder
+	for _, lw := range words {
+		fmt.Fprintf(&b, "%d\t%s\n", lw.at.Unix(), lw.word)
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// The log is merged into the snapshots, uses from before a word was forgotten
+// are dropped and rare old words are pruned
+func TestCompact(t *testing.T) {
+	dir := t.TempDir()
+	p := pathsIn(dir)
+	now := time.Now().Truncate(time.Second)
+	day := 24 * time.Hour
+	if err := SaveSnapshot(p.snapshot, Snapshot{
+		"kept":   {5, now.Add(-400 * day)},
+		"old":    {1, now.Add(-365 * day)},
+		"buried": {3, now.Add(-10 * day)},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SavePhraseCounts(p.phrases, map[string]int{"good morning": 4}); err != nil {
+		t.Fatal(err)
+	}
+	writeLearnLog(t, p.learnLog, []LearnedWord{
+		{"buried", now.Add(-7 * day)},
+		{"buried", now.Add(-2 * day)},
+		{"good", now.Add(-3 * time.Minute)},
+		{"morning", now.Add(-3*time.Minute + time.Second)},
+		{"good", now.Add(-2 * time.Minute)},
+		{"morning", now.Add(-2*time.Minute + time.Second)},
+		{"new", now},
+	})
+	tombstones := Tombstones{"buried": now.Add(-5 * day)}
+
+	report, err := Compact(p.learnLog, p.snapshot, p.phrases, tombstones, defaultPrune, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.merged != 7 || report.pruned != 2 {
+		t.Errorf("report %+v", report)
+	}
+	counts, err := LoadSnapshot(p.snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Snapshot{"kept": {5, now.Add(-400 * day)}, "buried": {1, now.Add(-2 * day)}, "good": {2, now.Add(-2 * time.Minute)},
+		"morning": {2, now.Add(-2*time.Minute + time.Second)}, "new": {1, now}}
+	if len(counts) != len(want) {
+		t.Errorf("snapshot %v, want %v", counts, want)
+	}
+	for word, u := range want {
+		if got := counts[word]; got.count != u.count || !got.last.Equal(u.last) {
+			t.Errorf("%s: %+v, want %+v", word, got, u)
+		}
+	}
+	phrases, err := LoadPhraseCounts(p.phrases)
+	if err != nil || phrases["good morning"] != 6 {
+		t.Errorf("phrases %v, %v", phrases, err)
+	}
+	if fileSize(p.learnLog) != 0 {
+		t.Error("learn log not emptied")
+	}
+}
+
+// Nothing is lost when the snapshot cannot be saved: the log stays as it is
+func TestCompactSaveFails(t *testing.T) {
+	dir := t.TempDir()
+	p := pathsIn(dir)
+	writeLearnLog(t, p.learnLog, []LearnedWord{{"hello", time.Now()}})
+	before := fileSize(p.learnLog)
+
+	snapshot := filepath.Join(dir, "missing", snapshotFile)
+	if _, err := Compact(p.learnLog, snapshot, p.phrases, Tombstones{}, defaultPrune, time.Now()); err == nil {
+		t.Fatal("compacted into a directory which does not exist")
+	}
+	if fileSize(p.learnLog) != before {
+		t.Errorf("learn log truncated to %d bytes", fileSize(p.learnLog))
+	}
+}
+
+// Words learned through the log are compacted while it is open, not once it is closed
+func TestLearnLogCompact(t *testing.T) {
+	p := pathsIn(t.TempDir())
+	l, err := OpenLearnLog(p.learnLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Append("hello", time.Now())
+	l.Append("hello", time.Now())
+	if report, err := l.Compact(p.snapshot, p.phrases, Tombstones{}, PrunePolicy{}); err != nil || report.merged != 2 {
+		t.Fatalf("report %+v, %v", report, err)
+	}
+	l.Append("world", time.Now())
+	if counts, err := LoadSnapshot(p.snapshot); err != nil || counts["hello"].count != 2 || fileSize(p.learnLog) == 0 {
+		t.Errorf("snapshot %v, %v, the log holds %d bytes", counts, err, fileSize(p.learnLog))
+	}
+
+	l.Close()
+	if _, err := l.Compact(p.snapshot, p.phrases, Tombstones{}, PrunePolicy{}); err == nil {
+		t.Error("compacted a closed learn log")
+	}
+}
+
+func TestPrunePolicy(t *testing.T) {
+	now := time.Now()
+	old := now.Add(-defaultPrune.maxAge - time.Second)
+	for _, tc := range []struct {
+		policy PrunePolicy
+		u      Usage
+		pruned bool
+	}{
+		{defaultPrune, Usage{defaultPrune.minCount - 1, old}, true},
+		{defaultPrune, Usage{defaultPrune.minCount, old}, false},                               // used often enough
+		{defaultPrune, Usage{defaultPrune.minCount - 1, now.Add(-defaultPrune.maxAge)}, false}, // not older than maxAge
+		{defaultPrune, Usage{0, time.Time{}}, true},                                            // never used
+		{PrunePolicy{}, Usage{0, time.Time{}}, false},                                          // prunes nothing
+		{PrunePolicy{minCount: 10}, Usage{1, now.Add(-time.Second)}, true},                     // any age
+	} {
+		if got := tc.policy.Prune(tc.u, now); got != tc.pruned {
+			t.Errorf("%+v pruning %+v: %v, want %v", tc.policy, tc.u, got, tc.pruned)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/b0tShaman/autocomplete-cli/trie"
+)
+
+// Start of a compiled dictionary, which goes on with the length of the
+// metadata lines of its word list, those lines and the encoded trie. A mapped
+// one has the trie in the layout of trie.Map, it is read where it lies
+const (
+	compiledMagic = "autocomplete-dict 1\n"
+	mappedMagic   = "autocomplete-dict 2\n"
+)
+
+// Reports whether data is a compiled dictionary rather than a word list
+func isCompiled(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(compiledMagic)) || bytes.HasPrefix(data, []byte(mappedMagic))
+}
+
+// Reads the dictionary at path, memory mapping it when it is a mapped one
+func readDictionaryFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	magic := make([]byte, len(mappedMagic))
+	n, _ := io.ReadFull(f, magic)
+	f.Close()
+	if string(magic[:n]) == mappedMagic {
+		return mapFile(path)
+	}
+	return os.ReadFile(path)
+}
+
+// Splits a compiled dictionary into the metadata lines and the encoded trie
+func splitCompiled(data []byte) (string, []byte, error) {
+	rest := data[len(compiledMagic):]
+	size, n := binary.Uvarint(rest)
+	if n <= 0 || size > uint64(len(rest)-n) {
+		return "", nil, errors.New("compiled dictionary is corrupt")
+	}
+	rest = rest[n:]
+	return string(rest[:size]), rest[size:], nil
+}
+
+// The words of a dictionary file, a word list or compiled, with their metadata.
+// A word is a use of it for every time the word list has it
+func parseDictionaryFile(data []byte) (*trie.Trie, Metadata, error) {
+	if !isCompiled(data) {
+		words, meta := parseDictionary(string(data))
+		t := trie.New()
+		for _, word := range words {
+			t.Insert(word)
+		}
+		return t, meta, nil
+	}
+	lines, encoded, err := splitCompiled(data)
+	if err != nil {
+		return trie.New(), make(Metadata), err
+	}
+	_, meta := parseDictionary(lines)
+	var t *trie.Trie
+	if bytes.HasPrefix(data, []byte(mappedMagic)) {
+		t, err = trie.Map(encoded)
+	} else {
+		t, err = trie.Decode(bytes.NewReader(encoded))
+	}
+	if err != nil {
+		return trie.New(), meta, fmt.Errorf("compiled dictionary is corrupt: %v", err)
+	}
+	return t, meta, nil
+}
+
+// autocomplete compile [--mapped] <words.txt> [-o words.dict]
+// Writes the trie built from a word list, with the counts of its words, for
+// the dictionary config to load without building it again. --mapped writes it
+// to be memory mapped instead of loaded
+func compileCommand(args []string) error {
+	var input, output string
+	mapped := false
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "-o" && i+1 < len(args):
+			i++
+			output = args[i]
+		case args[i] == "--mapped":
+			mapped = true
+		case input == "" && !strings.HasPrefix(args[i], "-"):
+			input = args[i]
+		default:
+			return fmt.Errorf("usage: compile [--mapped] <words.txt> [-o words.dict]")
+		}
+	}
+	if input == "" {
+		return fmt.Errorf("usage: compile [--mapped] <words.txt> [-o words.dict]")
+	}
+	if output == "" {
+		output = strings.TrimSuffix(input, filepath.Ext(input)) + ".dict"
+	}
+
+	data, err := os.ReadFile(input)
+	if err != nil {
+		return err
+	}
+	if isCompiled(data) {
+		return fmt.Errorf("%s is compiled already", input)
+	}
+	var lines strings.Builder
+	for _, line := range strings.Split(string(data), "\n") {
+		if fields := strings.Split(strings.TrimSpace(line), "\t"); len(fields) >= 2 && isMetadata(fields[1:]) {
+			lines.WriteString(line + "\n")
+		}
+	}
+	t, _, _ := parseDictionaryFile(data)
+
+	var b bytes.Buffer
+	encode := t.Encode
+	if mapped {
+		b.WriteString(mappedMagic)
+		encode = t.EncodeMapped
+	} else {
+		b.WriteString(compiledMagic)
+	}
+	b.Write(binary.AppendUvarint(nil, uint64(lines.Len())))
+	b.WriteString(lines.String())
+	if err := encode(&b); err != nil {
+		return err
+	}
+	// Replaced rather than written over, a process may have the old one mapped
+	tmp := output + ".tmp"
+	if err := os.WriteFile(tmp, b.Bytes(), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, output); err != nil {
+		return err
+	}
+	stats := t.Stats()
+	fmt.Printf("compiled %d words (%d uses) into %s, %d bytes\n", stats.Words, stats.Uses, output, b.Len())
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompile(t *testing.T) {
+	dir := t.TempDir()
+	words := filepath.Join(dir, "words.txt")
+	if err := os.WriteFile(words, []byte("hello help\nhello\ndog\tpos=noun\ttags=animal\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := compileCommand([]string{words}); err != nil {
+		t.Fatal(err)
+	}
+	compiled, mapped := filepath.Join(dir, "words.dict"), filepath.Join(dir, "mapped.dict")
+	if err := compileCommand([]string{"--mapped", words, "-o", mapped}); err != nil {
+		t.Fatal(err)
+	}
+	v := NewVerifier(defaultConfig())
+	for _, dictionary := range []string{words, compiled, mapped} {
+		base, _, problems := loadBase(defaultConfig().dictSpecs(dictionary), false, false, v)
+		if len(problems) > 0 || base.Count("hello") != 2 || base.Count("help") != 1 || base.Count("dog") != 1 {
+			t.Errorf("%s: hello %d, help %d, dog %d, problems %q", dictionary, base.Count("hello"), base.Count("help"), base.Count("dog"), problems)
+		}
+		if status := LoadMetadata(dictionary, v).Status("dog"); status != "dog: noun; animal" {
+			t.Errorf("%s: metadata %q", dictionary, status)
+		}
+	}
+	if err := compileCommand([]string{compiled, "-o", filepath.Join(dir, "again.dict")}); err == nil {
+		t.Error("compiled a compiled dictionary")
+	}
+
+	// Compiling again replaces the mapped file, what is mapped already still reads
+	inUse, _, _ := loadBase(defaultConfig().dictSpecs(mapped), false, false, v)
+	os.WriteFile(words, []byte("dog\n"), 0644)
+	if err := compileCommand([]string{"--mapped", words, "-o", mapped}); err != nil {
+		t.Fatal(err)
+	}
+	if inUse.Count("hello") != 2 {
+		t.Errorf("the mapped words changed under the loaded ones: hello %d", inUse.Count("hello"))
+	}
+	if base, _, _ := loadBase(defaultConfig().dictSpecs(mapped), false, false, v); base.Count("hello") != 0 || base.Count("dog") != 1 {
+		t.Errorf("recompiled dictionary: hello %d, dog %d", base.Count("hello"), base.Count("dog"))
+	}
+
+	data, _ := os.ReadFile(compiled)
+	os.WriteFile(compiled, data[:len(data)-3], 0644)
+	if base, _, problems := loadBase(defaultConfig().dictSpecs(compiled), false, false, v); len(problems) != 1 || base.Count("hello") != 0 {
+		t.Errorf("truncated dictionary loaded hello %d, problems %q", base.Count("hello"), problems)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Compose sequences shipped with the binary. compose.txt in the data directory
+// adds to them and overrides those it repeats
+//
+//go:embed compose.txt
+var builtinCompose string
+
+const composeFile = "compose.txt"
+
+// Keys typed after the compose key, or starting with a dead key, and the text
+// they turn into. Eg:- 'e --> é
+type ComposeTable map[string]string
+
+// Adds the "sequence<TAB>text" lines of data to table, # starts a comment.
+// Malformed lines are reported in problems
+func (table ComposeTable) parse(name, data string) []string {
+	var problems []string
+	for i, line := range strings.Split(data, "\n") {
+		if line = strings.TrimRight(line, "\r"); strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		seq, text, ok := strings.Cut(line, "\t")
+		if !ok || seq == "" || text == "" {
+			problems = append(problems, fmt.Sprintf("%s:%d: expected sequence<TAB>text", name, i+1))
+			continue
+		}
+		table[seq] = text
+	}
+	return problems
+}
+
+// Loads the built-in sequences and those of compose.txt in the data directory, if any
+func LoadCompose() (ComposeTable, []string) {
+	table := make(ComposeTable)
+	table.parse(composeFile, builtinCompose)
+	local := inDataDir(composeFile)
+	data, err := os.ReadFile(local)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return table, nil
+		}
+		return table, []string{fmt.Sprintf("compose table: %v", err)}
+	}
+	return table, table.parse(local, string(data))
+}
+
+// Reports whether a longer sequence starts with seq
+func (table ComposeTable) pending(seq string) bool {
+	for s := range table {
+		if len(s) > len(seq) && strings.HasPrefix(s, seq) {
+			return true
+		}
+	}
+	return false
+}
+
+// Runs key through the sequence being composed and returns the keys the editor
+// handles instead: none while a sequence is incomplete, the composed text once
+// it is done. A dead key which starts no sequence gives back what was typed,
+// once followed by SPACE just the dead key itself
+func (e *Editor) compose(key rune) []rune {
+	cfg := &e.cfg
+	if key == cfg.composeKey {
+		if e.composing != nil { // Pressed twice, give up
+			e.composing = nil
+			e.status(e.idleStatus())
+			return nil
+		}
+		e.composing, e.dead = []rune{}, false
+		e.status("compose")
+		return nil
+	}
+	if e.composing == nil {
+		if key > 0 && strings.ContainsRune(cfg.Keys.Dead, key) {
+			e.composing, e.dead = []rune{key}, true
+			return nil
+		}
+		return []rune{key}
+	}
+
+	typed := e.composing
+	e.composing = nil
+	switch {
+	case e.dead && key == ' ' && len(typed) == 1:
+		return typed
+	case key == BACKSPACE || key == DELETE:
+		return nil
+	case key < ' ': // Control keys and the Enter variants end the sequence
+		if e.dead {
+			return append(typed, key)
+		}
+		return []rune{key}
+	}
+
+	seq := string(append(typed, key))
+	if text, ok := e.composer[seq]; ok {
+		return []rune(text)
+	} else if e.composer.pending(seq) {
+		e.composing = append(typed, key)
+		return nil
+	}
+	if e.dead {
+		return []rune(seq)
+	}
+	e.status(fmt.Sprintf("no compose sequence %q", seq))
+	return nil
+}
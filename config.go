@@ -0,0 +1,451 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"unicode"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Settings read from config.toml in the config directory (~/.config/autocomplete-cli on Linux).
+// Every option is optional and can be overridden from the environment, see applyEnv().
+// Relative paths are relative to the data directory (~/.local/share/autocomplete-cli)
+//
+//	dictionary = "words.txt"
+//	debounce = "200ms"
+//
+//	[scoring]
+//	cap = 1000
+//
+//	[theme]
+//	status = "2;36"
+//
+//	[keys]
+//	t9 = "ctrl+t"
+type Config struct {
+	Dictionary       string            `toml:"dictionary"`        // word list loaded at startup, empty for none
+	Dictionaries     []string          `toml:"dictionaries"`      // more word lists merged with it, each "path[:weight=N]", Eg:- ["medical.txt:weight=2"]
+	Dawg             bool              `toml:"dawg"`              // share the common endings of the dictionary words, for dictionaries of millions of words
+	BuiltinWords     bool              `toml:"builtin_words"`     // the common English words built into the binary, below the dictionary
+	Watch            bool              `toml:"watch"`             // read the dictionaries again when their files change, keeping the learned words
+	Definitions      string            `toml:"definitions"`       // optional definitions shown in the status line
+	Translations     string            `toml:"translations"`      // glob matching the bilingual lists
+	Debounce         time.Duration     `toml:"debounce"`          // pause in typing before suggestions show up, 0 for none
+	AdaptiveDebounce bool              `toml:"adaptive_debounce"` // tune the debounce to the typing speed, see Pace
+	Blink            time.Duration     `toml:"blink"`             // no longer used, suggestions show as ghost text
+	Profile          string            `toml:"profile"`           // keeps learned data apart, Eg:- "work"
+	Contexts         map[string]string `toml:"contexts"`          // profile for each application the context control command names, Eg:- {thunderbird = "mail"}
+	NoLearn          bool              `toml:"no_learn"`          // use the learned data without adding to it
+	Recap            string            `toml:"recap"`             // session recap printed on exit: short, full or off
+	Verify           string            `toml:"verify"`            // "warn", "strict" or "off", see Verifier
+	TrustedKeys      []string          `toml:"trusted_keys"`      // base64 ed25519 public keys which sign manifests
+	Rerank           bool              `toml:"rerank"`            // reorder suggestions with the trained ranker, if any
+	MaxSuggestions   int               `toml:"max_suggestions"`   // suggestions TAB cycles through, 0 for all of them
+	MinPrefix        int               `toml:"min_prefix"`        // letters of a word typed before it is completed
+	ShortMargin      float64           `toml:"short_margin"`      // how many times the next one the shown suggestion for a single letter must score, see shortPrefixGuard
+	ShortMargins     ProfileMargins    `toml:"short_margins"`     // short_margin of each profile, Eg:- {mail = 1.5}
+	Spelling         string            `toml:"spelling"`          // "uk" or "us" prefers that spelling of the built-in British and American pairs, Eg:- colour over color
+	SpellingVariants string            `toml:"spelling_variants"` // demote or hide the variants of the preferred spellings
+	Prefer           map[string]string `toml:"prefer"`            // more spelling preferences, each word over its variant, Eg:- {grey = "gray"}
+	LowPower         bool              `toml:"low_power"`         // redraw less often for slow links, Eg:- SSH over a bad connection or a serial console
+	Perf             bool              `toml:"perf"`              // show the suggestion latency and cache hit rate in the status line
+	ShowSuggestions  int               `toml:"show_suggestions"`  // suggestions listed in the status line at once, 0 or 1 for just the shown one
+	ShowOrder        string            `toml:"show_order"`        // how the listed ones are ordered: rank, alphabetical or length
+	Preview          bool              `toml:"preview"`           // put the shown suggestion in the text instead of showing it as ghost text
+	NextWords        bool              `toml:"next_words"`        // predict the next word after a SPACE, before any of it is typed
+	RecentFiles      bool              `toml:"recent_files"`      // complete file names typed before from their base name, see RecentFiles
+	MatchCase        string            `toml:"match_case"`        // exact, typed to complete in any case keeping the typed letters, or word to spell them as learned
+	MatchAccents     bool              `toml:"match_accents"`     // complete cafe to café and café to cafe too
+	Casing           bool              `toml:"casing"`            // suggest the case variants of a word once, cased for where it goes
+	KeepCase         []string          `toml:"keep_case"`         // words which keep their casing, Eg:- ["May", "Go"]
+	WordChars        string            `toml:"word_chars"`        // punctuation which is part of words, Eg:- "-'" for state-of-the-art and don't
+	Fuzzy            int               `toml:"fuzzy"`             // typos tolerated in the typed word, 0 to 2, Eg:- teh --> the
+	Projects         bool              `toml:"projects"`          // layer the words and snippets of the project around the current directory
+	CodeProfiles     []string          `toml:"code_profiles"`     // profiles completing identifiers by their humps, Eg:- gNB --> getNodeBalance
+	Tokenizers       map[string]string `toml:"tokenizers"`        // how each profile splits what is typed into words: text, code or shell, see Tokenizer
+	Packs            []string          `toml:"packs"`             // keyword packs suggested after everything else, Eg:- ["sql", "go"]
+	Scoring          ScoringConfig     `toml:"scoring"`
+	Experiment       ExperimentConfig  `toml:"experiment"`
+	Theme            ThemeConfig       `toml:"theme"`
+	Keys             KeysConfig        `toml:"keys"`
+	Enter            EnterConfig       `toml:"enter"`
+	Menu             MenuConfig        `toml:"menu"`
+	Tags             []TagRule         `toml:"tags"`
+	Tokens           TokensConfig      `toml:"tokens"`
+	Serve            ServeConfig       `toml:"serve"`
+	Maintenance      MaintenanceConfig `toml:"maintenance"`
+	SizeGuard        SizeGuardConfig   `toml:"size_guard"`
+	Team             TeamConfig        `toml:"team"`
+	Pins             map[string]string `toml:"pins"` // completions suggested first for a prefix, Eg:- addr = "221B Baker Street"
+
+	acceptKey     rune // resolved Keys, keyNone when unbound
+	cycleKey      rune
+	cycleBackKey  rune
+	dismissKey    rune
+	deleteWordKey rune
+	quitKey       rune
+	t9Key         rune
+	snippetKey    rune
+	menuKey       rune
+	ignoreKey     rune
+	surpriseKey   rune
+	pinKey        rune
+	historyKey    rune
+	composeKey    rune
+	tempKey       rune
+	explainKey    rune
+	forgetKey     rune
+	helpKey       rune
+	teachKey      rune
+
+	dictWeight float64         // of Dictionary, resolved from its ":weight=N"
+	avoid      map[string]bool // resolved spelling preferences, see avoidedSpellings
+}
+
+type ScoringConfig struct {
+	Cap      int           `toml:"cap"`       // counts above cap rank the same, 0 disables capping
+	Log      bool          `toml:"log"`       // rank by log-scaled counts
+	Recency  float64       `toml:"recency"`   // how much more a word used right now ranks, Eg:- 2 for up to 3 times its count alone
+	HalfLife time.Duration `toml:"half_life"` // after which the boost of a use is halved
+}
+
+func (c ScoringConfig) scoring() Scoring {
+	return Scoring{cap: c.Cap, log: c.Log, recency: c.Recency, halfLife: c.HalfLife}
+}
+
+type ThemeConfig struct {
+	Status     string `toml:"status"`     // SGR parameters of the status line, Eg:- "2" for dim or "1;33" for bold yellow
+	Suggestion string `toml:"suggestion"` // SGR parameters of the ghost text of a suggestion, plain when empty
+}
+
+// Keys named ctrl+<letter>, tab, shift+tab, enter, esc, an arrow and so on, "" for none
+type KeysConfig struct {
+	Accept     string `toml:"accept"`      // accepts the shown suggestion, besides the Enter actions
+	Cycle      string `toml:"cycle"`       // shows the next suggestion
+	CycleBack  string `toml:"cycle_back"`  // shows the previous one
+	Dismiss    string `toml:"dismiss"`     // drops the shown suggestion
+	DeleteWord string `toml:"delete_word"` // deletes the word before the cursor
+	Quit       string `toml:"quit"`        // Ctrl+C always quits too
+	T9         string `toml:"t9"`          // toggles T9 mode
+	Snippet    string `toml:"snippet"`     // accepts a proposed snippet
+	Menu       string `toml:"menu"`        // lists the suggestions, typing then narrows them down
+	Ignore     string `toml:"ignore"`      // never suggests the shown word for this prefix again
+	Surprise   string `toml:"surprise"`    // inserts a random next word drawn from the model
+	Pin        string `toml:"pin"`         // pins the shown word to the typed prefix, or unpins it
+	History    string `toml:"history"`     // lists the completions accepted this session to insert one again
+	Compose    string `toml:"compose"`     // starts a compose sequence, Eg:- ctrl+k ' e --> é
+	Temporary  string `toml:"temporary"`   // turns the word just learned into a temporary one
+	Explain    string `toml:"explain"`     // shows how the shown suggestion was scored
+	Forget     string `toml:"forget"`      // forgets the shown word for good, like the forget command
+	Help       string `toml:"help"`        // lists the keys and modes in effect, ? on an empty line does too
+	Teach      string `toml:"teach"`       // learns the words of the next paste instead of inserting it
+	Dead       string `toml:"dead"`        // characters which start a compose sequence themselves, Eg:- "'`^"
+}
+
+func defaultConfig() Config {
+	return Config{
+		Dictionary:    inDataDir("words.txt"),
+		Definitions:   inDataDir(definitionsFile),
+		Translations:  inDataDir(translationsGlob),
+		Debounce:      200 * time.Millisecond,
+		BuiltinWords:  true,
+		Watch:         true,
+		dictWeight:    1,
+		MinPrefix:     2,
+		ShortMargin:   2,
+		Scoring:       ScoringConfig{Cap: defaultScoring.cap, Log: defaultScoring.log, Recency: defaultScoring.recency, HalfLife: defaultScoring.halfLife},
+		Verify:        "warn",
+		Rerank:        true,
+		Projects:      true,
+		NextWords:     true,
+		RecentFiles:   true,
+		WordChars:     defaultWordChars,
+		MatchCase:     matchExact,
+		Recap:         recapShort,
+		ShowOrder:     orderRank,
+		Tokens:        TokensConfig{Number: tokenSuggest, Hex: tokenIgnore, UUID: tokenSuggest, Ticket: tokenTemporary, TTL: defaultTTL},
+		Serve:         ServeConfig{Listen: "127.0.0.1:7878", Rate: 20, Burst: 40, MaxConcurrent: 16, TeamMembers: 2, TraceSample: 1},
+		Theme:         ThemeConfig{Status: "2", Suggestion: "2"},
+		Keys:          KeysConfig{Cycle: "tab", CycleBack: "shift+tab", Dismiss: "esc", DeleteWord: "alt+backspace", Quit: "ctrl+d", T9: "ctrl+t", Snippet: "ctrl+s", Menu: "ctrl+o", Ignore: "ctrl+x", Surprise: "ctrl+r", Pin: "ctrl+p", History: "ctrl+y", Compose: "ctrl+k", Temporary: "ctrl+g", Explain: "ctrl+e", Forget: "ctrl+f", Help: "f1", Teach: "ctrl+l"},
+		Enter:         EnterConfig{Enter: enterAccept, ShiftEnter: enterNewline, AltEnter: enterSubmit, Right: enterComplete, End: enterComplete},
+		Maintenance:   MaintenanceConfig{Idle: 10 * time.Second, Tasks: []string{"compact", "snapshot", "retrain", "warm", "guard"}},
+		SizeGuard:     SizeGuardConfig{Warn: 100, MinCount: 3, MaxAge: 90},
+		acceptKey:     keyNone,
+		cycleKey:      TAB,
+		cycleBackKey:  keyShiftTab,
+		dismissKey:    ESCAPE,
+		deleteWordKey: keyAltDelete,
+		quitKey:       CTRL_D,
+		t9Key:         CTRL_T,
+		snippetKey:    CTRL_S,
+		menuKey:       CTRL_O,
+		ignoreKey:     CTRL_X,
+		surpriseKey:   CTRL_R,
+		pinKey:        CTRL_P,
+		historyKey:    CTRL_Y,
+		composeKey:    CTRL_K,
+		tempKey:       CTRL_G,
+		explainKey:    CTRL_E,
+		forgetKey:     CTRL_F,
+		helpKey:       keyF1,
+		teachKey:      CTRL_L,
+	}
+}
+
+// Location of the config file, AUTOCOMPLETE_CONFIG points to a different one
+func configPath() string {
+	if path := os.Getenv(envPrefix + "_CONFIG"); path != "" {
+		return path
+	}
+	return filepath.Join(configDir(), "config.toml")
+}
+
+// Loads the config at path on top of the defaults, followed by the environment
+// overrides. A missing file gives the defaults
+func LoadConfig(path string) (Config, error) {
+	cfg := defaultConfig()
+
+	md, err := toml.DecodeFile(path, &cfg)
+	if err != nil && !os.IsNotExist(err) {
+		return defaultConfig(), err
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		return defaultConfig(), fmt.Errorf("unknown option %q", undecoded[0].String())
+	}
+	if err := applyEnv(&cfg); err != nil {
+		return defaultConfig(), err
+	}
+	spec, err := parseDictSpec(cfg.Dictionary)
+	if err != nil {
+		return defaultConfig(), fmt.Errorf("dictionary %w", err)
+	}
+	cfg.Dictionary, cfg.dictWeight = inDataDir(spec.path), spec.weight
+	cfg.Definitions = inDataDir(cfg.Definitions)
+	cfg.Translations = inDataDir(cfg.Translations)
+	return cfg, cfg.validate()
+}
+
+// Checks the values and resolves the key names
+func (cfg *Config) validate() error {
+	if cfg.Debounce < 0 {
+		return fmt.Errorf("debounce must not be negative")
+	}
+	if cfg.Scoring.Cap < 0 || cfg.Experiment.A.Cap < 0 || cfg.Experiment.B.Cap < 0 {
+		return fmt.Errorf("scoring caps must not be negative")
+	}
+	for _, c := range []ScoringConfig{cfg.Scoring, cfg.Experiment.A, cfg.Experiment.B} {
+		if c.Recency < 0 || c.Recency > 0 && c.HalfLife <= 0 {
+			return fmt.Errorf("scoring recency must not be negative and needs a positive half_life")
+		}
+	}
+	if strings.ContainsAny(cfg.Profile, `/\`) || cfg.Profile == "." || cfg.Profile == ".." {
+		return fmt.Errorf("profile %q must be a plain name", cfg.Profile)
+	}
+	for context, profile := range cfg.Contexts {
+		if strings.ContainsAny(profile, `/\`) || profile == "." || profile == ".." {
+			return fmt.Errorf("contexts.%s: profile %q must be a plain name", context, profile)
+		}
+	}
+	for profile, name := range cfg.Tokenizers {
+		if tokenizers[name] == nil {
+			return fmt.Errorf("tokenizers.%s: %q is not text, code or shell", profile, name)
+		}
+	}
+	if len(cfg.Dictionaries) >= maxDictionaries {
+		return fmt.Errorf("dictionaries: at most %d word lists are loaded together", maxDictionaries-1)
+	}
+	for _, s := range cfg.Dictionaries {
+		if _, err := parseDictSpec(s); err != nil {
+			return fmt.Errorf("dictionaries: %v", err)
+		}
+	}
+	if err := validateSpelling(cfg.Spelling, cfg.SpellingVariants, cfg.Prefer); err != nil {
+		return err
+	}
+	cfg.avoid = avoidedSpellings(cfg.Spelling, cfg.Prefer)
+	if cfg.Verify != "warn" && cfg.Verify != "strict" && cfg.Verify != "off" {
+		return fmt.Errorf("verify must be warn, strict or off")
+	}
+	for _, key := range cfg.TrustedKeys {
+		if raw, err := base64.StdEncoding.DecodeString(key); err != nil || len(raw) != ed25519.PublicKeySize {
+			return fmt.Errorf("trusted key %q is not a base64 ed25519 public key", key)
+		}
+	}
+	if strings.Trim(cfg.Theme.Status, "0123456789;") != "" {
+		return fmt.Errorf("theme.status %q is not a list of SGR parameters", cfg.Theme.Status)
+	}
+	if strings.Trim(cfg.Theme.Suggestion, "0123456789;") != "" {
+		return fmt.Errorf("theme.suggestion %q is not a list of SGR parameters", cfg.Theme.Suggestion)
+	}
+	if cfg.MinPrefix < 1 {
+		return fmt.Errorf("min_prefix must be at least 1")
+	}
+	if cfg.ShortMargin < 0 {
+		return fmt.Errorf("short_margin must not be negative")
+	}
+	for profile, margin := range cfg.ShortMargins {
+		if margin < 0 {
+			return fmt.Errorf("short_margins.%s must not be negative", profile)
+		}
+	}
+	if cfg.MaxSuggestions < 0 {
+		return fmt.Errorf("max_suggestions must not be negative")
+	}
+	if cfg.ShowSuggestions < 0 {
+		return fmt.Errorf("show_suggestions must not be negative")
+	}
+	if cfg.ShowOrder != orderRank && cfg.ShowOrder != orderAlphabetical && cfg.ShowOrder != orderLength {
+		return fmt.Errorf("show_order must be rank, alphabetical or length")
+	}
+	if cfg.Fuzzy < 0 || cfg.Fuzzy > maxTypos {
+		return fmt.Errorf("fuzzy must be between 0 and %d", maxTypos)
+	}
+	if cfg.Recap != recapShort && cfg.Recap != recapFull && cfg.Recap != recapOff {
+		return fmt.Errorf("recap must be short, full or off")
+	}
+	if cfg.MatchCase != matchExact && cfg.MatchCase != matchTyped && cfg.MatchCase != matchWord {
+		return fmt.Errorf("match_case must be exact, typed or word")
+	}
+	if strings.IndexFunc(cfg.WordChars, func(r rune) bool { return unicode.IsSpace(r) || isWordEdge(r) }) >= 0 {
+		return fmt.Errorf("word_chars must only hold punctuation")
+	}
+	if cfg.Menu.Limit < 0 {
+		return fmt.Errorf("menu.limit must not be negative")
+	}
+	for section, limit := range cfg.Menu.Limits {
+		if limit < 0 {
+			return fmt.Errorf("menu.limits.%s must not be negative", section)
+		}
+	}
+	if err := cfg.Tokens.validate(); err != nil {
+		return err
+	}
+	if err := cfg.Serve.validate(); err != nil {
+		return err
+	}
+	for _, rule := range cfg.Tags {
+		if err := rule.validate(); err != nil {
+			return err
+		}
+	}
+	if err := cfg.Enter.validate(); err != nil {
+		return err
+	}
+	if err := cfg.Maintenance.validate(); err != nil {
+		return err
+	}
+	if err := cfg.SizeGuard.validate(); err != nil {
+		return err
+	}
+	return cfg.resolveKeys()
+}
+
+// The name of the active profile
+func (cfg Config) profileName() string {
+	if cfg.Profile == "" {
+		return "default"
+	}
+	return cfg.Profile
+}
+
+// Reports whether the active profile completes identifiers by their humps
+func (cfg Config) codeMode() bool {
+	return slices.Contains(cfg.CodeProfiles, cfg.profileName())
+}
+
+// The short_margin of the active profile
+func (cfg Config) shortMargin() float64 {
+	if margin, ok := cfg.ShortMargins[cfg.profileName()]; ok {
+		return margin
+	}
+	return cfg.ShortMargin
+}
+
+// The Tokenizer of the active profile, text unless the tokenizers config names another
+func (cfg Config) tokenizer() Tokenizer {
+	if newTokenizer, ok := tokenizers[cfg.Tokenizers[cfg.profileName()]]; ok {
+		return newTokenizer(cfg.WordChars)
+	}
+	return tokenizers["text"](cfg.WordChars)
+}
+
+// Reports whether switching from old to cfg requires reloading the word lists
+func (cfg Config) sourcesChanged(old Config) bool {
+	return cfg.Dictionary != old.Dictionary || cfg.dictWeight != old.dictWeight || !slices.Equal(cfg.Dictionaries, old.Dictionaries) || cfg.Dawg != old.Dawg || cfg.BuiltinWords != old.BuiltinWords || cfg.Definitions != old.Definitions || cfg.Translations != old.Translations ||
+		cfg.Verify != old.Verify || !slices.Equal(cfg.TrustedKeys, old.TrustedKeys) || cfg.Tokens != old.Tokens ||
+		!slices.Equal(cfg.Packs, old.Packs) || cfg.Team.Subscribe != old.Team.Subscribe ||
+		cfg.Projects != old.Projects
+}
+
+// SGR parameters of the status line, read by render()
+var statusStyle atomic.Pointer[string]
+
+// Time render() waits after drawing a frame, frames sent meanwhile are drawn as one
+var renderEvery atomic.Int64
+
+const (
+	frameInterval    = 50 * time.Millisecond
+	lowPowerInterval = 300 * time.Millisecond // with low_power
+)
+
+// Makes the settings which are read outside the main loop take effect
+func (cfg Config) apply() {
+	scoring = cfg.Scoring.scoring()
+	paths = profilePaths(cfg.Profile)
+	style := cfg.Theme.Status
+	statusStyle.Store(&style)
+	renderEvery.Store(int64(frameInterval))
+	if cfg.LowPower {
+		renderEvery.Store(int64(lowPowerInterval))
+	}
+}
+
+// Signals on the returned channel when the config file at path changes or SIGHUP is received
+func watchConfig(path string) <-chan struct{} {
+	reload := make(chan struct{}, 1)
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+
+		last := modTime(path)
+		for {
+			select {
+			case <-hup:
+			case <-ticker.C:
+				m := modTime(path)
+				if m.Equal(last) {
+					continue
+				}
+				last = m
+			}
+			select {
+			case reload <- struct{}{}:
+			default: // a reload is already pending
+			}
+		}
+	}()
+	return reload
+}
+
+// Modification time of path, zero if it does not exist
+func modTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
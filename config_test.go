@@ -0,0 +1,77 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The config file sets what it has, the rest keeps the defaults, and values
+// which cannot work are refused with the defaults returned
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", dir)
+	path := filepath.Join(dir, "config.toml")
+	if cfg, err := LoadConfig(path); err != nil || cfg.MinPrefix != defaultConfig().MinPrefix {
+		t.Fatalf("without a config file: %v, min_prefix %d", err, cfg.MinPrefix)
+	}
+
+	os.WriteFile(path, []byte("min_prefix = 3\ndictionary = \"medical.txt:weight=2\"\n[scoring]\ncap = 5\n"), 0644)
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MinPrefix != 3 || cfg.Scoring.Cap != 5 || !cfg.Scoring.Log || cfg.Debounce != defaultConfig().Debounce {
+		t.Errorf("min_prefix %d, scoring %+v, debounce %v", cfg.MinPrefix, cfg.Scoring, cfg.Debounce)
+	}
+	if cfg.Dictionary != filepath.Join(dir, "autocomplete-cli", "medical.txt") || cfg.dictWeight != 2 {
+		t.Errorf("dictionary %s with weight %v", cfg.Dictionary, cfg.dictWeight)
+	}
+
+	for config, want := range map[string]string{
+		"min_prefix = 0":                        "min_prefix must be at least 1",
+		"debounce = \"-1s\"":                    "debounce must not be negative",
+		"verify = \"sometimes\"":                "verify must be warn, strict or off",
+		"[scoring]\nrecency = 1\nhalf_life = 0": "scoring recency must not be negative and needs a positive half_life",
+		"min_prefx = 2":                         `unknown option "min_prefx"`,
+	} {
+		os.WriteFile(path, []byte(config+"\n"), 0644)
+		cfg, err := LoadConfig(path)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: %v, want %s", config, err, want)
+		}
+		if config == "min_prefx = 2" && cfg.MinPrefix != defaultConfig().MinPrefix {
+			t.Errorf("refused config gave min_prefix %d", cfg.MinPrefix)
+		}
+	}
+}
+
+// Taking a section out of the config file and reloading it goes back to the
+// defaults, not to what the section said before
+func TestConfigReload(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", dir)
+	t.Cleanup(func() { defaultConfig().apply() })
+	path := filepath.Join(dir, "config.toml")
+
+	os.WriteFile(path, []byte("[scoring]\ncap = 5\nlog = false\nrecency = 0\n"), 0644)
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.apply()
+	if scoring.cap != 5 || scoring.log {
+		t.Fatalf("scoring %+v after applying the section", scoring)
+	}
+
+	os.WriteFile(path, []byte("min_prefix = 2\n"), 0644)
+	if cfg, err = LoadConfig(path); err != nil {
+		t.Fatal(err)
+	}
+	cfg.apply()
+	if scoring != defaultScoring || cfg.Scoring.Cap != 1000 || !cfg.Scoring.Log || cfg.Scoring.HalfLife != 7*24*time.Hour {
+		t.Errorf("scoring %+v after removing the section, want the defaults", scoring)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// A command received on the control interface
+type controlCommand struct {
+	name string
+	arg  string
+}
+
+// Commands accepted on the control interface and whether they take an argument
+var controlCommands = map[string]bool{
+	"learn":          true,
+	"forget":         true,
+	"switch-profile": true,
+	"context":        true,
+	"reload":         false,
+}
+
+// Where the named pipes of the running editors are, Eg:- $XDG_RUNTIME_DIR/autocomplete-cli
+func controlDir() string {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if !filepath.IsAbs(dir) {
+		return cacheDir()
+	}
+	return filepath.Join(dir, appName)
+}
+
+// Named pipe external scripts write commands for the editor with process id pid
+// to, one per line. Every editor has its own. Eg:-
+//
+//	echo "switch-profile work" > $XDG_RUNTIME_DIR/autocomplete-cli/control.4242
+func controlPath(pid int) string {
+	return filepath.Join(controlDir(), fmt.Sprintf("control.%d", pid))
+}
+
+// The named pipes of the editors, those left behind by one which crashed included
+func controlPaths() []string {
+	paths, _ := filepath.Glob(filepath.Join(controlDir(), "control.*"))
+	return paths
+}
+
+// Creates the named pipe at path and returns a channel receiving the valid
+// commands written to it
+func openControl(path string) (<-chan controlCommand, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	if err := makeFifo(path); err != nil && !os.IsExist(err) {
+		return nil, err
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode()&os.ModeNamedPipe == 0 {
+		return nil, fmt.Errorf("%s is not a named pipe", path)
+	}
+
+	// Opening for writing too keeps the pipe from reporting EOF whenever a writer goes away
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	commands := make(chan controlCommand)
+	go func() {
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if cmd, ok := parseControlCommand(scanner.Text()); ok {
+				commands <- cmd
+			}
+		}
+	}()
+	return commands, nil
+}
+
+// The profile for context, an application a script reports the focus moved to,
+// as the contexts config maps it. "" when it maps nothing, for the configured
+// profile. Eg:- {thunderbird = "mail"}, Thunderbird --> mail
+func (cfg Config) contextProfile(context string) string {
+	for name, profile := range cfg.Contexts {
+		if strings.EqualFold(name, context) {
+			return profile
+		}
+	}
+	return ""
+}
+
+// autocomplete control <command> [argument]
+// Writes a command to the control interface of every running editor
+func controlPipeCommand(args []string) error {
+	cmd, ok := parseControlCommand(strings.Join(args, " "))
+	if !ok {
+		return fmt.Errorf("usage: control learn|forget|switch-profile|context <argument>, or control reload")
+	}
+	sent := 0
+	for _, path := range controlPaths() {
+		// Nothing reads the pipe of an editor which is gone, so it fails right away
+		f, err := openFifoWriter(path)
+		if err != nil {
+			continue
+		}
+		_, err = fmt.Fprintln(f, strings.TrimSpace(cmd.name+" "+cmd.arg))
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("writing to %s: %v", path, err)
+		}
+		sent++
+	}
+	if sent == 0 {
+		return fmt.Errorf("no editor is listening in %s", controlDir())
+	}
+	return nil
+}
+
+// Learns the words of a learn command like those of a paste to learn, by their
+// token policies and not with no_learn. Returns the status to show
+func (e *Editor) learnControl(text string) string {
+	if e.cfg.NoLearn {
+		return "nothing is learned with no_learn"
+	}
+	words, fresh := e.learnWords(text)
+	return fmt.Sprintf("learned %d words, %d of them new", words, fresh)
+}
+
+// Parses "name [argument]", rejecting unknown commands and missing arguments
+func parseControlCommand(line string) (controlCommand, bool) {
+	name, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+	arg = strings.TrimSpace(arg)
+	needsArg, ok := controlCommands[name]
+	if !ok || needsArg != (arg != "") {
+		return controlCommand{}, false
+	}
+	return controlCommand{name, arg}, true
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// The context control command names an application, mapped to a profile in any case
+func TestContextProfile(t *testing.T) {
+	cmd, ok := parseControlCommand("context Thunderbird")
+	if !ok || cmd.name != "context" || cmd.arg != "Thunderbird" {
+		t.Fatalf("parsed %+v %v", cmd, ok)
+	}
+	cfg := defaultConfig()
+	cfg.Contexts = map[string]string{"thunderbird": "mail"}
+	if got := cfg.contextProfile(cmd.arg); got != "mail" {
+		t.Errorf("Thunderbird: profile %q, want mail", got)
+	}
+	if got := cfg.contextProfile("firefox"); got != "" {
+		t.Errorf("firefox: profile %q, want none", got)
+	}
+	cfg.Contexts["code"] = "../x"
+	if cfg.validate() == nil {
+		t.Error("a profile outside the profiles directory is valid")
+	}
+}
+
+// A learn command learns like a paste: by the token policies, without the
+// punctuation and not with no_learn
+func TestEditorLearnControl(t *testing.T) {
+	h := newHarness(t, t.TempDir())
+	if status := h.e.learnControl("(kubectl, 12345 deadbeef42"); !strings.HasPrefix(status, "learned 1 words, 1 of them new") {
+		t.Errorf("status %q", status)
+	}
+	if h.e.trie.Count("kubectl") != 1 || h.e.trie.Count("(kubectl,") != 0 || h.e.trie.Count("12345") != 0 || h.e.trie.Count("deadbeef42") != 0 {
+		t.Errorf("learned %v", h.e.trie.Layer(userLayer).Words())
+	}
+
+	h.e.cfg.NoLearn = true
+	if status := h.e.learnControl("kubectl"); h.e.trie.Count("kubectl") != 1 {
+		t.Errorf("learned with no_learn: %q", status)
+	}
+}
@@ -0,0 +1,49 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+// Every editor reads its own pipe, a command goes to all of them and the pipe
+// of one which is gone is passed over
+func TestControlPipes(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	var editors []<-chan controlCommand
+	for _, pid := range []int{100, 200} {
+		commands, err := openControl(controlPath(pid))
+		if err != nil {
+			t.Fatal(err)
+		}
+		editors = append(editors, commands)
+	}
+	if err := makeFifo(controlPath(300)); err != nil {
+		t.Fatal(err) // nothing reads it
+	}
+	if len(controlPaths()) != 3 {
+		t.Fatalf("pipes %v", controlPaths())
+	}
+
+	if err := controlPipeCommand([]string{"switch-profile", "work"}); err != nil {
+		t.Fatal(err)
+	}
+	for i, commands := range editors {
+		select {
+		case cmd := <-commands:
+			if cmd != (controlCommand{"switch-profile", "work"}) {
+				t.Errorf("editor %d got %+v", i, cmd)
+			}
+		case <-time.After(time.Second):
+			t.Errorf("editor %d got nothing", i)
+		}
+	}
+
+	os.Remove(controlPath(100))
+	os.Remove(controlPath(200))
+	if err := controlPipeCommand([]string{"reload"}); err == nil {
+		t.Error("sent to an editor which is gone")
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import "unicode"
+
+// Keys moving the cursor, arriving as escape sequences
+const (
+	keyLeft rune = -20 - iota
+	keyRight
+	keyHome
+	keyEnd
+	keyForwardDelete // Delete, the character after the cursor
+)
+
+// Escape sequences of the keys above, in the variants xterm, the Linux console
+// and others send. Eg:- ESC [ D --> left
+var cursorKeys = map[string]rune{
+	"\x1b[D": keyLeft, "\x1bOD": keyLeft,
+	"\x1b[C": keyRight, "\x1bOC": keyRight,
+	"\x1b[H": keyHome, "\x1bOH": keyHome, "\x1b[1~": keyHome, "\x1b[7~": keyHome,
+	"\x1b[F": keyEnd, "\x1bOF": keyEnd, "\x1b[4~": keyEnd, "\x1b[8~": keyEnd,
+	"\x1b[3~": keyForwardDelete,
+}
+
+// Handles a cursor key, returns false for any other key. Home and End go to
+// the start and end of the line the cursor is on
+func (e *Editor) moveCursor(key rune) bool {
+	switch key {
+	case keyLeft:
+		e.cursorBy(-1)
+	case keyRight:
+		e.cursorBy(1)
+	case keyHome:
+		e.cursorBy(-len([]rune(currentLine(e.input))))
+	case keyEnd:
+		e.cursorBy(lineEnd(e.after))
+	case keyForwardDelete:
+		if len(e.after) > 0 {
+			e.after = e.after[1:]
+		}
+	default:
+		return false
+	}
+	e.erased = "" // retyping elsewhere corrects nothing
+	e.status(e.idleStatus())
+	return true
+}
+
+// Moves the cursor n characters to the right, to the left when negative
+func (e *Editor) cursorBy(n int) {
+	for ; n < 0 && len(e.input) > 0; n++ {
+		e.after = append([]rune{e.input[len(e.input)-1]}, e.after...)
+		e.input = e.input[:len(e.input)-1]
+	}
+	for ; n > 0 && len(e.after) > 0; n-- {
+		e.input = append(e.input, e.after[0])
+		e.after = e.after[1:]
+	}
+}
+
+// Characters of after up to the end of its first line
+func lineEnd(after []rune) int {
+	end := 0
+	for end < len(after) && after[end] != '\n' {
+		end++
+	}
+	return end
+}
+
+// Reports whether the cursor is in the middle of a word, where completing the
+// part before it would leave the rest behind. Eg:- hel|lo --> true
+func (e *Editor) insideWord() bool {
+	return len(e.after) > 0 && !unicode.IsSpace(e.after[0])
+}
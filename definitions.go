@@ -0,0 +1,48 @@
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+// Optional local dictionary with one definition per line
+// Eg:- algorithm	a step by step procedure for solving a problem
+const definitionsFile = "definitions.txt"
+
+// Maps a word to its one-line definition
+type Definitions map[string]string
+
+// Loads definitions from path. A missing file simply disables the preview
+func LoadDefinitions(path string, v *Verifier) Definitions {
+	defs := make(Definitions)
+
+	data, err := os.ReadFile(path)
+	if err != nil || !v.Allow(path) {
+		return defs
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// word and definition are separated by a TAB, falling back to the first space
+		word, def, ok := strings.Cut(line, "\t")
+		if !ok {
+			word, def, _ = strings.Cut(line, " ")
+		}
+		if def = strings.TrimSpace(def); def != "" {
+			defs[word] = def
+		}
+	}
+	return defs
+}
+
+// Returns the status line text for word, empty if there is no definition
+func (d Definitions) Status(word string) string {
+	def, ok := d[word]
+	if !ok {
+		return ""
+	}
+	return word + ": " + def
+}
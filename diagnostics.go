@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+)
+
+// Problems found while starting up or running. They are shown in the status
+// line instead of being printed over the raw mode screen, and written to the log
+type Diagnostics struct {
+	mu       sync.Mutex
+	problems []string
+	shown    int         // problems already handed out by Unseen
+	logger   *log.Logger // nil until LogTo
+}
+
+var diagnostics Diagnostics
+
+// Older problems are dropped beyond this, a failing disk reports one per typed word
+const maxProblems = 100
+
+func (d *Diagnostics) Add(problems ...string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.problems = append(d.problems, problems...)
+	if drop := len(d.problems) - maxProblems; drop > 0 {
+		d.problems = d.problems[drop:]
+		d.shown = max(d.shown-drop, 0)
+	}
+	if d.logger != nil {
+		for _, problem := range problems {
+			d.logger.Print(problem)
+		}
+	}
+}
+
+// Writes the problems found so far and all later ones to logger
+func (d *Diagnostics) LogTo(logger *log.Logger) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.logger = logger
+	for _, problem := range d.problems {
+		logger.Print(problem)
+	}
+}
+
+func (d *Diagnostics) Addf(format string, args ...any) {
+	d.Add(fmt.Sprintf(format, args...))
+}
+
+// Returns the problems added since the last call
+func (d *Diagnostics) Unseen() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	unseen := d.problems[d.shown:]
+	d.shown = len(d.problems)
+	return unseen
+}
+
+// Joins problems for the status line. Eg:- 2 problems: dictionary words.txt: ...; plugin emoji: ...
+func problemStatus(problems []string) string {
+	switch len(problems) {
+	case 0:
+		return ""
+	case 1:
+		return problems[0]
+	}
+	return fmt.Sprintf("%d problems: %s", len(problems), strings.Join(problems, "; "))
+}
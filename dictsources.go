@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Most word lists loaded together, each has a bit in DictSources
+const maxDictionaries = 64
+
+// A word list and how much its words count. Eg:- "medical.txt:weight=2" makes
+// every word of medical.txt count as if it was listed twice
+type DictSpec struct {
+	path   string
+	weight float64
+}
+
+// Splits "path[:weight=N]" into the path and the weight, 1 when none is given
+func parseDictSpec(spec string) (DictSpec, error) {
+	i := strings.LastIndex(spec, ":weight=")
+	if i < 0 {
+		return DictSpec{path: spec, weight: 1}, nil
+	}
+	weight, err := strconv.ParseFloat(spec[i+len(":weight="):], 64)
+	if err != nil || !(weight > 0) || math.IsInf(weight, 1) {
+		return DictSpec{}, fmt.Errorf("%q: the weight must be a positive number", spec)
+	}
+	return DictSpec{path: spec[:i], weight: weight}, nil
+}
+
+// Eg:- "medical.txt ×2", just the name for a weight of 1
+func (s DictSpec) String() string {
+	if s.weight == 1 {
+		return filepath.Base(s.path)
+	}
+	return fmt.Sprintf("%s ×%g", filepath.Base(s.path), s.weight)
+}
+
+// Eg:- "words.txt, medical.txt ×2"
+func dictNames(specs []DictSpec) string {
+	names := make([]string, len(specs))
+	for i, s := range specs {
+		names[i] = s.String()
+	}
+	return strings.Join(names, ", ")
+}
+
+// A count of the word list scaled by its weight, at least a use
+func (s DictSpec) count(count int) int {
+	return max(1, int(math.Round(float64(count)*s.weight)))
+}
+
+// The word lists to load with dictionary as the first one, then the
+// dictionaries of the config
+func (cfg Config) dictSpecs(dictionary string) []DictSpec {
+	var specs []DictSpec
+	if dictionary != "" {
+		specs = append(specs, DictSpec{path: dictionary, weight: cfg.dictWeight})
+	}
+	for _, s := range cfg.Dictionaries {
+		if spec, err := parseDictSpec(s); err == nil && spec.path != "" { // checked by validate
+			spec.path = inDataDir(spec.path)
+			specs = append(specs, spec)
+		}
+	}
+	return specs
+}
+
+// Which of several word lists each word came from. nil for a single one, where
+// every dictionary word came from it
+type DictSources struct {
+	specs []DictSpec
+	words map[string]uint64 // bit i for specs[i]
+}
+
+func (s *DictSources) add(i int, word string) {
+	s.words[word] |= 1 << i
+}
+
+// The word lists which have word, in the order they are loaded
+func (s *DictSources) Of(word string) []DictSpec {
+	if s == nil {
+		return nil
+	}
+	var of []DictSpec
+	for i, spec := range s.specs {
+		if s.words[word]&(1<<i) != 0 {
+			of = append(of, spec)
+		}
+	}
+	return of
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Several dictionaries merge into one Trie, each count times the weight of its list
+func TestDictionaries(t *testing.T) {
+	dir := t.TempDir()
+	words, medical := filepath.Join(dir, "words.txt"), filepath.Join(dir, "medical.txt")
+	os.WriteFile(words, []byte("hello help\n"), 0644)
+	os.WriteFile(medical, []byte("hello hemostat\n"), 0644)
+	t.Setenv(envPrefix+"_DICTIONARY", "")
+	t.Setenv(envPrefix+"_DICTIONARIES", "")
+	if _, err := parseFlags([]string{"--dict", words, "--dict", medical + ":weight=2", "suggest"}); err != nil {
+		t.Fatal(err)
+	}
+	if os.Getenv(envPrefix+"_DICTIONARY") != words || os.Getenv(envPrefix+"_DICTIONARIES") != medical+":weight=2" {
+		t.Fatalf("--dict set %q and %q", os.Getenv(envPrefix+"_DICTIONARY"), os.Getenv(envPrefix+"_DICTIONARIES"))
+	}
+	cfg, err := LoadConfig(filepath.Join(dir, "config.toml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base, sources, problems := loadBase(cfg.dictSpecs(cfg.Dictionary), false, false, NewVerifier(cfg))
+	if len(problems) > 0 || base.Count("hello") != 3 || base.Count("hemostat") != 2 || base.Count("help") != 1 {
+		t.Errorf("hello %d, hemostat %d, help %d, problems %q", base.Count("hello"), base.Count("hemostat"), base.Count("help"), problems)
+	}
+	if names := dictNames(sources.Of("hello")); names != "words.txt, medical.txt ×2" {
+		t.Errorf("hello came from %q", names)
+	}
+	if names := dictNames(sources.Of("help")); names != "words.txt" {
+		t.Errorf("help came from %q", names)
+	}
+	if _, sources, _ := loadBase(defaultConfig().dictSpecs(words), false, false, NewVerifier(cfg)); sources != nil {
+		t.Error("a single dictionary tracked its sources")
+	}
+
+	for _, spec := range []string{"medical.txt:weight=0", "medical.txt:weight=x", "medical.txt:weight=-1"} {
+		cfg := defaultConfig()
+		cfg.Dictionaries = []string{spec}
+		if cfg.validate() == nil {
+			t.Errorf("accepted %q", spec)
+		}
+	}
+}
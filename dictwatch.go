@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/b0tShaman/autocomplete-cli/trie"
+)
+
+// The files of the dictionaries of cfg, for the dictWatcher
+func (cfg Config) dictPaths() []string {
+	var paths []string
+	for _, spec := range cfg.dictSpecs(cfg.Dictionary) {
+		paths = append(paths, spec.path)
+	}
+	return paths
+}
+
+// Reads the dictionaries again in the background when their files change, so
+// an edited words.txt shows without a restart. One reload runs at a time, a
+// change while it runs reads them once more after it
+type dictReloader struct {
+	done    chan func(e *Editor) string
+	running bool
+	again   bool // a file changed while running
+}
+
+func newDictReloader() *dictReloader {
+	return &dictReloader{done: make(chan func(e *Editor) string, 1)}
+}
+
+// Starts reading the dictionaries of cfg, or once the running reload is done
+func (r *dictReloader) Start(cfg Config) {
+	if r.running {
+		r.again = true
+		return
+	}
+	r.running = true
+	go func() { r.done <- reloadDictionaries(cfg) }()
+}
+
+// Swaps in what a reload read and returns the status to show
+func (r *dictReloader) Finish(e *Editor, apply func(e *Editor) string) string {
+	r.running = false
+	status := apply(e)
+	if r.again {
+		r.again = false
+		r.Start(e.cfg)
+	}
+	return status
+}
+
+// Reads the dictionaries of cfg again, off the main loop, and returns what swaps
+// them in for the bottom layer on it. The learned words and those of the session
+// stay as they are, and so do the old dictionary words when one cannot be read
+func reloadDictionaries(cfg Config) func(e *Editor) string {
+	verifier := NewVerifier(cfg)
+	base, sources, problems := loadBase(cfg.dictSpecs(cfg.Dictionary), cfg.Dawg, cfg.BuiltinWords, verifier)
+	integrity := verifier.Problems()
+	if integrity != "" && cfg.Verify == "strict" {
+		problems = append(problems, "integrity: "+integrity)
+	}
+	meta := LoadMetadata(cfg.Dictionary, verifier)
+	var humps HumpIndex
+	if cfg.codeMode() {
+		humps = NewHumpIndex(trie.NewStack(base)) // the learned words are added on the swap
+	}
+
+	return func(e *Editor) string {
+		if integrity != "" {
+			diagnostics.Add("integrity: " + integrity)
+		}
+		if len(problems) > 0 {
+			diagnostics.Add(problems...)
+			return "dictionary not reloaded: " + problemStatus(problems)
+		}
+		if e.cfg.sourcesChanged(cfg) || e.cfg.codeMode() != cfg.codeMode() {
+			return "dictionary reload dropped, the config was reloaded" // which read them already
+		}
+
+		e.trie.SetLayer(baseLayer, base)
+		e.sources = sources
+		e.meta = meta
+		if humps != nil {
+			for _, layer := range []int{userLayer, sessionLayer} {
+				for _, w := range e.trie.Layer(layer).Words() {
+					humps.Add(w.Value)
+				}
+			}
+		}
+		e.humps = humps
+		e.warm = NewPrefixCache() // the cached completions are those of the old words
+		return fmt.Sprintf("dictionary reloaded, %d words", base.Stats().Words)
+	}
+}
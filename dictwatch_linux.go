@@ -0,0 +1,100 @@
+//go:build linux
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+)
+
+// What inotify tells about the directories of the dictionaries: a file written
+// and closed, renamed in or out (editors often save by renaming a new file over
+// the old one) or deleted
+const dictWatchEvents = syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_TO | syscall.IN_MOVED_FROM | syscall.IN_DELETE
+
+// Signals on C when one of the files it watches changes, told by inotify. It
+// watches their directories, so a file replaced by another one is still watched
+type dictWatcher struct {
+	C chan struct{}
+
+	fd    int
+	f     *os.File // reads the events of fd, nil when inotify is unavailable
+	mu    sync.Mutex
+	dirs  map[int32]string // by watch descriptor
+	files map[string]bool
+}
+
+func watchDictionaries() *dictWatcher {
+	w := &dictWatcher{C: make(chan struct{}, 1)}
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		diagnostics.Addf("dictionaries not watched: %v", err)
+		return w
+	}
+	w.fd, w.f = fd, os.NewFile(uintptr(fd), "inotify")
+	go w.read()
+	return w
+}
+
+// Watches paths instead of the files watched so far
+func (w *dictWatcher) Watch(paths []string) {
+	if w.f == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for wd := range w.dirs {
+		syscall.InotifyRmWatch(w.fd, uint32(wd))
+	}
+	w.dirs, w.files = make(map[int32]string), make(map[string]bool)
+	for _, path := range paths {
+		path, _ = filepath.Abs(path)
+		w.files[path] = true
+		wd, err := syscall.InotifyAddWatch(w.fd, filepath.Dir(path), dictWatchEvents)
+		if err != nil {
+			diagnostics.Addf("%s not watched: %v", filepath.Base(path), err)
+			continue
+		}
+		w.dirs[int32(wd)] = filepath.Dir(path) // the same descriptor for a directory watched already
+	}
+}
+
+// Reads the events until Close, signalling those about a watched file
+func (w *dictWatcher) read() {
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		n, err := w.f.Read(buf)
+		if err != nil {
+			return
+		}
+		changed := false
+		w.mu.Lock()
+		for event := buf[:n]; len(event) >= syscall.SizeofInotifyEvent; {
+			wd := int32(binary.NativeEndian.Uint32(event))
+			size := int(binary.NativeEndian.Uint32(event[12:]))
+			name := event[syscall.SizeofInotifyEvent:min(len(event), syscall.SizeofInotifyEvent+size)]
+			if dir, ok := w.dirs[wd]; ok && w.files[filepath.Join(dir, string(bytes.TrimRight(name, "\x00")))] {
+				changed = true
+			}
+			event = event[len(name)+syscall.SizeofInotifyEvent:]
+		}
+		w.mu.Unlock()
+		if changed {
+			select {
+			case w.C <- struct{}{}:
+			default: // a reload is pending already
+			}
+		}
+	}
+}
+
+// Stops watching
+func (w *dictWatcher) Close() {
+	if w.f != nil {
+		w.f.Close()
+	}
+}
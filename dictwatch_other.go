@@ -0,0 +1,73 @@
+//go:build !linux
+
+package main
+
+import "time"
+
+// How often the files are checked, there is no inotify on this platform
+const dictWatchEvery = time.Second
+
+// Modification times of the dictionary files when they were last checked
+type dictWatch map[string]time.Time
+
+// Reports whether one of paths changed since the last check. Those not
+// checked before, Eg:- added by a config reload, count as unchanged
+func (w dictWatch) changed(paths []string) bool {
+	changed := false
+	for _, path := range paths {
+		m := modTime(path)
+		if last, ok := w[path]; ok && !last.Equal(m) {
+			changed = true
+		}
+		w[path] = m
+	}
+	return changed
+}
+
+// Signals on C when the modification time of one of the files it watches
+// changes, checked every dictWatchEvery
+type dictWatcher struct {
+	C chan struct{}
+
+	paths chan []string
+	stop  chan struct{}
+}
+
+func watchDictionaries() *dictWatcher {
+	w := &dictWatcher{C: make(chan struct{}, 1), paths: make(chan []string), stop: make(chan struct{})}
+	go w.poll()
+	return w
+}
+
+// Watches paths instead of the files watched so far
+func (w *dictWatcher) Watch(paths []string) {
+	w.paths <- paths
+}
+
+func (w *dictWatcher) poll() {
+	ticker := time.NewTicker(dictWatchEvery)
+	defer ticker.Stop()
+	var paths []string
+	watch := make(dictWatch)
+	for {
+		select {
+		case <-w.stop:
+			return
+		case paths = <-w.paths:
+			watch = make(dictWatch)
+			watch.changed(paths)
+		case <-ticker.C:
+			if watch.changed(paths) {
+				select {
+				case w.C <- struct{}{}:
+				default: // a reload is pending already
+				}
+			}
+		}
+	}
+}
+
+// Stops watching
+func (w *dictWatcher) Close() {
+	close(w.stop)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// An edited dictionary is read again into the bottom layer, the learned and session words stay
+func TestWatchDictionaries(t *testing.T) {
+	dir := t.TempDir()
+	h := newHarness(t, dir)
+	words := filepath.Join(dir, "words.txt")
+	os.WriteFile(words, []byte("alpha alpine\n"), 0644)
+	h.e.cfg.Dictionary, h.e.cfg.BuiltinWords = words, false
+	h.e.trie.Insert(userLayer, "learned")
+	h.e.trie.Insert(sessionLayer, "session")
+	w := watchDictionaries()
+	defer w.Close()
+	w.Watch(h.e.cfg.dictPaths())
+
+	os.WriteFile(filepath.Join(dir, "other.txt"), []byte("other\n"), 0644)
+	select {
+	case <-w.C:
+		t.Fatal("a file next to the dictionary counted as a change of it")
+	case <-time.After(100 * time.Millisecond):
+	}
+	os.WriteFile(words+".new", []byte("beta alpine\n"), 0644)
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(words+".new", later, later)
+	os.Rename(words+".new", words) // the way editors save
+	select {
+	case <-w.C:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the edit was not noticed")
+	}
+
+	r := newDictReloader()
+	r.Start(h.e.cfg)
+	r.Start(h.e.cfg) // changed again meanwhile
+	if status := r.Finish(h.e, <-r.done); status != "dictionary reloaded, 2 words" {
+		t.Fatalf("reload said %q", status)
+	}
+	for word, want := range map[string]int{"alpha": 0, "beta": 1, "alpine": 1, "hello": 0, "learned": 1, "session": 1} {
+		if got := h.e.trie.Count(word); got != want {
+			t.Errorf("%s counts %d after the reload, want %d", word, got, want)
+		}
+	}
+	r.Finish(h.e, <-r.done)
+
+	os.Remove(words)
+	if status := reloadDictionaries(h.e.cfg)(h.e); !strings.HasPrefix(status, "dictionary not reloaded") || h.e.trie.Count("beta") != 1 {
+		t.Errorf("reload of a missing dictionary said %q, beta %d", status, h.e.trie.Count("beta"))
+	}
+	os.WriteFile(words, []byte("gamma\n"), 0644)
+	apply := reloadDictionaries(h.e.cfg)
+	h.e.cfg.Dictionary = filepath.Join(dir, "other.txt")
+	if status := apply(h.e); !strings.Contains(status, "dropped") || h.e.trie.Count("beta") != 1 {
+		t.Errorf("reload read before a config reload said %q", status)
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/b0tShaman/autocomplete-cli/trie"
+)
+
+const diffListed = 20 // words listed per section of a diff, and top words compared
+
+// Reads the counts of a snapshot (see SaveSnapshot) or of a dictionary, where
+// every occurrence of a word is a use of it like in loadTrie
+func loadCounts(path string) (map[string]int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int)
+	if isSnapshot(string(data)) {
+		snapshot, err := LoadSnapshot(path)
+		for word, u := range snapshot {
+			counts[word] = u.count
+		}
+		return counts, err
+	}
+	t, _, err := parseDictionaryFile(data)
+	for _, w := range t.Words() {
+		counts[w.Value] = w.Count
+	}
+	return counts, err
+}
+
+// Reports whether every line of data is "word<TAB>count", maybe followed by more fields
+func isSnapshot(data string) bool {
+	lines := 0
+	for _, line := range strings.Split(data, "\n") {
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) < 2 {
+			return false
+		}
+		if _, err := strconv.Atoi(fields[1]); err != nil {
+			return false
+		}
+		lines++
+	}
+	return lines > 0
+}
+
+// Words of counts ranked by usage, most used first
+func rankCounts(counts map[string]int) []trie.Word {
+	words := make([]trie.Word, 0, len(counts))
+	for word, count := range counts {
+		words = append(words, trie.Word{Value: word, Count: count})
+	}
+	trie.Sort(words, trie.ByCount)
+	return words
+}
+
+// Writes what changed from old to new: the words added and removed, the counts
+// which changed and how the top words moved
+func writeDiff(w io.Writer, old, new map[string]int) {
+	var added, removed, changed []trie.Word
+	for word, count := range new {
+		if before, ok := old[word]; !ok {
+			added = append(added, trie.Word{Value: word, Count: count})
+		} else if before != count {
+			changed = append(changed, trie.Word{Value: word, Count: count - before})
+		}
+	}
+	for word, count := range old {
+		if _, ok := new[word]; !ok {
+			removed = append(removed, trie.Word{Value: word, Count: count})
+		}
+	}
+	fmt.Fprintf(w, "%d words added, %d removed, %d counts changed\n", len(added), len(removed), len(changed))
+
+	section := func(title string, words []trie.Word, score trie.Scorer, line func(trie.Word) string) {
+		if len(words) == 0 {
+			return
+		}
+		trie.Sort(words, score)
+		fmt.Fprintf(w, "\n%s:\n", title)
+		for _, word := range words[:min(diffListed, len(words))] {
+			fmt.Fprintln(w, line(word))
+		}
+		if len(words) > diffListed {
+			fmt.Fprintf(w, "  ... and %d more\n", len(words)-diffListed)
+		}
+	}
+	section("added", added, trie.ByCount, func(word trie.Word) string {
+		return fmt.Sprintf("+ %-20s %d", word.Value, word.Count)
+	})
+	section("removed", removed, trie.ByCount, func(word trie.Word) string {
+		return fmt.Sprintf("- %-20s %d", word.Value, word.Count)
+	})
+	// Biggest changes first, either way
+	delta := func(word string, d int) float64 { return float64(max(d, -d)) }
+	section("changed", changed, delta, func(word trie.Word) string {
+		return fmt.Sprintf("~ %-20s %d --> %d (%+d)", word.Value, old[word.Value], new[word.Value], word.Count)
+	})
+
+	oldRanks := make(map[string]int)
+	for i, word := range rankCounts(old) {
+		oldRanks[word.Value] = i + 1
+	}
+	top := rankCounts(new)
+	if len(top) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\ntop words:\n")
+	for i, word := range top[:min(diffListed, len(top))] {
+		move := "new"
+		if rank, ok := oldRanks[word.Value]; ok && rank == i+1 {
+			move = "="
+		} else if ok {
+			move = fmt.Sprintf("was %d (%+d)", rank, rank-(i+1))
+		}
+		fmt.Fprintf(w, "%3d. %-20s %-8d %s\n", i+1, word.Value, word.Count, move)
+	}
+}
+
+// autocomplete diff <old> <new>
+// Compares two dictionaries or snapshots, Eg:- before merging learned data
+func diffCommand(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("usage: diff <old> <new>, each a dictionary or a snapshot (counts.txt)")
+	}
+	old, err := loadCounts(args[0])
+	if err != nil {
+		return err
+	}
+	new, err := loadCounts(args[1])
+	if err != nil {
+		return err
+	}
+	writeDiff(os.Stdout, old, new)
+	return nil
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"golang.org/x/term"
+)
+
+// Outcome of one doctor check
+type checkResult struct {
+	level   string // ok, warn or FAIL
+	subject string
+	detail  string
+}
+
+// autocomplete doctor
+// Checks the config, dictionaries, terminal and permissions without starting the
+// editor and exits with an error when something would not work
+func doctorCommand(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("usage: doctor")
+	}
+	var results []checkResult
+	check := func(level, subject, format string, a ...any) {
+		results = append(results, checkResult{level, subject, fmt.Sprintf(format, a...)})
+	}
+
+	// Config
+	cfg, err := LoadConfig(configPath())
+	switch _, statErr := os.Stat(configPath()); {
+	case err != nil:
+		check("FAIL", "config", "%s: %v", configPath(), err)
+	case os.IsNotExist(statErr):
+		check("warn", "config", "%s does not exist, using the defaults (autocomplete setup creates it)", configPath())
+	default:
+		check("ok", "config", "%s", configPath())
+	}
+
+	// Dictionaries
+	verifier := NewVerifier(cfg)
+	builtin := len(strings.Fields(builtinWords))
+	if cfg.Dictionary == "" && cfg.BuiltinWords {
+		check("ok", "dictionary", "none configured, completing from the %d built-in words", builtin)
+	} else if cfg.Dictionary == "" {
+		check("warn", "dictionary", "none configured, completing from learned words only")
+	} else if _, err := os.Stat(cfg.Dictionary); os.IsNotExist(err) && cfg.BuiltinWords && cfg.Dictionary == defaultConfig().Dictionary {
+		check("ok", "dictionary", "%s does not exist, completing from the %d built-in words", cfg.Dictionary, builtin)
+	} else if data, err := os.ReadFile(cfg.Dictionary); err != nil {
+		check("FAIL", "dictionary", "%v", err)
+	} else if t, _, err := parseDictionaryFile(data); err != nil {
+		check("FAIL", "dictionary", "%s: %v", cfg.Dictionary, err)
+	} else if words := t.Stats().Words; words == 0 {
+		check("warn", "dictionary", "%s has no words", cfg.Dictionary)
+	} else {
+		check("ok", "dictionary", "%s, %d words", cfg.Dictionary, words)
+		verifier.Allow(cfg.Dictionary)
+	}
+	for _, spec := range cfg.dictSpecs("") {
+		if data, err := os.ReadFile(spec.path); err != nil {
+			check("FAIL", "dictionary", "%v", err)
+		} else if t, _, err := parseDictionaryFile(data); err != nil {
+			check("FAIL", "dictionary", "%s: %v", spec.path, err)
+		} else {
+			check("ok", "dictionary", "%s, %d words, weight %g", spec.path, t.Stats().Words, spec.weight)
+			verifier.Allow(spec.path)
+		}
+	}
+	if _, err := os.Stat(cfg.Definitions); err == nil {
+		if _, err := os.ReadFile(cfg.Definitions); err != nil {
+			check("FAIL", "definitions", "%v", err)
+		} else {
+			check("ok", "definitions", "%d definitions", len(LoadDefinitions(cfg.Definitions, verifier)))
+		}
+	}
+	if files, err := filepath.Glob(cfg.Translations); err != nil {
+		check("FAIL", "translations", "bad pattern %q: %v", cfg.Translations, err)
+	} else if len(files) > 0 {
+		check("ok", "translations", "%d languages", len(LoadBilingual(cfg.Translations, verifier)))
+	}
+	if _, problems := LoadPacks(cfg.Packs, verifier); len(problems) > 0 {
+		check("FAIL", "packs", "%s", problemStatus(problems))
+	} else if len(cfg.Packs) > 0 {
+		check("ok", "packs", "%d loaded", len(cfg.Packs))
+	}
+	if project, problems := openProject(cfg); len(problems) > 0 {
+		check("FAIL", "project", "%s", problemStatus(problems))
+	} else if project != nil {
+		check("ok", "project", "%s, %d words and %d snippets", project.dir, len(project.words), len(project.snippets))
+	}
+	if problems := verifier.Problems(); problems != "" {
+		level := "warn"
+		if cfg.Verify == "strict" {
+			level = "FAIL"
+		}
+		check(level, "integrity", "%s", problems)
+	}
+
+	// Learned data of the profile
+	if _, err := LoadSnapshot(paths.snapshot); err != nil {
+		check("FAIL", "learned data", "%v", err)
+	} else if _, err := ReadLearnLog(paths.learnLog); err != nil {
+		check("FAIL", "learned data", "%v", err)
+	} else {
+		profile := cfg.Profile
+		if profile == "" {
+			profile = "default"
+		}
+		check("ok", "learned data", "profile %s in %s", profile, paths.dir)
+	}
+
+	// Terminal
+	if !term.IsTerminal(int(syscall.Stdin)) {
+		check("FAIL", "terminal", "stdin is not a terminal, the editor needs one")
+	} else if restore, err := makeRaw(); err != nil {
+		check("FAIL", "terminal", "raw mode unavailable: %v", err)
+	} else {
+		restore()
+		width, height, _ := term.GetSize(int(syscall.Stdout))
+		check("ok", "terminal", "raw mode works, %dx%d", width, height)
+	}
+	switch os.Getenv("TERM") {
+	case "":
+		check("warn", "terminal", "TERM is not set, escape sequences may not work")
+	case "dumb":
+		check("warn", "terminal", "TERM=dumb does not support the escape sequences used for the status line")
+	}
+
+	// Permissions
+	for _, dir := range []struct{ name, path string }{
+		{"config dir", configDir()},
+		{"data dir", paths.dir},
+		{"cache dir", cacheDir()},
+		{"control dir", controlDir()},
+	} {
+		if err := writable(dir.path); err != nil {
+			check("FAIL", dir.name, "%v", err)
+		} else {
+			check("ok", dir.name, "%s is writable", dir.path)
+		}
+	}
+
+	// Plugins which would be skipped
+	entries, _ := os.ReadDir(filepath.Join(configDir(), pluginsDir))
+	for _, entry := range entries {
+		if info, err := entry.Info(); err == nil && info.Mode().IsRegular() && info.Mode()&0111 == 0 {
+			check("warn", "plugin "+entry.Name(), "not executable, skipped")
+		}
+	}
+
+	failed := 0
+	for _, r := range results {
+		fmt.Printf("%-4s  %-14s %s\n", r.level, r.subject, r.detail)
+		if r.level == "FAIL" {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d checks failed", failed)
+	}
+	return nil
+}
+
+// Tries to create a file in dir, creating dir when needed
+func writable(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, ".doctor-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
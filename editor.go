@@ -0,0 +1,497 @@
+package main
+
+import (
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/b0tShaman/autocomplete-cli/prompt"
+	"github.com/b0tShaman/autocomplete-cli/trie"
+)
+
+// Produces the candidates for the word being typed after up to contextWords
+// previous words, oldest first. The editor shows, cycles and accepts them the
+// same way whatever they come from, with the ghost text and completion of
+// package prompt, which other programs use with a prompt.Source of their own
+type CandidateSource func(previous []string, word string) []Candidate
+
+// The debounce timer, a *time.Timer outside of tests
+type debouncer interface {
+	Reset(d time.Duration) bool
+	Stop() bool
+}
+
+// The editor without the terminal: raw input and debounce timeouts go in, frames
+// for render() come out. main() owns the terminal, the timer and everything that
+// reloads the fields below
+type Editor struct {
+	cfg      Config
+	prof     *profile
+	trie     *trie.Stack
+	sources  *DictSources // which dictionary each word came from, nil for a single one
+	humps    HumpIndex    // nil unless the profile is about code
+	defs     Definitions
+	meta     Metadata
+	bi       Bilingual
+	packs    []*Pack
+	team     *TeamWords
+	project  *Project // nil outside of a project
+	plugins  []*Plugin
+	recent   RecentTokens // typed tokens offered again instead of being learned
+	composer ComposeTable
+	warm     *PrefixCache
+	perf     Perf // latency of the suggestion lookups
+	pace     Pace // typing speed, for the adaptive debounce
+	source   CandidateSource
+
+	input       []rune           // Store input characters, up to the cursor
+	after       []rune           // and those after it, see moveCursor
+	triggered   bool             // to keep track of keypresses after the autocomplete feature is triggered
+	suggestions []Candidate      // list of suggestions for current word
+	index       int              // index to track currently displayed suggestion
+	t9Mode      bool             // digits 2-9 are resolved into words like on a phone keypad
+	proposal    *snippetProposal // frequently typed phrase offered as a snippet, if any
+	arm         string           // experiment arm which ranked the current suggestions
+	menu        []Candidate      // suggestions the open menu narrows down, nil when closed
+	accepted    []string         // completions accepted this session, most recent first
+	panel       int              // completion highlighted in the history panel, -1 when closed
+	help        bool             // the help overlay is shown
+	teach       bool             // the teach key was pressed, the next paste is learned
+	tables      keyTables        // dispatch tables of the modes, see dispatch
+	preview     []rune           // the input as typed while the suggestion is put in it, see startPreview
+	pasted      []rune           // what was pasted so far when it is learned, nil otherwise
+	recap       Recap            // what this session typed, printed on exit
+	composing   []rune           // keys of the compose sequence typed so far, nil when not composing
+	dead        bool             // the sequence started with a dead key rather than the compose key
+	learned     string           // word learned by the last SPACE, which Alt+Backspace unlearns
+	erased      string           // the word as typed before BACKSPACE first deleted from it, a typo if corrected
+	erasedAt    int              // where that word starts in input
+
+	partial []byte // start of a UTF-8 sequence split across reads
+	escape  []byte // start of an escape sequence split across reads
+
+	out    chan<- frame
+	frames int // sent by status()
+	timer  debouncer
+	due    bool // suggestions are looked up once the input read is handled, with debounce = 0
+}
+
+// Returns an editor suggesting from its own Trie and profile, unless source is set
+func NewEditor(out chan<- frame, timer debouncer) *Editor {
+	e := &Editor{out: out, timer: timer, warm: NewPrefixCache(), panel: -1}
+	e.source = e.engineCandidates
+	return e
+}
+
+// Sends the input with status to render()
+func (e *Editor) status(status string) {
+	e.out <- frame{text: string(e.input), after: string(e.after), status: status}
+	e.frames++
+}
+
+// Handles a read from the terminal and reports whether the editor keeps running.
+// The quit key (Ctrl+D by default) and Ctrl+C quit, escape sequences
+// decodeKey does not know (function keys, Alt+key) are dropped and UTF-8
+// sequences become single keys
+func (e *Editor) Feed(chunk []byte) (ok bool) {
+	e.pace.Key(time.Now())
+	// Without a debounce the suggestions follow each read, so a paste is looked
+	// up once rather than for every letter of it
+	defer func() {
+		if e.due && ok {
+			e.due = false
+			e.Suggest()
+		}
+	}()
+	if len(chunk) == 1 && chunk[0] == ESCAPE && e.escape == nil {
+		return e.send(ESCAPE) // the key itself, not the start of a sequence
+	}
+	for _, b := range chunk {
+		if b == CTRL_C {
+			return false
+		}
+		if e.escape != nil || b == ESCAPE {
+			e.escape = append(e.escape, b)
+			if escapeDone(e.escape) {
+				key, known := decodeKey(e.escape)
+				e.escape = nil
+				if known && !e.send(key) {
+					return false
+				}
+			}
+			continue
+		}
+		if !e.feedByte(b) {
+			return false
+		}
+	}
+	return true
+}
+
+// Handles a decoded key, returns false for the quit key
+func (e *Editor) send(key rune) bool {
+	if key == e.cfg.quitKey {
+		return false
+	}
+	e.Key(key)
+	return true
+}
+
+// Reports whether seq, starting with ESC, is a whole escape sequence:
+// CSI (ESC [ parameters final), SS3 (ESC O key) or ESC followed by one key
+func escapeDone(seq []byte) bool {
+	switch {
+	case len(seq) < 2:
+		return false
+	case seq[1] == '[':
+		last := seq[len(seq)-1]
+		return len(seq) > 2 && last >= 0x40 && last <= 0x7e
+	case seq[1] == 'O':
+		return len(seq) > 2
+	}
+	return true
+}
+
+func (e *Editor) feedByte(b byte) bool {
+	if key, ok := byteKeys[b]; ok && len(e.partial) == 0 {
+		return e.send(key)
+	}
+	if len(e.partial) == 0 && b < utf8.RuneSelf {
+		return e.send(rune(b))
+	}
+	e.partial = append(e.partial, b)
+	for len(e.partial) > 0 && utf8.FullRune(e.partial) {
+		r, size := utf8.DecodeRune(e.partial)
+		e.partial = e.partial[size:]
+		if r == utf8.RuneError && size == 1 {
+			continue // not UTF-8, dropped
+		}
+		e.Key(r)
+	}
+	return true
+}
+
+// Looks up suggestions for the word being typed, once typing paused
+func (e *Editor) Suggest() {
+	if e.menu != nil || e.insideWord() {
+		return // the menu is narrowed down instead, a word is not completed from its middle
+	}
+	e.endPreview()
+	// get current word being typed
+	word := prompt.CurrentWord(e.input)
+	// Suggestions showing already are refreshed by the same arm
+	if !e.triggered {
+		e.arm = e.cfg.Experiment.pick()
+	}
+	start := time.Now()
+	e.suggestions = e.source(prompt.PreviousWords(e.input, contextWords), word)
+	e.perf.Add(time.Since(start))
+	if len(e.suggestions) == 0 {
+		if e.cfg.Perf {
+			e.status(e.idleStatus())
+		}
+		return
+	}
+	if e.cfg.MaxSuggestions > 0 {
+		e.suggestions = e.suggestions[:min(len(e.suggestions), e.cfg.MaxSuggestions)]
+	}
+	if !e.triggered {
+		e.record("suggest", word)
+		e.record("shown", word)
+	}
+	e.triggered = true
+	e.show()
+}
+
+// The built-in source: the Trie, snippets, model, translations, plugins, packs,
+// the recent files and the team dictionary, ranked by the current experiment arm, the ignores and
+// the ranker, with the learned corrections of a typo and the pinned completions first. Before anything of the word is
+// typed, the words which followed the previous one
+func (e *Editor) engineCandidates(previous []string, word string) []Candidate {
+	var candidates []Candidate
+	if e.t9Mode && isT9Sequence(word) {
+		candidates = t9Candidates(e.trie, word)
+	} else if word == "" {
+		if e.cfg.NextWords {
+			candidates = e.prof.phrases.Candidates(previous)
+		}
+	} else if len([]rune(word)) >= e.cfg.MinPrefix {
+		score, warm := e.prof.scorer(scoring), e.warm
+		if arm := e.cfg.Experiment.scoring(e.arm, scoring); arm != scoring {
+			score, warm = e.prof.scorer(arm), nil // warmed with the regular scoring
+		}
+		candidates = append(e.recent.Candidates(word), e.prof.temporary.Candidates(word, time.Now())...)
+		if e.cfg.RecentFiles {
+			candidates = append(candidates, e.prof.files.Candidates(word)...)
+		}
+		candidates = append(candidates, buildCandidates(e.trie, score, warm, e.bi, e.project.Snippets(e.prof.snippets), e.plugins, e.prof.model, TagRanking{e.meta, e.cfg.Tags}, previous, word)...)
+		candidates = matchCase(e.trie, score, e.cfg.MatchCase, e.cfg.MatchAccents, word, candidates)
+		candidates = append(candidates, e.humps.Candidates(e.trie, word)...)
+		candidates = append(candidates, fuzzyCandidates(e.trie, score, e.cfg.Fuzzy, word, candidates)...)
+		candidates = append(candidates, leadCandidates(e.trie, score, word, e.cfg.tokenizer(), candidates)...)
+		candidates = append(candidates, packCandidates(e.packs, word, candidates)...)
+		candidates = append(candidates, e.team.Candidates(score, previous, word, candidates)...)
+		if e.cfg.Casing && !e.cfg.codeMode() {
+			candidates = foldCase(e.trie, score, e.cfg.KeepCase, previous, word, candidates)
+		}
+	}
+	candidates = e.prof.ignores.Filter(word, candidates)
+	if e.prof.ranker != nil && e.cfg.Rerank {
+		e.prof.ranker.Rerank(candidates, word, func(w string) (int, time.Time) { return e.trie.Count(w), e.prof.lastUsed[w] })
+	}
+	candidates = e.prof.typos.Correct(word, candidates)
+	candidates = preferSpelling(e.cfg.avoid, e.cfg.SpellingVariants == variantsHide, candidates)
+	candidates = pinCandidates(e.cfg.Pins, e.prof.pins, word, candidates)
+	return shortPrefixGuard(e.trie, e.prof.score, e.cfg.shortMargin(), word, candidates)
+}
+
+// Shows the rest of the current suggestion as ghost text after the cursor, with
+// the menu or its description below
+func (e *Editor) show() {
+	e.endPreview()
+	c := e.suggestions[e.index%len(e.suggestions)]
+	status := candidateStatus(c, e.defs, e.meta)
+	if e.menu != nil {
+		var section func(Candidate) string
+		if e.cfg.Menu.Group {
+			section = e.prof.menuSection
+		}
+		status = menuStatus(e.suggestions, e.index%len(e.suggestions), prompt.CurrentWord(e.input), section)
+	} else if n := e.cfg.ShowSuggestions; n > 1 && len(e.suggestions) > 1 {
+		list := listStatus(e.suggestions, e.index%len(e.suggestions), n, e.cfg.ShowOrder)
+		if status != "" {
+			list += "  " + status
+		}
+		status = list
+	}
+	status = e.perfStatus(status)
+	ghost := prompt.GhostText(prompt.CurrentWord(e.input), c.word)
+	if e.cfg.Preview {
+		e.startPreview(c.word)
+		ghost = ""
+	}
+	if style := e.cfg.Theme.Suggestion; style != "" && ghost != "" {
+		ghost = "\033[" + style + "m" + ghost + "\033[0m"
+	}
+	e.out <- frame{text: string(e.input), ghost: ghost, after: string(e.after), status: status}
+	e.frames++
+}
+
+// Drops the suggestions
+func (e *Editor) dismiss() {
+	e.endPreview()
+	e.triggered, e.menu = false, nil
+	e.suggestions, e.index = []Candidate{}, 0
+}
+
+// Handles one key, after composing it with the keys before it
+func (e *Editor) Key(key rune) {
+	if e.pasteKey(key) {
+		return
+	}
+	for _, k := range e.compose(key) {
+		e.press(k)
+	}
+}
+
+func (e *Editor) press(key rune) {
+	e.endPreview() // keys act on what was typed, show puts the suggestion back
+	k := &keyPress{key: key, action: e.cfg.Enter.action(key)}
+	if k.action == enterMove || (!e.triggered && (key == keyRight || key == keyEnd)) {
+		k.action = "" // they move the cursor
+	}
+
+	// Reset timer on each keypress
+	e.startTimer()
+
+	if e.triggered {
+		// The screen may still show the ghost text when the key draws nothing
+		defer func(frames int) {
+			if !e.triggered && e.frames == frames {
+				e.status(e.idleStatus())
+			}
+		}(e.frames)
+	}
+	e.dispatch(k)
+}
+
+// Learns the word just finished according to its token policy
+func (e *Editor) learnLastWord() {
+	token := getLastWord(e.input)
+	if token == "" || e.input[len(e.input)-1] == ' ' || e.input[len(e.input)-1] == '\n' {
+		return // nothing typed since the last word was learned
+	}
+	if e.recap.typed++; e.cfg.NoLearn {
+		return
+	}
+	defer func() { e.erased = "" }()
+	if file := fileToken(token); file != "" && e.cfg.RecentFiles {
+		e.learnFile(file)
+	}
+	if policy := e.cfg.Tokens.Policy(token); policy != tokenLearn {
+		e.learnToken(token, policy)
+		return
+	}
+
+	// The punctuation around and between words is not learned. Eg:- (hello, --> hello
+	tokenizer := e.cfg.tokenizer()
+	words := tokenizer.Words(token)
+	if erased := tokenizer.Words(e.erased); len(words) == 1 && len(erased) == 1 &&
+		e.erasedAt == len(e.input)-len([]rune(token)) && isTypo(erased[0], words[0]) {
+		e.learnTypo(erased[0], words[0])
+	}
+	for _, word := range words {
+		e.learnToken(word, e.cfg.Tokens.Policy(word))
+	}
+}
+
+func (e *Editor) learnToken(word, policy string) {
+	switch policy {
+	case tokenLearn:
+		if e.trie.Count(word) == 0 {
+			e.recap.Learned(word)
+		}
+		e.trie.Insert(userLayer, word)
+		e.humps.Add(word)
+		e.warm.Forget(word)
+		e.prof.learn(word, time.Now())
+		e.proposal = proposeSnippet(e.prof.snippets, e.prof.phrases, e.prof.offered, word)
+		e.learned = word
+	case tokenSuggest:
+		e.recent.Add(word)
+	case tokenTemporary:
+		e.learnTemporary(word)
+	}
+}
+
+// Records that the file name was typed, from then on it completes from its base name
+func (e *Editor) learnFile(name string) {
+	e.prof.files.Add(name, time.Now())
+	if err := e.prof.files.Save(e.prof.paths.files); err != nil {
+		diagnostics.Addf("saving recent files failed: %v", err)
+	}
+}
+
+// Reports whether the SPACE before the cursor just learned the word before it
+func (e *Editor) justLearned() bool {
+	n := len(e.input)
+	if e.learned == "" || n < 2 || e.input[n-1] != ' ' || unicode.IsSpace(e.input[n-2]) {
+		return false
+	}
+	words := e.cfg.tokenizer().Words(getLastWord(e.input[:n-1]))
+	return len(words) > 0 && words[len(words)-1] == e.learned
+}
+
+// Remembers the word starting at start before the first BACKSPACE deletes from
+// it, so retyping it learns the typo. Eg:- teh ⌫⌫he --> teh was corrected to the
+func (e *Editor) noteErased(start int) {
+	if word := string(e.input[start:]); word != "" && (e.erased == "" || e.erasedAt != start) {
+		e.erased, e.erasedAt = word, start
+	}
+}
+
+// Learns that typo was corrected to fix, from then on fix is offered first for it
+func (e *Editor) learnTypo(typo, fix string) {
+	if e.cfg.NoLearn || typo == fix {
+		return
+	}
+	e.prof.typos.Add(typo, fix)
+	if err := e.prof.typos.Save(e.prof.paths.typos); err != nil {
+		diagnostics.Addf("saving typos failed: %v", err)
+	}
+}
+
+// Deletes the word before the cursor along with the spaces after it. Right
+// after the SPACE which learned it, the word is unlearned too so typos do not stick
+func (e *Editor) deleteLastWord() {
+	end := len(e.input)
+	for end > 0 && unicode.IsSpace(e.input[end-1]) {
+		end--
+	}
+	start := end
+	for start > 0 && !unicode.IsSpace(e.input[start-1]) {
+		start--
+	}
+	status := e.idleStatus()
+	if word := e.learned; e.justLearned() {
+		status = "unlearned " + word
+		if err := e.unlearn(word); err != nil {
+			status = "could not unlearn " + word + ": " + err.Error()
+		}
+	}
+	e.learned = ""
+	e.noteErased(start)
+	e.input = e.input[:start]
+	e.status(status)
+}
+
+// Takes back the last learn of word
+func (e *Editor) unlearn(word string) error {
+	if err := e.prof.unlearn(word); err != nil {
+		return err
+	}
+	if e.trie.Decrement(userLayer, word); e.trie.Count(word) == 0 { // a dictionary word stays
+		e.recap.Unlearned(word)
+	}
+	e.warm.Forget(word)
+	e.proposal = nil
+	return nil
+}
+
+// Forgets word like the forget command does, recording a tombstone so it does
+// not come back. Returns the status to show
+func (e *Editor) forget(word string) string {
+	e.forgetWord(word)
+	e.warm.Forget(word)
+	e.recap.Unlearned(word)
+	e.prof.tombstones[word] = time.Now()
+	if err := e.prof.tombstones.Save(e.prof.paths.tombstones); err != nil {
+		return "saving tombstones failed: " + err.Error()
+	}
+	return "forgot " + word
+}
+
+// Drops word from the learned and the session words and hides it in the
+// dictionary, which is never changed
+func (e *Editor) forgetWord(word string) {
+	e.trie.Delete(userLayer, word)
+	e.trie.Delete(sessionLayer, word)
+	e.trie.Hide(word)
+}
+
+// Records what happens to the current suggestion in the events log
+func (e *Editor) record(kind string, prefix string) {
+	if e.cfg.NoLearn || (e.cfg.Experiment.Name == "" && !e.cfg.Rerank) {
+		return // only the experiment report and the ranker read the events
+	}
+	ev := Event{Kind: kind, Prefix: prefix, Arm: e.arm}
+	if e.arm != "" {
+		ev.Experiment = e.cfg.Experiment.Name
+	}
+	if kind == "suggest" {
+		ev.Count = len(e.suggestions)
+	} else {
+		c := e.suggestions[e.index%len(e.suggestions)]
+		ev.Word, ev.Rank, ev.Source = c.word, e.index%len(e.suggestions), c.source
+		ev.Uses = e.trie.Count(c.word)
+		if last := e.prof.lastUsed[c.word]; !last.IsZero() {
+			ev.Last = last.Unix()
+		}
+	}
+	e.prof.events.Record(ev)
+}
+
+// Status line shown while no suggestion is displayed, problems found in the
+// background take precedence
+func (e *Editor) idleStatus() string {
+	if problems := diagnostics.Unseen(); len(problems) > 0 {
+		return problemStatus(problems)
+	}
+	if e.proposal != nil {
+		return e.proposal.Status()
+	}
+	if e.t9Mode {
+		return "T9 mode - CTRL+T to leave"
+	}
+	return e.perfStatus("")
+}
@@ -0,0 +1,984 @@
+package main
+
+import (
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/b0tShaman/autocomplete-cli/prompt"
+	"github.com/b0tShaman/autocomplete-cli/trie"
+)
+
+// Stands in for the debounce timer, the harness decides when typing pauses
+type fakeTimer struct{ armed bool }
+
+func (t *fakeTimer) Reset(time.Duration) bool { t.armed = true; return true }
+func (t *fakeTimer) Stop() bool               { t.armed = false; return true }
+
+// A headless editor with a small dictionary and learned data that only lives in memory
+type harness struct {
+	t      testing.TB
+	e      *Editor
+	out    chan frame
+	timer  *fakeTimer
+	shown  string // text of the last frame rendered
+	ghost  string // and its ghost text
+	status string // and its status line
+}
+
+func newHarness(t testing.TB, dir string) *harness {
+	cfg := defaultConfig()
+	cfg.apply()
+
+	h := &harness{t: t, out: make(chan frame, 1<<16), timer: &fakeTimer{}}
+	h.e = NewEditor(h.out, h.timer)
+	h.e.cfg = cfg
+	h.e.prof = &profile{
+		paths:      pathsIn(dir),
+		boosts:     Boosts{},
+		tombstones: Tombstones{},
+		snippets:   Snippets{"brb": "be right back"},
+		phrases:    NewPhrases(nil, nil),
+		offered:    map[string]bool{},
+		lastUsed:   map[string]time.Time{},
+		ignores:    Ignores{},
+		pins:       Pins{},
+		typos:      Typos{},
+		temporary:  TempWords{},
+		files:      RecentFiles{},
+	}
+	h.e.trie = newWords(trie.New())
+	for _, word := range []string{"hello", "help", "helmet", "world", "word", "golang", "go", "café", "naïve", "日本語"} {
+		h.e.trie.Insert(baseLayer, word)
+	}
+	h.e.humps = NewHumpIndex(h.e.trie)
+	return h
+}
+
+// Collects the frames sent so far
+func (h *harness) drain() {
+	for {
+		select {
+		case f := <-h.out:
+			h.shown, h.ghost, h.status = f.text+f.after, f.ghost, f.status
+		default:
+			return
+		}
+	}
+}
+
+// Feeds one read and checks the invariants. Returns false once the editor quit
+func (h *harness) feed(chunk []byte) bool {
+	running := h.e.Feed(chunk)
+	h.drain()
+	h.check()
+	return running
+}
+
+// Typing pauses, the debounce timer fires if it is armed
+func (h *harness) pause() {
+	if h.timer.armed {
+		h.timer.armed = false
+		h.e.Suggest()
+		h.drain()
+		h.check()
+	}
+}
+
+func (h *harness) check() {
+	e := h.e
+	text := string(e.input) + string(e.after)
+	if !utf8.ValidString(text) {
+		h.t.Fatalf("buffer is not UTF-8: %q", text)
+	}
+	for _, r := range text {
+		if unicode.IsControl(r) && r != '\n' {
+			h.t.Fatalf("control character %U in buffer %q", r, text)
+		}
+	}
+	if e.triggered {
+		if len(e.suggestions) == 0 || e.index < 0 {
+			h.t.Fatalf("suggestion shown without suggestions: %d of %v", e.index, e.suggestions)
+		}
+	} else if h.shown != text {
+		h.t.Fatalf("buffer %q, rendered %q", text, h.shown)
+	}
+	if e.menu != nil && !e.triggered {
+		h.t.Fatalf("menu open without suggestions")
+	}
+}
+
+// Checks that the editor left no goroutines behind
+func (h *harness) close(goroutines int) {
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > goroutines {
+		if time.Now().After(deadline) {
+			h.t.Fatalf("%d goroutines left running, %d before", runtime.NumGoroutine(), goroutines)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// Pieces keystroke sequences are generated from
+var (
+	testEscapes = [][]byte{
+		[]byte("\x1b[A"), []byte("\x1b[B"), []byte("\x1b[1;5C"), []byte("\x1bOP"),
+		[]byte("\x1b[200~"), []byte("\x1bb"), []byte("\x1b\x1b"), []byte("\x1b["),
+		[]byte("\x1b[13;2u"), []byte("\x1b\r"), []byte("\x1b[D"), []byte("\x1b[C"), []byte("\x1b[H"),
+		[]byte("\x1b[F"), []byte("\x1b[3~"), []byte("\x1b[Z"),
+	}
+	testRunes = []string{"é", "ï", "日", "😄", "ß"}
+	testKeys  = []byte{' ', TAB, '\r', '\n', BACKSPACE, DELETE, CTRL_O, CTRL_X, CTRL_T, CTRL_S, CTRL_R, CTRL_P, 1, 0}
+)
+
+// Turns fuzz input into keystrokes: every byte picks an action, some use the next byte.
+// Returns false once the editor quit
+func (h *harness) play(script []byte) bool {
+	for i := 0; i < len(script); i++ {
+		arg := byte(0)
+		if i+1 < len(script) {
+			arg = script[i+1]
+		}
+		var running bool
+		switch script[i] % 8 {
+		case 0, 1: // a letter, mostly from the dictionary words
+			running = h.feed([]byte{"helowrdgcan2345"[int(arg)%15]})
+			i++
+		case 2:
+			running = h.feed([]byte{testKeys[int(arg)%len(testKeys)]})
+			i++
+		case 3:
+			h.pause()
+			continue
+		case 4: // an escape sequence, sometimes split across reads
+			seq := testEscapes[int(arg)%len(testEscapes)]
+			if cut := int(arg>>4) % len(seq); cut > 0 {
+				if !h.feed(seq[:cut]) {
+					return false
+				}
+				seq = seq[cut:]
+			}
+			running = h.feed(seq)
+			i++
+		case 5: // a UTF-8 character, sometimes split across reads
+			r := []byte(testRunes[int(arg)%len(testRunes)])
+			cut := int(arg>>4) % len(r)
+			if cut > 0 && !h.feed(r[:cut]) {
+				return false
+			}
+			running = h.feed(r[cut:])
+			i++
+		case 6: // a lone UTF-8 fragment
+			running = h.feed([]byte{0x80 | arg&0x3f})
+			i++
+		case 7: // raw bytes
+			end := min(len(script), i+1+int(arg)%8)
+			running = h.feed(script[i+1 : end])
+			i = end - 1
+		}
+		if !running {
+			return false
+		}
+	}
+	return true
+}
+
+func FuzzEditor(f *testing.F) {
+	for _, enter := range []string{enterNewline, enterCommit, enterSubmit} {
+		f.Add([]byte("\x00h\x00i\x02\x02"), enter)
+	}
+	f.Add([]byte("\x00h\x00e\x00l\x03\x02\x01\x02\x02\x03"), enterAccept)             // hel, pause, tab, enter
+	f.Add([]byte("\x00w\x03\x02\x06\x00o\x00r\x02\x06\x03"), enterAccept)             // menu narrowed down
+	f.Add([]byte("\x04\x00\x05\x13\x00c\x00a\x00f\x03\x02\x07\x02\x00"), enterAccept) // arrows and split é
+	f.Add([]byte("\x02\x08\x002\x003\x03\x02\x00"), enterAccept)                      // T9 mode
+	dir := f.TempDir()
+	f.Fuzz(func(t *testing.T, script []byte, enter string) {
+		goroutines := runtime.NumGoroutine()
+		h := newHarness(t, dir)
+		if h.e.cfg.Enter.Enter = enter; h.e.cfg.Enter.validate() != nil {
+			h.e.cfg.Enter.Enter = enterAccept
+		}
+		h.e.cfg.Preview = len(script)%2 == 1 // suggestions put in the text too
+		h.play(script)
+		h.close(goroutines)
+	})
+}
+
+// Typing a word and accepting its first suggestion completes it
+func TestEditorAccept(t *testing.T) {
+	goroutines := runtime.NumGoroutine()
+	h := newHarness(t, t.TempDir())
+	h.feed([]byte("gola"))
+	h.pause()
+	if !h.e.triggered {
+		t.Fatal("no suggestion for gola")
+	}
+	h.feed([]byte("\r"))
+	if got := string(h.e.input); got != "golang " {
+		t.Fatalf("accepting gave %q", got)
+	}
+	h.close(goroutines)
+}
+
+// Escape sequences are dropped, ESC on its own dismisses and Ctrl+D quits
+func TestEditorEscapes(t *testing.T) {
+	h := newHarness(t, t.TempDir())
+	for _, chunk := range []string{"a", "\x1b[A", "\xc3", "\xa9", "\x1b[", "1;5C", "b"} {
+		if !h.feed([]byte(chunk)) {
+			t.Fatalf("quit on %q", chunk)
+		}
+	}
+	if got := string(h.e.input); got != "aéb" {
+		t.Fatalf("buffer %q", got)
+	}
+	h.feed([]byte(" gola"))
+	h.pause()
+	h.feed([]byte{TAB})
+	if !h.e.triggered || h.e.index == 0 {
+		t.Fatal("no suggestion to cycle for gola")
+	}
+	if !h.feed([]byte{ESCAPE}) {
+		t.Fatal("ESC quit")
+	}
+	if h.e.triggered || h.e.index != 0 {
+		t.Fatalf("ESC left the suggestion, triggered %v index %d", h.e.triggered, h.e.index)
+	}
+	if got := string(h.e.input); got != "aéb gola" {
+		t.Fatalf("ESC changed the buffer to %q", got)
+	}
+	if h.feed([]byte{CTRL_D}) {
+		t.Fatal("Ctrl+D did not quit")
+	}
+}
+
+// Candidates from another source are shown, cycled and accepted like the built-in ones
+func TestEditorCustomSource(t *testing.T) {
+	h := newHarness(t, t.TempDir())
+	var asked []string
+	h.e.source = func(previous []string, word string) []Candidate {
+		asked = append(previous, word)
+		return []Candidate{{word: word + "1", source: "host"}, {word: word + "2", source: "host"}}
+	}
+	h.feed([]byte("say x"))
+	h.pause()
+	h.feed([]byte{TAB})
+	h.feed([]byte("\r"))
+	if got := string(h.e.input); got != "say x2 " {
+		t.Fatalf("accepting gave %q", got)
+	}
+	if len(asked) != 2 || asked[0] != "say" || asked[1] != "x" {
+		t.Fatalf("source asked for %q", asked)
+	}
+}
+
+// Shift+Enter starts a new line and Alt+Enter submits the buffer with the defaults
+func TestEditorEnter(t *testing.T) {
+	dir := t.TempDir()
+	h := newHarness(t, dir)
+	h.feed([]byte("one"))
+	h.feed([]byte("\x1b[13;2u"))
+	h.feed([]byte("two"))
+	if got := string(h.e.input); got != "one\ntwo" {
+		t.Fatalf("buffer %q", got)
+	}
+	h.feed([]byte("\x1b\r"))
+	if len(h.e.input) != 0 {
+		t.Fatalf("buffer %q left after submitting", string(h.e.input))
+	}
+	lines, err := os.ReadFile(h.e.prof.paths.lines)
+	if err != nil || !strings.HasSuffix(string(lines), "\ttwo\n") {
+		t.Fatalf("line history %q, %v", lines, err)
+	}
+	if h.e.trie.Count("two") != 1 {
+		t.Fatal("submitting did not learn the last word")
+	}
+}
+
+// Ctrl+Y lists what was accepted, most recent first, and a number inserts it again
+func TestEditorHistoryPanel(t *testing.T) {
+	h := newHarness(t, t.TempDir())
+	for _, prefix := range []string{"golan", "caf", "golan"} {
+		h.feed([]byte(prefix))
+		h.pause()
+		h.feed([]byte("\r"))
+	}
+	if got := string(h.e.input); got != "golang café golang " {
+		t.Fatalf("buffer %q", got)
+	}
+	h.feed([]byte{CTRL_Y})
+	if h.e.panel != 0 || !slices.Equal(h.e.accepted, []string{"golang", "café"}) {
+		t.Fatalf("panel %d lists %q", h.e.panel, h.e.accepted)
+	}
+	h.feed([]byte("2"))
+	if got := string(h.e.input); got != "golang café golang café " || h.e.panel != -1 {
+		t.Fatalf("inserting gave %q", got)
+	}
+	h.feed([]byte{CTRL_Y, 'x'})
+	if got := string(h.e.input); got != "golang café golang café x" || h.e.panel != -1 {
+		t.Fatalf("closing the panel gave %q", got)
+	}
+}
+
+// max_suggestions limits what TAB cycles through
+func TestEditorMaxSuggestions(t *testing.T) {
+	h := newHarness(t, t.TempDir())
+	h.e.cfg.MaxSuggestions = 2
+	h.feed([]byte("hel"))
+	h.pause()
+	if len(h.e.suggestions) != 2 {
+		t.Fatalf("%d suggestions", len(h.e.suggestions))
+	}
+}
+
+// The compose key and dead keys turn sequences into accented characters
+func TestEditorCompose(t *testing.T) {
+	h := newHarness(t, t.TempDir())
+	h.e.composer = ComposeTable{}
+	h.e.composer.parse(composeFile, builtinCompose)
+	h.e.cfg.Keys.Dead = "^"
+	h.feed([]byte{'n', 'a', CTRL_K, '"', 'i', 'v', 'e'})
+	if got := string(h.e.input); got != "naïve" {
+		t.Fatalf("composing gave %q", got)
+	}
+	h.feed([]byte(" ^e ^x ^ "))
+	if got := string(h.e.input); got != "naïve ê ^x ^" {
+		t.Fatalf("dead keys gave %q", got)
+	}
+	h.feed([]byte{CTRL_K, 'q', 'q'})
+	if got := string(h.e.input); got != "naïve ê ^x ^q" {
+		t.Fatalf("unknown sequence gave %q", got)
+	}
+}
+
+// With fuzzy on, a typo still finds the word after the exact completions
+func TestEditorFuzzy(t *testing.T) {
+	h := newHarness(t, t.TempDir())
+	h.feed([]byte("hlep"))
+	h.pause()
+	if h.e.triggered {
+		t.Fatalf("suggested %v without fuzzy", h.e.suggestions)
+	}
+	h.e.cfg.Fuzzy = 1
+	h.feed([]byte{BACKSPACE, 'p'})
+	h.pause()
+	if len(h.e.suggestions) == 0 || h.e.suggestions[0].word != "help" || h.e.suggestions[0].source != "fuzzy" {
+		t.Fatalf("suggestions %v", h.e.suggestions)
+	}
+}
+
+// preview puts the shown suggestion in the text, cycling and dismissing take it
+// out again and only accepting keeps it
+func TestEditorPreview(t *testing.T) {
+	h := newHarness(t, t.TempDir())
+	h.e.cfg.Preview = true
+	h.feed([]byte("say brb"))
+	h.pause()
+	if h.shown != "say be right back" || h.ghost != "" {
+		t.Fatalf("shown %q with ghost %q", h.shown, h.ghost)
+	}
+	h.feed([]byte{ESCAPE})
+	if h.shown != "say brb" || string(h.e.input) != "say brb" {
+		t.Fatalf("dismissed to %q, input %q", h.shown, string(h.e.input))
+	}
+
+	h.feed([]byte(" hel"))
+	h.pause()
+	first := h.shown
+	h.feed([]byte{TAB})
+	if h.shown == first || !strings.HasPrefix(h.shown, "say brb hel") {
+		t.Fatalf("cycled from %q to %q", first, h.shown)
+	}
+	h.feed([]byte("m"))
+	if got := string(h.e.input); got != "say brb helm" {
+		t.Fatalf("typed into the preview: %q", got)
+	}
+	h.pause()
+	h.feed([]byte("\r"))
+	if got := string(h.e.input); got != "say brb helmet " {
+		t.Errorf("accepted %q", got)
+	}
+}
+
+// Words shorter than min_prefix are not completed
+func TestEditorMinPrefix(t *testing.T) {
+	h := newHarness(t, t.TempDir())
+	h.feed([]byte("g"))
+	h.pause()
+	if h.e.triggered {
+		t.Fatalf("suggested %v for g", h.e.suggestions)
+	}
+	h.feed([]byte("o"))
+	h.pause()
+	if !h.e.triggered {
+		t.Fatal("no suggestion for go")
+	}
+
+	h = newHarness(t, t.TempDir())
+	h.e.cfg.MinPrefix = 3
+	h.feed([]byte("go"))
+	h.pause()
+	if h.e.triggered {
+		t.Fatalf("suggested %v for go below min_prefix 3", h.e.suggestions)
+	}
+	h.feed([]byte("l"))
+	h.pause()
+	if !h.e.triggered || h.e.suggestions[0].word != "golang" {
+		t.Fatal("no suggestion for gol")
+	}
+}
+
+// A key goes to the top mode the editor is in first, the tables follow the key config
+func TestEditorModes(t *testing.T) {
+	h := newHarness(t, t.TempDir())
+	h.feed([]byte("hel"))
+	h.pause()
+	h.feed([]byte("\r"))
+	h.feed([]byte("hel"))
+	h.pause()
+	h.feed([]byte{0x19, TAB}) // ctrl+y opens the history panel on top of the suggestions
+	if h.e.triggered || h.e.panel != 0 {
+		t.Fatalf("triggered %v, panel %d after TAB in the history panel", h.e.triggered, h.e.panel)
+	}
+	h.feed([]byte("1"))
+	if got := string(h.e.input); !strings.HasPrefix(got, "hello hello ") || h.e.panel != -1 {
+		t.Fatalf("inserted %q from the history panel", got)
+	}
+
+	h = newHarness(t, t.TempDir())
+	h.feed([]byte("hel"))
+	h.pause()
+	h.feed([]byte{TAB})
+	h.e.cfg.Keys.Cycle = "ctrl+n"
+	if err := h.e.cfg.resolveKeys(); err != nil {
+		t.Fatal(err)
+	}
+	h.feed([]byte{TAB})
+	if h.e.triggered || string(h.e.input) != "hel" {
+		t.Fatalf("TAB acted once cycle is ctrl+n, triggered %v, buffer %q", h.e.triggered, string(h.e.input))
+	}
+	h.pause()
+	h.feed([]byte{0x0e})
+	if !h.e.triggered || h.e.index != 1 {
+		t.Fatalf("ctrl+n did not cycle, index %d", h.e.index)
+	}
+}
+
+// debounce = 0 looks the suggestions up right after each read, without the timer
+func TestEditorNoDebounce(t *testing.T) {
+	h := newHarness(t, t.TempDir())
+	h.e.cfg.Debounce = 0
+	h.e.cfg.AdaptiveDebounce = true // nothing to adapt
+	h.feed([]byte("go"))
+	if h.timer.armed || !h.e.triggered {
+		t.Fatalf("armed %v, suggested %v for go", h.timer.armed, h.e.suggestions)
+	}
+	h.feed([]byte("l"))
+	if !h.e.triggered || h.ghost == "" {
+		t.Fatal("no suggestion for gol")
+	}
+	// A key that stops the lookup leaves nothing due
+	h.feed([]byte{CTRL_Y})
+	if h.e.due {
+		t.Error("lookup due after the history panel opened")
+	}
+}
+
+// File names typed twice complete from their base name, and are saved
+func TestEditorRecentFiles(t *testing.T) {
+	dir := t.TempDir()
+	h := newHarness(t, dir)
+	isFile := func(c Candidate) bool { return c.source == "file" }
+	h.feed([]byte("vim ~/.config/app/config.toml e.g. "))
+	h.feed([]byte("conf"))
+	h.pause()
+	if slices.ContainsFunc(h.e.suggestions, isFile) {
+		t.Fatalf("suggested %v after one use", h.e.suggestions)
+	}
+	h.feed([]byte("ig.toml, (vim ~/.config/app/config.toml) co"))
+	h.pause()
+	if !h.e.triggered || h.e.suggestions[0].word != "~/.config/app/config.toml" {
+		t.Fatalf("suggested %v for co", h.e.suggestions)
+	}
+	h.feed([]byte("\r"))
+	if got := string(h.e.input); !strings.HasSuffix(got, " ~/.config/app/config.toml ") {
+		t.Errorf("accepted %q", got)
+	}
+
+	files, err := LoadRecentFiles(pathsIn(dir).files)
+	if err != nil || files["~/.config/app/config.toml"].uses != 3 || files["config.toml"].uses != 1 {
+		t.Errorf("saved %v, %v", files, err)
+	}
+	if _, ok := files["e.g"]; ok {
+		t.Error("e.g. was taken for a file name")
+	}
+}
+
+// --perf reports the lookup latency and the share of warmed prefixes
+func TestEditorPerf(t *testing.T) {
+	h := newHarness(t, t.TempDir())
+	if status := h.e.idleStatus(); status != "" {
+		t.Fatalf("status %q without perf", status)
+	}
+	h.e.cfg.Perf, h.e.cfg.MinPrefix = true, 1
+	h.e.warm.completions["h"] = []string{"ello"}
+	h.feed([]byte("h"))
+	h.pause()
+	h.feed([]byte(" wor"))
+	h.pause()
+	if status := h.e.idleStatus(); !strings.HasPrefix(status, "p50 ") || !strings.HasSuffix(status, " cache 50% of 2") {
+		t.Fatalf("status %q", status)
+	}
+
+	var p Perf
+	for i := 1; i <= 300; i++ {
+		p.Add(time.Duration(i) * time.Millisecond)
+	}
+	if p50, p95 := p.Percentile(0.5), p.Percentile(0.95); p50 != 201*time.Millisecond || p95 != 291*time.Millisecond {
+		t.Fatalf("p50 %v p95 %v of the latest %d", p50, p95, perfSamples)
+	}
+}
+
+// A word used today beats one used much more often a year ago
+func TestEditorFrecency(t *testing.T) {
+	h := newHarness(t, t.TempDir())
+	h.e.trie.InsertCount(userLayer, "hello", 500)
+	h.e.trie.InsertCount(userLayer, "help", 20)
+	h.e.prof.lastUsed["hello"] = time.Now().AddDate(-1, 0, 0)
+	h.e.prof.lastUsed["help"] = time.Now()
+	for _, c := range []struct {
+		recency float64
+		first   string
+	}{{0, "hello"}, {scoring.recency, "help"}} {
+		scoring.recency = c.recency
+		h.feed([]byte("hel"))
+		h.pause()
+		if len(h.e.suggestions) == 0 || h.e.suggestions[0].word != c.first {
+			t.Fatalf("recency %v suggested %v first", c.recency, h.e.suggestions)
+		}
+		h.feed([]byte{BACKSPACE, BACKSPACE, BACKSPACE})
+	}
+}
+
+// After a SPACE the word which usually follows is suggested before it is typed
+func TestEditorNextWords(t *testing.T) {
+	h := newHarness(t, t.TempDir())
+	h.feed([]byte("thank you thank you thank "))
+	h.pause()
+	if len(h.e.suggestions) != 1 || h.e.suggestions[0].word != "you" {
+		t.Fatalf("suggestions %v", h.e.suggestions)
+	}
+	h.feed([]byte("\r"))
+	if got := string(h.e.input); got != "thank you thank you thank you " {
+		t.Fatalf("accepting gave %q", got)
+	}
+	h.e.cfg.NextWords = false
+	h.feed([]byte("thank "))
+	h.pause()
+	if h.e.triggered {
+		t.Fatalf("suggested %v with next_words off", h.e.suggestions)
+	}
+}
+
+// Alt+Backspace right after a SPACE deletes the word and unlearns it
+func TestEditorUnlearn(t *testing.T) {
+	h := newHarness(t, t.TempDir())
+	var err error
+	if h.e.prof.learnLog, err = OpenLearnLog(h.e.prof.paths.learnLog); err != nil {
+		t.Fatal(err)
+	}
+	defer h.e.prof.learnLog.Close()
+
+	h.feed([]byte("hello helo "))
+	h.feed([]byte("\x1b\x7f"))
+	if got := string(h.e.input); got != "hello " || h.e.trie.Count("helo") != 0 {
+		t.Fatalf("buffer %q, helo used %d times", got, h.e.trie.Count("helo"))
+	}
+	if history, _ := ReadLearnLog(h.e.prof.paths.learnLog); len(history) != 1 || history[0].word != "hello" {
+		t.Fatalf("learn log %v", history)
+	}
+	// Only the last learn can be taken back, earlier words are just deleted
+	h.feed([]byte("\x1b\x7f"))
+	if got := string(h.e.input); got != "" || h.e.trie.Count("hello") != 2 {
+		t.Fatalf("buffer %q, hello used %d times", got, h.e.trie.Count("hello"))
+	}
+}
+
+// The suggestion shows as ghost text after the typed word, which alone is the text
+func TestEditorGhostText(t *testing.T) {
+	h := newHarness(t, t.TempDir())
+	h.e.cfg.Theme.Suggestion = ""
+	h.feed([]byte("gol"))
+	h.pause()
+	if h.shown != "gol" || h.ghost != "ang" {
+		t.Fatalf("rendered %q with ghost %q", h.shown, h.ghost)
+	}
+	h.feed([]byte("x"))
+	if h.shown != "golx" || h.ghost != "" {
+		t.Fatalf("typing on rendered %q with ghost %q", h.shown, h.ghost)
+	}
+	if got := prompt.GhostText("teh", "the"); got != " → the" {
+		t.Fatalf("ghost of a fuzzy match %q", got)
+	}
+}
+
+// A word deleted and typed again differently is learned as a typo, whose
+// correction is offered first the next time
+func TestEditorTypos(t *testing.T) {
+	h := newHarness(t, t.TempDir())
+	h.feed([]byte("wrod\x7f\x7f\x7ford "))
+	h.feed([]byte("hlep\x7f\x7f\x7f\x7fgo "))
+	if got := h.e.prof.typos.Fixes("wrod"); !slices.Equal(got, []string{"word"}) {
+		t.Fatalf("wrod corrected to %v", got)
+	}
+	if len(h.e.prof.typos["hlep"]) != 0 {
+		t.Fatalf("hlep retyped as another word learned as a typo: %v", h.e.prof.typos)
+	}
+
+	h.feed([]byte("wrod"))
+	h.pause()
+	if len(h.e.suggestions) == 0 || h.e.suggestions[0].word != "word" || h.e.suggestions[0].source != "typo" {
+		t.Fatalf("suggestions %v", h.e.suggestions)
+	}
+	h.feed([]byte("\r"))
+	if got := string(h.e.input); got != "word go word " {
+		t.Fatalf("buffer %q", got)
+	}
+	typos, err := LoadTypos(h.e.prof.paths.typos)
+	if err != nil || typos["wrod"]["word"] != 2 {
+		t.Fatalf("saved typos %v, %v", typos, err)
+	}
+}
+
+// Ticket IDs, and words made temporary with Ctrl+G, are suggested until their TTL passes
+func TestEditorTemporary(t *testing.T) {
+	h := newHarness(t, t.TempDir())
+	h.feed([]byte("PROJ-1234 golang "))
+	if h.e.trie.Count("PROJ-1234") != 0 || h.e.prof.temporary["PROJ-1234"].uses != 1 {
+		t.Fatalf("ticket learned %d times, temporary %v", h.e.trie.Count("PROJ-1234"), h.e.prof.temporary)
+	}
+	h.feed([]byte{CTRL_G})
+	if h.e.trie.Count("golang") != 1 || h.e.prof.temporary["golang"].uses != 1 {
+		t.Fatalf("golang learned %d times, temporary %v", h.e.trie.Count("golang"), h.e.prof.temporary)
+	}
+
+	h.feed([]byte("PRO"))
+	h.pause()
+	if len(h.e.suggestions) == 0 || h.e.suggestions[0].word != "PROJ-1234" || h.e.suggestions[0].label != "temporary, expires in 24h" {
+		t.Fatalf("suggestions %v", h.e.suggestions)
+	}
+	words, err := LoadTempWords(h.e.prof.paths.temporary, time.Now().Add(25*time.Hour))
+	if err != nil || len(words) != 0 {
+		t.Fatalf("after the TTL %v, %v", words, err)
+	}
+	if words, _ = LoadTempWords(h.e.prof.paths.temporary, time.Now()); len(words) != 2 {
+		t.Fatalf("saved %v", words)
+	}
+}
+
+// Arrows, Home and End move the cursor, typing and deleting happen where it is
+func TestEditorCursor(t *testing.T) {
+	h := newHarness(t, t.TempDir())
+	h.e.cfg.MinPrefix = 1 // w is completed, world standing out from word
+	h.e.trie.InsertCount(baseLayer, "world", 2)
+	h.feed([]byte("hello wrd"))
+	h.feed([]byte("\x1b[D\x1b[Do"))
+	if got := string(h.e.input) + "|" + string(h.e.after); got != "hello wo|rd" {
+		t.Fatalf("after typing in the middle %q", got)
+	}
+	h.feed([]byte("\x1b[3~\x7f"))
+	if got := string(h.e.input) + "|" + string(h.e.after); got != "hello w|d" {
+		t.Fatalf("after deleting around the cursor %q", got)
+	}
+	h.pause()
+	if h.e.triggered {
+		t.Fatalf("suggested %v in the middle of a word", h.e.suggestions)
+	}
+	h.feed([]byte("\x1b[HG\x1b[Fs"))
+	if got := string(h.e.input) + "|" + string(h.e.after); got != "Ghello wds|" {
+		t.Fatalf("after Home and End %q", got)
+	}
+
+	// Ghost text goes at the cursor, the rest of the line after it
+	h.feed([]byte("\x1b[H\x1b[C\x1b[C\x1b[C\x1b[C\x1b[C\x1b[C w"))
+	h.pause()
+	if string(h.e.input) != "Ghello w" || h.ghost == "" {
+		t.Fatalf("buffer %q, ghost %q", string(h.e.input), h.ghost)
+	}
+	rows, row, col := layout(frame{text: "ab", ghost: "cd", after: " ef", status: "x"}, 0)
+	if len(rows) != 2 || row != 0 || col != 2 || len(rows[0]) != 7 {
+		t.Fatalf("laid out %v, cursor %d:%d", rows, row, col)
+	}
+}
+
+// Shift+TAB goes back through the suggestions TAB cycles through
+func TestEditorShiftTab(t *testing.T) {
+	h := newHarness(t, t.TempDir())
+	h.feed([]byte("hel"))
+	h.pause()
+	n := len(h.e.suggestions)
+	first := h.e.suggestions[0].word
+	h.feed([]byte("\x1b[Z"))
+	if h.e.index != n-1 {
+		t.Fatalf("at %d of %d going back from the first", h.e.index, n)
+	}
+	h.feed([]byte{TAB})
+	if got := h.e.suggestions[h.e.index%n].word; got != first {
+		t.Fatalf("TAB after Shift+TAB shows %s, not %s", got, first)
+	}
+	h.e.dismiss()
+	h.feed([]byte("\x1b[Z"))
+	if got := string(h.e.input); got != "hel" {
+		t.Fatalf("Shift+TAB without suggestions typed into %q", got)
+	}
+}
+
+// Hyphens and apostrophes are part of words, other punctuation is not
+func TestEditorWordChars(t *testing.T) {
+	h := newHarness(t, t.TempDir())
+	h.feed([]byte("don't state-of-the-art, (zyxel) and/or "))
+	for _, word := range []string{"don't", "state-of-the-art", "zyxel", "and", "or"} {
+		if h.e.trie.Count(word) == 0 {
+			t.Fatalf("%s not learned", word)
+		}
+	}
+	if h.e.trie.Count("(zyxel)") != 0 || h.e.trie.Count("and/or") != 0 {
+		t.Fatal("learned the punctuation around words")
+	}
+
+	h.feed([]byte("(zyx"))
+	h.pause()
+	if len(h.e.suggestions) == 0 || h.e.suggestions[0].word != "(zyxel" {
+		t.Fatalf("after (zyx %v", h.e.suggestions)
+	}
+	h.feed([]byte("\x7f\x7f\x7f\x7fstate-of"))
+	h.pause()
+	if len(h.e.suggestions) == 0 || h.e.suggestions[0].word != "state-of-the-art" {
+		t.Fatalf("after state-of %v", h.e.suggestions)
+	}
+}
+
+// The right arrow and End complete the shown suggestion without a SPACE
+func TestEditorCompleteKeys(t *testing.T) {
+	h := newHarness(t, t.TempDir())
+	h.feed([]byte("hello "))
+	h.feed([]byte("hel"))
+	h.pause()
+	h.feed([]byte("\x1b[C"))
+	if got := string(h.e.input); got != "hello hello" || h.e.triggered {
+		t.Fatalf("after the right arrow %q", got)
+	}
+	h.feed([]byte("s hel"))
+	h.pause()
+	h.feed([]byte("\x1b[F"))
+	if got := string(h.e.input); got != "hello hellos hello" || h.e.trie.Count("hellos") != 1 {
+		t.Fatalf("after End %q, hellos learned %d times", got, h.e.trie.Count("hellos"))
+	}
+
+	h.e.cfg.Enter.Right = enterMove
+	h.feed([]byte(" hel"))
+	h.pause()
+	h.feed([]byte("\x1b[C"))
+	if got := string(h.e.input); got != "hello hellos hello hel" {
+		t.Fatalf("moving right %q", got)
+	}
+}
+
+// Ctrl+E shows how the shown suggestion was scored
+func TestEditorExplain(t *testing.T) {
+	h := newHarness(t, t.TempDir())
+	h.feed([]byte("so zyxel so zyxel so zyx"))
+	h.pause()
+	h.feed([]byte{CTRL_E})
+	if !strings.HasPrefix(h.status, "zyxel (trie): 2 uses 1.10 × recency 3.00 × boost 1.00 = 3.30") || !strings.HasSuffix(h.status, "followed the previous word 2x") {
+		t.Fatalf("status %q", h.status)
+	}
+	if !h.e.triggered {
+		t.Fatal("explaining dismissed the suggestion")
+	}
+}
+
+// The recap counts the words typed and the completions accepted, and names the
+// new words which are still learned
+func TestEditorRecap(t *testing.T) {
+	h := newHarness(t, t.TempDir())
+	h.feed([]byte("hello zyxel qwop "))
+	h.feed([]byte("\x1b\x7f")) // takes qwop back
+	h.feed([]byte("gola"))
+	h.pause()
+	h.feed([]byte("\r"))
+	want := "Session: 4 words typed, 1 completions accepted, 1 new words learned: zyxel\nLearned data saved in /data\n"
+	if got := h.e.recap.String(recapShort, "/data", false); got != want {
+		t.Fatalf("recap %q", got)
+	}
+	if got := h.e.recap.String(recapFull, "/data", false); !strings.Contains(got, "learned:\n  zyxel\n") {
+		t.Fatalf("full recap %q", got)
+	}
+	if got := h.e.recap.String(recapShort, "/data", true); !strings.HasSuffix(got, "nothing learned (no_learn)\n") {
+		t.Fatalf("recap without learning %q", got)
+	}
+}
+
+// F1, or ? on an empty line, lists the keys as configured and the modes, any
+// other key closes the list and is handled as usual
+func TestEditorHelp(t *testing.T) {
+	h := newHarness(t, t.TempDir())
+	h.e.cfg.Keys.Cycle = "ctrl+n"
+	if err := h.e.cfg.resolveKeys(); err != nil {
+		t.Fatal(err)
+	}
+	h.feed([]byte("\x1bOP"))
+	lines := strings.Split(h.status, "\n")
+	if !h.e.help || !slices.Contains(lines, "ctrl+n         cycle: shows the next suggestion") || !slices.Contains(lines, "enter          accept") {
+		t.Fatalf("help %q", h.status)
+	}
+	if last := lines[len(lines)-1]; last != "profile default, t9 off, learning on, casing off, match_case exact, match_accents off" {
+		t.Fatalf("modes %q", last)
+	}
+	for _, line := range lines {
+		if strings.HasPrefix(line, "accept ") {
+			t.Fatalf("unbound key listed: %q", line)
+		}
+	}
+
+	h.feed([]byte("a?"))
+	if h.e.help || string(h.e.input) != "a?" {
+		t.Fatalf("typing after the help gave %q", string(h.e.input))
+	}
+	h.feed([]byte{DELETE, DELETE, '?'})
+	if !h.e.help || string(h.e.input) != "" {
+		t.Fatal("? on an empty line did not open the help")
+	}
+	h.feed([]byte{'?'})
+	if h.e.help {
+		t.Fatal("? did not close the help")
+	}
+}
+
+// Ctrl+F forgets the shown suggestion for good, a typo learned by mistake or a
+// dictionary word alike
+func TestEditorForgetKey(t *testing.T) {
+	h := newHarness(t, t.TempDir())
+	h.feed([]byte("helo helo hel"))
+	h.pause()
+	if h.e.suggestions[0].word != "helo" {
+		t.Fatalf("suggested %q first", h.e.suggestions[0].word)
+	}
+	h.feed([]byte{CTRL_F})
+	if h.e.trie.Count("helo") != 0 || h.e.prof.tombstones["helo"].IsZero() || h.status != "forgot helo" {
+		t.Fatalf("helo counts %d after forgetting it, status %q", h.e.trie.Count("helo"), h.status)
+	}
+	if !h.e.triggered || h.e.suggestions[0].word == "helo" {
+		t.Fatalf("shown after forgetting %q", h.e.suggestions[0].word)
+	}
+	tombstones, err := LoadTombstones(h.e.prof.paths.tombstones)
+	if err != nil || tombstones["helo"].IsZero() {
+		t.Fatalf("tombstone not saved: %v", err)
+	}
+
+	h.feed([]byte{DELETE, DELETE, DELETE, 'w', 'o', 'r', 'l'})
+	h.pause()
+	h.feed([]byte{CTRL_F})
+	if h.e.triggered || h.e.trie.Count("world") != 0 || h.e.trie.Layer(baseLayer).Count("world") != 1 {
+		t.Fatal("forgetting the only suggestion did not dismiss it or changed the dictionary")
+	}
+}
+
+// The keys of accept, cycle, dismiss, delete word and quit are configurable
+func TestEditorKeyMap(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.Keys.Accept = "ctrl+y"
+	if err := cfg.validate(); err == nil || err.Error() != "keys.accept and keys.history are both ctrl+y" {
+		t.Fatalf("accept on the history key: %v", err)
+	}
+	cfg.Keys.Accept = "f2"
+	if err := cfg.validate(); err == nil || !strings.HasPrefix(err.Error(), `keys.accept: unsupported key "f2"`) {
+		t.Fatalf("accept on f2: %v", err)
+	}
+
+	h := newHarness(t, t.TempDir())
+	h.e.cfg.Keys.Accept, h.e.cfg.Keys.Cycle, h.e.cfg.Keys.Dismiss = "ctrl+a", "ctrl+n", "esc"
+	h.e.cfg.Keys.DeleteWord, h.e.cfg.Keys.Quit = "ctrl+w", "ctrl+q"
+	if err := h.e.cfg.resolveKeys(); err != nil {
+		t.Fatal(err)
+	}
+	h.feed([]byte("zyxel zyxyz zyx"))
+	h.pause()
+	first := h.ghost
+	h.feed([]byte{14})
+	if h.ghost == first || !h.e.triggered {
+		t.Fatalf("cycled from %q to %q", first, h.ghost)
+	}
+	h.feed([]byte{1})
+	if got := string(h.e.input); got != "zyxel zyxyz zyx"+h.e.accepted[0][3:]+" " {
+		t.Fatalf("accepted into %q", got)
+	}
+	h.feed([]byte("zyx"))
+	h.pause()
+	if !h.feed([]byte{ESCAPE}) || h.e.triggered {
+		t.Fatal("ESC did not just dismiss the suggestion")
+	}
+	h.feed([]byte{TAB, 23})
+	if got := string(h.e.input); got != "zyxel zyxyz zyx"+h.e.accepted[0][3:]+" " {
+		t.Fatalf("deleting a word left %q", got)
+	}
+	if h.feed([]byte{17}) {
+		t.Fatal("ctrl+q did not quit")
+	}
+}
+
+// Learning and forgetting change the learned words, never the dictionary below them
+func TestEditorLayers(t *testing.T) {
+	h := newHarness(t, t.TempDir())
+	h.feed([]byte("hello "))
+	if h.e.trie.Count("hello") != 2 || h.e.trie.Layer(baseLayer).Count("hello") != 1 {
+		t.Fatalf("hello counts %d, %d in the dictionary", h.e.trie.Count("hello"), h.e.trie.Layer(baseLayer).Count("hello"))
+	}
+	h.feed([]byte("\x1b\x7f"))
+	if h.e.trie.Count("hello") != 1 || h.e.trie.Layer(userLayer).Count("hello") != 0 {
+		t.Fatalf("after unlearning hello counts %d", h.e.trie.Count("hello"))
+	}
+	h.e.forgetWord("help")
+	if h.e.trie.Count("help") != 0 || h.e.trie.Layer(baseLayer).Count("help") != 1 {
+		t.Fatalf("forgotten help counts %d, %d in the dictionary", h.e.trie.Count("help"), h.e.trie.Layer(baseLayer).Count("help"))
+	}
+}
+
+// Ctrl+L learns the words of the next paste without inserting them, other
+// pastes are typed in
+func TestEditorTeach(t *testing.T) {
+	h := newHarness(t, t.TempDir())
+	h.feed([]byte("\x1b[200~kubectl\x1b[201~ "))
+	if got := string(h.e.input); got != "kubectl " {
+		t.Fatalf("pasted %q", got)
+	}
+
+	h = newHarness(t, t.TempDir())
+	h.feed([]byte{CTRL_L})
+	h.feed([]byte("\x1b[200~Grafana, and\r\nprometheus 42\x1b[201~"))
+	if len(h.e.input) != 0 || h.e.teach {
+		t.Fatalf("input %q, armed %v after the paste", string(h.e.input), h.e.teach)
+	}
+	if h.e.trie.Count("Grafana") != 1 || h.e.trie.Count("prometheus") != 1 || h.e.trie.Count("42") != 0 {
+		t.Errorf("counts %d, %d, %d", h.e.trie.Count("Grafana"), h.e.trie.Count("prometheus"), h.e.trie.Count("42"))
+	}
+	if want := "learned 3 words from the paste, 3 of them new"; h.status != want {
+		t.Errorf("status %q, want %q", h.status, want)
+	}
+	h.feed([]byte("pro"))
+	h.pause()
+	if !h.e.triggered || h.e.suggestions[0].word != "prometheus" {
+		t.Errorf("suggested %v for pro", h.e.suggestions)
+	}
+
+	// Typing disarms it
+	h.feed([]byte{CTRL_L})
+	h.feed([]byte("x\x1b[200~loki\x1b[201~"))
+	if h.e.trie.Count("loki") != 0 || !strings.HasSuffix(string(h.e.input), "xloki") {
+		t.Errorf("input %q after typing with the teach key armed", string(h.e.input))
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// What Enter does, each variant of it configured on its own
+const (
+	enterAccept   = "accept"   // completes the shown suggestion, nothing without one
+	enterComplete = "complete" // like accept, without the SPACE after the suggestion
+	enterMove     = "move"     // moves the cursor as usual, for the arrow and End only
+	enterNewline  = "newline"  // starts a new line
+	enterCommit   = "commit"   // records the line in the line history and starts a new one
+	enterSubmit   = "submit"   // records the line and clears the buffer
+)
+
+// Line history written by commit and submit
+const linesFile = "lines.log"
+
+// Keys arriving as escape sequences. Negative so they never clash with typed text
+const (
+	keyShiftEnter rune = -1
+	keyAltEnter   rune = -2
+	keyAltDelete  rune = -3 // Alt+Backspace
+	keyShiftTab   rune = -4
+)
+
+// Escape sequences of the keys above. Most terminals only tell Shift+Enter apart
+// with the kitty keyboard protocol or xterm's modifyOtherKeys
+var escapeKeys = map[string]rune{
+	"\x1b[13;2u":    keyShiftEnter,
+	"\x1b[27;2;13~": keyShiftEnter,
+	"\x1b\r":        keyAltEnter,
+	"\x1b\x7f":      keyAltDelete,
+	"\x1b\b":        keyAltDelete,
+	"\x1b[Z":        keyShiftTab,
+}
+
+type EnterConfig struct {
+	Enter      string `toml:"enter"`       // Enter and Ctrl+J
+	ShiftEnter string `toml:"shift_enter"` // Shift+Enter, where the terminal reports it
+	AltEnter   string `toml:"alt_enter"`
+	Right      string `toml:"right"` // the right arrow, while a suggestion is shown
+	End        string `toml:"end"`
+}
+
+func (c EnterConfig) validate() error {
+	for _, action := range []string{c.Enter, c.ShiftEnter, c.AltEnter} {
+		switch action {
+		case enterAccept, enterComplete, enterNewline, enterCommit, enterSubmit:
+		default:
+			return fmt.Errorf("enter: unknown action %q, expected accept, complete, newline, commit or submit", action)
+		}
+	}
+	for _, action := range []string{c.Right, c.End} {
+		switch action {
+		case enterAccept, enterComplete, enterMove:
+		default:
+			return fmt.Errorf("enter: unknown action %q for right or end, expected accept, complete or move", action)
+		}
+	}
+	return nil
+}
+
+// The action bound to key, "" when key is no Enter, the right arrow or End
+func (c EnterConfig) action(key rune) string {
+	switch key {
+	case '\r', '\n':
+		return c.Enter
+	case keyShiftEnter:
+		return c.ShiftEnter
+	case keyAltEnter:
+		return c.AltEnter
+	case keyRight:
+		return c.Right
+	case keyEnd:
+		return c.End
+	}
+	return ""
+}
+
+// Carries out an Enter action other than accept, once the suggestions are dismissed
+func (e *Editor) enter(action string) {
+	if action == enterAccept || action == enterComplete {
+		return // nothing to accept
+	}
+	e.learnLastWord()
+	if action == enterNewline {
+		e.input = append(e.input, '\n')
+		e.status(e.idleStatus())
+		return
+	}
+
+	e.cursorBy(lineEnd(e.after)) // the whole line is committed, not just what is before the cursor
+	line := string(e.input[len(e.input)-len([]rune(currentLine(e.input))):])
+	status := "line committed"
+	if !e.cfg.NoLearn && strings.TrimSpace(line) != "" {
+		if err := appendLine(e.prof.paths.lines, line, time.Now()); err != nil {
+			status = "saving the line failed: " + err.Error()
+		}
+	}
+	if action == enterSubmit {
+		e.input, e.after = nil, nil
+		if status == "line committed" {
+			status = "submitted"
+		}
+	} else {
+		e.input = append(e.input, '\n')
+	}
+	e.status(status)
+}
+
+// The line being typed. Eg:- first\nsecond li --> second li
+func currentLine(input []rune) string {
+	text := string(input)
+	return text[strings.LastIndexByte(text, '\n')+1:]
+}
+
+// Appends line to the line history at path, one "unix time<TAB>line" per line
+func appendLine(path string, line string, at time.Time) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(f, "%d\t%s\n", at.Unix(), line); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
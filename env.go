@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Prefix of the environment variables overriding config options
+const envPrefix = "AUTOCOMPLETE"
+
+// Overrides config options from the environment. Every option has a variable named
+// after its path in the config file. Eg:- debounce --> AUTOCOMPLETE_DEBOUNCE,
+// scoring.cap --> AUTOCOMPLETE_SCORING_CAP, no_learn --> AUTOCOMPLETE_NO_LEARN.
+// Lists are comma separated
+func applyEnv(cfg *Config) error {
+	return applyEnvStruct(reflect.ValueOf(cfg).Elem(), envPrefix)
+}
+
+func applyEnvStruct(v reflect.Value, prefix string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("toml")
+		if tag == "" {
+			continue // resolved values, not options
+		}
+		name := prefix + "_" + strings.ToUpper(tag)
+		field := v.Field(i)
+
+		if field.Kind() == reflect.Struct {
+			if err := applyEnvStruct(field, name); err != nil {
+				return err
+			}
+			continue
+		}
+
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if err := setField(field, value); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Parses value into field according to the field's type
+func setField(field reflect.Value, value string) error {
+	switch field.Interface().(type) {
+	case time.Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+	case string:
+		field.SetString(value)
+	case int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(n))
+	case float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case []string:
+		field.Set(reflect.ValueOf(strings.Split(value, ",")))
+	case bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported option type %s", field.Type())
+	}
+	return nil
+}
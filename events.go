@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// Suggestion events, one JSON object per line
+const eventsFile = "events.log"
+
+// The editor trims the events log to its newest eventsKeep bytes once it grows
+// past eventsLogSize and the user is idle, see maintenance.go
+const (
+	eventsLogSize = 4 << 20
+	eventsKeep    = 2 << 20
+)
+
+// Something that happened to a suggestion
+type Event struct {
+	Time       int64  `json:"time"`
+	Kind       string `json:"kind"`   // "suggest" when suggestions appear, "shown" for every candidate displayed, "accepted"
+	Prefix     string `json:"prefix"` // word being typed
+	Word       string `json:"word,omitempty"`
+	Rank       int    `json:"rank"` // position of Word among the candidates
+	Source     string `json:"source,omitempty"`
+	Count      int    `json:"count,omitempty"` // number of candidates, for "suggest"
+	Uses       int    `json:"uses,omitempty"`  // times Word was typed before
+	Last       int64  `json:"last,omitempty"`  // when Word was last typed
+	Experiment string `json:"experiment,omitempty"`
+	Arm        string `json:"arm,omitempty"`
+}
+
+// Appends events to the events log
+type EventLog struct {
+	mu  sync.Mutex // held while appending or trimming
+	f   *os.File
+	enc *json.Encoder
+}
+
+func OpenEventLog(path string) (*EventLog, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &EventLog{f: f, enc: json.NewEncoder(f)}, nil
+}
+
+// Records e, stamped with the current time. Safe to call on a nil log
+func (l *EventLog) Record(e Event) {
+	if l == nil {
+		return
+	}
+	e.Time = time.Now().Unix()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.enc.Encode(e)
+}
+
+// Drops the oldest events, whole lines, until at most keep bytes of them are
+// left. Returns how many bytes were dropped
+func (l *EventLog) Trim(keep int64) (int64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	path := l.f.Name()
+	data, err := os.ReadFile(path)
+	if err != nil || int64(len(data)) <= keep {
+		return 0, err
+	}
+	tail := data[int64(len(data))-keep:]
+	if data[int64(len(data))-keep-1] != '\n' {
+		_, tail, _ = bytes.Cut(tail, []byte("\n")) // the rest of a line cut in half
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, tail, 0644); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return 0, err
+	}
+	// The old file is gone, appending goes on in the new one
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return 0, err
+	}
+	l.f.Close()
+	l.f, l.enc = f, json.NewEncoder(f)
+	return int64(len(data) - len(tail)), nil
+}
+
+func (l *EventLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.f.Close()
+}
+
+// Reads the whole events log. A missing log has no events
+func ReadEvents(path string) ([]Event, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Event
+		if json.Unmarshal(scanner.Bytes(), &e) == nil {
+			events = append(events, e)
+		}
+	}
+	return events, scanner.Err()
+}
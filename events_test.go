@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestEventLogTrim(t *testing.T) {
+	path := t.TempDir() + "/" + eventsFile
+	l, err := OpenEventLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	for _, word := range []string{"hello", "help", "helmet", "world"} {
+		l.Record(Event{Kind: "accepted", Word: word})
+	}
+	before := fileSize(path)
+
+	// A line cut in half goes too
+	dropped, err := l.Trim(before/2 + 1)
+	if err != nil || dropped != before-fileSize(path) {
+		t.Fatalf("dropped %d of %d bytes, %d left: %v", dropped, before, fileSize(path), err)
+	}
+	l.Record(Event{Kind: "accepted", Word: "word"})
+	events, err := ReadEvents(path)
+	if err != nil || len(events) != 3 || events[0].Word != "helmet" || events[2].Word != "word" {
+		t.Fatalf("events after trimming %v, %v", events, err)
+	}
+
+	if dropped, err := l.Trim(before * 2); err != nil || dropped != 0 {
+		t.Errorf("trimmed a small log by %d bytes: %v", dropped, err)
+	}
+}
+
+// Events are only recorded for an experiment or the ranker, and never without learning
+func TestEditorEvents(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		set      func(cfg *Config)
+		recorded bool
+	}{
+		{"rerank", func(cfg *Config) {}, true},
+		{"experiment", func(cfg *Config) { cfg.Rerank, cfg.Experiment.Name = false, "lower-cap" }, true},
+		{"neither", func(cfg *Config) { cfg.Rerank = false }, false},
+		{"no_learn", func(cfg *Config) { cfg.NoLearn, cfg.Experiment.Name = true, "lower-cap" }, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t, t.TempDir())
+			tc.set(&h.e.cfg)
+			l, err := OpenEventLog(h.e.prof.paths.events)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer l.Close()
+			h.e.prof.events = l
+
+			h.feed([]byte("hel"))
+			h.pause()
+			h.feed([]byte{TAB})
+			events, err := ReadEvents(h.e.prof.paths.events)
+			if err != nil || (len(events) > 0) != tc.recorded {
+				t.Errorf("events %v, %v", events, err)
+			}
+		})
+	}
+}
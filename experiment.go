@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sort"
+)
+
+// A/B ranking experiment. Every time suggestions appear one of the two scoring
+// configurations is picked at random and recorded in the events log, the report
+// command then compares how often each arm's suggestions were accepted
+//
+//	[experiment]
+//	name = "lower-cap"
+//	a = { cap = 1000, log = true }
+//	b = { cap = 50, log = true }
+type ExperimentConfig struct {
+	Name string        `toml:"name"` // empty disables the experiment
+	A    ScoringConfig `toml:"a"`
+	B    ScoringConfig `toml:"b"`
+}
+
+// Picks an arm for the next suggestions, none without an experiment
+func (e ExperimentConfig) pick() string {
+	if e.Name == "" {
+		return ""
+	}
+	if rand.IntN(2) == 0 {
+		return "a"
+	}
+	return "b"
+}
+
+// Scoring used by arm, the regular one outside the experiment
+func (e ExperimentConfig) scoring(arm string, regular Scoring) Scoring {
+	switch arm {
+	case "a":
+		return e.A.scoring()
+	case "b":
+		return e.B.scoring()
+	}
+	return regular
+}
+
+// Acceptance of one experiment arm
+type armResult struct {
+	experiment string
+	arm        string
+	suggested  int // suggestion events
+	accepted   int
+	rankSum    int // of the accepted candidates
+}
+
+// Groups the events by experiment and arm. Events outside any experiment end up in one group
+func experimentResults(events []Event) []*armResult {
+	byArm := make(map[[2]string]*armResult)
+	get := func(e Event) *armResult {
+		key := [2]string{e.Experiment, e.Arm}
+		if byArm[key] == nil {
+			byArm[key] = &armResult{experiment: e.Experiment, arm: e.Arm}
+		}
+		return byArm[key]
+	}
+
+	for _, e := range events {
+		switch e.Kind {
+		case "suggest":
+			get(e).suggested++
+		case "accepted":
+			r := get(e)
+			r.accepted++
+			r.rankSum += e.Rank
+		}
+	}
+
+	results := make([]*armResult, 0, len(byArm))
+	for _, r := range byArm {
+		results = append(results, r)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].experiment != results[j].experiment {
+			return results[i].experiment < results[j].experiment
+		}
+		return results[i].arm < results[j].arm
+	})
+	return results
+}
+
+// autocomplete report [boosts|reset-boosts [word...]]
+// Prints the acceptance rate of the suggestions for every experiment arm, or the
+// ranking adjustments learned from ignored suggestions
+func reportCommand(args []string) error {
+	if len(args) > 0 {
+		b, err := LoadBoosts(paths.boosts)
+		if err != nil {
+			return err
+		}
+		switch args[0] {
+		case "boosts":
+			fmt.Print(b)
+			return nil
+		case "reset-boosts":
+			fmt.Println("reset", b.Reset(args[1:]), "words")
+			return b.Save(paths.boosts)
+		default:
+			return fmt.Errorf("unknown report %q, expected boosts or reset-boosts", args[0])
+		}
+	}
+
+	events, err := ReadEvents(paths.events)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%-20s %-4s %10s %10s %8s %10s\n", "EXPERIMENT", "ARM", "SUGGESTED", "ACCEPTED", "RATE", "MEAN RANK")
+	for _, r := range experimentResults(events) {
+		name, arm := r.experiment, r.arm
+		if name == "" {
+			name, arm = "(none)", "-"
+		}
+		var rate, rank float64
+		if r.suggested > 0 {
+			rate = 100 * float64(r.accepted) / float64(r.suggested)
+		}
+		if r.accepted > 0 {
+			rank = float64(r.rankSum) / float64(r.accepted)
+		}
+		fmt.Printf("%-20s %-4s %10d %10d %7.1f%% %10.2f\n", name, arm, r.suggested, r.accepted, rate, rank)
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// An arm ranks by its own scoring and leaves that of the config alone
+func TestExperimentArmScoring(t *testing.T) {
+	h := newHarness(t, t.TempDir())
+	for range 50 {
+		h.e.trie.Insert(baseLayer, "helmet")
+	}
+	for range 3 {
+		h.e.trie.Insert(baseLayer, "help")
+	}
+	h.e.prof.lastUsed["help"] = time.Now()
+	h.e.cfg.Experiment = ExperimentConfig{Name: "count-only", A: ScoringConfig{Cap: 1000, Log: true}, B: h.e.cfg.Scoring}
+
+	for arm, want := range map[string]string{"": "help", "a": "helmet", "b": "help"} {
+		h.e.arm = arm
+		if got := h.e.engineCandidates(nil, "hel"); len(got) == 0 || got[0].word != want {
+			t.Errorf("arm %q: got %v, want %q first", arm, got, want)
+		}
+		if scoring != defaultScoring {
+			t.Errorf("arm %q changed the scoring to %+v", arm, scoring)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/b0tShaman/autocomplete-cli/prompt"
+)
+
+// Candidates explained by the explain command
+const explainTop = 10
+
+// How a candidate came to rank where it does. Its usage score is frequency *
+// recency * boost, the ranker (when trained) reorders by its prediction
+type explanation struct {
+	Candidate
+	count     int     // times the word was typed
+	frequency float64 // the score of the count alone, capped and log-scaled
+	recency   float64 // factor for how recently it was used, 1 for never
+	next      int     // times it followed the previous word
+	boost     float64 // factor learned from the suggestions passed over for it
+	ranker    float64 // predicted chance of being accepted, -1 without a ranker
+	dicts     string  // the dictionaries which have it when there are several, Eg:- "words.txt, medical.txt ×2"
+}
+
+func (x explanation) score() float64 {
+	return x.frequency * x.recency * x.boost
+}
+
+// Explains candidates, shown in this order for prefix typed after previous.
+// count reports how often a word was typed, sources where it came from
+func (p *profile) explain(candidates []Candidate, previous []string, prefix string, count func(word string) int, sources *DictSources) []explanation {
+	var last string
+	if len(previous) > 0 {
+		last = previous[len(previous)-1]
+	}
+	now := time.Now()
+	var result []explanation
+	for i, c := range candidates {
+		x := explanation{Candidate: c, count: count(c.word), recency: 1, boost: p.boosts.Factor(c.word), ranker: -1, dicts: dictNames(sources.Of(c.word))}
+		x.frequency = scoring.Score(x.count)
+		if frequency := x.frequency; frequency > 0 {
+			x.recency = scoring.Frecency(x.count, p.lastUsed[c.word]) / frequency
+		}
+		if p.phrases != nil {
+			x.next = p.phrases.next[last][c.word]
+		}
+		if p.ranker != nil {
+			x.ranker = p.ranker.Predict(featureVector(c.source, i, prefix, x.count, p.lastUsed[c.word], now))
+		}
+		result = append(result, x)
+	}
+	return result
+}
+
+// One line summing up x for the status line. Eg:- hello (trie): 12 uses 2.56 × recency 1.80 × boost 1.00 = 4.61, followed the previous word 3x, in medical.txt ×2
+func (x explanation) String() string {
+	s := fmt.Sprintf("%s (%s): %d uses %.2f × recency %.2f × boost %.2f = %.2f", x.word, x.source, x.count, x.frequency, x.recency, x.boost, x.score())
+	if x.next > 0 {
+		s += fmt.Sprintf(", followed the previous word %dx", x.next)
+	}
+	if x.ranker >= 0 {
+		s += fmt.Sprintf(", ranker %.0f%%", 100*x.ranker)
+	}
+	if x.dicts != "" {
+		s += ", in " + x.dicts
+	}
+	return s
+}
+
+// Explains the shown suggestion in the status line
+func (e *Editor) explainSuggestion() {
+	previous := prompt.PreviousWords(e.input, contextWords)
+	x := e.prof.explain(e.suggestions, previous, prompt.CurrentWord(e.input), e.trie.Count, e.sources)
+	e.status(x[e.index%len(x)].String())
+}
+
+// autocomplete explain [previous words...] <prefix>
+// Prints the top candidates suggest would print with how each was scored
+func explainCommand(args []string) error {
+	words := strings.Fields(strings.Join(args, " "))
+	if len(words) == 0 {
+		return fmt.Errorf("usage: explain [previous words...] <prefix>")
+	}
+	cfg, err := LoadConfig(configPath())
+	if err != nil {
+		return err
+	}
+
+	prof, problems := openProfile(paths)
+	defer prof.Close()
+	verifier := NewVerifier(cfg)
+	t, sources, trieProblems := loadTrie(cfg.dictSpecs(cfg.Dictionary), cfg.Dawg, cfg.BuiltinWords, verifier, paths.snapshot, prof.tombstones, prof.history, cfg.Tokens)
+	d, packProblems := loadDictionaries(cfg, cfg.Dictionary, verifier)
+	project, projectProblems := openProject(cfg)
+	project.Layer(t, prof.tombstones)
+	prof.snippets = project.Snippets(prof.snippets)
+	if problems = append(append(append(problems, trieProblems...), packProblems...), projectProblems...); len(problems) > 0 {
+		return fmt.Errorf("%s", problemStatus(problems))
+	}
+
+	previous, word := words[:len(words)-1], words[len(words)-1]
+	previous = previous[max(0, len(previous)-contextWords):]
+	candidates := d.candidates(cfg, t, prof, previous, word, nil)
+	fmt.Printf("%-24s %-10s %6s %9s %8s %6s %7s %5s %7s  %s\n", "word", "source", "uses", "frequency", "recency", "boost", "score", "next", "ranker", "dictionaries")
+	for _, x := range prof.explain(candidates[:min(len(candidates), explainTop)], previous, word, t.Count, sources) {
+		ranker := "-"
+		if x.ranker >= 0 {
+			ranker = fmt.Sprintf("%.3f", x.ranker)
+		}
+		fmt.Printf("%-24s %-10s %6d %9.3f %8.3f %6.3f %7.3f %5d %7s  %s\n", x.word, x.source, x.count, x.frequency, x.recency, x.boost, x.score(), x.next, ranker, x.dicts)
+	}
+	return nil
+}
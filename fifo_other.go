@@ -0,0 +1,16 @@
+//go:build !unix
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+func makeFifo(path string) error {
+	return errors.New("named pipes are not supported on this platform")
+}
+
+func openFifoWriter(path string) (*os.File, error) {
+	return nil, errors.New("named pipes are not supported on this platform")
+}
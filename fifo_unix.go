@@ -0,0 +1,18 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+func makeFifo(path string) error {
+	return syscall.Mkfifo(path, 0600)
+}
+
+// Opens the named pipe at path for writing, failing instead of waiting when
+// nothing reads it
+func openFifoWriter(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_WRONLY|syscall.O_NONBLOCK, 0)
+}
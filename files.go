@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// File names typed, one "name<TAB>uses<TAB>unix time of the last use" per line
+const filesFile = "files.txt"
+
+const (
+	recentFilesLimit = 500 // file names remembered, the least used are dropped first
+	recentFileUses   = 2   // a file name is offered once it was typed this many times
+)
+
+// A name ending in an extension of a few letters or digits, of one only in a
+// path so abbreviations are no file names. Eg:- go.mod, src/main.c but not e.g
+var (
+	fileName  = regexp.MustCompile(`^[\w.-]*\w\.[A-Za-z0-9]{2,8}$`)
+	pathName  = regexp.MustCompile(`^[\w.-]*\w\.[A-Za-z0-9]{1,8}$`)
+	hasLetter = regexp.MustCompile(`[A-Za-z]`)
+)
+
+type fileUse struct {
+	uses int
+	last time.Time
+}
+
+// The file names and paths typed or accepted, completed from the start of their
+// base name whatever directory they are in. Eg:- conf --> ~/.config/app/config.toml
+type RecentFiles map[string]fileUse
+
+// Reads the file names. A missing file means none were typed yet
+func LoadRecentFiles(path string) (RecentFiles, error) {
+	files := make(RecentFiles)
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return files, nil
+	} else if err != nil {
+		return files, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) != 3 || fields[0] == "" {
+			continue
+		}
+		uses, err := strconv.Atoi(fields[1])
+		last, err2 := strconv.ParseInt(fields[2], 10, 64)
+		if err == nil && err2 == nil && uses > 0 {
+			files[fields[0]] = fileUse{uses, time.Unix(last, 0)}
+		}
+	}
+	return files, scanner.Err()
+}
+
+func (files RecentFiles) Save(path string) error {
+	var b strings.Builder
+	for _, name := range files.names() {
+		fmt.Fprintf(&b, "%s\t%d\t%d\n", name, files[name].uses, files[name].last.Unix())
+	}
+	return os.WriteFile(path, []byte(b.String()), 0644)
+}
+
+// The file names, most used first and the most recent among those used as often
+func (files RecentFiles) names() []string {
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := files[names[i]], files[names[j]]
+		if a.uses != b.uses {
+			return a.uses > b.uses
+		}
+		if !a.last.Equal(b.last) {
+			return a.last.After(b.last)
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// Records that name was typed at, dropping the least used names beyond the limit
+func (files RecentFiles) Add(name string, at time.Time) {
+	files[name] = fileUse{files[name].uses + 1, at}
+	if len(files) > recentFilesLimit {
+		names := files.names()
+		for _, name := range names[recentFilesLimit:] {
+			delete(files, name)
+		}
+	}
+}
+
+// Forgets a file name, returns false if it was never typed
+func (files RecentFiles) Remove(name string) bool {
+	if _, ok := files[name]; !ok {
+		return false
+	}
+	delete(files, name)
+	return true
+}
+
+// The file names typed often enough whose base name or path starts with prefix.
+// Eg:- main --> cmd/server/main.go
+func (files RecentFiles) Candidates(prefix string) []Candidate {
+	var result []Candidate
+	if prefix == "" {
+		return result
+	}
+	for _, name := range files.names() {
+		if files[name].uses < recentFileUses || name == prefix {
+			continue
+		}
+		if strings.HasPrefix(path.Base(name), prefix) || strings.HasPrefix(name, prefix) {
+			result = append(result, Candidate{word: name, label: fmt.Sprintf("recent file (%dx)", files[name].uses), source: "file"})
+		}
+	}
+	return result
+}
+
+// token without the punctuation of the sentence around it, if it is a file name
+// or a path to one. Eg:- (see ./cmd/main.go), --> ./cmd/main.go but e.g. --> ""
+func fileToken(token string) string {
+	token = strings.TrimLeft(token, `("'`)
+	token = strings.TrimRight(token, `.,;:!?)"'`)
+	if strings.Contains(token, "://") {
+		return "" // a URL
+	}
+	name := fileName
+	if strings.Contains(token, "/") {
+		name = pathName
+	}
+	if base := path.Base(token); name.MatchString(base) && hasLetter.MatchString(base) {
+		return token
+	}
+	return ""
+}
+
+// autocomplete files [list|remove <name>]
+// Prints the file names typed or forgets one
+func filesCommand(args []string) error {
+	files, err := LoadRecentFiles(paths.files)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 || args[0] == "list" {
+		for _, name := range files.names() {
+			fmt.Printf("%-40s %4d  %s\n", name, files[name].uses, files[name].last.Format("2006-01-02"))
+		}
+		return nil
+	}
+	if len(args) != 2 || args[0] != "remove" {
+		return fmt.Errorf("usage: files [list|remove <name>]")
+	}
+	if !files.Remove(args[1]) {
+		return fmt.Errorf("%s is not a recent file", args[1])
+	}
+	return files.Save(paths.files)
+}
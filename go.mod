@@ -1,7 +1,10 @@
-module autocomplete
+module github.com/b0tShaman/autocomplete-cli
 
 go 1.23
 
-require golang.org/x/term v0.30.0
+require (
+	github.com/BurntSushi/toml v1.5.0
+	golang.org/x/term v0.30.0
+)
 
-require golang.org/x/sys v0.31.0 // indirect
+require golang.org/x/sys v0.31.0
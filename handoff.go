@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/b0tShaman/autocomplete-cli/trie"
+)
+
+// How long the old daemon waits for the requests it is answering before it
+// hands over anyway
+const handoffTimeout = 10 * time.Second
+
+// What a daemon hands over to the one replacing it
+type handoffState struct {
+	Dictionary string                 `json:"dictionary"` // the one swapped in last by /admin/reload
+	Clients    map[string]clientState `json:"clients"`    // by client id, "" for the configured profile
+}
+
+// The learned words of a client as they are in memory
+type clientState struct {
+	Learned map[string]int `json:"learned"`
+	Session map[string]int `json:"session,omitempty"`
+	Hidden  []string       `json:"hidden,omitempty"` // forgotten words the dictionary still has
+}
+
+// Socket the daemon serving address waits for its replacement on, next to the
+// control pipes. Eg:- $XDG_RUNTIME_DIR/autocomplete-cli/handoff-127.0.0.1_7878
+func handoffPath(address string) string {
+	name := strings.NewReplacer(":", "_", "/", "_").Replace(address)
+	return filepath.Join(controlDir(), "handoff-"+name)
+}
+
+// Listens on the handoff socket, replacing the one of a daemon which is gone
+func listenHandoff(path string) (*net.UnixListener, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	os.Remove(path)
+	return net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+}
+
+// The learned words of every client, taken while nothing learns anymore
+func (s *server) state() handoffState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state := handoffState{Dictionary: s.shared.Load().dictionary, Clients: make(map[string]clientState, len(s.clients))}
+	for id, t := range s.clients {
+		t.mu.RLock()
+		state.Clients[id] = clientState{
+			Learned: layerCounts(t.trie.Layer(userLayer).Words()),
+			Session: layerCounts(t.trie.Layer(sessionLayer).Words()),
+			Hidden:  t.trie.Hidden(),
+		}
+		t.mu.RUnlock()
+	}
+	return state
+}
+
+// The words of a layer with their counts
+func layerCounts(words []trie.Word) map[string]int {
+	counts := make(map[string]int, len(words))
+	for _, w := range words {
+		counts[w.Value] = w.Count
+	}
+	return counts
+}
+
+// Loads the profile of client id with the words handed over, instead of the
+// learned counts on disk
+func (s *server) adopt(id string, c clientState) []string {
+	p := paths
+	if id != "" {
+		if !clientID.MatchString(id) {
+			return []string{fmt.Sprintf("handed over client %q dropped: not a client id", id)}
+		}
+		p = clientPaths(id)
+	}
+	prof, problems := openProfile(p)
+	t := newWords(s.shared.Load().base)
+	for word, count := range c.Learned {
+		t.InsertCount(userLayer, word, count)
+	}
+	for word, count := range c.Session {
+		t.InsertCount(sessionLayer, word, count)
+	}
+	for _, word := range c.Hidden {
+		t.Hide(word)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clients[id] = loadedTenant(t, prof)
+	return problems
+}
+
+// Waits on the handoff socket for the daemon replacing this one. It gets the
+// listener once the requests being answered are done and the learned data is
+// saved, connections made meanwhile wait in the listener for it. Returns once
+// handed over, or when h is closed
+func (s *server) awaitHandoff(h *net.UnixListener, srv *http.Server, ln net.Listener) error {
+	for {
+		conn, err := h.AcceptUnix()
+		if err != nil {
+			return nil
+		}
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		if strings.TrimSpace(line) != "handoff" {
+			fmt.Fprintln(conn, "expected handoff")
+			conn.Close()
+			continue
+		}
+		defer conn.Close()
+
+		// A copy of the listener, it stays open when srv closes its own
+		f, err := ln.(*net.TCPListener).File()
+		if err != nil {
+			return fmt.Errorf("handoff failed: %v", err)
+		}
+		defer f.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), handoffTimeout)
+		srv.Shutdown(ctx)
+		cancel()
+		state := s.state()
+		s.Close() // the new daemon reads the flushed learn logs
+
+		os.Remove(h.Addr().String()) // the new daemon listens there next
+		data, _ := json.Marshal(state)
+		if err := sendListener(conn, f); err != nil {
+			return fmt.Errorf("handoff failed, stopped serving: %v", err)
+		}
+		if _, err := conn.Write(data); err != nil {
+			return fmt.Errorf("handoff failed, stopped serving: %v", err)
+		}
+		fmt.Printf("handed over to the new daemon with %d clients\n", len(state.Clients))
+		return nil
+	}
+}
+
+// Starts a server taking over from the daemon serving cfg.Serve.Listen. The
+// dictionaries are loaded before asking, so lookups only wait for the handoff
+// itself unless the old daemon had swapped in another dictionary
+func takeOver(cfg Config) (*server, net.Listener, []string, error) {
+	d, problems := loadShared(cfg, cfg.Dictionary)
+
+	path := handoffPath(cfg.Serve.Listen)
+	conn, err := net.DialUnix("unix", nil, &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("no daemon to take over from at %s: %v", path, err)
+	}
+	defer conn.Close()
+	fmt.Fprintln(conn, "handoff")
+	f, err := receiveListener(conn)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("taking over failed: %v", err)
+	}
+	ln, err := net.FileListener(f)
+	f.Close()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("taking over failed: %v", err)
+	}
+
+	// From here on the old daemon is gone, whatever fails is only reported
+	var state handoffState
+	data, err := io.ReadAll(conn)
+	if err == nil {
+		err = json.Unmarshal(data, &state)
+	}
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("handed over state unreadable, learned data is loaded from disk: %v", err))
+	}
+	if state.Dictionary != "" && state.Dictionary != d.dictionary {
+		var more []string
+		d, more = loadShared(cfg, state.Dictionary)
+		problems = append(problems, more...)
+	}
+	s := newServerWith(cfg, d)
+	for id, c := range state.Clients {
+		problems = append(problems, s.adopt(id, c)...)
+	}
+	return s, ln, problems, nil
+}
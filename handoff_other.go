@@ -0,0 +1,17 @@
+//go:build !unix
+
+package main
+
+import (
+	"errors"
+	"net"
+	"os"
+)
+
+func sendListener(conn *net.UnixConn, f *os.File) error {
+	return errors.New("handing over the listener is not supported on this platform")
+}
+
+func receiveListener(conn *net.UnixConn) (*os.File, error) {
+	return nil, errors.New("handing over the listener is not supported on this platform")
+}
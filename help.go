@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Opens the help overlay, or closes it when it is open
+func (e *Editor) toggleHelp() {
+	e.dismiss()
+	e.stopTimer()
+	if e.help = !e.help; e.help {
+		e.status(e.helpStatus())
+	} else {
+		e.status(e.idleStatus())
+	}
+}
+
+// The keys as the config binds them and the modes in effect, one per line.
+// Eg:- tab            cycle: shows the next suggestion
+func (e *Editor) helpStatus() string {
+	cfg := &e.cfg
+	var lines []string
+	for _, b := range cfg.bindings() {
+		if *b.key != keyNone {
+			lines = append(lines, fmt.Sprintf("%-14s %s: %s", b.name, b.action, b.help))
+		}
+	}
+	lines = append(lines, fmt.Sprintf("%-14s %s", "ctrl+c", "quits"))
+	for _, a := range []struct{ key, action string }{
+		{"enter", cfg.Enter.Enter},
+		{"shift+enter", cfg.Enter.ShiftEnter},
+		{"alt+enter", cfg.Enter.AltEnter},
+		{"right", cfg.Enter.Right},
+		{"end", cfg.Enter.End},
+	} {
+		lines = append(lines, fmt.Sprintf("%-14s %s", a.key, a.action))
+	}
+
+	onOff := map[bool]string{true: "on", false: "off"}
+	profile := cfg.Profile
+	if profile == "" {
+		profile = "default"
+	}
+	lines = append(lines, fmt.Sprintf("profile %s, t9 %s, learning %s, casing %s, match_case %s, match_accents %s",
+		profile, onOff[e.t9Mode], onOff[!cfg.NoLearn], onOff[cfg.Casing], cfg.MatchCase, onOff[cfg.MatchAccents]))
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Every word learned while typing is appended to the learn log as "<unix time>\t<word>"
+const learnLogFile = "learned.log"
+
+const (
+	minPhraseWords = 2               // shortest sequence considered a phrase
+	maxPhraseWords = 6               // longest sequence considered a phrase
+	snippetMinUses = 5               // uses before a phrase is proposed as a snippet
+	phraseGap      = 5 * time.Minute // a pause this long ends the current sequence
+	nextMinUses    = 2               // times a word followed another before it is predicted after it
+	maxNextWords   = 5               // predictions offered after a word
+)
+
+// A word from the learn log together with when it was typed
+type LearnedWord struct {
+	word string
+	at   time.Time
+}
+
+// Reads the whole learn log. A missing log is not an error, it just means nothing was learned yet
+func ReadLearnLog(path string) ([]LearnedWord, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var history []LearnedWord
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		ts, word, ok := strings.Cut(scanner.Text(), "\t")
+		sec, err := strconv.ParseInt(ts, 10, 64)
+		if !ok || err != nil || word == "" {
+			continue // skip damaged lines instead of losing the whole log
+		}
+		history = append(history, LearnedWord{word, time.Unix(sec, 0)})
+	}
+	return history, scanner.Err()
+}
+
+// Appends learned words to the learn log
+type LearnLog struct {
+	mu   sync.Mutex // held while appending or compacting
+	f    *os.File
+	undo [2]int64 // size of the log before and after the last append, for Undo
+}
+
+func OpenLearnLog(path string) (*LearnLog, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &LearnLog{f: f}, nil
+}
+
+func (l *LearnLog) Append(word string, at time.Time) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	before := l.size()
+	n, err := fmt.Fprintf(l.f, "%d\t%s\n", at.Unix(), word)
+	l.undo = [2]int64{before, before + int64(n)}
+	return err
+}
+
+// Removes the entry appended last, unless the log changed since. Eg:- it was compacted
+func (l *LearnLog) Undo() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.undo[1] == 0 || l.size() != l.undo[1] {
+		return fmt.Errorf("the learn log changed since")
+	}
+	err := l.f.Truncate(l.undo[0])
+	l.undo = [2]int64{}
+	return err
+}
+
+func (l *LearnLog) size() int64 {
+	info, err := l.f.Stat()
+	if err != nil {
+		return -1
+	}
+	return info.Size()
+}
+
+// Closes the log once a compaction running on it is done, later ones fail.
+// Eg:- a flush of the upkeep still going when the profile is switched
+func (l *LearnLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return nil
+	}
+	err := l.f.Close()
+	l.f = nil // appending fails from now on too
+	return err
+}
+
+// Counts how often multi-word sequences were typed
+type Phrases struct {
+	counts map[string]int
+	next   map[string]map[string]int // counts of the two word phrases by their first word
+	recent []string                  // words of the sequence being typed, newest last
+	last   time.Time                 // when the newest word was typed
+}
+
+// Builds the phrase counts from the compacted counts in base followed by the learn log
+func NewPhrases(base map[string]int, history []LearnedWord) *Phrases {
+	p := &Phrases{counts: make(map[string]int), next: make(map[string]map[string]int)}
+	for phrase, count := range base {
+		p.counts[phrase] = count
+		if first, second, ok := strings.Cut(phrase, " "); ok && !strings.Contains(second, " ") {
+			p.follow(first, second, count)
+		}
+	}
+	for _, lw := range history {
+		p.Add(lw.word, lw.at)
+	}
+	return p
+}
+
+// Records word and returns the longest phrase ending with it along with its count
+func (p *Phrases) Add(word string, at time.Time) (string, int) {
+	if at.Sub(p.last) > phraseGap {
+		p.recent = p.recent[:0]
+	}
+	p.last = at
+
+	p.recent = append(p.recent, word)
+	if len(p.recent) > maxPhraseWords {
+		p.recent = p.recent[1:]
+	}
+
+	var phrase string
+	var count int
+	for n := minPhraseWords; n <= len(p.recent); n++ {
+		s := strings.Join(p.recent[len(p.recent)-n:], " ")
+		p.counts[s]++
+		if n == 2 {
+			p.follow(p.recent[len(p.recent)-2], word, 1)
+		}
+		if p.counts[s] >= snippetMinUses {
+			phrase, count = s, p.counts[s]
+		}
+	}
+	return phrase, count
+}
+
+func (p *Phrases) follow(previous, word string, n int) {
+	if p.next[previous] == nil {
+		p.next[previous] = make(map[string]int)
+	}
+	p.next[previous][word] += n
+}
+
+// Predicts the word typed after previous, the last of them, before any of it is
+// typed: the words which followed it most often. Eg:- thank --> you
+func (p *Phrases) Candidates(previous []string) []Candidate {
+	if len(previous) == 0 {
+		return nil
+	}
+	var next []PhraseCount
+	for word, count := range p.next[previous[len(previous)-1]] {
+		if count >= nextMinUses {
+			next = append(next, PhraseCount{word, count})
+		}
+	}
+	sort.Slice(next, func(i, j int) bool {
+		if next[i].count != next[j].count {
+			return next[i].count > next[j].count
+		}
+		return next[i].phrase < next[j].phrase
+	})
+	var result []Candidate
+	for _, pc := range next[:min(maxNextWords, len(next))] {
+		result = append(result, Candidate{word: pc.phrase, source: "next"})
+	}
+	return result
+}
+
+// Takes back the last Add of word
+func (p *Phrases) Undo(word string) {
+	if len(p.recent) == 0 || p.recent[len(p.recent)-1] != word {
+		return
+	}
+	for n := minPhraseWords; n <= len(p.recent); n++ {
+		s := strings.Join(p.recent[len(p.recent)-n:], " ")
+		if p.counts[s]--; p.counts[s] <= 0 {
+			delete(p.counts, s)
+		}
+		if n == 2 {
+			p.follow(p.recent[len(p.recent)-2], word, -1)
+		}
+	}
+	p.recent = p.recent[:len(p.recent)-1]
+}
+
+// A phrase and how many times it was typed
+type PhraseCount struct {
+	phrase string
+	count  int
+}
+
+// Returns phrases used at least min times, most used first. A phrase is left out
+// when a longer phrase containing it was used just as often
+func (p *Phrases) Frequent(min int) []PhraseCount {
+	var result []PhraseCount
+	for phrase, count := range p.counts {
+		if count < min || p.subsumed(phrase, count) {
+			continue
+		}
+		result = append(result, PhraseCount{phrase, count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].count != result[j].count {
+			return result[i].count > result[j].count
+		}
+		return result[i].phrase < result[j].phrase
+	})
+	return result
+}
+
+func (p *Phrases) subsumed(phrase string, count int) bool {
+	for other, c := range p.counts {
+		if c >= count && len(other) > len(phrase) && strings.Contains(" "+other+" ", " "+phrase+" ") {
+			return true
+		}
+	}
+	return false
+}
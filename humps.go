@@ -0,0 +1,101 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/b0tShaman/autocomplete-cli/trie"
+)
+
+// Completes identifiers from the initials of their subwords in code profiles,
+// Eg:- gNB --> getNodeBalance, fp --> file_path
+type HumpIndex map[string][]string // lowercased initials to the identifiers having them
+
+// Splits an identifier at underscores, dashes and case changes
+// Eg:- parseHTTPResponse --> [parse HTTP Response]
+func subwords(ident string) []string {
+	var words []string
+	r := []rune(ident)
+	start := 0
+	for i := 0; i <= len(r); i++ {
+		boundary := i == len(r) || r[i] == '_' || r[i] == '-'
+		if !boundary && i > start {
+			prev := r[i-1]
+			boundary = unicode.IsLower(prev) && unicode.IsUpper(r[i]) ||
+				unicode.IsUpper(prev) && unicode.IsUpper(r[i]) && i+1 < len(r) && unicode.IsLower(r[i+1]) ||
+				unicode.IsDigit(prev) != unicode.IsDigit(r[i])
+		}
+		if boundary {
+			if i > start {
+				words = append(words, string(r[start:i]))
+			}
+			start = i
+			if i < len(r) && (r[i] == '_' || r[i] == '-') {
+				start++
+			}
+		}
+	}
+	return words
+}
+
+// Lowercased first letters of the subwords, empty if ident is a single word
+func initials(ident string) string {
+	words := subwords(ident)
+	if len(words) < 2 {
+		return ""
+	}
+	var b strings.Builder
+	for _, w := range words {
+		b.WriteRune(unicode.ToLower([]rune(w)[0]))
+	}
+	return b.String()
+}
+
+// Indexes every identifier made of several subwords in trie
+func NewHumpIndex(t *trie.Stack) HumpIndex {
+	h := make(HumpIndex)
+	for _, w := range t.Words() {
+		h.Add(w.Value)
+	}
+	return h
+}
+
+// Indexes ident, does nothing on a nil index
+func (h HumpIndex) Add(ident string) {
+	key := initials(ident)
+	if key == "" || h == nil {
+		return
+	}
+	for _, known := range h[key] {
+		if known == ident {
+			return
+		}
+	}
+	h[key] = append(h[key], ident)
+}
+
+// Identifiers whose initials start with the typed humps, most used first. Typed
+// capitals mark where a subword starts, so gNB and gnb find the same identifiers
+func (h HumpIndex) Candidates(t *trie.Stack, typed string) []Candidate {
+	var result []Candidate
+	if len([]rune(typed)) < 2 || h == nil {
+		return result
+	}
+	query := strings.ToLower(typed)
+
+	var matches []trie.Word
+	for key, idents := range h {
+		if strings.HasPrefix(key, query) {
+			for _, ident := range idents {
+				if !strings.HasPrefix(ident, typed) { // plain completions come from the trie already
+					matches = append(matches, trie.Word{Value: ident, Count: t.Count(ident)})
+				}
+			}
+		}
+	}
+	trie.Sort(matches, usageScore)
+	for _, w := range matches {
+		result = append(result, Candidate{word: w.Value, source: "hump"})
+	}
+	return result
+}
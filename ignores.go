@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Suggestions rejected for a prefix, one "prefix<TAB>word" per line
+const ignoresFile = "ignored.txt"
+
+// Maps a typed prefix to the words never to suggest for it
+type Ignores map[string]map[string]bool
+
+// Reads the ignore list. A missing file means nothing was rejected
+func LoadIgnores(path string) (Ignores, error) {
+	ig := make(Ignores)
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return ig, nil
+	} else if err != nil {
+		return ig, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		prefix, word, ok := strings.Cut(scanner.Text(), "\t")
+		if ok && prefix != "" && word != "" {
+			ig.Add(prefix, word)
+		}
+	}
+	return ig, scanner.Err()
+}
+
+func (ig Ignores) Save(path string) error {
+	var b strings.Builder
+	for _, line := range ig.sorted() {
+		b.WriteString(line + "\n")
+	}
+	return os.WriteFile(path, []byte(b.String()), 0644)
+}
+
+// "prefix<TAB>word" of every rejection, sorted
+func (ig Ignores) sorted() []string {
+	var lines []string
+	for prefix, words := range ig {
+		for word := range words {
+			lines = append(lines, prefix+"\t"+word)
+		}
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+func (ig Ignores) Add(prefix, word string) {
+	if ig[prefix] == nil {
+		ig[prefix] = make(map[string]bool)
+	}
+	ig[prefix][word] = true
+}
+
+// Takes back a rejection, returns false if there was none
+func (ig Ignores) Remove(prefix, word string) bool {
+	if !ig[prefix][word] {
+		return false
+	}
+	delete(ig[prefix], word)
+	if len(ig[prefix]) == 0 {
+		delete(ig, prefix)
+	}
+	return true
+}
+
+// Leaves out the candidates rejected for prefix
+func (ig Ignores) Filter(prefix string, candidates []Candidate) []Candidate {
+	rejected := ig[prefix]
+	if len(rejected) == 0 {
+		return candidates
+	}
+	var result []Candidate
+	for _, c := range candidates {
+		if !rejected[c.word] {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// autocomplete ignores [list|remove <prefix> <word>]
+// Prints the rejected suggestions or takes one back
+func ignoresCommand(args []string) error {
+	ig, err := LoadIgnores(paths.ignores)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 || args[0] == "list" {
+		for _, line := range ig.sorted() {
+			prefix, word, _ := strings.Cut(line, "\t")
+			fmt.Printf("%-12s %s\n", prefix, word)
+		}
+		return nil
+	}
+	if args[0] != "remove" || len(args) != 3 {
+		return fmt.Errorf("usage: ignores [list|remove <prefix> <word>]")
+	}
+	if !ig.Remove(args[1], args[2]) {
+		return fmt.Errorf("%s is not ignored for %s", args[2], args[1])
+	}
+	return ig.Save(paths.ignores)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	keyNone rune = -100 // never arrives, for actions without a key
+	keyF1   rune = -40
+)
+
+// Escape sequences of the function keys
+var functionKeys = map[string]rune{
+	"\x1bOP": keyF1, "\x1b[11~": keyF1,
+}
+
+// Names of the keys which are not ctrl+<letter>, as the config file spells them
+var keyNames = map[string]rune{
+	"tab":           TAB,
+	"shift+tab":     keyShiftTab,
+	"enter":         '\r',
+	"shift+enter":   keyShiftEnter,
+	"alt+enter":     keyAltEnter,
+	"backspace":     DELETE,
+	"alt+backspace": keyAltDelete,
+	"esc":           ESCAPE,
+	"left":          keyLeft,
+	"right":         keyRight,
+	"home":          keyHome,
+	"end":           keyEnd,
+	"delete":        keyForwardDelete,
+	"f1":            keyF1,
+}
+
+// Converts a key name into the key the editor is given for it, keyNone for "".
+// Eg:- ctrl+t --> 20, shift+tab --> keyShiftTab
+func parseKey(name string) (rune, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return keyNone, nil
+	}
+	if letter, ok := strings.CutPrefix(name, "ctrl+"); ok && len(letter) == 1 && letter[0] >= 'a' && letter[0] <= 'z' {
+		return rune(letter[0]-'a') + 1, nil
+	}
+	if key, ok := keyNames[name]; ok {
+		return key, nil
+	}
+	return 0, fmt.Errorf("unsupported key %q, expected ctrl+<letter>, tab, shift+tab, enter, esc, backspace, alt+backspace, an arrow, home, end, delete or f1", name)
+}
+
+// The key a whole escape sequence stands for. Eg:- ESC [ Z --> keyShiftTab
+func decodeKey(seq []byte) (rune, bool) {
+	if key, ok := escapeKeys[string(seq)]; ok {
+		return key, true
+	}
+	if key, ok := functionKeys[string(seq)]; ok {
+		return key, true
+	}
+	if key, ok := pasteKeys[string(seq)]; ok {
+		return key, true
+	}
+	key, ok := cursorKeys[string(seq)]
+	return key, ok
+}
+
+// An action of cfg.Keys, with the key it is bound to
+type keyBinding struct {
+	action, name string
+	key          *rune
+	help         string // what the key does, for the help overlay
+}
+
+// The actions of cfg.Keys in the order the help overlay lists them
+func (cfg *Config) bindings() []keyBinding {
+	k := cfg.Keys
+	return []keyBinding{
+		{"accept", k.Accept, &cfg.acceptKey, "accepts the suggestion"},
+		{"cycle", k.Cycle, &cfg.cycleKey, "shows the next suggestion"},
+		{"cycle_back", k.CycleBack, &cfg.cycleBackKey, "shows the previous suggestion"},
+		{"dismiss", k.Dismiss, &cfg.dismissKey, "drops the suggestion"},
+		{"delete_word", k.DeleteWord, &cfg.deleteWordKey, "deletes the word before the cursor"},
+		{"quit", k.Quit, &cfg.quitKey, "quits, like ctrl+c"},
+		{"t9", k.T9, &cfg.t9Key, "toggles T9 mode"},
+		{"snippet", k.Snippet, &cfg.snippetKey, "accepts the proposed snippet"},
+		{"menu", k.Menu, &cfg.menuKey, "lists the suggestions"},
+		{"ignore", k.Ignore, &cfg.ignoreKey, "never suggests the word for this prefix again"},
+		{"surprise", k.Surprise, &cfg.surpriseKey, "inserts a random next word"},
+		{"pin", k.Pin, &cfg.pinKey, "pins the suggestion to the prefix, or unpins it"},
+		{"history", k.History, &cfg.historyKey, "lists the completions accepted this session"},
+		{"compose", k.Compose, &cfg.composeKey, "composes a character, Eg:- ' e --> é"},
+		{"temporary", k.Temporary, &cfg.tempKey, "makes the word just learned temporary"},
+		{"explain", k.Explain, &cfg.explainKey, "explains how the suggestion was scored"},
+		{"forget", k.Forget, &cfg.forgetKey, "forgets the suggested word"},
+		{"teach", k.Teach, &cfg.teachKey, "learns the words of the next paste instead of inserting it"},
+		{"help", k.Help, &cfg.helpKey, "shows this help"},
+	}
+}
+
+// Resolves the key names of cfg.Keys, two actions may not share a key
+func (cfg *Config) resolveKeys() error {
+	bound := make(map[rune]string)
+	for _, b := range cfg.bindings() {
+		key, err := parseKey(b.name)
+		if err != nil {
+			return fmt.Errorf("keys.%s: %w", b.action, err)
+		}
+		if other, ok := bound[key]; ok && key != keyNone {
+			return fmt.Errorf("keys.%s and keys.%s are both %s", other, b.action, b.name)
+		}
+		bound[key], *b.key = b.action, key
+	}
+	return nil
+}
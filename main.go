@@ -1,14 +1,17 @@
 package main
 
 import (
-	"context"
+	"bytes"
+	"flag"
 	"fmt"
 	"os"
-	"sort"
+	"path/filepath"
 	"strings"
 	"syscall"
 	"time"
 
+	"github.com/b0tShaman/autocomplete-cli/trie"
+
 	"golang.org/x/term"
 )
 
@@ -17,227 +20,434 @@ const (
 	BACKSPACE = 8
 	DELETE    = 127
 	ESCAPE    = 27
+	CTRL_B    = 2
 	CTRL_C    = 3
+	CTRL_D    = 4
+	CTRL_E    = 5
+	CTRL_F    = 6
+	CTRL_G    = 7
+	CTRL_K    = 11
+	CTRL_L    = 12
+	CTRL_O    = 15
+	CTRL_P    = 16
+	CTRL_R    = 18
+	CTRL_S    = 19
+	CTRL_T    = 20
+	CTRL_U    = 21
+	CTRL_X    = 24
+	CTRL_Y    = 25
 )
 
-var (
-	autoCompleteTriggered bool     // to keep track of keypresses after the autocomplete feature is triggered
-	suggestions           []string // list of suggestions for current word
-	suggestionIndex       int      // index to track currently displayed suggestion
-)
-
-// The core data structure
-type Trie struct {
-	children  map[rune]*Trie
-	wordCount int
-}
-
-// Descibes a word and how many times its been used
-type Word struct {
-	value string
-	count int
+// A single screen update: the typed text, the ghost text of a suggestion after
+// the cursor and an optional status line shown below it
+type frame struct {
+	text   string
+	ghost  string // may hold SGR escape sequences
+	after  string // text after the cursor
+	status string
 }
 
-// To sort suggestions based on usage
-type Suggestions []Word
-
-func (m Suggestions) Len() int           { return len(m) }
-func (m Suggestions) Less(i, j int) bool { return m[i].count > m[j].count }
-func (m Suggestions) Swap(i, j int)      { m[i], m[j] = m[j], m[i] }
-
-func TrieConstructor() *Trie {
-	return &Trie{
-		children:  make(map[rune]*Trie),
-		wordCount: 0,
-	}
-}
-
-// Insert word into the Trie
-func (root *Trie) Insert(word string) {
-	for _, s := range word {
-		if root.children[s] == nil {
-			root.children[s] = TrieConstructor()
-		}
-		root = root.children[s]
+func main() {
+	args, err := parseFlags(os.Args[1:])
+	if err == flag.ErrHelp {
+		return
+	} else if err != nil {
+		os.Exit(2)
 	}
-	root.wordCount++
-}
-
-// Returns list of suggestions for auto-completion. The suggestions are sorted in order of usage
-func (root *Trie) Autofill(word string) []string {
-	var output Suggestions
-	var result []string
-
-	if len(word) == 0 {
-		return result
+	if len(args) > 0 && args[0] != "run" {
+		os.Exit(runCommand(args))
+	} else if len(args) > 1 {
+		fmt.Fprintln(os.Stderr, "usage: autocomplete [flags] run")
+		os.Exit(2)
 	}
 
-	for _, s := range word {
-		if root.children[s] == nil {
-			return result
-		} else {
-			root = root.children[s]
+	// First launch, ask a few questions before taking over the terminal
+	if _, err := os.Stat(configPath()); os.IsNotExist(err) && term.IsTerminal(int(syscall.Stdin)) {
+		if err := paths.mkdir(); err == nil {
+			err = runWizard(os.Stdin, os.Stdout)
+		}
+		if err != nil {
+			fmt.Println("Setup failed:", err)
 		}
 	}
 
-	dfs(root, "", &output)
-	sort.Sort(output)
-
-	result = make([]string, len(output))
-	for i, word := range output {
-		result[i] = word.value
-	}
-
-	return result
-}
-
-func dfs(root *Trie, prefix string, output *Suggestions) {
-	if root.wordCount > 0 {
-		*output = append(*output, Word{prefix, root.wordCount})
-	}
-
-	for k, v := range root.children {
-		dfs(v, prefix+string(k), output)
-	}
-}
+	var recap string // printed once the terminal is restored
+	defer func() {
+		if recap != "" {
+			fmt.Print("\n" + recap) // below what was typed
+		}
+	}()
 
-func main() {
 	// Enable raw mode to capture keypresses instantly - from stack overflow
-	oldState, err := term.MakeRaw(int(syscall.Stdin))
+	restore, err := makeRaw()
 	if err != nil {
 		fmt.Println("Error:", err)
 		return
 	}
-	defer term.Restore(int(syscall.Stdin), oldState)
+	defer restore()
+	fmt.Print(bracketedPasteOn) // for the teach key
+	defer fmt.Print(bracketedPasteOff)
 
-	data, err := os.ReadFile("words.txt")
+	cfg, err := LoadConfig(configPath())
 	if err != nil {
-		fmt.Println("ReadFile failed:", err)
+		diagnostics.Addf("invalid config, using the defaults: %v", err)
+	}
+	cfg.apply()
+	reloads := watchConfig(configPath())
+	dictChanges := watchDictionaries()
+	defer dictChanges.Close()
+	dictReloads := newDictReloader()
+
+	ch := make(chan frame, 1000)
+	timer := time.NewTimer(cfg.Debounce) // timer to trigger autocomplete suggestions
+	e := NewEditor(ch, timer)
+	e.cfg = cfg
+
+	var problems []string
+	e.prof, problems = openProfile(paths)
+	diagnostics.Add(problems...)
+	defer func() { e.prof.Close() }()
+
+	verifier := NewVerifier(cfg)
+	e.defs = LoadDefinitions(cfg.Definitions, verifier)
+	e.meta = LoadMetadata(cfg.Dictionary, verifier)
+	e.bi = LoadBilingual(cfg.Translations, verifier)
+	e.packs, problems = LoadPacks(cfg.Packs, verifier)
+	diagnostics.Add(problems...)
+	e.team, problems = subscribeTeam(cfg.Team)
+	diagnostics.Add(problems...)
+	e.composer, problems = LoadCompose()
+	diagnostics.Add(problems...)
+
+	e.plugins, problems = StartPlugins(filepath.Join(configDir(), pluginsDir))
+	diagnostics.Add(problems...)
+	for _, p := range e.plugins {
+		defer p.Stop()
 	}
 
-	// Convert the file content to a string and split it into words
-	content := string(data)
-	words := strings.Fields(content) // Splits on spaces, newlines, and tabs ( better than strings.Split(content, " "))
+	logger, logName := openLogger()
+	diagnostics.LogTo(logger)
+	dumps, flushes := notifyUserSignals()
+
+	control, err := openControl(controlPath(os.Getpid()))
+	if err != nil {
+		diagnostics.Addf("control interface unavailable: %v", err)
+	} else {
+		defer os.Remove(controlPath(os.Getpid())) // only this editor's, the others still read theirs
+	}
 
-	ch := make(chan string, 1000)
 	// Goroutine to render text on terminal
 	go render(ch)
 
-	var input []rune             // Store input characters
-	inputChan := make(chan byte) // Channel for keypresses
-	trie := TrieConstructor()
+	inputChan := make(chan []byte) // Channel for keypresses
+	e.trie, e.sources, problems = loadTrie(cfg.dictSpecs(cfg.Dictionary), cfg.Dawg, cfg.BuiltinWords, verifier, e.prof.paths.snapshot, e.prof.tombstones, e.prof.history, cfg.Tokens)
+	diagnostics.Add(problems...)
+	if problems := verifier.Problems(); problems != "" {
+		diagnostics.Add("integrity: " + problems)
+	}
+	e.project, problems = openProject(cfg)
+	e.project.Layer(e.trie, e.prof.tombstones)
+	diagnostics.Add(problems...)
+	if cfg.codeMode() {
+		e.humps = NewHumpIndex(e.trie)
+	}
+
+	upkeep := NewScheduler(cfg.Maintenance.Idle)
 
-	// Insert all words from "words.txt"
-	for _, word := range words {
-		trie.Insert(word)
+	// Applies a new config, reloading whatever it changed. Returns the status to show
+	var profileOverride string // set by the switch-profile and context commands
+	configured := cfg.Profile  // the profile of the config, without the override
+	reload := func() string {
+		newCfg, err := LoadConfig(configPath())
+		if err == nil {
+			configured = newCfg.Profile
+		}
+		if err == nil && profileOverride != "" {
+			newCfg.Profile = profileOverride
+			err = newCfg.validate()
+		}
+		if err != nil {
+			// Keep running with the previous config
+			return "invalid config: " + err.Error()
+		}
+		status := "config reloaded"
+		profileChanged := newCfg.Profile != e.cfg.Profile
+		sourcesChanged := newCfg.sourcesChanged(e.cfg)
+		if profileChanged {
+			e.prof.Close()
+		}
+		e.cfg = newCfg
+		e.cfg.apply()
+		dictChanges.Watch(e.cfg.dictPaths())
+		var problems []string
+		if profileChanged {
+			e.prof, problems = openProfile(paths)
+		}
+		if profileChanged || sourcesChanged {
+			// The learn log holds everything learned so far, including this session
+			history, _ := ReadLearnLog(paths.learnLog)
+			verifier := NewVerifier(e.cfg)
+			var trieProblems, packProblems, teamProblems, projectProblems []string
+			e.trie, e.sources, trieProblems = loadTrie(e.cfg.dictSpecs(e.cfg.Dictionary), e.cfg.Dawg, e.cfg.BuiltinWords, verifier, e.prof.paths.snapshot, e.prof.tombstones, history, e.cfg.Tokens)
+			e.project, projectProblems = openProject(e.cfg)
+			e.project.Layer(e.trie, e.prof.tombstones)
+			e.defs = LoadDefinitions(e.cfg.Definitions, verifier)
+			e.meta = LoadMetadata(e.cfg.Dictionary, verifier)
+			e.bi = LoadBilingual(e.cfg.Translations, verifier)
+			e.packs, packProblems = LoadPacks(e.cfg.Packs, verifier)
+			e.team, teamProblems = subscribeTeam(e.cfg.Team)
+			problems = append(append(append(append(problems, trieProblems...), projectProblems...), packProblems...), teamProblems...)
+			if p := verifier.Problems(); p != "" {
+				problems = append(problems, "integrity: "+p)
+			}
+		}
+		var composeProblems []string
+		e.composer, composeProblems = LoadCompose()
+		problems = append(problems, composeProblems...)
+		if e.humps = nil; e.cfg.codeMode() {
+			e.humps = NewHumpIndex(e.trie)
+		}
+		e.warm = NewPrefixCache() // the scoring may have changed
+		upkeep.Prewarm(e)
+		if len(problems) > 0 {
+			diagnostics.Add(problems...)
+			status = problemStatus(diagnostics.Unseen())
+		}
+		return status
 	}
 
 	// Goroutine to read input
 	go inputReader(inputChan)
+	upkeep.Prewarm(e) // so the first suggestions come quickly even from a huge dictionary
+	dictChanges.Watch(e.cfg.dictPaths())
 
-	timer := time.NewTimer(200 * time.Millisecond) // timer to trigger autocomplete suggestions
-
-	ctx, cancel := context.WithCancel(context.TODO())
 	fmt.Println("START TYPING")
+	if problems := diagnostics.Unseen(); len(problems) > 0 {
+		ch <- frame{status: problemStatus(problems)}
+	}
 	for {
 		select {
-		case <-timer.C:
-			// get current word being typed
-			word := getCurrentWord(input)
-			suggestions = trie.Autofill(word)
-			if len(suggestions) == 0 {
-				continue
-			}
-			autoCompleteTriggered = true
-			cancel()
-
-			ctx, cancel = context.WithCancel(context.TODO())
-			go recommendation(ctx, suggestions[suggestionIndex%len(suggestions)], input, ch)
+		case <-reloads:
+			e.status(reload())
 
-		case key, ok := <-inputChan:
-			if !ok {
-				return // Exit if input channel is closed
+		case <-dictChanges.C:
+			if e.cfg.Watch {
+				dictReloads.Start(e.cfg)
 			}
 
-			// Reset timer on each keypress
-			timer.Reset(200 * time.Millisecond)
-
-			// Key press detected while autocomplete suggestion is displayed66
-			if autoCompleteTriggered {
-				cancel()
-				if key == TAB { // Loop through suggestions
-					suggestionIndex++
-					// ctx, cancel = context.WithTimeout(context.TODO(), 10*time.Second)
-					ctx, cancel = context.WithCancel(context.TODO())
-					go recommendation(ctx, suggestions[suggestionIndex%len(suggestions)], input, ch)
+		case apply := <-dictReloads.done:
+			e.status(dictReloads.Finish(e, apply))
+			upkeep.Prewarm(e)
+
+		case <-dumps:
+			logger.Print("stats\n" + statsReport(e.trie, e.prof.boosts, e.cfg.Profile))
+			e.status("stats written to " + logName)
+
+		case <-flushes:
+			status := "learn log unavailable"
+			if e.prof.learnLog != nil {
+				// Nothing is pruned, a flush only makes sure the snapshots are up to date
+				report, err := e.prof.learnLog.Compact(paths.snapshot, paths.phrases, e.prof.tombstones, PrunePolicy{})
+				status = "flushed learned data: " + report.String()
+				if err != nil {
+					status = "flush failed: " + err.Error()
+				}
+			}
+			logger.Print(status)
+			e.status(status)
+
+		case command := <-control:
+			var status string
+			switch command.name {
+			case "learn":
+				status = e.learnControl(command.arg)
+			case "forget":
+				status = e.forget(command.arg)
+			case "switch-profile":
+				profileOverride = command.arg
+				status = reload()
+				if status == "config reloaded" {
+					status = "switched to profile " + command.arg
+				}
+			case "context":
+				// Sent on every focus change, so staying on the same profile does nothing
+				profile := e.cfg.contextProfile(command.arg)
+				if profile == "" {
+					profile = configured
+				}
+				if profile == e.cfg.Profile {
 					continue
-				} else if key == '\n' || key == '\r' { // Suggestion has been selected. Perform autocomplete
-					input = append(input, []rune(suggestions[suggestionIndex%len(suggestions)])...)
-					key = ' '
 				}
-
-				autoCompleteTriggered = false
-				suggestions = []string{}
-				suggestionIndex = 0
+				if profileOverride = profile; profile == configured {
+					profileOverride = ""
+				}
+				status = reload()
+				if status == "config reloaded" {
+					status = "switched to profile " + profile + " for " + command.arg
+				}
+			case "reload":
+				status = reload()
 			}
+			e.status(status)
 
-			// Ignore TAB and Enter -> to simplify getCurrentWord() and getLastWord() logic
-			if key == TAB || key == '\n' || key == '\r' {
-				continue
-			}
+		case <-timer.C:
+			e.Suggest()
+
+		case <-upkeep.timer.C:
+			upkeep.Run(e)
 
-			// On detecting SPACE, store the last typed word into the Trie
-			if key == ' ' {
-				word := getLastWord(input)
-				trie.Insert(word)
+		case apply := <-upkeep.done:
+			if report, err := upkeep.Finish(e, apply); err != nil {
+				diagnostics.Add(err.Error())
+			} else {
+				logger.Print(report)
 			}
 
-			// Handle backspace
-			if key == BACKSPACE || key == DELETE {
-				if len(input) > 0 {
-					input = input[:len(input)-1]
-					fmt.Print("\b \b")
-					ch <- string(input)
+		case chunk, ok := <-inputChan:
+			upkeep.Touch(e.cfg.Maintenance.Idle)
+			if !ok || !e.Feed(chunk) {
+				if e.cfg.Recap != recapOff {
+					recap = e.recap.String(e.cfg.Recap, e.prof.paths.dir, e.cfg.NoLearn)
 				}
-				continue
+				return // Exit if input channel is closed or on Ctrl+C / Ctrl+D
+			}
+			if e.warm.Burst() {
+				upkeep.Prewarm(e)
 			}
-
-			// Add character and send to render() function
-			input = append(input, rune(key))
-			ch <- string(input)
 		}
 	}
 }
 
-// Goroutine which sends input + suggestion to render() with a blinking effect
-func recommendation(ctx context.Context, r string, input []rune, inputchan chan string) {
-	ticker := time.NewTicker(200 * time.Millisecond)
-	defer ticker.Stop()
+// Layers of the words suggestions come from, bottom first. Each change goes to
+// the layer it belongs to, the dictionary is only read
+const (
+	baseLayer    = iota // the dictionary, which serve mode shares between its clients
+	userLayer           // the learned counts, which learning and forgetting change
+	sessionLayer        // words kept until the editor quits, Eg:- those of the project
+)
 
-	alt := []string{string(input) + r, string(input)}
+// Returns layers of words on top of the dictionary words in base
+func newWords(base *trie.Trie) *trie.Stack {
+	return trie.NewStack(base, trie.New(), trie.New())
+}
 
-	for i := 0; ; i++ {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			inputchan <- alt[i%2]
+// Builds the words from the dictionaries, the learned counts and the learn log,
+// see loadBase and layerWords
+func loadTrie(dictionaries []DictSpec, dawg, builtin bool, v *Verifier, snapshot string, tombstones Tombstones, history []LearnedWord, tokens TokensConfig) (*trie.Stack, *DictSources, []string) {
+	base, sources, problems := loadBase(dictionaries, dawg, builtin, v)
+	t, layerProblems := layerWords(base, snapshot, tombstones, history, tokens)
+	return t, sources, append(problems, layerProblems...)
+}
+
+// Reads the words of the dictionaries, word lists or compiled (see compile,
+// mapped ones are memory mapped), nothing from those v refuses. Several of them
+// or a weight other than 1 merge into one Trie, each count times the weight of
+// its list, and the sources tell which list each word came from. With builtin
+// the built-in words go below them, also when the default words.txt is
+// missing. With dawg the words share their common endings, see Trie.Minimize
+func loadBase(dictionaries []DictSpec, dawg, builtin bool, v *Verifier) (*trie.Trie, *DictSources, []string) {
+	var problems []string
+	var t *trie.Trie
+	var mapped bool
+	var sources *DictSources
+	if len(dictionaries) == 0 || len(dictionaries) == 1 && dictionaries[0].weight == 1 {
+		unavailable := "dictionary unavailable, completing from learned words only: %v"
+		if builtin {
+			unavailable = "dictionary unavailable, completing from the built-in and learned words only: %v"
+		}
+		var path string
+		if len(dictionaries) == 1 {
+			path = dictionaries[0].path
 		}
+		var err error
+		if t, mapped, err = readWords(path, builtin, v); err != nil {
+			problems = append(problems, fmt.Sprintf(unavailable, err))
+		}
+	} else {
+		t = trie.New()
+		sources = &DictSources{specs: dictionaries, words: make(map[string]uint64)}
+		for i, spec := range dictionaries {
+			words, _, err := readWords(spec.path, builtin, v)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("dictionary %s unavailable, completing without it: %v", filepath.Base(spec.path), err))
+			}
+			for _, w := range words.Words() {
+				t.InsertCount(w.Value, spec.count(w.Count))
+				sources.add(i, w.Value)
+			}
+		}
+	}
+
+	if builtin && !mapped { // a mapped one is read-only
+		addBuiltinWords(t)
+	}
+	if dawg {
+		t = t.Minimize()
 	}
+	return t, sources, problems
 }
 
-// To get the current word being typed
-// Eg:- this is a tes  --> getCurrentWord() returns tes
-func getCurrentWord(input []rune) string {
-	var str []rune
-	for i := len(input) - 1; i >= 0; i-- {
-		if input[i] == ' ' {
-			break
-		} else {
-			str = append(append([]rune{}, input[i]), str...)
+// Reads the words of the word list at path and whether it is a mapped one,
+// nothing for no path or when v refuses it. The file stays mapped until the
+// words read from it are no longer used
+func readWords(path string, builtin bool, v *Verifier) (*trie.Trie, bool, error) {
+	var data []byte
+	if path != "" && v.Allow(path) {
+		var err error
+		data, err = readDictionaryFile(path)
+		if err != nil && !(builtin && os.IsNotExist(err) && path == defaultConfig().Dictionary) {
+			return trie.New(), false, err
 		}
 	}
-	return string(str)
+	mapped := bytes.HasPrefix(data, []byte(mappedMagic))
+	t, _, err := parseDictionaryFile(data)
+	if mapped && !t.OnRelease(func() { unmapFile(data) }) {
+		unmapFile(data) // corrupt, nothing was read from it
+	}
+	return t, mapped, err
+}
+
+// Puts the learned counts and the learn log on top of the dictionary words in
+// base, hiding forgotten words and leaving out learned tokens whose policy is
+// not to learn them. Unreadable files are returned as problems, the words are
+// built from whatever could be read
+func layerWords(base *trie.Trie, snapshot string, tombstones Tombstones, history []LearnedWord, tokens TokensConfig) (*trie.Stack, []string) {
+	t := newWords(base)
+	var problems []string
+
+	// Forgotten words show again once learned after they were forgotten
+	for word := range tombstones {
+		t.Hide(word)
+	}
+	counts, err := LoadSnapshot(snapshot)
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("learned counts unavailable: %v", err))
+	}
+	for word, u := range counts {
+		if !tombstones.Buried(word, u.last) && tokens.Policy(word) == tokenLearn {
+			t.InsertCount(userLayer, word, u.count)
+		}
+	}
+	// and whatever was learned since the last compaction
+	for _, lw := range history {
+		if !tombstones.Buried(lw.word, lw.at) && tokens.Policy(lw.word) == tokenLearn {
+			t.Insert(userLayer, lw.word)
+		}
+	}
+	return t, problems
+}
+
+// Status line of c: translations show their label, other candidates their
+// definition (if any)
+func candidateStatus(c Candidate, defs Definitions, meta Metadata) string {
+	status := c.label
+	if status == "" {
+		status = defs.Status(c.word)
+	}
+	if status == "" {
+		status = meta.Status(c.word)
+	}
+	return status
 }
 
 // To get the previous word that was typed
@@ -246,9 +456,9 @@ func getLastWord(input []rune) string {
 	var str []rune
 	var wordEncountered bool
 	for i := len(input) - 1; i >= 0; i-- {
-		if input[i] == ' ' && wordEncountered {
+		if (input[i] == ' ' || input[i] == '\n') && wordEncountered {
 			break
-		} else if input[i] != ' ' {
+		} else if input[i] != ' ' && input[i] != '\n' {
 			str = append(append([]rune{}, input[i]), str...)
 			wordEncountered = true
 		}
@@ -256,30 +466,89 @@ func getLastWord(input []rune) string {
 	return string(str)
 }
 
-// Read keypressed and sends it to main loop
-func inputReader(inputChan chan byte) {
-	var b [1]byte
+// Read keypresses and sends them to main loop. A key with an escape sequence or a
+// UTF-8 character usually arrives in one read, it is sent as one chunk
+func inputReader(inputChan chan []byte) {
+	var b [64]byte
 	for {
-		_, err := os.Stdin.Read(b[:])
+		n, err := os.Stdin.Read(b[:])
 		if err != nil {
 			fmt.Println("\nError reading input:", err)
 			close(inputChan)
 			return
 		}
-		// Exit program on Ctrl+C or Esc
-		if b[0] == ESCAPE || b[0] == CTRL_C {
-			close(inputChan)
-			return
+		inputChan <- append([]byte(nil), b[:n]...)
+	}
+}
+
+// Render function. Draws each frame over the previous one, changing only what
+// differs. Only the last of the frames sent while it waited is drawn
+func render(in <-chan frame) {
+	var s screen
+	for f := range in {
+		for latest := true; latest; {
+			select {
+			case next, ok := <-in:
+				if ok {
+					f = next
+				}
+				latest = ok
+			default:
+				latest = false
+			}
+		}
+		width, _, err := term.GetSize(int(syscall.Stdout))
+		if err != nil {
+			width = 0
+		}
+		fmt.Print(s.draw(f, width))
+		time.Sleep(time.Duration(renderEvery.Load()))
+	}
+}
+
+// Truncates s to the terminal width so the status never wraps. SGR escape
+// sequences take no room
+func fitWidth(s string) string {
+	width, _, err := term.GetSize(int(syscall.Stdout))
+	if err != nil || width <= 2 {
+		return s
+	}
+	r := []rune(s)
+	var visible int
+	for i := 0; i < len(r); i += escapeLen(r[i:]) {
+		if escapeLen(r[i:]) == 1 {
+			visible++
 		}
-		inputChan <- b[0]
 	}
+	if visible <= width-1 {
+		return s
+	}
+
+	// Escape sequences are kept so the styles still end where they should
+	var b strings.Builder
+	visible = 0
+	for i := 0; i < len(r); {
+		n := escapeLen(r[i:])
+		if n == 1 && visible < width-2 {
+			visible++
+			b.WriteRune(r[i])
+		} else if n > 1 {
+			b.WriteString(string(r[i : i+n]))
+		}
+		i += n
+	}
+	return b.String() + "…"
 }
 
-// Render function
-func render(in <-chan string) {
-	for str := range in {
-		fmt.Print("\033[H\033[2J") // Clear screen
-		fmt.Print(str)
-		time.Sleep(50 * time.Millisecond)
+// Length of the SGR escape sequence r starts with, 1 for any other rune
+func escapeLen(r []rune) int {
+	if r[0] != '\033' {
+		return 1
+	}
+	for i, c := range r {
+		if c == 'm' {
+			return i + 1
+		}
 	}
+	return len(r)
 }
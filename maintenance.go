@@ -0,0 +1,362 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/b0tShaman/autocomplete-cli/trie"
+)
+
+const (
+	maintenanceStep = 10 * time.Millisecond // between steps while the user stays idle
+	snapshotEvery   = 10 * time.Minute      // the learn log is flushed into the snapshots at most this often
+	retrainEvents   = 64 << 10              // bytes of new events before the ranker is trained again
+	warmMinWords    = 500                   // prefixes completing to fewer words are quick enough as they are
+	warmMaxPrefix   = 2                     // longest prefix kept warm
+	warmBurst       = 100                   // words learned after which every prefix is warmed again at once
+)
+
+// Expensive upkeep, run only once the user stopped typing for a while so it never
+// competes with the keystrokes
+type MaintenanceConfig struct {
+	Idle  time.Duration `toml:"idle"`  // pause in typing before upkeep runs, 0 turns it off
+	Tasks []string      `toml:"tasks"` // any of compact, snapshot, retrain, warm and guard
+}
+
+func (m MaintenanceConfig) validate() error {
+	if m.Idle < 0 {
+		return fmt.Errorf("maintenance.idle must not be negative")
+	}
+	for _, task := range m.Tasks {
+		if !slices.ContainsFunc(maintenanceTasks, func(t maintenanceTask) bool { return t.name == task }) {
+			return fmt.Errorf("maintenance: unknown task %q, expected compact, snapshot, retrain, warm or guard", task)
+		}
+	}
+	return nil
+}
+
+// Work done in the background. What it returns runs back on the main loop and
+// reports the outcome
+type maintenanceJob func() func(e *Editor) (string, error)
+
+// Runs a step of the task on the main loop. Slow work is returned as a job
+type maintenanceTask struct {
+	name string
+	due  func(e *Editor) bool
+	step func(e *Editor) maintenanceJob
+}
+
+var maintenanceTasks = []maintenanceTask{
+	{"compact", compactDue, compactStep},
+	{"guard", guardDue, guardStep},
+	{"snapshot", snapshotDue, snapshotStep},
+	{"retrain", retrainDue, retrainStep},
+	{"warm", warmDue, warmStep},
+}
+
+// Picks the next task once the user is idle, one at a time
+type Scheduler struct {
+	timer *time.Timer
+	done  chan func(e *Editor) (string, error)
+	busy  bool      // a job is running
+	input time.Time // last keystroke
+}
+
+func NewScheduler(idle time.Duration) *Scheduler {
+	s := &Scheduler{timer: time.NewTimer(idle), done: make(chan func(e *Editor) (string, error), 1), input: time.Now()}
+	if idle <= 0 {
+		s.timer.Stop()
+	}
+	return s
+}
+
+// Notes a keystroke, putting the upkeep off until the next pause
+func (s *Scheduler) Touch(idle time.Duration) {
+	s.input = time.Now()
+	if s.timer.Stop(); idle > 0 {
+		s.timer.Reset(idle)
+	}
+}
+
+// Runs a step of the first enabled task which is due, called when the timer fires
+func (s *Scheduler) Run(e *Editor) {
+	if s.busy || e.cfg.Maintenance.Idle <= 0 || time.Since(s.input) < e.cfg.Maintenance.Idle {
+		return
+	}
+	for _, task := range maintenanceTasks {
+		if !slices.Contains(e.cfg.Maintenance.Tasks, task.name) || !task.due(e) {
+			continue
+		}
+		if job := task.step(e); job != nil {
+			s.busy = true
+			go func() { s.done <- job() }()
+		} else {
+			s.timer.Reset(maintenanceStep)
+		}
+		return
+	}
+}
+
+// Warms the short prefixes in the background right away, see
+// PrefixCache.prewarm, unless warming is off or another job is running. Called
+// on startup and after a burst of learning
+func (s *Scheduler) Prewarm(e *Editor) {
+	if s.busy || !slices.Contains(e.cfg.Maintenance.Tasks, "warm") {
+		return
+	}
+	job := e.warm.prewarm(e.trie, e.prof.frozenScore())
+	s.busy = true
+	go func() { s.done <- job() }()
+}
+
+// Applies the outcome of a finished job and moves on to the next task, unless
+// the user started typing meanwhile
+func (s *Scheduler) Finish(e *Editor, apply func(e *Editor) (string, error)) (string, error) {
+	s.busy = false
+	if e.cfg.Maintenance.Idle > 0 && time.Since(s.input) >= e.cfg.Maintenance.Idle {
+		s.timer.Reset(maintenanceStep)
+	}
+	return apply(e)
+}
+
+func compactDue(e *Editor) bool {
+	return (e.prof.learnLog != nil && fileSize(e.prof.paths.learnLog) > compactLogSize) || eventsDue(e)
+}
+
+func eventsDue(e *Editor) bool {
+	return e.prof.events != nil && fileSize(e.prof.paths.events) > eventsLogSize
+}
+
+// Trims the events log, then compacts and prunes the learn log like the compact
+// command does
+func compactStep(e *Editor) maintenanceJob {
+	if eventsDue(e) {
+		return trimJob(e.prof)
+	}
+	return flushJob(e.prof, defaultPrune, "compacted")
+}
+
+// Drops the oldest events, see EventLog.Trim
+func trimJob(prof *profile) maintenanceJob {
+	return func() func(e *Editor) (string, error) {
+		dropped, err := prof.events.Trim(eventsKeep)
+		return func(e *Editor) (string, error) {
+			if err != nil {
+				return "", fmt.Errorf("trimming the events failed: %v", err)
+			}
+			prof.trainedEvents = max(0, prof.trainedEvents-dropped) // the new events since stay new
+			return fmt.Sprintf("trimmed the events, dropped %d bytes", dropped), nil
+		}
+	}
+}
+
+func snapshotDue(e *Editor) bool {
+	return e.prof.boostsChanged || (e.prof.learnLog != nil && fileSize(e.prof.paths.learnLog) > 0 && time.Since(e.prof.flushed) >= snapshotEvery)
+}
+
+// Saves the ranking adjustments, then flushes the learn log into the snapshots
+// without pruning anything
+func snapshotStep(e *Editor) maintenanceJob {
+	if e.prof.boostsChanged {
+		e.prof.boostsChanged = false
+		if err := e.prof.boosts.Save(e.prof.paths.boosts); err != nil {
+			diagnostics.Addf("saving boosts failed: %v", err)
+		}
+		return nil
+	}
+	e.prof.flushed = time.Now() // not tried again this often either way
+	return flushJob(e.prof, PrunePolicy{}, "flushed")
+}
+
+func flushJob(prof *profile, policy PrunePolicy, done string) maintenanceJob {
+	tombstones := maps.Clone(prof.tombstones) // forget may add to them meanwhile
+	return func() func(e *Editor) (string, error) {
+		report, err := prof.learnLog.Compact(prof.paths.snapshot, prof.paths.phrases, tombstones, policy)
+		return func(e *Editor) (string, error) {
+			prof.flushed = time.Now()
+			if err != nil {
+				return "", fmt.Errorf("%s learned data: %v", done, err)
+			}
+			return done + " learned data: " + report.String(), nil
+		}
+	}
+}
+
+// Only a ranker trained before is trained again, the first one is up to the user
+func retrainDue(e *Editor) bool {
+	return e.prof.ranker != nil && fileSize(e.prof.paths.events)-e.prof.trainedEvents >= retrainEvents
+}
+
+// Trains the ranker on events.log like ranker train does
+func retrainStep(e *Editor) maintenanceJob {
+	prof := e.prof
+	size := fileSize(prof.paths.events)
+	prof.trainedEvents = size // not tried again until as many new events came in
+	return func() func(e *Editor) (string, error) {
+		var r *Ranker
+		events, err := ReadEvents(prof.paths.events)
+		if examples := trainingExamples(events); err == nil && len(examples) > 0 {
+			r = TrainRanker(examples)
+			err = r.Save(prof.paths.ranker)
+		}
+		return func(e *Editor) (string, error) {
+			if err != nil {
+				return "", fmt.Errorf("training the ranker failed: %v", err)
+			}
+			if r == nil {
+				return "no suggestions to train the ranker on", nil
+			}
+			prof.ranker = r
+			return fmt.Sprintf("ranker trained on %d suggestions", r.Examples), nil
+		}
+	}
+}
+
+func warmDue(e *Editor) bool {
+	return e.warm.Due()
+}
+
+// Warms one prefix
+func warmStep(e *Editor) maintenanceJob {
+	e.warm.Step(e.trie, e.prof.score)
+	return nil
+}
+
+// Ranked completions of the short prefixes with the most words, which are the
+// slowest to complete. Cached completions do not take tag rules or experiment
+// arms into account, callers skip the cache for those
+type PrefixCache struct {
+	completions map[string][]string
+	todo        []string // prefixes to look at, the first letters when nil
+	started     bool
+	hits        int // lookups answered from the cache
+	lookups     int
+	changed     int             // words forgotten since the last pre-warm
+	dirty       map[string]bool // prefixes forgotten while a pre-warm runs, nil when none does
+}
+
+func NewPrefixCache() *PrefixCache {
+	return &PrefixCache{completions: make(map[string][]string)}
+}
+
+// Cached completions of prefix, nothing on a nil cache
+func (c *PrefixCache) Get(prefix string) ([]string, bool) {
+	if c == nil {
+		return nil, false
+	}
+	completions, ok := c.completions[prefix]
+	c.lookups++
+	if ok {
+		c.hits++
+	}
+	return completions, ok
+}
+
+// Returns how many lookups the cache answered out of how many, since it was made
+func (c *PrefixCache) Rate() (hits, lookups int) {
+	if c == nil {
+		return 0, 0
+	}
+	return c.hits, c.lookups
+}
+
+// Drops everything ranked with word, which was learned or whose ranking changed.
+// Its prefixes are warmed again on the next pause
+func (c *PrefixCache) Forget(word string) {
+	c.changed++
+	runes := []rune(word)
+	for n := 1; n <= min(warmMaxPrefix, len(runes)); n++ {
+		prefix := string(runes[:n])
+		if c.dirty != nil {
+			c.dirty[prefix] = true
+		}
+		if _, ok := c.completions[prefix]; ok {
+			delete(c.completions, prefix)
+			c.todo = append(c.todo, prefix)
+		}
+	}
+}
+
+// Reports whether so much was learned since the last pre-warm that warming one
+// prefix per pause would leave the short prefixes cold for long. Eg:- after
+// pasting a document
+func (c *PrefixCache) Burst() bool {
+	return c.changed >= warmBurst && c.dirty == nil
+}
+
+func (c *PrefixCache) Due() bool {
+	return !c.started || len(c.todo) > 0
+}
+
+// Looks at the next prefix: caches its completions when it has many, and queues
+// the longer prefixes below it
+func (c *PrefixCache) Step(t *trie.Stack, score trie.Scorer) {
+	if !c.started {
+		c.started = true
+		for r := range t.Children() {
+			c.todo = append(c.todo, string(r))
+		}
+		return
+	}
+	prefix := c.todo[0]
+	c.todo = c.todo[1:]
+	node := t.Node(prefix)
+	if node == nil || node.Stats().Words < warmMinWords {
+		return
+	}
+	c.completions[prefix] = t.AutofillTop(prefix, maxCompletion, score)
+	if len([]rune(prefix)) < warmMaxPrefix {
+		for r := range node.Children() {
+			if _, ok := c.completions[prefix+string(r)]; !ok {
+				c.todo = append(c.todo, prefix+string(r))
+			}
+		}
+	}
+}
+
+// Warms every prefix of up to warmMaxPrefix letters with many words at once, in
+// the background on a copy of t. The dictionary layer is shared, it never
+// changes. What is forgotten meanwhile is warmed again one prefix at a time
+func (c *PrefixCache) prewarm(t *trie.Stack, score trie.Scorer) maintenanceJob {
+	c.changed, c.dirty = 0, make(map[string]bool)
+	frozen := t.Clone(userLayer)
+	return func() func(e *Editor) (string, error) {
+		warmed := make(map[string][]string)
+		var walk func(node *trie.Stack, prefix string)
+		walk = func(node *trie.Stack, prefix string) {
+			for r, child := range node.Children() {
+				if child.Stats().Words < warmMinWords {
+					continue // and so do the longer prefixes below
+				}
+				warmed[prefix+string(r)] = frozen.AutofillTop(prefix+string(r), maxCompletion, score)
+				if len([]rune(prefix)) < warmMaxPrefix-1 {
+					walk(child, prefix+string(r))
+				}
+			}
+		}
+		walk(frozen, "")
+		return func(e *Editor) (string, error) {
+			if e.warm != c {
+				return "pre-warmed prefixes dropped, the config was reloaded", nil
+			}
+			for prefix, completions := range warmed {
+				if !c.dirty[prefix] {
+					c.completions[prefix] = completions
+				}
+			}
+			for prefix := range c.dirty {
+				if _, ok := warmed[prefix]; ok && !slices.Contains(c.todo, prefix) {
+					c.todo = append(c.todo, prefix)
+				}
+			}
+			c.dirty, c.started = nil, true
+			c.todo = slices.DeleteFunc(c.todo, func(prefix string) bool {
+				_, ok := c.completions[prefix]
+				return ok
+			})
+			return fmt.Sprintf("pre-warmed %d prefixes", len(warmed)), nil
+		}
+	}
+}
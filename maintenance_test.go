@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Idle upkeep warms the prefixes with many completions, and what is learned
+// afterwards is not answered from the cache
+func TestMaintenanceWarm(t *testing.T) {
+	h := newHarness(t, t.TempDir())
+	for i := 0; i < warmMinWords; i++ {
+		h.e.trie.Insert(baseLayer, fmt.Sprintf("ha%03d", i))
+	}
+	h.e.cfg.Maintenance = MaintenanceConfig{Idle: time.Nanosecond, Tasks: []string{"warm"}}
+	s := NewScheduler(time.Hour)
+	for steps := 0; h.e.warm.Due(); steps++ {
+		if steps > 1000 {
+			t.Fatal("warming never finished")
+		}
+		s.Run(h.e)
+	}
+	for _, prefix := range []string{"h", "ha"} {
+		if got, ok := h.e.warm.Get(prefix); !ok || !slices.Equal(got, h.e.trie.AutofillTop(prefix, maxCompletion, h.e.prof.score)) {
+			t.Fatalf("%s not warmed", prefix)
+		}
+	}
+	if _, ok := h.e.warm.Get("w"); ok {
+		t.Fatal("w has too few words to be warmed")
+	}
+
+	h.feed([]byte("ha123 ha123 "))
+	if _, ok := h.e.warm.Get("ha"); ok || !h.e.warm.Due() {
+		t.Fatal("learning did not drop the cached completions")
+	}
+	h.feed([]byte("ha"))
+	h.pause()
+	if h.e.suggestions[0].word != "ha123" {
+		t.Fatalf("suggested %q first", h.e.suggestions[0].word)
+	}
+}
+
+// Pre-warming caches the short prefixes in one go in the background, except
+// those learned meanwhile, and runs again after a burst of learning
+func TestMaintenancePrewarm(t *testing.T) {
+	h := newHarness(t, t.TempDir())
+	for i := 0; i < warmMinWords; i++ {
+		h.e.trie.Insert(baseLayer, fmt.Sprintf("ha%03d", i))
+		h.e.trie.Insert(baseLayer, fmt.Sprintf("wo%03d", i))
+	}
+	s := NewScheduler(time.Hour)
+	s.Prewarm(h.e)
+	h.feed([]byte("wo123 ")) // while it runs
+	if _, err := s.Finish(h.e, <-s.done); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := h.e.warm.Get("ha"); !ok || !slices.Equal(got, h.e.trie.AutofillTop("ha", maxCompletion, h.e.prof.score)) {
+		t.Fatal("ha not pre-warmed")
+	}
+	if _, ok := h.e.warm.Get("wo"); ok || !h.e.warm.Due() {
+		t.Fatal("wo was pre-warmed before learning wo123")
+	}
+	if _, ok := h.e.warm.Get("g"); ok {
+		t.Fatal("g has too few words to be warmed")
+	}
+
+	for i := 0; i < warmBurst; i++ {
+		h.feed([]byte("ha001 "))
+	}
+	if !h.e.warm.Burst() {
+		t.Fatal("learning many words is no burst")
+	}
+	s.Prewarm(h.e)
+	if h.e.warm.Burst() {
+		t.Fatal("pre-warming did not start")
+	}
+	s.Finish(h.e, <-s.done)
+	if got, _ := h.e.warm.Get("ha"); len(got) == 0 || got[0] != "001" {
+		t.Fatalf("pre-warmed ha to %q first", got[:min(len(got), 1)])
+	}
+}
+
+// Past size_guard.warn the status line warns once, past size_guard.prune the
+// learned data is pruned with its policy
+func TestMaintenanceGuard(t *testing.T) {
+	h := newHarness(t, t.TempDir())
+	log, err := OpenLearnLog(h.e.prof.paths.learnLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+	h.e.prof.learnLog = log
+	var b strings.Builder
+	for i := 0; b.Len() <= 1<<20; i++ {
+		fmt.Fprintf(&b, "rare%06d\t1\t0\n", i)
+	}
+	b.WriteString("hello\t5\t0\n")
+	os.WriteFile(h.e.prof.paths.snapshot, []byte(b.String()), 0644)
+	h.e.cfg.Maintenance = MaintenanceConfig{Idle: time.Nanosecond, Tasks: []string{"guard"}}
+	h.e.cfg.SizeGuard = SizeGuardConfig{Warn: 1, MinCount: 3, MaxAge: 90}
+	s := NewScheduler(time.Hour)
+
+	diagnostics.Unseen()
+	if s.Run(h.e); s.busy || !h.e.prof.sizeWarned {
+		t.Fatal("no warning past size_guard.warn")
+	}
+	if problems := diagnostics.Unseen(); len(problems) != 1 || !strings.Contains(problems[0], "past size_guard.warn of 1MB") {
+		t.Fatalf("warned %q", problems)
+	}
+	if s.Run(h.e); s.busy || len(diagnostics.Unseen()) > 0 {
+		t.Fatal("warned again, or pruned without size_guard.prune")
+	}
+
+	h.e.cfg.SizeGuard.Prune = 1
+	if s.Run(h.e); !s.busy {
+		t.Fatal("not pruned past size_guard.prune")
+	}
+	if report, err := s.Finish(h.e, <-s.done); err != nil || !strings.HasPrefix(report, "pruned") {
+		t.Fatalf("pruning reported %q, %v", report, err)
+	}
+	counts, err := LoadSnapshot(h.e.prof.paths.snapshot)
+	if err != nil || len(counts) != 1 || counts["hello"].count != 5 {
+		t.Fatalf("%d words left, %v", len(counts), err)
+	}
+	if guardDue(h.e) {
+		t.Fatal("guard due again right after pruning")
+	}
+}
+
+// Changed boosts are saved and the learn log flushed once the user is idle
+func TestMaintenanceSnapshot(t *testing.T) {
+	h := newHarness(t, t.TempDir())
+	log, err := OpenLearnLog(h.e.prof.paths.learnLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+	h.e.prof.learnLog = log
+	h.e.prof.flushed = time.Now()
+	h.e.cfg.Maintenance = MaintenanceConfig{Idle: time.Nanosecond, Tasks: []string{"snapshot"}}
+	s := NewScheduler(time.Hour)
+
+	h.e.prof.boosts.Feedback("help", "hello")
+	h.e.prof.boostsChanged = true
+	s.Run(h.e)
+	if _, err := os.Stat(h.e.prof.paths.boosts); err != nil || h.e.prof.boostsChanged {
+		t.Fatalf("boosts not saved: %v", err)
+	}
+
+	h.feed([]byte("hello world "))
+	if s.Run(h.e); s.busy {
+		t.Fatal("flushed before snapshotEvery passed")
+	}
+	h.e.prof.flushed = time.Now().Add(-snapshotEvery)
+	if s.Run(h.e); !s.busy {
+		t.Fatal("learn log not flushed")
+	}
+	if report, err := s.Finish(h.e, <-s.done); err != nil || !strings.HasPrefix(report, "flushed") {
+		t.Fatalf("flush reported %q, %v", report, err)
+	}
+	counts, err := LoadSnapshot(h.e.prof.paths.snapshot)
+	if err != nil || counts["world"].count != 1 || fileSize(h.e.prof.paths.learnLog) != 0 {
+		t.Fatalf("snapshot %v, %v", counts, err)
+	}
+
+	// A flush which only gets the log once the profile closed it does nothing
+	h.feed([]byte("again "))
+	job := flushJob(h.e.prof, PrunePolicy{}, "flushed")
+	log.Close()
+	if _, err := job()(h.e); err == nil || fileSize(h.e.prof.paths.learnLog) == 0 {
+		t.Errorf("flushed a closed learn log: %v", err)
+	}
+}
+
+func TestMaintenanceTrimEvents(t *testing.T) {
+	h := newHarness(t, t.TempDir())
+	l, err := OpenEventLog(h.e.prof.paths.events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	h.e.prof.events = l
+	h.e.cfg.Maintenance = MaintenanceConfig{Idle: time.Nanosecond, Tasks: []string{"compact"}}
+	s := NewScheduler(time.Hour)
+
+	line := strings.Repeat("x", 1023) + "\n"
+	if err := os.WriteFile(h.e.prof.paths.events, []byte(strings.Repeat(line, eventsLogSize/len(line)+1)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	h.e.prof.trainedEvents = fileSize(h.e.prof.paths.events) - 10
+	if s.Run(h.e); !s.busy {
+		t.Fatal("events not trimmed")
+	}
+	if report, err := s.Finish(h.e, <-s.done); err != nil || !strings.HasPrefix(report, "trimmed") {
+		t.Fatalf("trimming reported %q, %v", report, err)
+	}
+	if size := fileSize(h.e.prof.paths.events); size > eventsKeep || h.e.prof.trainedEvents != size-10 {
+		t.Errorf("%d bytes of events left, trained on %d", size, h.e.prof.trainedEvents)
+	}
+	if s.Run(h.e); s.busy {
+		t.Error("trimmed again")
+	}
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Checksums of the files in a directory, written next to them
+// Eg:- {"files": {"words.txt": {"sha256": "9f86d0..."}}}
+// A detached ed25519 signature of the manifest may be stored in manifest.json.sig
+const manifestFile = "manifest.json"
+
+type Manifest struct {
+	Files map[string]ManifestEntry `json:"files"`
+}
+
+type ManifestEntry struct {
+	SHA256 string `json:"sha256"`
+}
+
+// Checks dictionaries against the manifest of their directory before they are loaded
+type Verifier struct {
+	mode      string // "warn" loads mismatching files anyway, "strict" refuses them, "off" skips checks
+	keys      []ed25519.PublicKey
+	manifests map[string]*Manifest // by directory, nil when there is none
+	problems  []string             // for the status line
+}
+
+func NewVerifier(cfg Config) *Verifier {
+	v := &Verifier{mode: cfg.Verify, manifests: make(map[string]*Manifest)}
+	for _, key := range cfg.TrustedKeys {
+		// validate() made sure the keys decode
+		raw, _ := base64.StdEncoding.DecodeString(key)
+		v.keys = append(v.keys, ed25519.PublicKey(raw))
+	}
+	return v
+}
+
+// Reports whether the file at path may be loaded, mismatches are recorded as
+// problems. Files without a manifest entry are accepted unless strict mode or
+// trusted keys need every file listed in a signed manifest
+func (v *Verifier) Allow(path string) bool {
+	if v == nil || v.mode == "off" {
+		return true
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return true // nothing to load, the caller reports it
+	}
+
+	_, err := v.check(path)
+	if err == nil {
+		return true
+	}
+	if v.strict() {
+		v.problems = append(v.problems, fmt.Sprintf("refused %s: %v", path, err))
+		return false
+	}
+	v.problems = append(v.problems, fmt.Sprintf("%s: %v", path, err))
+	return true
+}
+
+// Whether files which cannot be verified are refused
+func (v *Verifier) strict() bool {
+	return v.mode == "strict" || len(v.keys) > 0
+}
+
+// Checks path against its manifest. listed is false when the manifest has no
+// entry for it, which is an error when v is strict
+func (v *Verifier) check(path string) (listed bool, err error) {
+	m, err := v.manifest(filepath.Dir(path))
+	if err != nil {
+		return false, err
+	}
+	if m == nil {
+		if v.strict() {
+			return false, fmt.Errorf("no %s", manifestFile)
+		}
+		return false, nil
+	}
+	entry, ok := m.Files[filepath.Base(path)]
+	if !ok {
+		if v.strict() {
+			return false, fmt.Errorf("not in %s", manifestFile)
+		}
+		return false, nil
+	}
+	sum, err := fileSHA256(path)
+	if err == nil && sum != entry.SHA256 {
+		err = fmt.Errorf("checksum mismatch")
+	}
+	return true, err
+}
+
+// Problems found so far, joined for the status line
+func (v *Verifier) Problems() string {
+	return strings.Join(v.problems, "; ")
+}
+
+func (v *Verifier) manifest(dir string) (*Manifest, error) {
+	if m, ok := v.manifests[dir]; ok {
+		return m, nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, manifestFile))
+	if os.IsNotExist(err) {
+		v.manifests[dir] = nil
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := v.checkSignature(dir, data); err != nil {
+		return nil, err
+	}
+
+	m := new(Manifest)
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	v.manifests[dir] = m
+	return m, nil
+}
+
+// With trusted keys configured the manifest must be signed by one of them
+func (v *Verifier) checkSignature(dir string, manifest []byte) error {
+	if len(v.keys) == 0 {
+		return nil
+	}
+	sig, err := os.ReadFile(filepath.Join(dir, manifestFile+".sig"))
+	if err != nil {
+		return fmt.Errorf("manifest is not signed")
+	}
+	// raw 64 byte signature or its base64 encoding
+	if len(sig) != ed25519.SignatureSize {
+		if sig, err = base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig))); err != nil {
+			return fmt.Errorf("unreadable manifest signature")
+		}
+	}
+	for _, key := range v.keys {
+		if ed25519.Verify(key, manifest, sig) {
+			return nil
+		}
+	}
+	return fmt.Errorf("manifest signature does not match any trusted key")
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Records the checksums of files in the manifest of their directory
+func WriteManifest(files []string) error {
+	byDir := make(map[string][]string)
+	for _, file := range files {
+		byDir[filepath.Dir(file)] = append(byDir[filepath.Dir(file)], file)
+	}
+
+	for dir, files := range byDir {
+		path := filepath.Join(dir, manifestFile)
+		m := &Manifest{Files: make(map[string]ManifestEntry)}
+		if data, err := os.ReadFile(path); err == nil {
+			if err := json.Unmarshal(data, m); err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+		}
+		for _, file := range files {
+			sum, err := fileSHA256(file)
+			if err != nil {
+				return err
+			}
+			m.Files[filepath.Base(file)] = ManifestEntry{SHA256: sum}
+		}
+
+		data, err := json.MarshalIndent(m, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// The dictionaries named by the config which exist
+func (cfg Config) dictionaryFiles() []string {
+	var files []string
+	named := []string{cfg.Dictionary, cfg.Definitions}
+	for _, spec := range cfg.dictSpecs("") {
+		named = append(named, spec.path)
+	}
+	for _, path := range named {
+		if _, err := os.Stat(path); err == nil {
+			files = append(files, path)
+		}
+	}
+	translations, _ := filepath.Glob(cfg.Translations)
+	return append(files, translations...)
+}
+
+// autocomplete manifest [file...]
+// Records checksums for the given files, or for the configured dictionaries
+func manifestCommand(args []string) error {
+	files := args
+	if len(files) == 0 {
+		cfg, _ := LoadConfig(configPath())
+		files = cfg.dictionaryFiles()
+	}
+	return WriteManifest(files)
+}
+
+// autocomplete verify [file...]
+// Checks the given files, or the configured dictionaries, against their manifests
+func verifyCommand(args []string) error {
+	cfg, _ := LoadConfig(configPath())
+	files := args
+	if len(files) == 0 {
+		files = cfg.dictionaryFiles()
+	}
+
+	v := NewVerifier(cfg)
+	var failed int
+	for _, file := range files {
+		listed, err := v.check(file)
+		switch {
+		case err != nil:
+			fmt.Printf("FAILED %s: %v\n", file, err)
+			failed++
+		case listed:
+			fmt.Println("ok", file)
+		default:
+			fmt.Println("unverified", file)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files failed verification", failed, len(files))
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Strict mode and trusted keys refuse files no signed manifest lists
+func TestVerifier(t *testing.T) {
+	dir := t.TempDir()
+	words, extra := filepath.Join(dir, "words.txt"), filepath.Join(dir, "extra.txt")
+	os.WriteFile(words, []byte("hello\n"), 0644)
+	os.WriteFile(extra, []byte("world\n"), 0644)
+	strict := defaultConfig()
+	strict.Verify = "strict"
+	if !NewVerifier(defaultConfig()).Allow(words) || NewVerifier(strict).Allow(words) {
+		t.Error("strict mode accepted a directory without a manifest")
+	}
+	if !NewVerifier(strict).Allow(filepath.Join(dir, "missing.txt")) {
+		t.Error("refused a missing file")
+	}
+
+	if err := WriteManifest([]string{words}); err != nil {
+		t.Fatal(err)
+	}
+	if v := NewVerifier(strict); !v.Allow(words) || v.Allow(extra) {
+		t.Errorf("strict mode: %s", v.Problems())
+	}
+	if !NewVerifier(defaultConfig()).Allow(extra) {
+		t.Error("warn mode refused a file missing from the manifest")
+	}
+
+	public, private, _ := ed25519.GenerateKey(nil)
+	signed := defaultConfig()
+	signed.TrustedKeys = []string{base64.StdEncoding.EncodeToString(public)}
+	if NewVerifier(signed).Allow(words) {
+		t.Error("accepted an unsigned manifest")
+	}
+	manifest, _ := os.ReadFile(filepath.Join(dir, manifestFile))
+	os.WriteFile(filepath.Join(dir, manifestFile+".sig"), ed25519.Sign(private, manifest), 0644)
+	if v := NewVerifier(signed); !v.Allow(words) || v.Allow(extra) {
+		t.Errorf("trusted keys: %s", v.Problems())
+	}
+	os.Remove(filepath.Join(dir, manifestFile))
+	if NewVerifier(signed).Allow(words) {
+		t.Error("accepted a file once the manifest was deleted")
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// While the menu is open the status line lists the suggestions and typing
+// narrows them down, instead of looking the word up again
+const (
+	menuSeparator = "  "
+	menuCurrent   = "\033[7m" // reverse video for the highlighted suggestion
+	menuMatch     = "\033[4m" // underline for the part matching the typed word
+)
+
+// Returns the rune index of filter in word ignoring case, -1 when it is not there
+func matchIndex(word, filter string) int {
+	w, f := []rune(strings.ToLower(word)), []rune(strings.ToLower(filter))
+	if len(w) != len([]rune(word)) {
+		return -1 // lowering changed the length, positions would not line up
+	}
+	for i := 0; i+len(f) <= len(w); i++ {
+		if string(w[i:i+len(f)]) == string(f) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Keeps the candidates containing filter, in their order
+func filterCandidates(candidates []Candidate, filter string) []Candidate {
+	var result []Candidate
+	for _, c := range candidates {
+		if matchIndex(c.word, filter) >= 0 {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// Status line listing candidates with the current one highlighted and the
+// matched part of each underlined. With section set, each section starts with
+// its name. Eg:- Learned: hello  help  Emoji: 👋
+func menuStatus(candidates []Candidate, current int, filter string, section func(Candidate) string) string {
+	var b strings.Builder
+	for i, c := range candidates {
+		if i > 0 {
+			b.WriteString(menuSeparator)
+		}
+		if section != nil && (i == 0 || section(c) != section(candidates[i-1])) {
+			b.WriteString("\033[1m" + section(c) + ":\033[22m ")
+		}
+		if i == current {
+			b.WriteString(menuCurrent)
+		}
+		word := []rune(c.word)
+		if at := matchIndex(c.word, filter); at >= 0 && filter != "" {
+			end := at + len([]rune(filter))
+			b.WriteString(string(word[:at]) + menuMatch + string(word[at:end]) + "\033[24m" + string(word[end:]))
+		} else {
+			b.WriteString(c.word)
+		}
+		if i == current {
+			b.WriteString("\033[27m")
+		}
+	}
+	return b.String()
+}
+
+// Sections the grouped menu lists first, in this order, then those of the
+// plugins (named after them) and everything else in the order they come up
+var menuSections = []string{"Learned", "Dictionary", "Snippets"}
+
+type MenuConfig struct {
+	Group  bool           `toml:"group"`  // list the menu in sections by where the suggestions come from
+	Limit  int            `toml:"limit"`  // suggestions per section, 0 for all of them
+	Limits map[string]int `toml:"limits"` // per section, overriding limit. Eg:- Emoji = 3
+}
+
+// The section of the grouped menu c is listed in. Words of the trie count as
+// learned once used with p
+func (p *profile) menuSection(c Candidate) string {
+	switch source, _, _ := strings.Cut(c.source, ":"); source {
+	case "trie", "lead", "hump", "fuzzy", "t9", "model", "next":
+		if !p.lastUsed[c.word].IsZero() {
+			return "Learned"
+		}
+		return "Dictionary"
+	case "token", "temporary", "typo", "pin", "file":
+		return "Learned"
+	case "snippet":
+		return "Snippets"
+	case "translation":
+		return "Translations"
+	case "pack":
+		return "Packs"
+	case "team":
+		return "Team"
+	case "plugin":
+		name := []rune(strings.TrimPrefix(c.source, "plugin:"))
+		if len(name) > 0 {
+			name[0] = unicode.ToUpper(name[0])
+		}
+		return string(name)
+	}
+	return "Other"
+}
+
+// Orders candidates by section, keeping their order within each, and drops
+// those beyond the limit of their section
+func (m MenuConfig) group(candidates []Candidate, menuSection func(Candidate) string) []Candidate {
+	order := slices.Clone(menuSections)
+	bySection := make(map[string][]Candidate)
+	for _, c := range candidates {
+		section := menuSection(c)
+		if !slices.Contains(order, section) {
+			order = append(order, section)
+		}
+		limit, ok := m.Limits[section]
+		if !ok {
+			limit = m.Limit
+		}
+		if limit <= 0 || len(bySection[section]) < limit {
+			bySection[section] = append(bySection[section], c)
+		}
+	}
+	var result []Candidate
+	for _, section := range order {
+		result = append(result, bySection[section]...)
+	}
+	return result
+}
+
+// Orders of the suggestions listed by show_suggestions
+const (
+	orderRank         = "rank"         // best first, the order TAB cycles through
+	orderAlphabetical = "alphabetical" // easier to scan
+	orderLength       = "length"       // shortest first
+)
+
+const listSeparator = " | "
+
+// Status line listing up to n of the candidates, the page holding the current
+// one, in the given order with the current one highlighted. Eg:- hello | [help] | helmet
+func listStatus(candidates []Candidate, current, n int, order string) string {
+	start := current / n * n
+	page := slices.Clone(candidates[start:min(len(candidates), start+n)])
+	highlighted := candidates[current].word
+	switch order {
+	case orderAlphabetical:
+		slices.SortStableFunc(page, func(a, b Candidate) int { return strings.Compare(a.word, b.word) })
+	case orderLength:
+		slices.SortStableFunc(page, func(a, b Candidate) int { return len([]rune(a.word)) - len([]rune(b.word)) })
+	}
+
+	var b strings.Builder
+	for i, c := range page {
+		if i > 0 {
+			b.WriteString(listSeparator)
+		}
+		if c.word == highlighted {
+			b.WriteString(menuCurrent + c.word + "\033[27m")
+		} else {
+			b.WriteString(c.word)
+		}
+	}
+	if len(candidates) > n {
+		b.WriteString(listSeparator + "…")
+	}
+	return b.String()
+}
+
+// Keys which narrow down the open menu
+func isFilterKey(key rune) bool {
+	return key > ' ' && key != DELETE && !unicode.IsControl(key)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// show_suggestions lists the page holding the current suggestion in the status line
+func TestListStatus(t *testing.T) {
+	var candidates []Candidate
+	for _, w := range []string{"helmet", "help", "hello", "helium", "he"} {
+		candidates = append(candidates, Candidate{word: w})
+	}
+	for _, c := range []struct {
+		current int
+		order   string
+		want    string
+	}{
+		{1, orderRank, "helmet | \033[7mhelp\033[27m | hello | …"},
+		{0, orderAlphabetical, "hello | \033[7mhelmet\033[27m | help | …"},
+		{4, orderLength, "\033[7mhe\033[27m | helium | …"},
+	} {
+		if got := listStatus(candidates, c.current, 3, c.order); got != c.want {
+			t.Errorf("listStatus(%d, %s) = %q, want %q", c.current, c.order, got, c.want)
+		}
+	}
+}
+
+// menu.group lists the menu in sections, each up to its limit
+func TestMenuGroups(t *testing.T) {
+	h := newHarness(t, t.TempDir())
+	h.e.cfg.Menu = MenuConfig{Group: true, Limits: map[string]int{"Dictionary": 1}}
+	h.e.prof.lastUsed["helmet"] = time.Now()
+	h.feed([]byte("hel"))
+	h.pause()
+	h.feed([]byte{CTRL_O})
+	var words []string
+	for _, c := range h.e.menu {
+		words = append(words, h.e.prof.menuSection(c)+":"+c.word)
+	}
+	if len(words) != 2 || words[0] != "Learned:helmet" || !strings.HasPrefix(words[1], "Dictionary:") {
+		t.Fatalf("menu %v", words)
+	}
+	status := menuStatus(h.e.menu, 0, "hel", h.e.prof.menuSection)
+	if !strings.HasPrefix(status, "\033[1mLearned:\033[22m ") || !strings.Contains(status, "\033[1mDictionary:\033[22m ") {
+		t.Fatalf("status %q", status)
+	}
+	if got := h.e.prof.menuSection(Candidate{word: "😄", source: "plugin:emoji"}); got != "Emoji" {
+		t.Fatalf("plugin section %q", got)
+	}
+}
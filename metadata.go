@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Dictionary lines of a word followed by TAB separated key=value pairs describe the
+// word, every other line is a plain list of words
+// Eg:- dog	pos=noun	tags=animal,pet	source=wordnet
+type WordInfo struct {
+	pos    string   // part of speech
+	tags   []string // categories
+	source string   // where the entry comes from
+}
+
+// Reports whether the word is the part of speech or carries the tag
+func (w *WordInfo) Has(tag string) bool {
+	return w.pos == tag || slices.Contains(w.tags, tag)
+}
+
+// Maps a dictionary word to its metadata
+type Metadata map[string]*WordInfo
+
+// Splits a dictionary into its words and the metadata of the annotated ones
+func parseDictionary(data string) ([]string, Metadata) {
+	var words []string
+	meta := make(Metadata)
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Split(strings.TrimSpace(line), "\t")
+		if len(fields) < 2 || !isMetadata(fields[1:]) {
+			words = append(words, strings.Fields(line)...)
+			continue
+		}
+
+		word, info := fields[0], &WordInfo{}
+		for _, field := range fields[1:] {
+			key, value, _ := strings.Cut(strings.TrimSpace(field), "=")
+			switch key {
+			case "pos":
+				info.pos = value
+			case "tags":
+				info.tags = strings.Split(value, ",")
+			case "source":
+				info.source = value
+			}
+		}
+		words = append(words, word)
+		meta[word] = info
+	}
+	return words, meta
+}
+
+// Reports whether all fields are key=value pairs rather than more words
+func isMetadata(fields []string) bool {
+	for _, field := range fields {
+		if !strings.Contains(field, "=") {
+			return false
+		}
+	}
+	return true
+}
+
+// Loads the metadata of the annotated words in the dictionary at path
+func LoadMetadata(path string, v *Verifier) Metadata {
+	data, err := os.ReadFile(path)
+	if err != nil || !v.Allow(path) {
+		return make(Metadata)
+	}
+	text := string(data)
+	if isCompiled(data) {
+		text, _, _ = splitCompiled(data) // only the metadata lines
+	}
+	_, meta := parseDictionary(text)
+	return meta
+}
+
+// Short description for the status line, Eg:- "dog: noun; animal, pet (wordnet)".
+// Empty when word has no metadata
+func (m Metadata) Status(word string) string {
+	info := m[word]
+	if info == nil {
+		return ""
+	}
+	var parts []string
+	if info.pos != "" {
+		parts = append(parts, info.pos)
+	}
+	if len(info.tags) > 0 {
+		parts = append(parts, strings.Join(info.tags, ", "))
+	}
+	status := word + ": " + strings.Join(parts, "; ")
+	if info.source != "" {
+		status += " (" + info.source + ")"
+	}
+	return status
+}
+
+// Changes the ranking of words carrying a tag, optionally only after certain words
+//
+//	[[tags]]
+//	after = ["the", "a", "an"]
+//	tag = "noun"
+//	boost = 2
+type TagRule struct {
+	After []string `toml:"after"` // previous words the rule applies after, always when empty
+	Tag   string   `toml:"tag"`   // part of speech or tag
+	Boost float64  `toml:"boost"` // score multiplier, 0 hides the words
+}
+
+func (r TagRule) validate() error {
+	if r.Tag == "" {
+		return fmt.Errorf("tags: every rule needs a tag")
+	}
+	if r.Boost < 0 {
+		return fmt.Errorf("tags: boost of %q must not be negative", r.Tag)
+	}
+	return nil
+}
+
+// Tag rules together with the metadata they look at
+type TagRanking struct {
+	meta  Metadata
+	rules []TagRule
+}
+
+// Multiplier applied to the score of word typed after the previous words
+func (t TagRanking) Factor(previous []string, word string) float64 {
+	info := t.meta[word]
+	if info == nil {
+		return 1
+	}
+	var last string
+	if len(previous) > 0 {
+		last = strings.ToLower(previous[len(previous)-1])
+	}
+
+	factor := 1.0
+	for _, r := range t.rules {
+		if info.Has(r.Tag) && (len(r.After) == 0 || slices.Contains(r.After, last)) {
+			factor *= r.Boost
+		}
+	}
+	return factor
+}
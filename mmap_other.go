@@ -0,0 +1,13 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// Reads the file at path, there is no memory mapping on this platform
+func mapFile(path string) ([]byte, error) {
+	return os.ReadFile(path)
+}
+
+// Nothing to unmap, the data is on the heap
+func unmapFile(data []byte) {}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	modelFile      = "model.json"
+	modelFormat    = "autocomplete-cli/kneser-ney"
+	modelVersion   = 1    // bumped when the file layout changes
+	modelOrder     = 6    // characters of context plus the predicted one
+	modelDiscount  = 0.75 // absolute discount of Kneser-Ney smoothing
+	modelMaxLength = 24   // longest completion searched for
+	modelMaxSteps  = 2000 // expansions per search
+	maxPredicted   = 3    // completions offered by the model
+)
+
+// Character-level n-gram model with interpolated Kneser-Ney smoothing. It sees
+// the text before the word being typed, so the same prefix can complete
+// differently depending on the previous words
+type Model struct {
+	Format   string                    `json:"format"`
+	Version  int                       `json:"version"`
+	Order    int                       `json:"order"`
+	Discount float64                   `json:"discount"`
+	Trained  time.Time                 `json:"trained"`
+	Runes    int                       `json:"runes"` // size of the training text
+	Contexts map[string]map[string]int `json:"contexts"`
+	// Contexts maps the preceding characters to the counts of the next one. For
+	// the longest contexts those are plain counts, the shorter ones hold
+	// continuation counts: in how many different longer contexts it followed
+
+	totals map[string]int // sum of the counts of each context
+}
+
+// Normalizes text into the words the model sees, separated by single spaces
+func modelText(text string) []rune {
+	words := strings.FieldsFunc(text, unicode.IsSpace)
+	if len(words) == 0 {
+		return []rune{' '}
+	}
+	return []rune(" " + strings.Join(words, " ") + " ")
+}
+
+// Trains a model of the given order on the text read from r, as it reads it:
+// only the last order characters are held, so a corpus of any size fits. The
+// text is seen as modelText would normalize it
+func TrainModel(r io.Reader, order int) (*Model, error) {
+	m := &Model{
+		Format:   modelFormat,
+		Version:  modelVersion,
+		Order:    order,
+		Discount: modelDiscount,
+		Trained:  time.Now(),
+		Contexts: make(map[string]map[string]int),
+	}
+	add := func(ctx string, next string) bool {
+		if m.Contexts[ctx] == nil {
+			m.Contexts[ctx] = make(map[string]int)
+		}
+		m.Contexts[ctx][next]++
+		return m.Contexts[ctx][next] == 1
+	}
+	window := make([]rune, 0, order) // the last characters seen, the newest last
+	see := func(c rune) {
+		m.Runes++
+		if len(window) == order {
+			copy(window, window[1:])
+			window = window[:order-1]
+		}
+		window = append(window, c)
+		// Positions without a full context are skipped, so the shorter contexts
+		// only ever hold continuation counts
+		if len(window) < order {
+			return
+		}
+		next := string(c)
+		// A longer context seen with next for the first time continues the
+		// next shorter one once more
+		for k := 0; k < order; k++ {
+			if !add(string(window[k:order-1]), next) {
+				break
+			}
+		}
+	}
+
+	see(' ')
+	in := bufio.NewReader(r)
+	space := false // between words
+	for {
+		c, _, err := in.ReadRune()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if unicode.IsSpace(c) {
+			space = true
+			continue
+		}
+		if space && window[len(window)-1] != ' ' {
+			see(' ')
+		}
+		space = false
+		see(c)
+	}
+	if window[len(window)-1] != ' ' {
+		see(' ')
+	}
+	m.index()
+	return m, nil
+}
+
+func (m *Model) index() {
+	m.totals = make(map[string]int, len(m.Contexts))
+	for ctx, next := range m.Contexts {
+		for _, n := range next {
+			m.totals[ctx] += n
+		}
+	}
+}
+
+// Probability of next following ctx, interpolated from the longest context down
+func (m *Model) Prob(ctx []rune, next string) float64 {
+	if len(ctx) > m.Order-1 {
+		ctx = ctx[len(ctx)-(m.Order-1):]
+	}
+	// The shortest context falls back to a uniform distribution over the characters seen
+	p := 1 / float64(max(1, len(m.Contexts[""])))
+	for k := 0; k <= len(ctx); k++ {
+		h := string(ctx[len(ctx)-k:])
+		total := m.totals[h]
+		if total == 0 {
+			break // no longer context was seen either
+		}
+		reserved := m.Discount * float64(len(m.Contexts[h])) / float64(total)
+		p = math.Max(float64(m.Contexts[h][next])-m.Discount, 0)/float64(total) + reserved*p
+	}
+	return p
+}
+
+// Partial completion explored by Complete
+type completion struct {
+	suffix []rune
+	logp   float64
+}
+
+type completions []completion
+
+func (c completions) Len() int           { return len(c) }
+func (c completions) Less(i, j int) bool { return c[i].logp > c[j].logp }
+func (c completions) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+func (c *completions) Push(x any)        { *c = append(*c, x.(completion)) }
+func (c *completions) Pop() any {
+	old := *c
+	last := old[len(old)-1]
+	*c = old[:len(old)-1]
+	return last
+}
+
+// Returns up to n of the most likely words starting with prefix when typed
+// after before, most likely first
+func (m *Model) Complete(before, prefix string, n int) []string {
+	ctx := append(modelText(before), []rune(prefix)...)
+
+	var result []string
+	queue := &completions{{}}
+	for steps := 0; queue.Len() > 0 && len(result) < n && steps < modelMaxSteps; steps++ {
+		c := heap.Pop(queue).(completion)
+		if len(c.suffix) > 0 && c.suffix[len(c.suffix)-1] == ' ' {
+			if len(c.suffix) > 1 {
+				result = append(result, prefix+string(c.suffix[:len(c.suffix)-1]))
+			}
+			continue
+		}
+		if len(c.suffix) >= modelMaxLength {
+			continue
+		}
+		history := append(append([]rune{}, ctx...), c.suffix...)
+		for next := range m.Contexts[""] {
+			suffix := append(append([]rune{}, c.suffix...), []rune(next)...)
+			heap.Push(queue, completion{suffix, c.logp + math.Log(m.Prob(history, next))})
+		}
+	}
+	return result
+}
+
+// Model suggestions for the word being typed after the previous words
+func (m *Model) Candidates(previous []string, word string) []Candidate {
+	var result []Candidate
+	if m == nil || word == "" {
+		return result
+	}
+	for _, w := range m.Complete(strings.Join(previous, " "), word, maxPredicted) {
+		result = append(result, Candidate{word: w, source: "model"})
+	}
+	return result
+}
+
+// Loads a model file. Returns nil without error when there is none
+func LoadModel(path string) (*Model, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var m Model
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if m.Format != modelFormat {
+		return nil, fmt.Errorf("%s: not a model file", path)
+	}
+	if m.Version > modelVersion {
+		return nil, fmt.Errorf("%s: model version %d is newer than the supported %d", path, m.Version, modelVersion)
+	}
+	if m.Order < 1 || m.Discount <= 0 || m.Discount >= 1 {
+		return nil, fmt.Errorf("%s: invalid order or discount", path)
+	}
+	m.index()
+	return &m, nil
+}
+
+func (m *Model) Save(path string) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (m *Model) String() string {
+	return fmt.Sprintf("model version %d, order %d, trained %s on %d characters, %d contexts",
+		m.Version, m.Order, m.Trained.Format(time.DateTime), m.Runes, len(m.Contexts))
+}
+
+// autocomplete model [info|export <file>|import <file>]
+// Inspects the trained model or copies it out of or into the profile
+func modelCommand(args []string) error {
+	action := "info"
+	if len(args) > 0 {
+		action = args[0]
+	}
+	if action != "info" && len(args) != 2 {
+		return fmt.Errorf("usage: autocomplete model [info|export <file>|import <file>]")
+	}
+
+	from, to := paths.model, ""
+	switch action {
+	case "info":
+	case "export":
+		to = args[1]
+	case "import":
+		from, to = args[1], paths.model
+	default:
+		return fmt.Errorf("unknown model action %q, expected info, export or import", action)
+	}
+
+	// Loading validates the file before anything is written
+	m, err := LoadModel(from)
+	if err != nil {
+		return err
+	}
+	if m == nil {
+		return fmt.Errorf("no model at %s, run: autocomplete train", from)
+	}
+	if to != "" {
+		if err := m.Save(to); err != nil {
+			return err
+		}
+		fmt.Println(action+"ed", m)
+		return nil
+	}
+	fmt.Println(m)
+	return nil
+}
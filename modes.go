@@ -0,0 +1,457 @@
+package main
+
+import (
+	"slices"
+	"unicode"
+
+	"github.com/b0tShaman/autocomplete-cli/prompt"
+)
+
+// A key being handled, with the Enter action it stands for (see EnterConfig)
+type keyPress struct {
+	key    rune
+	action string
+}
+
+// Handles a key, false passes it on to the mode below
+type keyHandler func(e *Editor, k *keyPress) bool
+
+// A mode of the editor with keys of its own. Every key goes down the stack of
+// modes from the top, each mode the editor is in looks it up in its table and
+// the first one handling it wins. Eg:- TAB moves through the history panel while
+// it is open, through the suggestions while they show and is ignored otherwise
+type mode struct {
+	toggle keyHandler                            // keys opening and closing the mode from any other, nil for none
+	on     func(e *Editor) bool                  // reports whether the editor is in the mode
+	keys   func(cfg *Config) map[rune]keyHandler // the dispatch table, nil for none
+	other  keyHandler                            // keys the table has not, nil passes them down
+}
+
+// The modes, the top one first. A new mode goes where its keys take precedence
+var modes = []*mode{helpMode, teachMode, historyMode, menuMode, suggestionMode, insertMode}
+
+// Passes k down the modes until one handles it. A key in the table of a mode
+// is handled there or passed down, other only gets the rest
+func (e *Editor) dispatch(k *keyPress) {
+	e.tables.rebind(&e.cfg)
+	for _, m := range modes {
+		if m.toggle != nil && m.toggle(e, k) {
+			return
+		}
+		if !m.on(e) {
+			continue
+		}
+		var handle keyHandler
+		if m.keys != nil {
+			handle = e.tables.table(m, &e.cfg)[k.key]
+		}
+		if handle == nil {
+			handle = m.other
+		}
+		if handle != nil && handle(e, k) {
+			return
+		}
+	}
+}
+
+// The dispatch tables of the modes, built as keys reach them for the keys the
+// config binds
+type keyTables struct {
+	bound  KeysConfig
+	tables map[*mode]map[rune]keyHandler
+}
+
+// Drops the tables once the config binds other keys than they were built for
+func (t *keyTables) rebind(cfg *Config) {
+	if t.tables == nil || cfg.Keys != t.bound {
+		t.bound, t.tables = cfg.Keys, make(map[*mode]map[rune]keyHandler)
+	}
+}
+
+// The table of m, built on first use
+func (t *keyTables) table(m *mode, cfg *Config) map[rune]keyHandler {
+	table, ok := t.tables[m]
+	if !ok {
+		table = m.keys(cfg)
+		t.tables[m] = table
+	}
+	return table
+}
+
+// Adds the keys to table unless a key before them took them already, so the
+// first binding of a key wins. Unbound keys are left out
+func bindKeys(table map[rune]keyHandler, handle keyHandler, keys ...rune) {
+	for _, key := range keys {
+		if _, ok := table[key]; !ok && key != keyNone {
+			table[key] = handle
+		}
+	}
+}
+
+// The keys and modes, ? opens them on an empty line where it types nothing
+// much. Any other key closes them and is handled as usual
+var helpMode = &mode{
+	toggle: func(e *Editor, k *keyPress) bool {
+		if k.key == e.cfg.helpKey || (k.key == '?' && !e.help && len(e.input) == 0 && len(e.after) == 0) {
+			e.toggleHelp()
+			return true
+		}
+		return false
+	},
+	on: func(e *Editor) bool { return e.help },
+	other: func(e *Editor, k *keyPress) bool {
+		e.help = false
+		e.status(e.idleStatus())
+		return false
+	},
+}
+
+// The next paste is learned, any other key disarms it and is handled as usual
+var teachMode = &mode{
+	toggle: func(e *Editor, k *keyPress) bool {
+		if k.key == e.cfg.teachKey {
+			e.toggleTeach()
+			return true
+		}
+		return false
+	},
+	on: func(e *Editor) bool { return e.teach },
+	other: func(e *Editor, k *keyPress) bool {
+		e.teach = false
+		e.status(e.idleStatus())
+		return false
+	},
+}
+
+// Recently accepted completions: the cycle keys move to the next completion and
+// back, Enter or its number inserts one. Any other key closes the panel and is
+// handled as usual
+var historyMode = &mode{
+	toggle: func(e *Editor, k *keyPress) bool {
+		if k.key == e.cfg.historyKey {
+			e.togglePanel()
+			return true
+		}
+		return false
+	},
+	on: func(e *Editor) bool { return e.panel >= 0 },
+	keys: func(cfg *Config) map[rune]keyHandler {
+		table := make(map[rune]keyHandler)
+		bindKeys(table, func(e *Editor, k *keyPress) bool { return e.movePanel(1) }, cfg.cycleKey)
+		bindKeys(table, func(e *Editor, k *keyPress) bool { return e.movePanel(-1) }, cfg.cycleBackKey)
+		for key := '1'; key <= '9'; key++ {
+			bindKeys(table, func(e *Editor, k *keyPress) bool {
+				if int(k.key-'1') >= len(e.accepted) {
+					e.panel = -1
+					return false
+				}
+				e.panel = int(k.key - '1')
+				return e.insertRecalled()
+			}, key)
+		}
+		bindKeys(table, func(e *Editor, k *keyPress) bool { return e.insertRecalled() }, cfg.acceptKey)
+		return table
+	},
+	other: func(e *Editor, k *keyPress) bool {
+		if k.action == enterAccept {
+			return e.insertRecalled()
+		}
+		e.panel = -1
+		return false
+	},
+}
+
+// The suggestions listed in the status line: typing narrows them down, the menu
+// key closes it. Any other key goes to the suggestions below
+var menuMode = &mode{
+	on: func(e *Editor) bool { return e.menu != nil },
+	keys: func(cfg *Config) map[rune]keyHandler {
+		table := make(map[rune]keyHandler)
+		bindKeys(table, func(e *Editor, k *keyPress) bool {
+			e.menu = nil
+			e.show()
+			return true
+		}, cfg.menuKey)
+		bindKeys(table, (*Editor).narrowMenu, BACKSPACE, DELETE)
+		return table
+	},
+	other: func(e *Editor, k *keyPress) bool {
+		return isFilterKey(k.key) && e.narrowMenu(k)
+	},
+}
+
+// A suggestion shows: its keys act on it, accepting it goes on as a SPACE and
+// any other key drops the suggestions and is handled as usual
+var suggestionMode = &mode{
+	on: func(e *Editor) bool { return e.triggered },
+	keys: func(cfg *Config) map[rune]keyHandler {
+		table := make(map[rune]keyHandler)
+		bindKeys(table, (*Editor).openMenu, cfg.menuKey)
+		bindKeys(table, (*Editor).ignoreShown, cfg.ignoreKey)
+		bindKeys(table, (*Editor).pinShown, cfg.pinKey)
+		bindKeys(table, func(e *Editor, k *keyPress) bool {
+			e.explainSuggestion()
+			return true
+		}, cfg.explainKey)
+		bindKeys(table, (*Editor).forgetShown, cfg.forgetKey)
+		bindKeys(table, func(e *Editor, k *keyPress) bool {
+			e.dismiss()
+			e.stopTimer()
+			return true
+		}, cfg.dismissKey)
+		bindKeys(table, func(e *Editor, k *keyPress) bool { return e.cycle(1) }, cfg.cycleKey)
+		bindKeys(table, func(e *Editor, k *keyPress) bool { return e.cycle(-1) }, cfg.cycleBackKey)
+		bindKeys(table, (*Editor).acceptShown, cfg.acceptKey)
+		return table
+	},
+	other: func(e *Editor, k *keyPress) bool {
+		if k.action == enterAccept || k.action == enterComplete || (k.key == ' ' && e.t9Mode && isT9Sequence(prompt.CurrentWord(e.input))) {
+			return e.acceptShown(k)
+		}
+		e.dismiss()
+		return false
+	},
+}
+
+// Typing, at the bottom of the stack: what no other mode handles is typed
+var insertMode = &mode{
+	on: func(e *Editor) bool { return true },
+	keys: func(cfg *Config) map[rune]keyHandler {
+		table := make(map[rune]keyHandler)
+		// Enter, the arrows, Home and End move the cursor unless Enter has another action for them
+		bindKeys(table, func(e *Editor, k *keyPress) bool {
+			if k.action != "" {
+				e.enter(k.action)
+				return true
+			}
+			return e.moveCursor(k.key)
+		}, '\r', '\n', keyShiftEnter, keyAltEnter, keyLeft, keyRight, keyHome, keyEnd, keyForwardDelete)
+		// Ignore TAB -> to simplify prompt.CurrentWord() and getLastWord() logic
+		bindKeys(table, func(*Editor, *keyPress) bool { return true }, TAB, keyShiftTab, cfg.cycleKey, cfg.cycleBackKey, cfg.dismissKey, cfg.acceptKey)
+		bindKeys(table, (*Editor).acceptSnippet, cfg.snippetKey)
+		bindKeys(table, func(e *Editor, k *keyPress) bool { // Draw a plausible next word
+			e.surprise()
+			return true
+		}, cfg.surpriseKey)
+		bindKeys(table, func(e *Editor, k *keyPress) bool { // Keep the word just learned for a while only
+			e.status(e.makeTemporary())
+			return true
+		}, cfg.tempKey)
+		bindKeys(table, func(e *Editor, k *keyPress) bool { // Toggle T9 numeric input
+			e.t9Mode = !e.t9Mode
+			e.status(e.idleStatus())
+			return true
+		}, cfg.t9Key)
+		bindKeys(table, func(e *Editor, k *keyPress) bool {
+			e.learnSpace()
+			return e.typeKey(k)
+		}, ' ')
+		bindKeys(table, func(e *Editor, k *keyPress) bool { // Alt+Backspace deletes a whole word
+			e.deleteLastWord()
+			return true
+		}, cfg.deleteWordKey)
+		bindKeys(table, (*Editor).backspace, BACKSPACE, DELETE)
+		return table
+	},
+	other: (*Editor).typeKey,
+}
+
+// Moves the history panel by n completions
+func (e *Editor) movePanel(n int) bool {
+	e.panel = (e.panel + n + len(e.accepted)) % len(e.accepted)
+	e.status(panelStatus(e.accepted, e.panel))
+	return true
+}
+
+// Inserts the completion highlighted in the history panel and closes it
+func (e *Editor) insertRecalled() bool {
+	word := e.accepted[e.panel]
+	e.panel = -1
+	e.input = prompt.Complete(e.input, word)
+	e.learnLastWord() // used once more
+	e.input = append(e.input, ' ')
+	e.status("inserted " + word)
+	return true
+}
+
+// Opens the menu of the suggestions, in sections with menu.group
+func (e *Editor) openMenu(*keyPress) bool {
+	e.menu = e.suggestions
+	if e.cfg.Menu.Group {
+		current := e.suggestions[e.index%len(e.suggestions)]
+		e.menu = e.cfg.Menu.group(e.suggestions, e.prof.menuSection)
+		e.suggestions, e.index = e.menu, max(0, slices.Index(e.menu, current))
+	}
+	e.show()
+	return true
+}
+
+// Narrow down the menu with the key typed or deleted
+func (e *Editor) narrowMenu(k *keyPress) bool {
+	e.stopTimer()
+	if k.key == BACKSPACE || k.key == DELETE {
+		e.input = e.input[:max(0, len(e.input)-1)]
+	} else {
+		e.input = append(e.input, k.key)
+	}
+	if filtered := filterCandidates(e.menu, prompt.CurrentWord(e.input)); len(filtered) > 0 {
+		e.suggestions, e.index = filtered, 0
+		e.show()
+		return true
+	}
+	// Nothing left, look the word up again
+	e.startTimer()
+	e.dismiss()
+	e.status("no match in the menu")
+	return true
+}
+
+// Never suggest the shown word for the prefix again
+func (e *Editor) ignoreShown(*keyPress) bool {
+	prof := e.prof
+	word, rejected := prompt.CurrentWord(e.input), e.suggestions[e.index%len(e.suggestions)].word
+	prof.ignores.Add(word, rejected)
+	status := "won't suggest " + rejected + " for " + word + " again"
+	if err := prof.ignores.Save(prof.paths.ignores); err != nil {
+		status = "saving ignore list failed: " + err.Error()
+	}
+	e.suggestions = prof.ignores.Filter(word, e.suggestions)
+	if e.menu != nil {
+		e.menu = prof.ignores.Filter(word, e.menu)
+	}
+	if len(e.suggestions) > 0 {
+		e.show()
+		return true
+	}
+	e.dismiss()
+	e.stopTimer()
+	e.status(status)
+	return true
+}
+
+// Pin the shown word to the prefix, or unpin it
+func (e *Editor) pinShown(*keyPress) bool {
+	prof := e.prof
+	word, c := prompt.CurrentWord(e.input), e.suggestions[e.index%len(e.suggestions)]
+	status := "pinned " + c.word + " to " + word
+	if prof.pins.Has(word, c.word) {
+		prof.pins.Remove(word, c.word)
+		status = "unpinned " + c.word + " from " + word
+	} else {
+		prof.pins.Add(word, c.word)
+	}
+	if err := prof.pins.Save(prof.paths.pins); err != nil {
+		status = "saving pins failed: " + err.Error()
+	}
+	e.dismiss()
+	e.stopTimer()
+	e.status(status)
+	return true
+}
+
+// Forget the shown word, Eg:- a typo learned by mistake
+func (e *Editor) forgetShown(*keyPress) bool {
+	forgotten := e.suggestions[e.index%len(e.suggestions)].word
+	status := e.forget(forgotten)
+	e.suggestions = slices.DeleteFunc(e.suggestions, func(c Candidate) bool { return c.word == forgotten })
+	if e.menu != nil {
+		e.menu = slices.DeleteFunc(e.menu, func(c Candidate) bool { return c.word == forgotten })
+	}
+	if len(e.suggestions) > 0 {
+		e.index = 0
+		e.show()
+		e.status(status)
+		return true
+	}
+	e.dismiss()
+	e.stopTimer()
+	e.status(status)
+	return true
+}
+
+// Loop through the suggestions, back when n is negative. The index counts on,
+// show takes it modulo the number of suggestions
+func (e *Editor) cycle(n int) bool {
+	if n > 0 {
+		e.index++
+	} else {
+		e.index = (e.index%len(e.suggestions) + len(e.suggestions) - 1) % len(e.suggestions)
+	}
+	e.record("shown", prompt.CurrentWord(e.input))
+	e.show()
+	return true
+}
+
+// Suggestion has been selected. Perform autocomplete. Unless Enter completes
+// only, the key goes on as a SPACE after the word
+func (e *Editor) acceptShown(k *keyPress) bool {
+	prof := e.prof
+	e.record("accepted", prompt.CurrentWord(e.input))
+	if top, accepted := e.suggestions[0], e.suggestions[e.index%len(e.suggestions)]; top.source == "trie" && accepted.source == "trie" && !e.cfg.NoLearn {
+		prof.boosts.Feedback(top.word, accepted.word)
+		prof.boostsChanged = true // saved once the user is idle
+		e.warm.Forget(top.word)
+		e.warm.Forget(accepted.word)
+	}
+	if c := e.suggestions[e.index%len(e.suggestions)]; c.source == "fuzzy" || c.source == "typo" {
+		e.learnTypo(prompt.CurrentWord(e.input), c.word)
+	}
+	e.input = prompt.Complete(e.input, e.suggestions[e.index%len(e.suggestions)].word)
+	e.remember(e.suggestions[e.index%len(e.suggestions)].word)
+	e.recap.accepted++
+	complete := k.action == enterComplete // the word may go on, it is learned with the next SPACE
+	e.dismiss()
+	if complete {
+		return true
+	}
+	k.key, k.action = ' ', ""
+	return false
+}
+
+// Turn the proposed phrase into a snippet
+func (e *Editor) acceptSnippet(*keyPress) bool {
+	prof := e.prof
+	if e.proposal != nil {
+		prof.snippets[e.proposal.abbr] = e.proposal.phrase
+		status := "created snippet " + e.proposal.abbr + " → " + e.proposal.phrase
+		if err := prof.snippets.Save(prof.paths.snippets); err != nil {
+			status = "saving snippets failed: " + err.Error()
+		}
+		e.proposal = nil
+		e.status(status)
+	}
+	return true
+}
+
+// On detecting SPACE, store the last typed word into the Trie
+func (e *Editor) learnSpace() {
+	// In T9 mode an unresolved digit sequence becomes its most used word
+	if word := prompt.CurrentWord(e.input); e.t9Mode && isT9Sequence(word) {
+		if words := t9Words(e.trie, word); len(words) > 0 {
+			e.input = prompt.Complete(e.input, words[0])
+		}
+	}
+	e.learnLastWord()
+}
+
+// Handle backspace
+func (e *Editor) backspace(*keyPress) bool {
+	if len(e.input) > 0 {
+		e.noteErased(len(e.input) - len([]rune(prompt.CurrentWord(e.input))))
+		e.input = e.input[:len(e.input)-1]
+		e.status(e.idleStatus())
+	}
+	return true
+}
+
+// Add character and send to render() function. Other control characters would
+// end up on the screen as they are, the keys of escape sequences nothing is
+// bound to are dropped
+func (e *Editor) typeKey(k *keyPress) bool {
+	if k.key < 0 || unicode.IsControl(k.key) {
+		return true
+	}
+	e.input = append(e.input, k.key)
+	e.status(e.idleStatus())
+	return true
+}
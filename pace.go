@@ -0,0 +1,66 @@
+package main
+
+import "time"
+
+const (
+	paceGap     = time.Second           // longer gaps between keys are pauses, not typing
+	paceWeight  = 0.2                   // of the newest gap in the average
+	paceMin     = 30 * time.Millisecond // the adaptive debounce never goes below
+	paceMax     = time.Second           // nor above
+	paceSamples = 5                     // gaps measured before the average is trusted
+)
+
+// How fast the user types, from the time between reads of the terminal. With
+// adaptive_debounce the suggestions wait for a pause a bit longer than the
+// usual gap between keys. Eg:- 80ms between keys --> 120ms debounce
+type Pace struct {
+	last    time.Time
+	average time.Duration // moving average of the gaps
+	samples int
+}
+
+// Records a key read at now
+func (p *Pace) Key(now time.Time) {
+	if gap := now.Sub(p.last); !p.last.IsZero() && gap < paceGap {
+		if p.samples == 0 {
+			p.average = gap
+		} else {
+			p.average += time.Duration(paceWeight * float64(gap-p.average))
+		}
+		p.samples++
+	}
+	p.last = now
+}
+
+// The debounce for the measured pace, fallback until enough gaps were measured
+func (p *Pace) Debounce(fallback time.Duration) time.Duration {
+	if p.samples < paceSamples {
+		return fallback
+	}
+	return min(max(p.average*3/2, paceMin), paceMax)
+}
+
+// The pause after which suggestions show up, 0 for none
+func (e *Editor) debounce() time.Duration {
+	if e.cfg.AdaptiveDebounce && e.cfg.Debounce > 0 {
+		return e.pace.Debounce(e.cfg.Debounce)
+	}
+	return e.cfg.Debounce
+}
+
+// Starts waiting for the pause again, see debounce. Without one nothing waits:
+// Feed looks the suggestions up once it handled what was read
+func (e *Editor) startTimer() {
+	if d := e.debounce(); d > 0 {
+		e.timer.Reset(d)
+	} else {
+		e.stopTimer()
+		e.due = true
+	}
+}
+
+// Stops waiting for the pause, no suggestions are looked up
+func (e *Editor) stopTimer() {
+	e.timer.Stop()
+	e.due = false
+}
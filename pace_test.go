@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// With adaptive_debounce the pause before suggestions follows the typing speed
+func TestPace(t *testing.T) {
+	var fast, slow Pace
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		fast.Key(start.Add(time.Duration(i) * 80 * time.Millisecond))
+		slow.Key(start.Add(time.Duration(i) * 600 * time.Millisecond))
+	}
+	if got := fast.Debounce(200 * time.Millisecond); got != 120*time.Millisecond {
+		t.Fatalf("fast typist waits %v", got)
+	}
+	if got := slow.Debounce(200 * time.Millisecond); got != 900*time.Millisecond {
+		t.Fatalf("slow typist waits %v", got)
+	}
+	// A long pause is not a keystroke gap
+	fast.Key(start.Add(time.Minute))
+	if got := fast.Debounce(200 * time.Millisecond); got != 120*time.Millisecond {
+		t.Fatalf("after a pause the fast typist waits %v", got)
+	}
+	var fresh Pace
+	fresh.Key(start)
+	if got := fresh.Debounce(200 * time.Millisecond); got != 200*time.Millisecond {
+		t.Fatalf("unmeasured pace waits %v", got)
+	}
+}
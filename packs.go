@@ -0,0 +1,125 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Keyword packs shipped with the binary. A file of the same name in the packs
+// directory of the data directory replaces one, other files there add packs.
+// One keyword per line, most common first, # starts a comment
+//
+//go:embed packs/*.txt
+var builtinPacks embed.FS
+
+const packsDir = "packs"
+
+// Keywords of a technical vocabulary, suggested after everything else
+type Pack struct {
+	name  string
+	words []string
+}
+
+func parsePack(name string, data []byte) *Pack {
+	p := &Pack{name: name}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			p.words = append(p.words, line)
+		}
+	}
+	return p
+}
+
+// Names of all packs, the built-in ones and those in the packs directory
+func availablePacks() []string {
+	names := make(map[string]bool)
+	builtin, _ := builtinPacks.ReadDir(packsDir)
+	for _, e := range builtin {
+		names[strings.TrimSuffix(e.Name(), ".txt")] = true
+	}
+	local, _ := filepath.Glob(filepath.Join(dataDir(), packsDir, "*.txt"))
+	for _, file := range local {
+		names[strings.TrimSuffix(filepath.Base(file), ".txt")] = true
+	}
+
+	var result []string
+	for name := range names {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Loads the named packs. Packs which do not exist or are refused by v are reported in problems
+func LoadPacks(names []string, v *Verifier) ([]*Pack, []string) {
+	var packs []*Pack
+	var problems []string
+	for _, name := range names {
+		local := filepath.Join(dataDir(), packsDir, name+".txt")
+		if data, err := os.ReadFile(local); err == nil {
+			if v.Allow(local) {
+				packs = append(packs, parsePack(name, data))
+			}
+			continue
+		}
+		data, err := builtinPacks.ReadFile(path.Join(packsDir, name+".txt"))
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("unknown pack %q", name))
+			continue
+		}
+		packs = append(packs, parsePack(name, data))
+	}
+	return packs, problems
+}
+
+// Keywords of the packs starting with word, ignoring case, which are not among
+// the suggestions already. Keywords in capitals are lowercased for a lowercase
+// word, Eg:- sel --> select
+func packCandidates(packs []*Pack, word string, suggested []Candidate) []Candidate {
+	var result []Candidate
+	if word == "" {
+		return result
+	}
+	seen := make(map[string]bool)
+	for _, c := range suggested {
+		seen[strings.ToLower(c.word)] = true
+	}
+
+	lower := strings.ToLower(word)
+	for _, p := range packs {
+		for _, kw := range p.words {
+			key := strings.ToLower(kw)
+			if !strings.HasPrefix(key, lower) || key == lower || seen[key] {
+				continue
+			}
+			seen[key] = true
+			if word == lower && strings.ToUpper(kw) == kw && strings.IndexFunc(kw, unicode.IsLetter) >= 0 {
+				kw = key
+			}
+			result = append(result, Candidate{word: kw, label: p.name, source: "pack:" + p.name})
+		}
+	}
+	return result
+}
+
+// autocomplete packs
+// Lists the keyword packs, marking the enabled ones
+func packsCommand(args []string) error {
+	cfg, _ := LoadConfig(configPath())
+	for _, name := range availablePacks() {
+		mark := " "
+		for _, enabled := range cfg.Packs {
+			if enabled == name {
+				mark = "*"
+			}
+		}
+		fmt.Println(mark, name)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Bracketed paste: the terminal sends what is pasted between these two keys
+const (
+	keyPasteStart rune = -50
+	keyPasteEnd   rune = -51
+)
+
+var pasteKeys = map[string]rune{
+	"\x1b[200~": keyPasteStart, "\x1b[201~": keyPasteEnd,
+}
+
+// Printed to turn bracketed paste on and off again on exit
+const (
+	bracketedPasteOn  = "\x1b[?2004h"
+	bracketedPasteOff = "\x1b[?2004l"
+)
+
+// Arms the teach key: the next paste is learned instead of inserted. Pressing it
+// again disarms it
+func (e *Editor) toggleTeach() {
+	e.dismiss()
+	e.stopTimer()
+	switch e.teach = !e.teach; {
+	case !e.teach:
+		e.status("not learning the paste")
+	case e.cfg.NoLearn:
+		e.teach = false
+		e.status("nothing is learned with no_learn")
+	default:
+		e.status("paste the text to learn, " + e.cfg.Keys.Teach + " cancels")
+	}
+}
+
+// Handles a key of a paste. Reports whether the key was one, with the teach key
+// armed it goes into the text to learn rather than the input
+func (e *Editor) pasteKey(key rune) bool {
+	switch {
+	case key == keyPasteStart && e.teach:
+		e.teach, e.pasted = false, []rune{}
+		e.status("learning the paste…")
+	case key == keyPasteStart || (key == keyPasteEnd && e.pasted == nil):
+		// A paste typed in as it is
+	case key == keyPasteEnd:
+		e.status(e.learnText(string(e.pasted)))
+		e.pasted = nil
+	case e.pasted != nil:
+		e.pasted = append(e.pasted, key)
+	default:
+		return false
+	}
+	return true
+}
+
+// Learns the words of a paste, returns the status to show. Eg:- learned 120
+// words from the paste, 14 of them new
+func (e *Editor) learnText(text string) string {
+	words, fresh := e.learnWords(text)
+	return fmt.Sprintf("learned %d words from the paste, %d of them new", words, fresh)
+}
+
+// Learns the words of text like the words typed, by their token policies.
+// Returns how many there were and how many of them were new
+func (e *Editor) learnWords(text string) (words, fresh int) {
+	before := len(e.recap.learned)
+	tokenizer := e.cfg.tokenizer()
+	for _, token := range strings.FieldsFunc(text, unicode.IsSpace) {
+		if policy := e.cfg.Tokens.Policy(token); policy != tokenLearn {
+			e.learnToken(token, policy)
+			continue
+		}
+		for _, word := range tokenizer.Words(token) {
+			e.learnToken(word, e.cfg.Tokens.Policy(word))
+			words++
+		}
+	}
+	// Alt+Backspace and the snippet key are about what was typed, not pasted
+	e.learned, e.proposal = "", nil
+	return words, len(e.recap.learned) - before
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Where the learned data of a profile is kept
+type DataPaths struct {
+	dir        string
+	learnLog   string
+	snapshot   string
+	phrases    string
+	tombstones string
+	snippets   string
+	events     string
+	ranker     string
+	model      string
+	boosts     string
+	ignores    string
+	lines      string
+	pins       string
+	typos      string
+	temporary  string
+	files      string
+}
+
+// Learned data of the active profile, set once the config is loaded
+var paths = profilePaths("")
+
+// The default profile keeps its learned data in the data directory,
+// every other profile in its profiles/<name> subdirectory
+func profilePaths(profile string) DataPaths {
+	dir := dataDir()
+	if profile != "" {
+		dir = filepath.Join(dir, "profiles", profile)
+	}
+	return pathsIn(dir)
+}
+
+// Learned data of a serve mode client lives in the clients/<id> subdirectory
+func clientPaths(id string) DataPaths {
+	return pathsIn(filepath.Join(dataDir(), "clients", id))
+}
+
+func pathsIn(dir string) DataPaths {
+	return DataPaths{
+		dir:        dir,
+		learnLog:   filepath.Join(dir, learnLogFile),
+		snapshot:   filepath.Join(dir, snapshotFile),
+		phrases:    filepath.Join(dir, phrasesFile),
+		tombstones: filepath.Join(dir, tombstonesFile),
+		snippets:   filepath.Join(dir, snippetsFile),
+		events:     filepath.Join(dir, eventsFile),
+		ranker:     filepath.Join(dir, rankerFile),
+		model:      filepath.Join(dir, modelFile),
+		boosts:     filepath.Join(dir, boostsFile),
+		ignores:    filepath.Join(dir, ignoresFile),
+		lines:      filepath.Join(dir, linesFile),
+		pins:       filepath.Join(dir, pinsFile),
+		typos:      filepath.Join(dir, typosFile),
+		temporary:  filepath.Join(dir, temporaryFile),
+		files:      filepath.Join(dir, filesFile),
+	}
+}
+
+// Creates the profile directory if needed
+func (p DataPaths) mkdir() error {
+	return os.MkdirAll(p.dir, 0755)
+}
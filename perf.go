@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+const perfSamples = 200 // latest lookups the percentiles are taken over
+
+// How long the latest suggestion lookups took, shown in the status line with
+// --perf along with how often the warmed prefixes answered them
+type Perf struct {
+	samples []time.Duration // oldest overwritten first
+	next    int
+}
+
+func (p *Perf) Add(d time.Duration) {
+	if len(p.samples) < perfSamples {
+		p.samples = append(p.samples, d)
+		return
+	}
+	p.samples[p.next] = d
+	p.next = (p.next + 1) % perfSamples
+}
+
+// Returns the duration q (0 to 1) of the lookups took at most, 0 before any
+func (p *Perf) Percentile(q float64) time.Duration {
+	if len(p.samples) == 0 {
+		return 0
+	}
+	sorted := slices.Clone(p.samples)
+	slices.Sort(sorted)
+	return sorted[min(len(sorted)-1, int(q*float64(len(sorted))))]
+}
+
+// Eg:- p50 0.21ms p95 1.4ms cache 38% of 52
+func (p *Perf) Status(warm *PrefixCache) string {
+	status := fmt.Sprintf("p50 %s p95 %s", formatLatency(p.Percentile(0.5)), formatLatency(p.Percentile(0.95)))
+	if hits, lookups := warm.Rate(); lookups > 0 {
+		status += fmt.Sprintf(" cache %d%% of %d", hits*100/lookups, lookups)
+	}
+	return status
+}
+
+// Milliseconds with 2 significant digits, enough to compare backends
+func formatLatency(d time.Duration) string {
+	ms := float64(d) / float64(time.Millisecond)
+	switch {
+	case ms < 1:
+		return fmt.Sprintf("%.2fms", ms)
+	case ms < 10:
+		return fmt.Sprintf("%.1fms", ms)
+	}
+	return fmt.Sprintf("%.0fms", ms)
+}
+
+// Adds the perf report to status when --perf is set
+func (e *Editor) perfStatus(status string) string {
+	if !e.cfg.Perf {
+		return status
+	} else if status == "" {
+		return e.perf.Status(e.warm)
+	}
+	return status + " | " + e.perf.Status(e.warm)
+}
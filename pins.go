@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Completions pinned to a prefix, one "prefix<TAB>completion" per line in the order they were pinned
+const pinsFile = "pins.txt"
+
+// Maps a typed prefix to the completions always suggested first for it.
+// Eg:- addr --> [221B Baker Street, London]
+type Pins map[string][]string
+
+// Reads the pins. A missing file means nothing was pinned
+func LoadPins(path string) (Pins, error) {
+	pins := make(Pins)
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return pins, nil
+	} else if err != nil {
+		return pins, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		prefix, completion, ok := strings.Cut(scanner.Text(), "\t")
+		if ok && prefix != "" && completion != "" {
+			pins.Add(prefix, completion)
+		}
+	}
+	return pins, scanner.Err()
+}
+
+func (pins Pins) Save(path string) error {
+	var b strings.Builder
+	for _, prefix := range pins.prefixes() {
+		for _, completion := range pins[prefix] {
+			b.WriteString(prefix + "\t" + completion + "\n")
+		}
+	}
+	return os.WriteFile(path, []byte(b.String()), 0644)
+}
+
+func (pins Pins) prefixes() []string {
+	var prefixes []string
+	for prefix := range pins {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	return prefixes
+}
+
+func (pins Pins) Add(prefix, completion string) {
+	if !slices.Contains(pins[prefix], completion) {
+		pins[prefix] = append(pins[prefix], completion)
+	}
+}
+
+// Unpins completion, returns false if it was not pinned to prefix
+func (pins Pins) Remove(prefix, completion string) bool {
+	i := slices.Index(pins[prefix], completion)
+	if i < 0 {
+		return false
+	}
+	if pins[prefix] = slices.Delete(pins[prefix], i, i+1); len(pins[prefix]) == 0 {
+		delete(pins, prefix)
+	}
+	return true
+}
+
+// Reports whether completion is pinned to prefix
+func (pins Pins) Has(prefix, completion string) bool {
+	return slices.Contains(pins[prefix], completion)
+}
+
+// Puts the completions pinned in config and then in pins ahead of candidates,
+// dropping them further down. A pin applies to its prefix, and to longer typed
+// words as long as the completion still starts with them. Eg:- pinned he --> hello
+// also applies to hel
+func pinCandidates(config map[string]string, pins Pins, word string, candidates []Candidate) []Candidate {
+	var pinned []Candidate
+	add := func(prefix, completion string) {
+		if word == prefix || strings.HasPrefix(word, prefix) && strings.HasPrefix(completion, word) {
+			if !slices.ContainsFunc(pinned, func(c Candidate) bool { return c.word == completion }) {
+				pinned = append(pinned, Candidate{word: completion, label: "pinned", source: "pin"})
+			}
+		}
+	}
+	var prefixes []string
+	for prefix := range config {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	for _, prefix := range prefixes {
+		add(prefix, config[prefix])
+	}
+	for _, prefix := range pins.prefixes() {
+		for _, completion := range pins[prefix] {
+			add(prefix, completion)
+		}
+	}
+	if len(pinned) == 0 {
+		return candidates
+	}
+
+	result := pinned
+	for _, c := range candidates {
+		if !slices.ContainsFunc(pinned, func(p Candidate) bool { return p.word == c.word }) {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// autocomplete pins [list|add <prefix> <completion...>|remove <prefix> <completion...>]
+// Prints, pins or unpins completions
+func pinsCommand(args []string) error {
+	pins, err := LoadPins(paths.pins)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 || args[0] == "list" {
+		for _, prefix := range pins.prefixes() {
+			for _, completion := range pins[prefix] {
+				fmt.Printf("%-12s %s\n", prefix, completion)
+			}
+		}
+		return nil
+	}
+	if len(args) < 3 || (args[0] != "add" && args[0] != "remove") {
+		return fmt.Errorf("usage: pins [list|add <prefix> <completion...>|remove <prefix> <completion...>]")
+	}
+	prefix, completion := args[1], strings.Join(args[2:], " ")
+	if args[0] == "add" {
+		pins.Add(prefix, completion)
+	} else if !pins.Remove(prefix, completion) {
+		return fmt.Errorf("%s is not pinned to %s", completion, prefix)
+	}
+	return pins.Save(paths.pins)
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Executables in the plugins directory become completion sources. They speak
+// JSON lines over stdin/stdout:
+//
+//	plugin -> {"name": "emoji", "trigger": ":", "priority": 5}      once, on startup
+//	editor -> {"id": 1, "word": ":smi", "previous": ["so", "happy"]} for every lookup
+//	plugin -> {"id": 1, "candidates": [{"word": "😄", "label": "smile"}]}
+//
+// previous holds up to contextWords words typed before the word, oldest first.
+// A plugin is only asked about words starting with its trigger (every word when empty).
+// Plugins with a positive priority are listed before the built-in suggestions, the
+// others after them, higher priorities first
+const pluginsDir = "plugins"
+
+const (
+	pluginHelloTimeout = time.Second            // time a plugin has to introduce itself
+	pluginTimeout      = 100 * time.Millisecond // time a plugin has to answer a lookup
+)
+
+// A running plugin
+type Plugin struct {
+	Name     string `json:"name"`
+	Trigger  string `json:"trigger"`
+	Priority int    `json:"priority"`
+
+	cmd       *exec.Cmd
+	stdin     io.WriteCloser
+	requests  chan []byte // written to stdin by a goroutine, so a plugin which stops reading cannot block the editor
+	responses chan pluginResponse
+	lastID    int
+	timeout   time.Duration // pluginTimeout outside of tests
+	writing   atomic.Bool   // a request is not written to stdin yet
+	skipped   int           // lookups the plugin was not asked, still reading an earlier one
+}
+
+type pluginRequest struct {
+	ID       int      `json:"id"`
+	Word     string   `json:"word"`
+	Previous []string `json:"previous,omitempty"`
+}
+
+type pluginResponse struct {
+	ID         int `json:"id"`
+	Candidates []struct {
+		Word  string `json:"word"`
+		Label string `json:"label"`
+	} `json:"candidates"`
+}
+
+// Starts every executable in dir. Plugins which fail to start or to introduce
+// themselves are reported in the returned problems and left out
+func StartPlugins(dir string) ([]*Plugin, []string) {
+	var plugins []*Plugin
+	var problems []string
+
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() || info.Mode()&0111 == 0 {
+			continue
+		}
+		p, err := startPlugin(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("plugin %s: %v", entry.Name(), err))
+			continue
+		}
+		plugins = append(plugins, p)
+	}
+
+	sort.SliceStable(plugins, func(i, j int) bool { return plugins[i].Priority > plugins[j].Priority })
+	return plugins, problems
+}
+
+func startPlugin(path string) (*Plugin, error) {
+	cmd := exec.Command(path)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	p := &Plugin{Name: filepath.Base(path), cmd: cmd, stdin: stdin, requests: make(chan []byte, 1), responses: make(chan pluginResponse, 16), timeout: pluginTimeout}
+	hello := make(chan error, 1)
+
+	go func() {
+		for req := range p.requests {
+			if _, err := stdin.Write(req); err != nil {
+				return
+			}
+			p.writing.Store(false)
+		}
+	}()
+
+	// Goroutine reading the introduction and then the responses
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		if !scanner.Scan() {
+			hello <- fmt.Errorf("exited without introducing itself")
+			return
+		}
+		hello <- json.Unmarshal(scanner.Bytes(), p)
+
+		for scanner.Scan() {
+			var resp pluginResponse
+			if json.Unmarshal(scanner.Bytes(), &resp) == nil {
+				p.responses <- resp
+			}
+		}
+		close(p.responses)
+	}()
+
+	select {
+	case err = <-hello:
+	case <-time.After(pluginHelloTimeout):
+		err = fmt.Errorf("did not introduce itself within %v", pluginHelloTimeout)
+	}
+	if err != nil {
+		p.Stop()
+		return nil, err
+	}
+	return p, nil
+}
+
+// Reports whether the plugin wants to complete word
+func (p *Plugin) Triggered(word string) bool {
+	return strings.HasPrefix(word, p.Trigger)
+}
+
+// Asks the plugin for candidates for word typed after the previous words. A plugin
+// which does not answer in time, or is still reading an earlier lookup, contributes
+// nothing
+func (p *Plugin) Suggest(word string, previous []string) []Candidate {
+	if !p.writing.CompareAndSwap(false, true) {
+		p.skipped++
+		return nil // the plugin stopped reading
+	}
+	p.lastID++
+	req, _ := json.Marshal(pluginRequest{ID: p.lastID, Word: word, Previous: previous})
+	p.requests <- append(req, '\n') // never waits, nothing else is queued while writing
+
+	timeout := time.After(p.timeout)
+	for {
+		select {
+		case resp, ok := <-p.responses:
+			if !ok {
+				return nil
+			}
+			if resp.ID != p.lastID {
+				continue // late answer to an earlier lookup
+			}
+			var result []Candidate
+			for _, c := range resp.Candidates {
+				label := c.Label
+				if label == "" {
+					label = p.Name
+				}
+				result = append(result, Candidate{word: c.Word, label: label, source: "plugin:" + p.Name})
+			}
+			return result
+		case <-timeout:
+			return nil
+		}
+	}
+}
+
+func (p *Plugin) Stop() {
+	close(p.requests)
+	p.stdin.Close()
+	p.cmd.Process.Kill()
+	p.cmd.Wait()
+}
+
+// Candidates from each of the given plugins which are triggered by word, asked
+// all at once so the slowest one sets how long it takes
+func pluginCandidates(plugins []*Plugin, previous []string, word string) [][]Candidate {
+	result := make([][]Candidate, len(plugins))
+	var wg sync.WaitGroup
+	for i, p := range plugins {
+		if p.Triggered(word) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				result[i] = p.Suggest(word, previous)
+			}()
+		}
+	}
+	wg.Wait()
+	return result
+}
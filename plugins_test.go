@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Plugins are asked at once, and one which stops reading its stdin does not
+// hold up the editor
+func TestPlugins(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are shell scripts")
+	}
+	dir := t.TempDir()
+	scripts := map[string]string{
+		"a-stuck": `echo '{"name": "stuck", "priority": 1}'; sleep 10`,
+		"b-slow":  `echo '{"name": "slow", "priority": 1}'; while read -r line; do :; done`,
+		"c-echo": `echo '{"name": "echo"}'
+while read -r line; do
+	id=$(echo "$line" | sed 's/.*"id":\([0-9]*\).*/\1/')
+	echo "{\"id\": $id, \"candidates\": [{\"word\": \"hi\"}]}"
+done`,
+	}
+	for name, script := range scripts {
+		os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script+"\n"), 0755)
+	}
+	plugins, problems := StartPlugins(dir)
+	if len(problems) > 0 || len(plugins) != 3 {
+		t.Fatalf("%d plugins, problems %q", len(plugins), problems)
+	}
+	for _, p := range plugins {
+		defer p.Stop()
+		p.timeout = 500 * time.Millisecond
+	}
+
+	// One after the other, the two which do not answer would take a second
+	start := time.Now()
+	answers := pluginCandidates(plugins, nil, "h")
+	if took := time.Since(start); took >= time.Second {
+		t.Errorf("asking 3 plugins took %v", took)
+	}
+	if len(answers[0]) != 0 || len(answers[1]) != 0 || len(answers[2]) != 1 || answers[2][0].word != "hi" {
+		t.Errorf("answers %v", answers)
+	}
+
+	// Once a lookup is stuck in the pipe, the plugin is not asked again
+	// until it is read
+	long := strings.Repeat("h", 1<<20) // more than the pipe holds
+	for i := range 10 {
+		if got := plugins[0].Suggest(long, nil); got != nil {
+			t.Errorf("lookup %d answered %v", i, got)
+		}
+	}
+	if plugins[0].skipped != 9 {
+		t.Errorf("a plugin which stopped reading was asked %d of 10 lookups", 10-plugins[0].skipped)
+	}
+}
@@ -0,0 +1,23 @@
+package main
+
+import "github.com/b0tShaman/autocomplete-cli/prompt"
+
+// With preview set the shown suggestion is put in the input in place of the
+// typed word, so it reads in context, and taken out again before the next key
+// is handled. Only accepting it keeps it. Eg:- brb TAB --> "be right back" in
+// the text, ESC --> brb again
+
+// Puts word in the input in place of the typed word, after taking out the one
+// put there before
+func (e *Editor) startPreview(word string) {
+	e.endPreview()
+	e.preview = e.input
+	e.input = prompt.Complete(e.input, word)
+}
+
+// Puts the input back as it was typed
+func (e *Editor) endPreview() {
+	if e.preview != nil {
+		e.input, e.preview = e.preview, nil
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Learned data of the active profile
+type profile struct {
+	paths      DataPaths
+	boosts     Boosts
+	tombstones Tombstones
+	snippets   Snippets
+	history    []LearnedWord   // learn log since the last compaction
+	phrases    *Phrases        // counts of repeated phrases
+	offered    map[string]bool // phrases already offered as snippets this session
+	lastUsed   map[string]time.Time
+	ranker     *Ranker // nil until trained
+	model      *Model  // nil until trained
+	ignores    Ignores
+	pins       Pins
+	typos      Typos
+	temporary  TempWords
+	files      RecentFiles
+	learnLog   *LearnLog // nil when the log cannot be written
+	events     *EventLog // nil when the log cannot be written
+
+	boostsChanged bool        // boosts not saved yet
+	flushed       time.Time   // when the learn log was last flushed into the snapshots
+	trainedEvents int64       // size of the event log when the ranker was last trained
+	sizeWarned    bool        // the learned data was reported past size_guard.warn
+	pruned        time.Time   // when the learned data was last pruned past size_guard.prune
+	undo          LearnedWord // the word learned last and when it was used before, for unlearn
+}
+
+// Loads the learned data of the profile at paths. Whatever fails to load is
+// left empty and reported in problems
+func openProfile(paths DataPaths) (*profile, []string) {
+	var problems []string
+	report := func(what string, err error) {
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s failed: %v", what, err))
+		}
+	}
+
+	report("Creating profile directory", paths.mkdir())
+
+	p := &profile{paths: paths, offered: make(map[string]bool)}
+	var err error
+	p.tombstones, err = LoadTombstones(paths.tombstones)
+	report("LoadTombstones", err)
+	p.snippets, err = LoadSnippets(paths.snippets)
+	report("LoadSnippets", err)
+	p.history, err = ReadLearnLog(paths.learnLog)
+	report("ReadLearnLog", err)
+	phraseCounts, err := LoadPhraseCounts(paths.phrases)
+	report("LoadPhraseCounts", err)
+	p.phrases = NewPhrases(phraseCounts, p.history)
+	p.ranker, err = LoadRanker(paths.ranker)
+	report("LoadRanker", err)
+	p.model, err = LoadModel(paths.model)
+	report("LoadModel", err)
+	p.boosts, err = LoadBoosts(paths.boosts)
+	report("LoadBoosts", err)
+	p.ignores, err = LoadIgnores(paths.ignores)
+	report("LoadIgnores", err)
+	p.pins, err = LoadPins(paths.pins)
+	report("LoadPins", err)
+	p.typos, err = LoadTypos(paths.typos)
+	report("LoadTypos", err)
+	p.temporary, err = LoadTempWords(paths.temporary, time.Now())
+	report("LoadTempWords", err)
+	p.files, err = LoadRecentFiles(paths.files)
+	report("LoadRecentFiles", err)
+
+	p.lastUsed = make(map[string]time.Time)
+	counts, err := LoadSnapshot(paths.snapshot)
+	report("LoadSnapshot", err)
+	for word, u := range counts {
+		p.lastUsed[word] = u.last
+	}
+	for _, lw := range p.history {
+		p.lastUsed[lw.word] = lw.at
+	}
+
+	p.learnLog, err = OpenLearnLog(paths.learnLog)
+	report("OpenLearnLog", err)
+	p.events, err = OpenEventLog(paths.events)
+	report("OpenEventLog", err)
+	p.flushed = time.Now()
+	p.trainedEvents = fileSize(paths.events)
+	return p, problems
+}
+
+// Records that word was typed at
+func (p *profile) learn(word string, at time.Time) {
+	p.undo = LearnedWord{word, p.lastUsed[word]}
+	p.lastUsed[word] = at
+	if p.learnLog != nil {
+		if err := p.learnLog.Append(word, at); err != nil {
+			diagnostics.Addf("learning %s failed: %v", word, err)
+		}
+	}
+}
+
+// Takes back the last learn, of word. Fails once the learn log was compacted
+func (p *profile) unlearn(word string) error {
+	if p.undo.word != word {
+		return fmt.Errorf("%s was not learned last", word)
+	}
+	if p.learnLog != nil {
+		if err := p.learnLog.Undo(); err != nil {
+			return err
+		}
+	}
+	if p.undo.at.IsZero() {
+		delete(p.lastUsed, word)
+	} else {
+		p.lastUsed[word] = p.undo.at
+	}
+	p.phrases.Undo(word)
+	p.undo = LearnedWord{}
+	return nil
+}
+
+// Saves what is left of the session: the boosts, and the learn log merged into
+// the snapshots so the next start reads the counts instead of the whole log
+func (p *profile) Close() {
+	if p.boostsChanged {
+		p.boosts.Save(p.paths.boosts)
+	}
+	if p.learnLog != nil {
+		if fileSize(p.paths.learnLog) > 0 {
+			if _, err := p.learnLog.Compact(p.paths.snapshot, p.paths.phrases, p.tombstones, PrunePolicy{}); err != nil {
+				diagnostics.Addf("flushing learned data failed: %v", err)
+			}
+		}
+		p.learnLog.Close()
+	}
+	if p.events != nil {
+		p.events.Close()
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// Words learned in one session are there in the next, from the snapshot
+func TestProfilePersists(t *testing.T) {
+	p := pathsIn(t.TempDir())
+	prof, problems := openProfile(p)
+	if len(problems) > 0 {
+		t.Fatal(problems)
+	}
+	for _, word := range []string{"gopher", "gopher", "kubectl"} {
+		prof.learn(word, time.Now())
+	}
+	prof.Close()
+	if fileSize(p.learnLog) != 0 {
+		t.Fatal("learn log not merged on exit")
+	}
+
+	prof, _ = openProfile(p)
+	defer prof.Close()
+	loaded, _, problems := loadTrie(nil, false, false, NewVerifier(defaultConfig()), p.snapshot, prof.tombstones, prof.history, defaultConfig().Tokens)
+	if loaded.Count("gopher") != 2 || loaded.Count("kubectl") != 1 {
+		t.Fatalf("next session counts gopher %d, kubectl %d (%v)", loaded.Count("gopher"), loaded.Count("kubectl"), problems)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/b0tShaman/autocomplete-cli/trie"
+)
+
+// A directory with one of these files is a project. Launched anywhere below it, the
+// editor layers the project's words and snippets over the global ones
+const (
+	projectFile         = ".autocomplete.toml"
+	projectWordsFile    = ".autocomplete-words.txt"    // default project dictionary, same format as words.txt
+	projectSnippetsFile = ".autocomplete-snippets.txt" // default project snippets, same format as snippets.txt
+)
+
+// Contents of .autocomplete.toml, paths are relative to the project directory
+type ProjectConfig struct {
+	Dictionary string `toml:"dictionary"`
+	Snippets   string `toml:"snippets"`
+}
+
+// Words and snippets of the project the editor was launched in
+type Project struct {
+	dir      string
+	words    []string
+	snippets Snippets
+}
+
+// Returns the closest directory from dir upwards holding a project file, "" if none
+func findProject(dir string) string {
+	for {
+		for _, name := range []string{projectFile, projectWordsFile} {
+			if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+				return dir
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// Loads the project around the current directory, nil when there is none or
+// projects are turned off. Unreadable files are returned as problems
+func openProject(cfg Config) (*Project, []string) {
+	if !cfg.Projects {
+		return nil, nil
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, nil
+	}
+	dir := findProject(cwd)
+	if dir == "" {
+		return nil, nil
+	}
+
+	var problems []string
+	pc := ProjectConfig{Dictionary: projectWordsFile, Snippets: projectSnippetsFile}
+	if _, err := toml.DecodeFile(filepath.Join(dir, projectFile), &pc); err != nil && !os.IsNotExist(err) {
+		problems = append(problems, fmt.Sprintf("project %s: %v", dir, err))
+	}
+	inProject := func(path string) string {
+		if filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(dir, path)
+	}
+
+	p := &Project{dir: dir}
+	if data, err := os.ReadFile(inProject(pc.Dictionary)); err == nil {
+		p.words, _ = parseDictionary(string(data))
+	} else if !os.IsNotExist(err) {
+		problems = append(problems, fmt.Sprintf("project dictionary unavailable: %v", err))
+	}
+	p.snippets, err = LoadSnippets(inProject(pc.Snippets))
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("project snippets unavailable: %v", err))
+	}
+	return p, problems
+}
+
+// Adds the project words to the session layer of t, except the forgotten ones.
+// Nothing on a nil project
+func (p *Project) Layer(t *trie.Stack, tombstones Tombstones) {
+	if p == nil {
+		return
+	}
+	for _, word := range p.words {
+		if !tombstones.Buried(word, time.Time{}) {
+			t.Insert(sessionLayer, word)
+		}
+	}
+}
+
+// The global snippets with the project's on top
+func (p *Project) Snippets(global Snippets) Snippets {
+	if p == nil || len(p.snippets) == 0 {
+		return global
+	}
+	merged := make(Snippets, len(global)+len(p.snippets))
+	maps.Copy(merged, global)
+	maps.Copy(merged, p.snippets)
+	return merged
+}
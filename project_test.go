@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/b0tShaman/autocomplete-cli/trie"
+)
+
+// The closest directory upwards with a project file is the project
+func TestFindProject(t *testing.T) {
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, "app", "src", "deep"), 0755)
+	os.WriteFile(filepath.Join(root, projectFile), nil, 0644)
+	os.WriteFile(filepath.Join(root, "app", projectWordsFile), []byte("widget\n"), 0644)
+
+	for dir, want := range map[string]string{
+		root:                       root,
+		filepath.Join(root, "app"): filepath.Join(root, "app"),
+		filepath.Join(root, "app", "src", "deep"): filepath.Join(root, "app"),
+	} {
+		if got := findProject(dir); got != want {
+			t.Errorf("project of %s: %q, want %q", dir, got, want)
+		}
+	}
+}
+
+// Launched below a project the editor gets its words and snippets, from the
+// files its .autocomplete.toml names
+func TestOpenProject(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "src")
+	os.MkdirAll(sub, 0755)
+	os.WriteFile(filepath.Join(root, projectFile), []byte(`snippets = "snips.txt"`), 0644)
+	os.WriteFile(filepath.Join(root, projectWordsFile), []byte("widget\ngadget\n"), 0644)
+	os.WriteFile(filepath.Join(root, "snips.txt"), []byte("wdg\twidget gadget\n"), 0644)
+	cwd, _ := os.Getwd()
+	os.Chdir(sub)
+	t.Cleanup(func() { os.Chdir(cwd) })
+
+	cfg := defaultConfig()
+	cfg.Projects = true
+	p, problems := openProject(cfg)
+	if len(problems) > 0 || p == nil || p.dir != root {
+		t.Fatalf("project %+v, problems %q", p, problems)
+	}
+	if !slices.Equal(p.words, []string{"widget", "gadget"}) || p.snippets["wdg"] != "widget gadget" {
+		t.Errorf("words %v, snippets %v", p.words, p.snippets)
+	}
+
+	// A forgotten project word is left out
+	s := newWords(trie.New())
+	p.Layer(s, Tombstones{"gadget": time.Now()})
+	if s.Count("widget") != 1 || s.Count("gadget") != 0 {
+		t.Errorf("layered %v", s.Layer(sessionLayer).Words())
+	}
+
+	os.WriteFile(filepath.Join(root, projectFile), []byte(`snippets = `), 0644)
+	if _, problems := openProject(cfg); len(problems) == 0 {
+		t.Error("a broken .autocomplete.toml is no problem")
+	}
+	cfg.Projects = false
+	if p, _ := openProject(cfg); p != nil {
+		t.Errorf("projects turned off, got %+v", p)
+	}
+}
+
+// Project snippets override the global ones with the same abbreviation
+func TestProjectSnippets(t *testing.T) {
+	global := Snippets{"brb": "be right back", "ty": "thank you"}
+	p := &Project{snippets: Snippets{"ty": "thank you kindly", "lgtm": "looks good to me"}}
+	merged := p.Snippets(global)
+	if len(merged) != 3 || merged["ty"] != "thank you kindly" || merged["brb"] != "be right back" || merged["lgtm"] == "" {
+		t.Errorf("merged %v", merged)
+	}
+	if global["ty"] != "thank you" || len(global) != 2 {
+		t.Errorf("the global snippets changed: %v", global)
+	}
+	if got := (*Project)(nil).Snippets(global); len(got) != 2 {
+		t.Errorf("without a project: %v", got)
+	}
+}
@@ -0,0 +1,224 @@
+// Package prompt completes the word being typed in a line with ghost text:
+// the rest of a suggestion shows after the cursor, TAB cycles through the
+// suggestions and Enter accepts the shown one. Where the suggestions come
+// from is up to the host application, which passes a Source. The autocomplete
+// editor shows, cycles and accepts its own suggestions the same way.
+//
+//	line := prompt.New(func(previous []string, word string) []prompt.Candidate {
+//		return []prompt.Candidate{{Word: word + "lo"}}
+//	})
+//	for _, key := range "hel\t\r" {
+//		if line.Key(key) {
+//			break
+//		}
+//		line.Render(os.Stdout)
+//	}
+//
+// A Line is not safe for concurrent use
+package prompt
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Keys Line.Key handles, as a terminal in raw mode sends them
+const (
+	KeyTab       = '\t'
+	KeyEnter     = '\r'
+	KeyEscape    = 27
+	KeyBackspace = 127
+	KeyCtrlH     = 8 // BACKSPACE of some terminals
+)
+
+// A suggestion for the word being typed
+type Candidate struct {
+	Word  string // replaces the word being typed when accepted
+	Label string // what it is, Eg:- a translation or where it came from; not shown by Line
+}
+
+// Returns the candidates for word, typed after the previous words of the line
+// (up to ContextWords, oldest first), the best first
+type Source func(previous []string, word string) []Candidate
+
+// Words before the current one passed to a Source
+const ContextWords = 5
+
+// One line being typed and the suggestions for its last word
+type Line struct {
+	Style string // SGR parameters of the ghost text, Eg:- "2" for dim, plain when empty
+
+	source      Source
+	input       []rune
+	suggestions []Candidate
+	index       int // of the shown suggestion
+}
+
+// Returns an empty line completing from source
+func New(source Source) *Line {
+	return &Line{source: source, Style: "2"}
+}
+
+// Handles one key: TAB shows the next suggestion, Enter accepts the shown one,
+// ESC drops the suggestions and BACKSPACE deletes a letter. Other keys are
+// typed, letters ask the Source again. Reports true for Enter while no
+// suggestion is shown, when the line is done
+func (l *Line) Key(key rune) (done bool) {
+	switch key {
+	case KeyTab:
+		l.Cycle(1)
+	case KeyEnter:
+		return !l.Accept()
+	case KeyEscape:
+		l.Dismiss()
+	case KeyBackspace, KeyCtrlH:
+		l.Backspace()
+		l.Suggest()
+	default:
+		if key < ' ' {
+			return false
+		}
+		l.Insert(key)
+		l.Suggest()
+	}
+	return false
+}
+
+// Types r, a space or newline ends the word and drops its suggestions
+func (l *Line) Insert(r rune) {
+	l.input = append(l.input, r)
+	if r == ' ' || r == '\n' {
+		l.Dismiss()
+	}
+}
+
+// Deletes the last letter
+func (l *Line) Backspace() {
+	if len(l.input) > 0 {
+		l.input = l.input[:len(l.input)-1]
+	}
+}
+
+// Asks the Source for the suggestions of the word being typed, none without a word
+func (l *Line) Suggest() {
+	l.Dismiss()
+	if word := CurrentWord(l.input); word != "" {
+		l.suggestions = l.source(PreviousWords(l.input, ContextWords), word)
+	}
+}
+
+// Shows the next suggestion, or with a negative n the previous one. Reports
+// whether there are any
+func (l *Line) Cycle(n int) bool {
+	if len(l.suggestions) == 0 {
+		return false
+	}
+	l.index = Cycle(l.index, len(l.suggestions), n)
+	return true
+}
+
+// Completes the word with the shown suggestion, reports whether one was shown
+func (l *Line) Accept() bool {
+	c, ok := l.Shown()
+	if ok {
+		l.input = Complete(l.input, c.Word)
+		l.Dismiss()
+	}
+	return ok
+}
+
+// Drops the suggestions
+func (l *Line) Dismiss() {
+	l.suggestions, l.index = nil, 0
+}
+
+// The suggestion shown, false when there is none
+func (l *Line) Shown() (Candidate, bool) {
+	if len(l.suggestions) == 0 {
+		return Candidate{}, false
+	}
+	return l.suggestions[l.index], true
+}
+
+// The suggestions of the word being typed, best first
+func (l *Line) Suggestions() []Candidate {
+	return l.suggestions
+}
+
+// The text typed
+func (l *Line) String() string {
+	return string(l.input)
+}
+
+// The ghost text of the shown suggestion in Style, empty when none is shown
+func (l *Line) Ghost() string {
+	c, ok := l.Shown()
+	if !ok {
+		return ""
+	}
+	ghost := GhostText(CurrentWord(l.input), c.Word)
+	if l.Style != "" && ghost != "" {
+		ghost = "\033[" + l.Style + "m" + ghost + "\033[0m"
+	}
+	return ghost
+}
+
+// Draws the line over the terminal row it is on and puts the cursor back
+// before the ghost text
+func (l *Line) Render(w io.Writer) error {
+	out := "\r\033[K" + string(l.input) + l.Ghost()
+	if c, ok := l.Shown(); ok {
+		if n := len([]rune(GhostText(CurrentWord(l.input), c.Word))); n > 0 {
+			out += fmt.Sprintf("\033[%dD", n)
+		}
+	}
+	_, err := io.WriteString(w, out)
+	return err
+}
+
+// The word being typed at the end of input
+// Eg:- this is a tes  --> tes
+func CurrentWord(input []rune) string {
+	i := len(input)
+	for i > 0 && input[i-1] != ' ' && input[i-1] != '\n' {
+		i--
+	}
+	return string(input[i:])
+}
+
+// Up to n words typed before the current one, oldest first
+// Eg:- so very hap, 2 --> [so very]
+func PreviousWords(input []rune, n int) []string {
+	before := input[:len(input)-len([]rune(CurrentWord(input)))]
+	words := strings.Fields(string(before))
+	return words[max(0, len(words)-n):]
+}
+
+// Replaces the word being typed at the end of input with word
+func Complete(input []rune, word string) []rune {
+	base := input[:len(input)-len([]rune(CurrentWord(input)))]
+	return append(append([]rune{}, base...), []rune(word)...)
+}
+
+// What is shown after the typed word for a suggestion: the rest of it, or the
+// whole suggestion when it does not start with the word in any casing.
+// Eg:- hel, hello --> lo and teh, the --> " → the"
+func GhostText(word, suggestion string) string {
+	if rest, ok := strings.CutPrefix(suggestion, word); ok {
+		return rest
+	}
+	if r, n := []rune(suggestion), len([]rune(word)); len(r) >= n && strings.EqualFold(string(r[:n]), word) {
+		return string(r[n:]) // cased differently, Eg:- th, The --> e
+	}
+	return " → " + suggestion
+}
+
+// The index of the suggestion n after index among count of them, before it
+// for a negative n, wrapping around. Eg:- 2, 3, 1 --> 0
+func Cycle(index, count, n int) int {
+	if count == 0 {
+		return 0
+	}
+	return ((index+n)%count + count) % count
+}
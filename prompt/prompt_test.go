@@ -0,0 +1,66 @@
+package prompt
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLine(t *testing.T) {
+	var asked [][]string
+	line := New(func(previous []string, word string) []Candidate {
+		asked = append(asked, append(slices.Clone(previous), word))
+		if strings.HasPrefix("hello", word) {
+			return []Candidate{{Word: "hello"}, {Word: "help"}}
+		}
+		return nil
+	})
+	line.Style = ""
+	for _, key := range "say he" {
+		line.Key(key)
+	}
+	if got := line.Ghost(); got != "llo" {
+		t.Errorf("ghost %q, want llo", got)
+	}
+	if !slices.Equal(asked[len(asked)-1], []string{"say", "he"}) {
+		t.Errorf("asked for %q", asked[len(asked)-1])
+	}
+
+	line.Key(KeyTab)
+	if got := line.Ghost(); got != "lp" {
+		t.Errorf("ghost after TAB %q, want lp", got)
+	}
+	line.Key(KeyTab)
+	if c, _ := line.Shown(); c.Word != "hello" {
+		t.Errorf("TAB did not wrap around, shows %q", c.Word)
+	}
+	if line.Key(KeyEnter) || line.String() != "say hello" {
+		t.Errorf("accepting gave %q", line.String())
+	}
+	if !line.Key(KeyEnter) {
+		t.Error("Enter without a suggestion did not end the line")
+	}
+
+	var out strings.Builder
+	line.Key(' ')
+	line.Key('h')
+	line.Render(&out)
+	if got := out.String(); got != "\r\033[Ksay hello hello\033[4D" {
+		t.Errorf("rendered %q", got)
+	}
+}
+
+func TestGhostText(t *testing.T) {
+	for _, tc := range []struct{ word, suggestion, want string }{
+		{"hel", "hello", "lo"},
+		{"th", "The", "e"},
+		{"teh", "the", " → the"},
+	} {
+		if got := GhostText(tc.word, tc.suggestion); got != tc.want {
+			t.Errorf("GhostText(%q, %q) = %q, want %q", tc.word, tc.suggestion, got, tc.want)
+		}
+	}
+	if got := Cycle(0, 3, -1); got != 2 {
+		t.Errorf("Cycle(0, 3, -1) = %d", got)
+	}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	rankerFile    = "ranker.json" // trained weights, next to the events log
+	rankerEpochs  = 300
+	rankerRate    = 0.1
+	rankerL2      = 0.001
+	recencyWindow = 30 * 24 * time.Hour // a word last used this long ago has recency 1/e
+)
+
+// Names of the features the ranker looks at, in the order of featureVector
+var rankerFeatures = []string{"bias", "frequency", "recency", "rank", "prefix", "trie", "snippet", "translation", "plugin", "t9", "model", "token", "hump", "pack"}
+
+// Logistic regression over the features of a candidate, predicting how likely it is accepted
+type Ranker struct {
+	Weights  map[string]float64 `json:"weights"`
+	Examples int                `json:"examples"` // candidates shown in the training data
+	Accepted int                `json:"accepted"`
+	Trained  time.Time          `json:"trained"`
+}
+
+// Features of a candidate shown at rank while prefix was typed. uses and last
+// describe how often and when the candidate was typed before
+func featureVector(source string, rank int, prefix string, uses int, last, now time.Time) []float64 {
+	f := make([]float64, len(rankerFeatures))
+	f[0] = 1
+	f[1] = math.Log1p(float64(uses))
+	if !last.IsZero() {
+		f[2] = math.Exp(-float64(now.Sub(last)) / float64(recencyWindow))
+	}
+	f[3] = float64(rank)
+	f[4] = float64(len([]rune(prefix)))
+	kind, _, _ := strings.Cut(source, ":") // plugin:<name>
+	for i, name := range rankerFeatures[5:] {
+		if kind == name {
+			f[5+i] = 1
+		}
+	}
+	return f
+}
+
+func sigmoid(x float64) float64 {
+	return 1 / (1 + math.Exp(-x))
+}
+
+// Returns the probability of a candidate with features f being accepted
+func (r *Ranker) Predict(f []float64) float64 {
+	var z float64
+	for i, name := range rankerFeatures {
+		z += r.Weights[name] * f[i]
+	}
+	return sigmoid(z)
+}
+
+// Reorders candidates by their predicted acceptance. usage reports how often
+// and when a word was typed before
+func (r *Ranker) Rerank(candidates []Candidate, prefix string, usage func(word string) (int, time.Time)) {
+	now := time.Now()
+	scores := make(map[string]float64, len(candidates))
+	for i, c := range candidates {
+		uses, last := usage(c.word)
+		scores[c.word+"\x00"+c.source] = r.Predict(featureVector(c.source, i, prefix, uses, last, now))
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return scores[candidates[i].word+"\x00"+candidates[i].source] > scores[candidates[j].word+"\x00"+candidates[j].source]
+	})
+}
+
+// A shown candidate and whether it was accepted
+type example struct {
+	features []float64
+	accepted bool
+}
+
+// Turns the events log into training examples. Every candidate shown is an example,
+// accepted if it was accepted before the next suggestions showed up
+func trainingExamples(events []Event) []example {
+	var examples []example
+	var pending []Event // shown since the last suggest
+	flush := func(accepted *Event) {
+		for _, e := range pending {
+			ok := accepted != nil && e.Word == accepted.Word && e.Rank == accepted.Rank
+			var last time.Time
+			if e.Last > 0 {
+				last = time.Unix(e.Last, 0)
+			}
+			examples = append(examples, example{featureVector(e.Source, e.Rank, e.Prefix, e.Uses, last, time.Unix(e.Time, 0)), ok})
+		}
+		pending = nil
+	}
+
+	for i := range events {
+		switch events[i].Kind {
+		case "suggest":
+			flush(nil)
+		case "shown":
+			// Cycling past the last candidate shows the first one again
+			if len(pending) == 0 || events[i].Rank > pending[len(pending)-1].Rank {
+				pending = append(pending, events[i])
+			}
+		case "accepted":
+			flush(&events[i])
+		}
+	}
+	flush(nil)
+	return examples
+}
+
+// Fits a ranker to examples with batch gradient descent
+func TrainRanker(examples []example) *Ranker {
+	w := make([]float64, len(rankerFeatures))
+	grad := make([]float64, len(w))
+	r := &Ranker{Weights: make(map[string]float64), Examples: len(examples), Trained: time.Now()}
+
+	for epoch := 0; epoch < rankerEpochs && len(examples) > 0; epoch++ {
+		clear(grad)
+		for _, ex := range examples {
+			var z float64
+			for i, x := range ex.features {
+				z += w[i] * x
+			}
+			diff := sigmoid(z)
+			if ex.accepted {
+				diff--
+			}
+			for i, x := range ex.features {
+				grad[i] += diff * x
+			}
+		}
+		for i := range w {
+			w[i] -= rankerRate * (grad[i]/float64(len(examples)) + rankerL2*w[i])
+		}
+	}
+
+	for _, ex := range examples {
+		if ex.accepted {
+			r.Accepted++
+		}
+	}
+	for i, name := range rankerFeatures {
+		r.Weights[name] = w[i]
+	}
+	return r
+}
+
+// Loads the trained ranker. Returns nil without error when none was trained yet
+func LoadRanker(path string) (*Ranker, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var r Ranker
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &r, nil
+}
+
+func (r *Ranker) Save(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (r *Ranker) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "trained %s on %d shown candidates, %d accepted\n", r.Trained.Format(time.DateTime), r.Examples, r.Accepted)
+	for _, name := range rankerFeatures {
+		fmt.Fprintf(&b, "%-12s %8.4f\n", name, r.Weights[name])
+	}
+	return b.String()
+}
+
+// autocomplete ranker [train|weights]
+// Retrains the ranker from the events log, or prints its weights
+func rankerCommand(args []string) error {
+	action := "weights"
+	if len(args) > 0 {
+		action = args[0]
+	}
+
+	switch action {
+	case "train":
+		events, err := ReadEvents(paths.events)
+		if err != nil {
+			return err
+		}
+		examples := trainingExamples(events)
+		if len(examples) == 0 {
+			return fmt.Errorf("no suggestions in %s yet", paths.events)
+		}
+		r := TrainRanker(examples)
+		if err := r.Save(paths.ranker); err != nil {
+			return err
+		}
+		fmt.Print(r)
+	case "weights":
+		r, err := LoadRanker(paths.ranker)
+		if err != nil {
+			return err
+		}
+		if r == nil {
+			return fmt.Errorf("no ranker trained yet, run: autocomplete ranker train")
+		}
+		fmt.Print(r)
+	default:
+		return fmt.Errorf("unknown ranker action %q, expected train or weights", action)
+	}
+	return nil
+}
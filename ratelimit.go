@@ -0,0 +1,128 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Clients not seen for this long are forgotten by the rate limiter
+const limiterIdle = 10 * time.Minute
+
+// Token bucket of one client
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limits the request rate of every client separately
+type limiter struct {
+	mu      sync.Mutex
+	rate    float64 // tokens added per second
+	burst   float64 // size of each bucket
+	clients map[string]*bucket
+	swept   time.Time
+}
+
+func newLimiter(rate float64, burst int) *limiter {
+	return &limiter{rate: rate, burst: float64(max(burst, 1)), clients: make(map[string]*bucket)}
+}
+
+// Takes a token from the client's bucket. Returns false when it is empty
+func (l *limiter) Allow(client string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.swept) > limiterIdle {
+		for c, b := range l.clients {
+			if now.Sub(b.last) > limiterIdle {
+				delete(l.clients, c)
+			}
+		}
+		l.swept = now
+	}
+
+	b := l.clients[client]
+	if b == nil {
+		b = &bucket{tokens: l.burst, last: now}
+		l.clients[client] = b
+	}
+	b.tokens = min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Bearer token of the request, empty if there is none
+func bearerToken(r *http.Request) string {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// Identifies the client for rate limiting, by its token when it is one of cfg's
+// or else by its address, so made up tokens do not each get a bucket
+func clientKey(cfg ServeConfig, r *http.Request) string {
+	if token := bearerToken(r); validToken(cfg.Tokens, token) || validToken(cfg.AdminTokens, token) {
+		return "token:" + token
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "addr:" + host
+}
+
+// Wraps next with the authentication, rate and concurrency limits of cfg
+func guard(cfg ServeConfig, next http.Handler) http.Handler {
+	var limit *limiter
+	if cfg.Rate > 0 {
+		limit = newLimiter(cfg.Rate, cfg.Burst)
+	}
+	var slots chan struct{}
+	if cfg.MaxConcurrent > 0 {
+		slots = make(chan struct{}, cfg.MaxConcurrent)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(cfg.Tokens) > 0 && !validToken(cfg.Tokens, bearerToken(r)) && !validToken(cfg.AdminTokens, bearerToken(r)) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "missing or invalid token"})
+			return
+		}
+		if limit != nil && !limit.Allow(clientKey(cfg, r), time.Now()) {
+			w.Header().Set("Retry-After", "1")
+			writeJSON(w, http.StatusTooManyRequests, map[string]string{"error": "rate limit exceeded"})
+			return
+		}
+		if slots != nil {
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+			default:
+				writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "too many concurrent requests"})
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Compares in constant time so tokens cannot be guessed from response times
+func validToken(tokens []string, token string) bool {
+	valid := false
+	for _, t := range tokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			valid = true
+		}
+	}
+	return valid && token != ""
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Clients are rate limited by their token only once it is a valid one
+func TestRateLimit(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	get := func(h http.Handler, token string) int {
+		r := httptest.NewRequest("GET", "/suggest?word=he", nil)
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	open := guard(ServeConfig{Rate: 1, Burst: 2}, ok)
+	for i, want := range []int{200, 200, 429} {
+		if code := get(open, fmt.Sprint("made-up-", i)); code != want {
+			t.Errorf("request %d with a new made up token: %d, want %d", i, code, want)
+		}
+	}
+
+	tokens := guard(ServeConfig{Rate: 1, Burst: 1, Tokens: []string{"a", "b"}}, ok)
+	for _, token := range []string{"a", "b"} {
+		if code := get(tokens, token); code != 200 {
+			t.Errorf("first request of %s: %d", token, code)
+		}
+	}
+	if code := get(tokens, "a"); code != 429 {
+		t.Errorf("second request of a: %d", code)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Completions accepted this session listed by the history panel, most recent first
+const maxRecalled = 9
+
+// Remembers an accepted completion for the history panel
+func (e *Editor) remember(word string) {
+	if i := slices.Index(e.accepted, word); i >= 0 {
+		e.accepted = slices.Delete(e.accepted, i, i+1)
+	}
+	e.accepted = slices.Insert(e.accepted, 0, word)
+	e.accepted = e.accepted[:min(len(e.accepted), maxRecalled)]
+}
+
+// Opens the history panel, or closes it when it is open
+func (e *Editor) togglePanel() {
+	e.dismiss()
+	e.stopTimer()
+	if e.panel >= 0 {
+		e.panel = -1
+		e.status(e.idleStatus())
+		return
+	}
+	if len(e.accepted) == 0 {
+		e.status("nothing accepted yet this session")
+		return
+	}
+	e.panel = 0
+	e.status(panelStatus(e.accepted, e.panel))
+}
+
+// Status line numbering the completions, with the highlighted one in reverse video
+func panelStatus(words []string, current int) string {
+	var b strings.Builder
+	for i, word := range words {
+		if i > 0 {
+			b.WriteString(menuSeparator)
+		}
+		if i == current {
+			b.WriteString(menuCurrent)
+		}
+		fmt.Fprintf(&b, "%d %s", i+1, word)
+		if i == current {
+			b.WriteString("\033[27m")
+		}
+	}
+	return b.String()
+}
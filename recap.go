@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// How the session recap printed on exit goes
+const (
+	recapShort = "short" // the counts and the first new words
+	recapFull  = "full"  // every new word, one per line
+	recapOff   = "off"
+)
+
+// New words the short recap names, the rest are only counted
+const recapWords = 10
+
+// What was typed this session, for the recap printed on exit
+type Recap struct {
+	typed    int      // words typed, learned or not
+	accepted int      // completions accepted
+	learned  []string // words learned for the first time, in the order they were typed
+}
+
+// Notes that word is learned for the first time
+func (r *Recap) Learned(word string) {
+	if !slices.Contains(r.learned, word) {
+		r.learned = append(r.learned, word)
+	}
+}
+
+// Drops word from the new words, it was taken back or forgotten
+func (r *Recap) Unlearned(word string) {
+	if i := slices.Index(r.learned, word); i >= 0 {
+		r.learned = slices.Delete(r.learned, i, i+1)
+	}
+}
+
+// The recap for the mode of the recap config, saved to the data of the profile
+// in dir unless noLearn is set. Eg:-
+//
+//	Session: 120 words typed, 14 completions accepted, 2 new words learned: kubectl, grafana
+//	Learned data saved in /home/me/.local/share/autocomplete-cli
+func (r *Recap) String(mode, dir string, noLearn bool) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Session: %d words typed, %d completions accepted, ", r.typed, r.accepted)
+	switch {
+	case noLearn:
+		b.WriteString("nothing learned (no_learn)\n")
+		return b.String()
+	case len(r.learned) == 0:
+		b.WriteString("no new words learned\n")
+	case mode == recapFull:
+		fmt.Fprintf(&b, "%d new words learned:\n", len(r.learned))
+		for _, word := range r.learned {
+			fmt.Fprintf(&b, "  %s\n", word)
+		}
+	default:
+		fmt.Fprintf(&b, "%d new words learned: %s", len(r.learned), strings.Join(r.learned[:min(len(r.learned), recapWords)], ", "))
+		if more := len(r.learned) - recapWords; more > 0 {
+			fmt.Fprintf(&b, " and %d more (recap = \"full\" lists them)", more)
+		}
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "Learned data saved in %s\n", dir)
+	return b.String()
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"maps"
+	"math"
+	"time"
+
+	"github.com/b0tShaman/autocomplete-cli/trie"
+)
+
+// Scoring layer used to rank suggestions by usage. A word pasted thousands of
+// times would otherwise permanently beat everything else, and a word used a lot
+// last year everything used today
+type Scoring struct {
+	cap      int           // counts above cap rank the same as cap, 0 disables capping
+	log      bool          // compare log-scaled counts so large differences matter less
+	recency  float64       // weight of a use right now against the count, 0 ranks by count alone
+	halfLife time.Duration // after which a use weighs half as much
+}
+
+// The scoring without a [scoring] section. Never changed, Config.apply sets scoring
+var defaultScoring = Scoring{cap: 1000, log: true, recency: 2, halfLife: 7 * 24 * time.Hour}
+
+// The scoring of the config in use, see Config.apply
+var scoring = defaultScoring
+
+// Returns the ranking score of a word used count times
+func (s Scoring) Score(count int) float64 {
+	if s.cap > 0 && count > s.cap {
+		count = s.cap
+	}
+	if s.log {
+		return math.Log1p(float64(count))
+	}
+	return float64(count)
+}
+
+// Returns the frecency score of a word used count times, last at last: the
+// usage score raised by up to recency times for a use right now, by half of that
+// a half-life ago. Eg:- 20 uses today beat 500 a year ago
+func (s Scoring) Frecency(count int, last time.Time) float64 {
+	score := s.Score(count)
+	if s.recency <= 0 || last.IsZero() {
+		return score
+	}
+	age := max(0, time.Since(last))
+	return score * (1 + s.recency*math.Exp2(-float64(age)/float64(s.halfLife)))
+}
+
+// Ranks completions by frecency, adjusted by what was learned from ignored
+// suggestions, with the last uses and boosts of the profile completed for.
+// Eg:- t.AutofillTop(word, k, prof.score)
+func (p *profile) score(word string, count int) float64 {
+	return scoring.Frecency(count, p.lastUsed[word]) * p.boosts.Factor(word)
+}
+
+// Like p.score with s instead of the scoring of the config, Eg:- that of an
+// experiment arm
+func (p *profile) scorer(s Scoring) trie.Scorer {
+	return func(word string, count int) float64 {
+		return s.Frecency(count, p.lastUsed[word]) * p.boosts.Factor(word)
+	}
+}
+
+// p.score as of now, for ranking in another goroutine while the scoring, the
+// last uses and the boosts change
+func (p *profile) frozenScore() trie.Scorer {
+	s, used, b := scoring, maps.Clone(p.lastUsed), p.boosts.Clone()
+	return func(word string, count int) float64 {
+		return s.Frecency(count, used[word]) * b.Factor(word)
+	}
+}
+
+// Ranks words by usage alone
+func usageScore(word string, count int) float64 {
+	return scoring.Score(count)
+}
+
+// Rescales counts so the largest becomes limit while keeping their order, using
+// log-scaling so that rarely used words keep a meaningful share. Counts are left
+// untouched when none exceeds limit
+func Rebalance(counts Snapshot, limit int) {
+	var largest int
+	for _, u := range counts {
+		largest = max(largest, u.count)
+	}
+	if largest <= limit {
+		return
+	}
+
+	for word, u := range counts {
+		scaled := math.Round(float64(limit) * math.Log1p(float64(u.count)) / math.Log1p(float64(largest)))
+		u.count = max(1, int(scaled))
+		counts[word] = u
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// One character on screen and the SGR parameters it is drawn with, "" for none
+type cell struct {
+	r     rune
+	style string
+}
+
+// What render() last drew, from where the editor started drawing, so the next
+// frame only has to move the cursor to the characters which changed and redraw
+// those. Rows leave the last column of the terminal empty, breaking to the next
+// row ourselves, so the terminal never wraps and the position stays known
+type screen struct {
+	rows     [][]cell
+	row, col int // where the cursor is
+	width    int // of the terminal when the rows were drawn, 0 if unknown
+	started  bool
+}
+
+// Returns the output which turns the screen into frame f on a terminal width
+// columns wide, 0 for no limit
+func (s *screen) draw(f frame, width int) string {
+	var b strings.Builder
+	if !s.started {
+		b.WriteString("\r") // whatever was printed before, the editor starts on a new row
+		s.started = true
+	}
+	if width != s.width {
+		// The terminal rewrapped the old rows, start over from the top of them
+		s.moveTo(&b, 0, 0)
+		b.WriteString("\033[J")
+		s.rows, s.width = nil, width
+	}
+
+	rows, row, col := layout(f, width)
+	for i := 0; i < max(len(rows), len(s.rows)); i++ {
+		var old, new []cell
+		if i < len(s.rows) {
+			old = s.rows[i]
+		}
+		if i < len(rows) {
+			new = rows[i]
+		}
+		same := 0
+		for same < min(len(old), len(new)) && old[same] == new[same] {
+			same++
+		}
+		if same == len(old) && same == len(new) {
+			continue
+		}
+		s.moveTo(&b, i, columns(new[:same]))
+		style := ""
+		for _, c := range new[same:] {
+			if c.style != style {
+				if style != "" {
+					b.WriteString("\033[0m")
+				}
+				if c.style != "" {
+					b.WriteString("\033[" + c.style + "m")
+				}
+				style = c.style
+			}
+			b.WriteRune(c.r)
+			s.col += runeWidth(c.r)
+		}
+		if style != "" {
+			b.WriteString("\033[0m")
+		}
+		if columns(new) < columns(old) {
+			b.WriteString("\033[K")
+		}
+	}
+	s.moveTo(&b, row, col)
+	s.rows = rows
+	return b.String()
+}
+
+// Moves the cursor relative to where it is. Going down uses new lines, which
+// scroll the terminal when the rows do not fit yet
+func (s *screen) moveTo(b *strings.Builder, row, col int) {
+	if row < s.row {
+		fmt.Fprintf(b, "\033[%dA", s.row-row)
+	}
+	for ; s.row < row; s.row++ {
+		b.WriteString("\r\n")
+		s.col = 0
+	}
+	if col < s.col {
+		fmt.Fprintf(b, "\033[%dD", s.col-col)
+	} else if col > s.col {
+		fmt.Fprintf(b, "\033[%dC", col-s.col)
+	}
+	s.row, s.col = row, col
+}
+
+// Lays frame f out in rows of less than width columns, 0 for no limit: the
+// text, the ghost text at the cursor, the text after the cursor and the status
+// on the row after that. Returns the rows and where the cursor goes
+func layout(f frame, width int) (rows [][]cell, row, col int) {
+	rows = [][]cell{nil}
+	add := func(cells []cell) {
+		for _, c := range cells {
+			if c.r == '\n' {
+				rows = append(rows, nil)
+				continue
+			}
+			if last := rows[len(rows)-1]; width > 1 && len(last) > 0 && columns(last)+runeWidth(c.r) > width-1 {
+				rows = append(rows, nil)
+			}
+			rows[len(rows)-1] = append(rows[len(rows)-1], c)
+		}
+	}
+	add(styled(f.text, ""))
+	row, col = len(rows)-1, columns(rows[len(rows)-1])
+	add(styled(f.ghost, ""))
+	add(styled(f.after, ""))
+	if f.status != "" {
+		for _, line := range strings.Split(f.status, "\n") {
+			rows = append(rows, nil)
+			add(styled(fitWidth(line), *statusStyle.Load()))
+		}
+	}
+	for len(rows) > row+1 && len(rows[len(rows)-1]) == 0 {
+		rows = rows[:len(rows)-1]
+	}
+	return rows, row, col
+}
+
+// Splits s into cells, applying its SGR escape sequences on top of style.
+// Eg:- a\033[7mb\033[0m, 2 --> a with 2 and b with 2;7
+func styled(s, style string) []cell {
+	var cells []cell
+	current := style
+	r := []rune(s)
+	for i := 0; i < len(r); {
+		n := escapeLen(r[i:])
+		if n == 1 {
+			cells = append(cells, cell{r[i], current})
+		} else if params, ok := strings.CutPrefix(string(r[i:i+n]), "\033["); ok && strings.HasSuffix(params, "m") {
+			switch params = strings.TrimSuffix(params, "m"); {
+			case params == "" || params == "0":
+				current = style
+			case current == "":
+				current = params
+			default:
+				current += ";" + params
+			}
+		}
+		i += n
+	}
+	return cells
+}
+
+// Columns taken by cells
+func columns(cells []cell) int {
+	var n int
+	for _, c := range cells {
+		n += runeWidth(c.r)
+	}
+	return n
+}
+
+// Columns a rune takes on a terminal: none for combining marks, two for the
+// wide East Asian characters and emoji. Eg:- é --> 1, 日 --> 2
+func runeWidth(r rune) int {
+	switch {
+	case unicode.Is(unicode.Mn, r):
+		return 0
+	case r >= 0x1100 && r <= 0x115F, r >= 0x2E80 && r <= 0xA4CF, r >= 0xAC00 && r <= 0xD7A3,
+		r >= 0xF900 && r <= 0xFAFF, r >= 0xFE30 && r <= 0xFE4F, r >= 0xFF00 && r <= 0xFF60,
+		r >= 0xFFE0 && r <= 0xFFE6, r >= 0x1F300 && r <= 0x1F64F, r >= 0x1F900 && r <= 0x1F9FF,
+		r >= 0x20000 && r <= 0x3FFFD:
+		return 2
+	}
+	return 1
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+)
+
+// Frames are drawn over the previous one, only what changed is printed
+func TestScreen(t *testing.T) {
+	style := "2"
+	statusStyle.Store(&style)
+	var s screen
+	if got := s.draw(frame{text: "hel", ghost: "\033[2mlo\033[0m"}, 20); got != "\r\033[Jhel\033[2mlo\033[0m\033[2D" {
+		t.Fatalf("first frame %q", got)
+	}
+	if got := s.draw(frame{text: "help"}, 20); got != "p\033[K" {
+		t.Fatalf("typing %q", got)
+	}
+	if got := s.draw(frame{text: "help", status: "used 3 times"}, 20); got != "\r\n\033[2mused 3 times\033[0m\033[1A\033[8D" {
+		t.Fatalf("status %q", got)
+	}
+	if got := s.draw(frame{text: "help"}, 20); got != "\r\n\033[K\033[1A\033[4C" {
+		t.Fatalf("clearing the status %q", got)
+	}
+	// Rows break before the last column
+	if got := s.draw(frame{text: "help 日本"}, 8); got != "\033[4D\033[Jhelp 日\r\n本" {
+		t.Fatalf("wrapped %q", got)
+	}
+}
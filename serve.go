@@ -0,0 +1,439 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/b0tShaman/autocomplete-cli/trie"
+)
+
+// HTTP completion service for other applications
+//
+//	GET  /suggest?word=he&previous=so,very   --> {"candidates": [{"word": "hello", "label": "", "source": "trie"}]}
+//	POST /learn {"word": "hello"}
+//	POST /admin/reload, /admin/snapshot, /admin/restore, see handleAdmin
+//
+// Plugins are not consulted, they answer one lookup at a time
+type ServeConfig struct {
+	Listen        string   `toml:"listen"`         // address to listen on
+	Tokens        []string `toml:"tokens"`         // accepted bearer tokens, anyone may connect when empty
+	Rate          float64  `toml:"rate"`           // requests per second per client, 0 for no limit
+	Burst         int      `toml:"burst"`          // requests a client may make at once before the rate applies
+	MaxConcurrent int      `toml:"max_concurrent"` // requests handled at the same time, others get 503
+	AdminTokens   []string `toml:"admin_tokens"`   // bearer tokens for /admin, only local clients may use it when empty
+	Team          bool     `toml:"team"`           // collect the counts of team members under /team, see TeamConfig
+	TeamMembers   int      `toml:"team_members"`   // members who must use a word before it is shared with the team
+	Trace         string   `toml:"trace"`          // OTLP/HTTP endpoint of an OpenTelemetry collector to send spans to, Eg:- "http://localhost:4318", see tracer
+	TraceSample   float64  `toml:"trace_sample"`   // share of the requests traced, of those whose caller did not decide
+}
+
+func (s ServeConfig) validate() error {
+	if s.Rate < 0 || s.Burst < 0 || s.MaxConcurrent < 0 {
+		return fmt.Errorf("serve: rate, burst and max_concurrent must not be negative")
+	}
+	if s.TeamMembers < 1 {
+		return fmt.Errorf("serve: team_members must be at least 1")
+	}
+	if u, err := url.Parse(s.Trace); s.Trace != "" && (err != nil || u.Scheme != "http" && u.Scheme != "https" || u.Host == "") {
+		return fmt.Errorf("serve: trace %q is not an http or https URL", s.Trace)
+	}
+	if s.TraceSample < 0 || s.TraceSample > 1 {
+		return fmt.Errorf("serve: trace_sample must be between 0 and 1")
+	}
+	return nil
+}
+
+// Clients identify themselves with an X-Client-ID header (or a client query
+// parameter) and each gets its own learned data in clients/<id> of the data
+// directory. Requests without one use the configured profile. Only requests
+// with a valid token add a client which has no directory yet
+const maxClients = 256
+
+// Clients not used for this long are saved and unloaded to make room for others
+const tenantIdle = 10 * time.Minute
+
+var clientID = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// The engine behind the HTTP service
+type server struct {
+	cfg    Config
+	shared atomic.Pointer[dictionaries] // swapped as a whole by reload
+
+	mu      sync.Mutex         // guards clients and evicted
+	clients map[string]*tenant // by client id, "" for the configured profile
+	evicted map[string]*tenant // being saved after they were unloaded
+	saving  sync.WaitGroup     // for the evicted clients
+
+	trace *tracer // nil unless serve.trace is set
+}
+
+// Word lists shared by all clients
+type dictionaries struct {
+	dictionary string     // path of the word list
+	base       *trie.Trie // its words below the learned ones of every client, nil until loaded
+	bi         Bilingual
+	meta       Metadata
+	packs      []*Pack
+}
+
+// Loads the dictionaries of cfg other than the word list itself, see loadBase
+func loadDictionaries(cfg Config, dictionary string, v *Verifier) (*dictionaries, []string) {
+	packs, problems := LoadPacks(cfg.Packs, v)
+	return &dictionaries{
+		dictionary: dictionary,
+		bi:         LoadBilingual(cfg.Translations, v),
+		meta:       LoadMetadata(dictionary, v),
+		packs:      packs,
+	}, problems
+}
+
+// Suggestions for word without the plugins and the editor's own sources (the
+// model is used, the ranker and experiments are not). The steps are spans of
+// sp, which is nil when not traced
+func (d *dictionaries) candidates(cfg Config, t *trie.Stack, prof *profile, previous []string, word string, sp *span) []Candidate {
+	if len([]rune(word)) < cfg.MinPrefix {
+		return nil // too short to be worth completing
+	}
+	step := sp.Child("lookup")
+	candidates := buildCandidates(t, prof.score, nil, d.bi, prof.snippets, nil, prof.model, TagRanking{d.meta, cfg.Tags}, previous, word)
+	candidates = matchCase(t, prof.score, cfg.MatchCase, cfg.MatchAccents, word, candidates)
+	step.Set("candidates", len(candidates))
+	step.End()
+
+	step = sp.Child("sources")
+	candidates = append(candidates, fuzzyCandidates(t, prof.score, cfg.Fuzzy, word, candidates)...)
+	candidates = append(candidates, leadCandidates(t, prof.score, word, cfg.tokenizer(), candidates)...)
+	candidates = append(candidates, packCandidates(d.packs, word, candidates)...)
+	if cfg.Casing && !cfg.codeMode() {
+		candidates = foldCase(t, prof.score, cfg.KeepCase, previous, word, candidates)
+	}
+	step.Set("candidates", len(candidates))
+	step.End()
+
+	step = sp.Child("rank")
+	defer step.End()
+	candidates = prof.ignores.Filter(word, candidates)
+	candidates = prof.typos.Correct(word, candidates)
+	candidates = preferSpelling(cfg.avoid, cfg.SpellingVariants == variantsHide, candidates)
+	candidates = pinCandidates(cfg.Pins, prof.pins, word, candidates)
+	candidates = shortPrefixGuard(t, prof.score, cfg.shortMargin(), word, candidates)
+	if cfg.MaxSuggestions > 0 {
+		candidates = candidates[:min(len(candidates), cfg.MaxSuggestions)]
+	}
+	step.Set("candidates", len(candidates))
+	return candidates
+}
+
+// Learned data of one client. Lookups share the lock, learning takes it alone
+type tenant struct {
+	mu   sync.RWMutex
+	trie *trie.Stack
+	prof *profile
+
+	ready chan struct{} // closed once trie and prof are loaded
+	saved chan struct{} // closed once prof is closed after eviction
+	users int           // requests using it, guarded by server.mu
+	used  time.Time     // last request, guarded by server.mu
+}
+
+// A tenant with its data loaded already
+func loadedTenant(t *trie.Stack, prof *profile) *tenant {
+	ready := make(chan struct{})
+	close(ready)
+	return &tenant{trie: t, prof: prof, ready: ready, saved: make(chan struct{}), used: time.Now()}
+}
+
+type candidateJSON struct {
+	Word   string `json:"word"`
+	Label  string `json:"label"`
+	Source string `json:"source"`
+}
+
+// Loads the dictionaries of cfg with dictionary as the word list
+func loadShared(cfg Config, dictionary string) (*dictionaries, []string) {
+	verifier := NewVerifier(cfg)
+	d, problems := loadDictionaries(cfg, dictionary, verifier)
+	var baseProblems []string
+	d.base, _, baseProblems = loadBase(cfg.dictSpecs(dictionary), cfg.Dawg, cfg.BuiltinWords, verifier)
+	problems = append(problems, baseProblems...)
+	if p := verifier.Problems(); p != "" {
+		problems = append(problems, "integrity: "+p)
+	}
+	return d, problems
+}
+
+func newServer(cfg Config) (*server, []string) {
+	d, problems := loadShared(cfg, cfg.Dictionary)
+	s := newServerWith(cfg, d)
+	// The configured profile is loaded right away so its problems show up
+	t, profProblems, _ := s.tenant("")
+	s.release(t)
+	return s, append(problems, profProblems...)
+}
+
+// A server answering from d, with no clients loaded yet
+func newServerWith(cfg Config, d *dictionaries) *server {
+	s := &server{cfg: cfg, clients: make(map[string]*tenant), evicted: make(map[string]*tenant)}
+	s.shared.Store(d)
+	return s
+}
+
+// Returns the learned data of the client, loading it on first use. Other
+// requests only wait for the loading when they are for the same client. Give
+// it back with release when done
+func (s *server) tenant(id string) (*tenant, []string, error) {
+	s.mu.Lock()
+	t := s.clients[id]
+	if t != nil {
+		t.users++
+		s.mu.Unlock()
+		<-t.ready
+		return t, nil, nil
+	}
+	s.evict(time.Now())
+	if len(s.clients) >= maxClients {
+		s.mu.Unlock()
+		return nil, nil, fmt.Errorf("too many clients")
+	}
+	t = &tenant{ready: make(chan struct{}), saved: make(chan struct{}), users: 1}
+	s.clients[id] = t
+	old := s.evicted[id]
+	s.mu.Unlock()
+
+	if old != nil {
+		<-old.saved // its learned data is on disk before it is read again
+	}
+	p := paths
+	if id != "" {
+		p = clientPaths(id)
+	}
+	prof, problems := openProfile(p)
+	loaded, trieProblems := layerWords(s.shared.Load().base, p.snapshot, prof.tombstones, prof.history, s.cfg.Tokens)
+	t.trie, t.prof = loaded, prof
+	close(t.ready)
+	return t, append(problems, trieProblems...), nil
+}
+
+// Gives back a tenant of the tenant method
+func (s *server) release(t *tenant) {
+	s.mu.Lock()
+	t.users--
+	t.used = time.Now()
+	s.mu.Unlock()
+}
+
+// Unloads the clients idle for tenantIdle, and when that leaves no room the one
+// idle the longest, saving their learned data in the background. The configured
+// profile stays. Called with s.mu held
+func (s *server) evict(now time.Time) {
+	var oldest *tenant
+	var oldestID string
+	for id, t := range s.clients {
+		if id == "" || t.users > 0 {
+			continue
+		}
+		if now.Sub(t.used) > tenantIdle {
+			s.unload(id, t)
+		} else if oldest == nil || t.used.Before(oldest.used) {
+			oldest, oldestID = t, id
+		}
+	}
+	if len(s.clients) >= maxClients && oldest != nil {
+		s.unload(oldestID, oldest)
+	}
+}
+
+func (s *server) unload(id string, t *tenant) {
+	delete(s.clients, id)
+	s.evicted[id] = t
+	s.saving.Add(1)
+	go func() {
+		defer s.saving.Done()
+		t.prof.Close()
+		close(t.saved)
+		s.mu.Lock()
+		if s.evicted[id] == t {
+			delete(s.evicted, id)
+		}
+		s.mu.Unlock()
+	}()
+}
+
+// Finds the client of the request, answering it with an error if there is none
+func (s *server) requestTenant(w http.ResponseWriter, r *http.Request) *tenant {
+	id := r.Header.Get("X-Client-ID")
+	if id == "" {
+		id = r.URL.Query().Get("client")
+	}
+	if id != "" && !clientID.MatchString(id) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "client id must be 1-64 letters, digits, _ or -"})
+		return nil
+	}
+	if token := bearerToken(r); id != "" && !validToken(s.cfg.Serve.Tokens, token) && !validToken(s.cfg.Serve.AdminTokens, token) {
+		if _, err := os.Stat(clientPaths(id).dir); err != nil {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "a valid token is needed to add a client"})
+			return nil
+		}
+	}
+	t, _, err := s.tenant(id)
+	if err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
+		return nil
+	}
+	return t
+}
+
+// Saves the learned data of every client, once handed over too
+func (s *server) Close() {
+	s.mu.Lock()
+	for _, t := range s.clients {
+		<-t.ready
+		t.prof.Close()
+	}
+	clear(s.clients)
+	s.mu.Unlock()
+	s.saving.Wait() // the evicted ones
+}
+
+func (s *server) handleSuggest(w http.ResponseWriter, r *http.Request) {
+	word := r.URL.Query().Get("word")
+	var previous []string
+	if p := r.URL.Query().Get("previous"); p != "" {
+		previous = strings.Split(p, ",")
+		previous = previous[max(0, len(previous)-contextWords):]
+	}
+
+	sp := s.trace.Start(r, "suggest")
+	defer sp.End()
+	sp.Set("word.length", len([]rune(word)))
+	sp.Set("previous", len(previous))
+	step := sp.Child("tenant")
+	t := s.requestTenant(w, r)
+	step.End()
+	if t == nil {
+		sp.Set("error", true)
+		return
+	}
+	defer s.release(t)
+	t.mu.RLock()
+	candidates := s.shared.Load().candidates(s.cfg, t.trie, t.prof, previous, word, sp)
+	t.mu.RUnlock()
+
+	step = sp.Child("encode")
+	defer step.End()
+	result := make([]candidateJSON, len(candidates))
+	for i, c := range candidates {
+		result[i] = candidateJSON{c.word, c.label, c.source}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"candidates": result})
+}
+
+func (s *server) handleLearn(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "use POST"})
+		return
+	}
+	var req struct {
+		Word string `json:"word"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Word) == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": `expected {"word": "..."}`})
+		return
+	}
+	if s.cfg.NoLearn || s.cfg.Tokens.Policy(req.Word) != tokenLearn {
+		writeJSON(w, http.StatusOK, map[string]bool{"learned": false})
+		return
+	}
+
+	t := s.requestTenant(w, r)
+	if t == nil {
+		return
+	}
+	defer s.release(t)
+	t.mu.Lock()
+	t.trie.Insert(userLayer, req.Word)
+	t.prof.learn(req.Word, time.Now())
+	t.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]bool{"learned": true})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// autocomplete serve [--takeover] [address]
+// Serves completions over HTTP until interrupted, or until a new daemon started
+// with --takeover replaces this one
+func serveCommand(args []string) error {
+	cfg, err := LoadConfig(configPath())
+	if err != nil {
+		return err
+	}
+	takeover := len(args) > 0 && args[0] == "--takeover"
+	if takeover {
+		args = args[1:]
+	}
+	if len(args) > 0 {
+		cfg.Serve.Listen = args[0]
+	}
+
+	var s *server
+	var ln net.Listener
+	var problems []string
+	if takeover {
+		if s, ln, problems, err = takeOver(cfg); err != nil {
+			return err
+		}
+	} else {
+		s, problems = newServer(cfg)
+		if ln, err = net.Listen("tcp", cfg.Serve.Listen); err != nil {
+			s.Close()
+			return err
+		}
+	}
+	defer s.Close()
+	s.trace = newTracer(cfg.Serve)
+	defer s.trace.Close() // after the last request, before the learned data is saved
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/suggest", s.handleSuggest)
+	mux.HandleFunc("/learn", s.handleLearn)
+	mux.Handle("/admin/", adminOnly(cfg.Serve, http.HandlerFunc(s.handleAdmin)))
+	if cfg.Serve.Team {
+		team, err := openTeamStore(inDataDir(teamStoreFile), cfg.Serve.TeamMembers)
+		if err != nil {
+			return err
+		}
+		mux.HandleFunc("/team/", team.handle)
+	}
+
+	srv := &http.Server{Handler: guard(cfg.Serve, mux)}
+	handedOver := make(chan error, 1)
+	if h, err := listenHandoff(handoffPath(cfg.Serve.Listen)); err != nil {
+		fmt.Println("no upgrades without downtime:", err)
+		handedOver <- nil
+	} else {
+		defer h.Close()
+		go func() { handedOver <- s.awaitHandoff(h, srv, ln) }()
+	}
+
+	fmt.Println("serving completions on", cfg.Serve.Listen)
+	if err := srv.Serve(ln); err != http.ErrServerClosed {
+		return err
+	}
+	return <-handedOver
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Only a valid token adds a serve mode client, and idle clients make room for new ones
+func TestServeClients(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", dir)
+	old := paths
+	paths = pathsIn(filepath.Join(dir, "profile"))
+	t.Cleanup(func() { paths = old })
+	cfg := defaultConfig()
+	cfg.Dictionary = filepath.Join(dir, "words.txt")
+	cfg.Serve.Tokens = []string{"secret"}
+	s, _ := newServer(cfg)
+	defer s.Close()
+
+	learn := func(id, token string) int {
+		r := httptest.NewRequest("POST", "/learn", strings.NewReader(`{"word": "gopher"}`))
+		r.Header.Set("X-Client-ID", id)
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		s.handleLearn(w, r)
+		return w.Code
+	}
+	if code := learn("alice", "made-up"); code != http.StatusForbidden {
+		t.Errorf("made up token added a client: %d", code)
+	}
+	if _, err := os.Stat(clientPaths("alice").dir); err == nil {
+		t.Error("made up token created a client directory")
+	}
+	if code := learn("alice", "secret"); code != 200 {
+		t.Errorf("valid token: %d", code)
+	}
+	if code := learn("alice", "made-up"); code != 200 {
+		t.Errorf("existing client: %d", code)
+	}
+
+	s.mu.Lock()
+	s.clients["alice"].used = time.Now().Add(-2 * tenantIdle)
+	s.mu.Unlock()
+	if code := learn("bob", "secret"); code != 200 {
+		t.Errorf("bob: %d", code)
+	}
+	s.saving.Wait()
+	if _, ok := s.clients["alice"]; ok {
+		t.Error("idle client stayed loaded")
+	}
+	alice, _, _ := s.tenant("alice")
+	defer s.release(alice)
+	if alice.trie.Count("gopher") != 2 {
+		t.Errorf("reloaded alice counts gopher %d times, want 2", alice.trie.Count("gopher"))
+	}
+}
+
+// Each client ranks by its own boosts and last uses, not those of the others
+func TestServeClientScoring(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", dir)
+	old := paths
+	paths = pathsIn(filepath.Join(dir, "profile"))
+	t.Cleanup(func() { paths = old })
+	cfg := defaultConfig()
+	cfg.Dictionary = filepath.Join(dir, "words.txt")
+	os.WriteFile(cfg.Dictionary, []byte("quokka\nquoll\n"), 0644)
+	s, _ := newServer(cfg)
+	defer s.Close()
+
+	alice, _, _ := s.tenant("alice")
+	defer s.release(alice)
+	bob, _, _ := s.tenant("bob")
+	defer s.release(bob)
+	alice.prof.boosts = Boosts{"quoll": {level: 3}}
+	bob.prof.lastUsed = map[string]time.Time{"quokka": time.Now()}
+	for _, tc := range []struct {
+		id   string
+		c    *tenant
+		want string
+	}{{"alice", alice, "quoll"}, {"bob", bob, "quokka"}} {
+		got := s.shared.Load().candidates(cfg, tc.c.trie, tc.c.prof, nil, "quo", nil)
+		if len(got) == 0 || got[0].word != tc.want {
+			t.Errorf("%s ranks %v, want %s first", tc.id, got, tc.want)
+		}
+	}
+}
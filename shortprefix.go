@@ -0,0 +1,35 @@
+package main
+
+import (
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/b0tShaman/autocomplete-cli/trie"
+)
+
+// Sources which know about the context of the typed letter, their suggestions
+// show after a single letter whatever they score. Eg:- the model predicting the
+// word after the previous ones
+var contextSources = map[string]bool{"model": true, "snippet": true, "pin": true, "typo": true, "next": true}
+
+// short_margin by profile name
+type ProfileMargins map[string]float64
+
+// The candidates for word, none when it is a single letter unless the first
+// one stands out: it comes from the context, or it scores at least margin times
+// as high as the next one. A guess from one letter is mostly wrong and the ghost
+// text gets in the way of typing. Eg:- after t, the used 12 times against the 10
+// uses of to shows nothing, used 30 times it shows. A margin of 0 turns the guard off
+func shortPrefixGuard(t *trie.Stack, score trie.Scorer, margin float64, word string, candidates []Candidate) []Candidate {
+	if r, size := utf8.DecodeRuneInString(word); margin <= 0 || size != len(word) || !unicode.IsLetter(r) || len(candidates) == 0 {
+		return candidates
+	}
+	if contextSources[candidates[0].source] || len(candidates) == 1 {
+		return candidates
+	}
+	first := score(candidates[0].word, t.Count(candidates[0].word))
+	if first > 0 && first >= margin*score(candidates[1].word, t.Count(candidates[1].word)) {
+		return candidates
+	}
+	return nil
+}
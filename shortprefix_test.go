@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+)
+
+// With min_prefix = 1 a single letter shows a suggestion only when it stands out
+func TestShortPrefixGuard(t *testing.T) {
+	h := newHarness(t, t.TempDir())
+	h.e.cfg.MinPrefix = 1
+	h.feed([]byte("w"))
+	h.pause()
+	if h.e.triggered {
+		t.Fatalf("suggested %v for w, world and word are used as often", h.e.suggestions)
+	}
+	h.e.trie.InsertCount(baseLayer, "world", 2)
+	h.feed([]byte("\x7fw"))
+	h.pause()
+	if !h.e.triggered || h.e.suggestions[0].word != "world" {
+		t.Fatalf("suggested %v for w, world stands out", h.e.suggestions)
+	}
+	h.e.cfg.ShortMargins = ProfileMargins{"default": 4}
+	h.feed([]byte("\x7fw"))
+	h.pause()
+	if h.e.triggered {
+		t.Fatalf("suggested %v for w below the margin of the profile", h.e.suggestions)
+	}
+	h.e.cfg.ShortMargins = ProfileMargins{"default": 0}
+	h.feed([]byte("\x7fh"))
+	h.pause()
+	if !h.e.triggered {
+		t.Fatal("no suggestion for h with the guard off")
+	}
+
+	// A snippet knows what it is for
+	h = newHarness(t, t.TempDir())
+	h.e.cfg.MinPrefix = 1
+	h.e.prof.snippets["w"] = "with"
+	h.feed([]byte("w"))
+	h.pause()
+	if !h.e.triggered || h.e.suggestions[0].source != "snippet" {
+		t.Fatalf("suggested %v for the snippet w", h.e.suggestions)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// There are no user signals, the channels never fire
+func notifyUserSignals() (dump, flush <-chan os.Signal) {
+	return nil, nil
+}
@@ -0,0 +1,18 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// SIGUSR1 dumps the engine statistics, SIGUSR2 flushes the learned data
+func notifyUserSignals() (dump, flush <-chan os.Signal) {
+	usr1 := make(chan os.Signal, 1)
+	usr2 := make(chan os.Signal, 1)
+	signal.Notify(usr1, syscall.SIGUSR1)
+	signal.Notify(usr2, syscall.SIGUSR2)
+	return usr1, usr2
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Pruning past size_guard.prune is tried again at most this often, in case it
+// cannot get below the limit
+const guardEvery = time.Hour
+
+// Limits on the learned data of a profile: the learn log, counts.txt and
+// phrases.txt, which grow with everything typed for as long as it is used
+type SizeGuardConfig struct {
+	Warn     int `toml:"warn"`      // MB past which the status line warns once, 0 for never
+	Prune    int `toml:"prune"`     // MB past which upkeep compacts with the policy below, 0 for never
+	MinCount int `toml:"min_count"` // pruning drops the words used fewer times than this ...
+	MaxAge   int `toml:"max_age"`   // ... and not within this many days
+}
+
+func (g SizeGuardConfig) validate() error {
+	if g.Warn < 0 || g.Prune < 0 || g.MinCount < 0 || g.MaxAge < 0 {
+		return fmt.Errorf("size_guard: warn, prune, min_count and max_age must not be negative")
+	}
+	return nil
+}
+
+// Which words pruning past the prune limit drops
+func (g SizeGuardConfig) policy() PrunePolicy {
+	return PrunePolicy{minCount: g.MinCount, maxAge: time.Duration(g.MaxAge) * 24 * time.Hour}
+}
+
+// Bytes used by the learned data of the profile at paths, as compact counts them
+func learnedSize(paths DataPaths) int64 {
+	return fileSize(paths.learnLog) + fileSize(paths.snapshot) + fileSize(paths.phrases)
+}
+
+func guardDue(e *Editor) bool {
+	g, size := e.cfg.SizeGuard, learnedSize(e.prof.paths)
+	warn := g.Warn > 0 && size > int64(g.Warn)<<20 && !e.prof.sizeWarned
+	prune := g.Prune > 0 && size > int64(g.Prune)<<20 && e.prof.learnLog != nil && time.Since(e.prof.pruned) >= guardEvery
+	return warn || prune
+}
+
+// Warns once the learned data is past the warn limit, then compacts it with
+// the policy of size_guard once it is past the prune limit
+func guardStep(e *Editor) maintenanceJob {
+	g, size := e.cfg.SizeGuard, learnedSize(e.prof.paths)
+	if g.Warn > 0 && size > int64(g.Warn)<<20 && !e.prof.sizeWarned {
+		e.prof.sizeWarned = true
+		advice := "run autocomplete compact or set size_guard.prune"
+		if g.Prune > 0 {
+			advice = fmt.Sprintf("it is pruned past %dMB", g.Prune)
+		}
+		diagnostics.Addf("learned data is %dMB, past size_guard.warn of %dMB: %s", size>>20, g.Warn, advice)
+		return nil
+	}
+	e.prof.pruned = time.Now() // not tried again this often either way
+	return flushJob(e.prof, g.policy(), "pruned")
+}
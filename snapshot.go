@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Snapshot of learned usage counts, one "word<TAB>count<TAB>last used unix time" per line
+const snapshotFile = "counts.txt"
+
+// How often and when a word was last used
+type Usage struct {
+	count int
+	last  time.Time
+}
+
+// Learned usage of every word
+type Snapshot map[string]Usage
+
+// Adds n uses of word made at time at
+func (s Snapshot) Add(word string, n int, at time.Time) {
+	u := s[word]
+	u.count += n
+	if at.After(u.last) {
+		u.last = at
+	}
+	s[word] = u
+}
+
+// Reads the snapshot. A missing snapshot is the same as an empty one
+func LoadSnapshot(path string) (Snapshot, error) {
+	counts := make(Snapshot)
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return counts, nil
+	} else if err != nil {
+		return counts, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) < 2 || fields[0] == "" {
+			continue
+		}
+		count, err := strconv.Atoi(fields[1])
+		if err != nil || count <= 0 {
+			continue
+		}
+		var last time.Time
+		if len(fields) > 2 {
+			if sec, err := strconv.ParseInt(fields[2], 10, 64); err == nil {
+				last = time.Unix(sec, 0)
+			}
+		}
+		counts.Add(fields[0], count, last)
+	}
+	return counts, scanner.Err()
+}
+
+// Writes counts to path, most used first. The file is replaced atomically so a
+// crash never leaves a half written snapshot behind
+func SaveSnapshot(path string, counts Snapshot) error {
+	words := make([]string, 0, len(counts))
+	for word := range counts {
+		words = append(words, word)
+	}
+	sort.Slice(words, func(i, j int) bool {
+		if counts[words[i]].count != counts[words[j]].count {
+			return counts[words[i]].count > counts[words[j]].count
+		}
+		return words[i] < words[j]
+	})
+
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, word := range words {
+		u := counts[word]
+		if u.last.IsZero() {
+			fmt.Fprintf(w, "%s\t%d\n", word, u.count)
+		} else {
+			fmt.Fprintf(w, "%s\t%d\t%d\n", word, u.count, u.last.Unix())
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// autocomplete rebalance [max count]
+// Renormalizes the stored counts so no word can dominate the rankings forever
+func rebalanceCommand(args []string) error {
+	limit := defaultScoring.cap
+	if len(args) > 0 {
+		if _, err := fmt.Sscan(args[0], &limit); err != nil || limit <= 0 {
+			return fmt.Errorf("invalid max count %q", args[0])
+		}
+	}
+
+	counts, err := LoadSnapshot(paths.snapshot)
+	if err != nil {
+		return err
+	}
+	Rebalance(counts, limit)
+	if err := SaveSnapshot(paths.snapshot, counts); err != nil {
+		return err
+	}
+	fmt.Printf("rebalanced %d words to a maximum count of %d\n", len(counts), limit)
+	return nil
+}
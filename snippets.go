@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Abbreviations expanding into longer text, one "abbreviation<TAB>expansion" per line
+const snippetsFile = "snippets.txt"
+
+// Maps an abbreviation to its expansion
+type Snippets map[string]string
+
+// Loads snippets from path. A missing file means no snippets
+func LoadSnippets(path string) (Snippets, error) {
+	snippets := make(Snippets)
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return snippets, nil
+	} else if err != nil {
+		return snippets, err
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		abbr, expansion, ok := strings.Cut(line, "\t")
+		if ok && abbr != "" && expansion != "" {
+			snippets[abbr] = expansion
+		}
+	}
+	return snippets, nil
+}
+
+// Writes all snippets to path, sorted by abbreviation
+func (s Snippets) Save(path string) error {
+	var b strings.Builder
+	for _, abbr := range s.sorted() {
+		fmt.Fprintf(&b, "%s\t%s\n", abbr, s[abbr])
+	}
+	return os.WriteFile(path, []byte(b.String()), 0644)
+}
+
+func (s Snippets) sorted() []string {
+	abbrs := make([]string, 0, len(s))
+	for abbr := range s {
+		abbrs = append(abbrs, abbr)
+	}
+	sort.Strings(abbrs)
+	return abbrs
+}
+
+// Reports whether phrase already has an abbreviation
+func (s Snippets) Has(phrase string) bool {
+	for _, expansion := range s {
+		if expansion == phrase {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns expansions of the abbreviations starting with word, exact match first
+func (s Snippets) Candidates(word string) []Candidate {
+	var result []Candidate
+	for _, abbr := range s.sorted() {
+		if !strings.HasPrefix(abbr, word) {
+			continue
+		}
+		c := Candidate{word: s[abbr], label: "snippet " + abbr, source: "snippet"}
+		if abbr == word {
+			result = append([]Candidate{c}, result...)
+		} else {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// Proposes an unused abbreviation for phrase built from the initials of its words
+// Eg:- kind regards, John --> krj
+func (s Snippets) Abbreviate(phrase string) string {
+	var initials []rune
+	for _, word := range strings.Fields(phrase) {
+		for _, r := range word {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				initials = append(initials, unicode.ToLower(r))
+				break
+			}
+		}
+	}
+
+	abbr := string(initials)
+	for i := 2; s[abbr] != ""; i++ {
+		abbr = fmt.Sprintf("%s%d", string(initials), i)
+	}
+	return abbr
+}
+
+// A frequently typed phrase offered to become a snippet
+type snippetProposal struct {
+	phrase string
+	abbr   string
+	count  int
+}
+
+func (p *snippetProposal) Status() string {
+	return fmt.Sprintf("you've typed '%s' %d times - CTRL+S to create abbreviation '%s'", p.phrase, p.count, p.abbr)
+}
+
+// autocomplete snippets [list | add <abbreviation> <expansion...> | remove <abbreviation> | discover [min uses]]
+func snippetsCommand(args []string) error {
+	snippets, err := LoadSnippets(paths.snippets)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+
+	switch args[0] {
+	case "list":
+		for _, abbr := range snippets.sorted() {
+			fmt.Printf("%s\t%s\n", abbr, snippets[abbr])
+		}
+		return nil
+
+	case "add":
+		if len(args) < 3 {
+			return fmt.Errorf("usage: snippets add <abbreviation> <expansion...>")
+		}
+		snippets[args[1]] = strings.Join(args[2:], " ")
+		return snippets.Save(paths.snippets)
+
+	case "remove":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: snippets remove <abbreviation>")
+		}
+		if _, ok := snippets[args[1]]; !ok {
+			return fmt.Errorf("no snippet %q", args[1])
+		}
+		delete(snippets, args[1])
+		return snippets.Save(paths.snippets)
+
+	case "discover":
+		min := snippetMinUses
+		if len(args) > 1 {
+			if _, err := fmt.Sscan(args[1], &min); err != nil {
+				return fmt.Errorf("invalid min uses %q", args[1])
+			}
+		}
+		history, err := ReadLearnLog(paths.learnLog)
+		if err != nil {
+			return err
+		}
+		phraseCounts, err := LoadPhraseCounts(paths.phrases)
+		if err != nil {
+			return err
+		}
+		for _, pc := range NewPhrases(phraseCounts, history).Frequent(min) {
+			if !snippets.Has(pc.phrase) {
+				fmt.Printf("%d\t%s\t(suggested abbreviation: %s)\n", pc.count, pc.phrase, snippets.Abbreviate(pc.phrase))
+			}
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unknown snippets command %q", args[0])
+}
+
+// Offers a snippet when the phrase just completed was typed often enough.
+// Each phrase is offered at most once per session
+func proposeSnippet(snippets Snippets, phrases *Phrases, offered map[string]bool, word string) *snippetProposal {
+	phrase, count := phrases.Add(word, time.Now())
+	if phrase == "" || offered[phrase] || snippets.Has(phrase) {
+		return nil
+	}
+	offered[phrase] = true
+	return &snippetProposal{phrase, snippets.Abbreviate(phrase), count}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+)
+
+// Words spelled differently in British and American English, one pair a line
+// with the British spelling first. Eg:- "colour color". Words which are right
+// either way, like meter or program, are left out
+//
+//go:embed spellings.txt
+var spellingPairs string
+
+// Spellings preferred by spelling
+const (
+	spellingUK = "uk"
+	spellingUS = "us"
+)
+
+// What happens to the variants of the preferred spellings
+const (
+	variantsDemote = "demote" // suggested after everything else, also when empty
+	variantsHide   = "hide"   // not suggested at all
+)
+
+// The avoided spellings, from the built-in pairs for spelling and from prefer,
+// where each word is preferred over its value. Eg:- "uk" avoids color
+func avoidedSpellings(spelling string, prefer map[string]string) map[string]bool {
+	avoid := make(map[string]bool)
+	if spelling != "" {
+		for _, line := range strings.Split(spellingPairs, "\n") {
+			uk, us, ok := strings.Cut(line, " ")
+			if !ok {
+				continue
+			}
+			if spelling == spellingUK {
+				avoid[us] = true
+			} else {
+				avoid[uk] = true
+			}
+		}
+	}
+	for preferred, variant := range prefer {
+		avoid[strings.ToLower(variant)] = true
+		delete(avoid, strings.ToLower(preferred)) // in case a pair says otherwise
+	}
+	return avoid
+}
+
+// Checks the spelling options
+func validateSpelling(spelling, variants string, prefer map[string]string) error {
+	if spelling != "" && spelling != spellingUK && spelling != spellingUS {
+		return fmt.Errorf("spelling must be uk, us or empty")
+	}
+	if variants != "" && variants != variantsDemote && variants != variantsHide {
+		return fmt.Errorf("spelling_variants must be demote or hide")
+	}
+	for preferred, variant := range prefer {
+		if preferred == "" || strings.EqualFold(preferred, variant) {
+			return fmt.Errorf("prefer.%s: %q must be another spelling of it", preferred, variant)
+		}
+	}
+	return nil
+}
+
+// Moves the candidates spelled the avoided way after the others, or with hide
+// leaves them out, even when the dictionary has both. Eg:- with spelling = "uk"
+// col completes to colour before color
+func preferSpelling(avoid map[string]bool, hide bool, candidates []Candidate) []Candidate {
+	if len(avoid) == 0 {
+		return candidates
+	}
+	var preferred, variants []Candidate
+	for _, c := range candidates {
+		if avoid[strings.ToLower(c.word)] {
+			variants = append(variants, c)
+		} else {
+			preferred = append(preferred, c)
+		}
+	}
+	if hide || len(variants) == 0 {
+		return preferred
+	}
+	return append(preferred, variants...)
+}
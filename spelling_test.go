@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+)
+
+// A spelling preference puts the variants after the preferred spelling, or hides them
+func TestSpellingPreference(t *testing.T) {
+	h := newHarness(t, t.TempDir())
+	h.e.trie.InsertCount(baseLayer, "color", 3)
+	h.e.trie.InsertCount(baseLayer, "colour", 1)
+	h.e.cfg.Spelling = spellingUK
+	if err := h.e.cfg.validate(); err != nil {
+		t.Fatal(err)
+	}
+	h.feed([]byte("colo"))
+	h.pause()
+	if !h.e.triggered || h.e.suggestions[0].word != "colour" || h.e.suggestions[len(h.e.suggestions)-1].word != "color" {
+		t.Fatalf("suggested %v for colo preferring colour", h.e.suggestions)
+	}
+
+	cfg := defaultConfig()
+	cfg.Spelling, cfg.SpellingVariants, cfg.Prefer = spellingUS, variantsHide, map[string]string{"Grey": "gray"}
+	if err := cfg.validate(); err != nil {
+		t.Fatal(err)
+	}
+	candidates := []Candidate{{word: "colour"}, {word: "gray"}, {word: "grey"}, {word: "color"}}
+	if got := preferSpelling(cfg.avoid, true, candidates); len(got) != 2 || got[0].word != "grey" || got[1].word != "color" {
+		t.Errorf("hid the variants into %v", got)
+	}
+	cfg.Spelling = "ca"
+	if cfg.validate() == nil {
+		t.Error("accepted spelling = \"ca\"")
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/b0tShaman/autocomplete-cli/trie"
+
+	"golang.org/x/term"
+)
+
+// Number of words listed in a stats dump
+const statsTopWords = 20
+
+// Human readable dump of the engine statistics, written on SIGUSR1
+func statsReport(t *trie.Stack, boosts Boosts, profile string) string {
+	if profile == "" {
+		profile = "default"
+	}
+	s := t.Stats()
+	var b strings.Builder
+	fmt.Fprintf(&b, "profile %s: %d words, %d nodes, %d uses\n", profile, s.Words, s.Nodes, s.Uses)
+	fmt.Fprintf(&b, "learn log: %d bytes, snapshot: %d bytes\n", fileSize(paths.learnLog), fileSize(paths.snapshot))
+	fmt.Fprintf(&b, "ranking adjusted for %d words (autocomplete report boosts)\n", boosts.Adjusted())
+	for i, w := range t.Top(statsTopWords, usageScore) {
+		fmt.Fprintf(&b, "%3d. %s (%d)\n", i+1, w.Value, w.Count)
+	}
+	return b.String()
+}
+
+// Log file used while the terminal is busy with the editor
+func logPath() string {
+	return filepath.Join(cacheDir(), "autocomplete.log")
+}
+
+// Logs to stderr when it is redirected, otherwise to the log file so the editor's screen stays intact
+func openLogger() (*log.Logger, string) {
+	if !term.IsTerminal(int(os.Stderr.Fd())) {
+		return log.New(os.Stderr, "", log.LstdFlags), "stderr"
+	}
+	if err := os.MkdirAll(filepath.Dir(logPath()), 0755); err == nil {
+		if f, err := os.OpenFile(logPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644); err == nil {
+			return log.New(f, "", log.LstdFlags), logPath()
+		}
+	}
+	return log.New(io.Discard, "", 0), "nowhere"
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// autocomplete suggest [previous words...] <prefix>
+// Prints what the editor would suggest for the last word after the others, one
+// "word<TAB>source" per line. Eg:- autocomplete suggest so he
+func suggestCommand(args []string) error {
+	words := strings.Fields(strings.Join(args, " "))
+	if len(words) == 0 {
+		return fmt.Errorf("usage: suggest [previous words...] <prefix>")
+	}
+	cfg, err := LoadConfig(configPath())
+	if err != nil {
+		return err
+	}
+
+	prof, problems := openProfile(paths)
+	defer prof.Close()
+	verifier := NewVerifier(cfg)
+	t, _, trieProblems := loadTrie(cfg.dictSpecs(cfg.Dictionary), cfg.Dawg, cfg.BuiltinWords, verifier, paths.snapshot, prof.tombstones, prof.history, cfg.Tokens)
+	d, packProblems := loadDictionaries(cfg, cfg.Dictionary, verifier)
+	project, projectProblems := openProject(cfg)
+	project.Layer(t, prof.tombstones)
+	prof.snippets = project.Snippets(prof.snippets)
+	if problems = append(append(append(problems, trieProblems...), packProblems...), projectProblems...); len(problems) > 0 {
+		return fmt.Errorf("%s", problemStatus(problems))
+	}
+
+	previous, word := words[:len(words)-1], words[len(words)-1]
+	for _, c := range d.candidates(cfg, t, prof, previous[max(0, len(previous)-contextWords):], word, nil) {
+		fmt.Printf("%s\t%s\n", c.word, c.source)
+	}
+	return nil
+}
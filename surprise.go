@@ -0,0 +1,96 @@
+package main
+
+import (
+	"math/rand"
+	"sort"
+	"strings"
+
+	"github.com/b0tShaman/autocomplete-cli/prompt"
+	"github.com/b0tShaman/autocomplete-cli/trie"
+)
+
+// Draws a word to follow the words in before, one character at a time in
+// proportion to the model's probabilities. "" when the model has seen nothing
+func (m *Model) Sample(before string) string {
+	var chars []string
+	for c := range m.Contexts[""] {
+		chars = append(chars, c)
+	}
+	sort.Strings(chars) // the same draws for the same random numbers
+
+	ctx := modelText(before)
+	var word []rune
+	for len(word) < modelMaxLength {
+		probs := make([]float64, len(chars))
+		total := 0.0
+		for i, c := range chars {
+			if c == " " && len(word) == 0 {
+				continue // the word has to start first
+			}
+			probs[i] = m.Prob(ctx, c)
+			total += probs[i]
+		}
+		if total == 0 {
+			break
+		}
+		next, x := "", rand.Float64()*total
+		for i, c := range chars {
+			if probs[i] == 0 {
+				continue
+			}
+			next = c // the last one when rounding leaves x above 0
+			if x -= probs[i]; x < 0 {
+				break
+			}
+		}
+		if next == " " {
+			break
+		}
+		word = append(word, []rune(next)...)
+		ctx = append(ctx, []rune(next)...)
+	}
+	return string(word)
+}
+
+// Picks a word at random, the more often used the likelier
+func randomWord(t *trie.Stack) string {
+	words := t.Words()
+	sort.Slice(words, func(i, j int) bool { return words[i].Value < words[j].Value })
+	total := 0.0
+	for _, w := range words {
+		total += scoring.Score(w.Count)
+	}
+	x := rand.Float64() * total
+	for _, w := range words {
+		if x -= scoring.Score(w.Count); x < 0 {
+			return w.Value
+		}
+	}
+	if len(words) > 0 {
+		return words[len(words)-1].Value
+	}
+	return ""
+}
+
+// Inserts a random plausible next word after the text typed so far. Drawn words
+// are not learned
+func (e *Editor) surprise() {
+	e.learnLastWord()
+	if len(e.input) > 0 && e.input[len(e.input)-1] != ' ' && e.input[len(e.input)-1] != '\n' {
+		e.input = append(e.input, ' ')
+	}
+
+	var word, status string
+	if e.prof.model != nil {
+		word, status = e.prof.model.Sample(strings.Join(prompt.PreviousWords(e.input, contextWords), " ")), "surprise from the model"
+	}
+	if word == "" {
+		word, status = randomWord(e.trie), "surprise from your most used words"
+	}
+	if word == "" {
+		status = "nothing to draw from yet"
+	} else {
+		e.input = append(append(e.input, []rune(word)...), ' ')
+	}
+	e.status(status)
+}
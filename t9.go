@@ -0,0 +1,73 @@
+package main
+
+import (
+	"unicode"
+
+	"github.com/b0tShaman/autocomplete-cli/trie"
+)
+
+// Phone keypad letter groups
+var t9Keys = map[rune]byte{
+	'a': '2', 'b': '2', 'c': '2',
+	'd': '3', 'e': '3', 'f': '3',
+	'g': '4', 'h': '4', 'i': '4',
+	'j': '5', 'k': '5', 'l': '5',
+	'm': '6', 'n': '6', 'o': '6',
+	'p': '7', 'q': '7', 'r': '7', 's': '7',
+	't': '8', 'u': '8', 'v': '8',
+	'w': '9', 'x': '9', 'y': '9', 'z': '9',
+}
+
+// Reports whether word is a non empty sequence of the digits 2-9
+func isT9Sequence(word string) bool {
+	for _, r := range word {
+		if r < '2' || r > '9' {
+			return false
+		}
+	}
+	return len(word) > 0
+}
+
+// Returns the words whose letters map onto the digit sequence on a phone keypad.
+// Words exactly as long as the sequence come first, followed by longer completions,
+// both sorted in order of usage. Eg:- 4663 --> good, home, gone, ... , goods, homes
+func t9Words(t *trie.Stack, digits string) []string {
+	var exact, longer []trie.Word
+	t9dfs(t, digits, "", &exact, &longer)
+	trie.Sort(exact, usageScore)
+	trie.Sort(longer, usageScore)
+
+	result := make([]string, 0, len(exact)+len(longer))
+	for _, word := range append(exact, longer...) {
+		result = append(result, word.Value)
+	}
+	return result
+}
+
+func t9dfs(t *trie.Stack, digits string, prefix string, exact, longer *[]trie.Word) {
+	if len(digits) == 0 {
+		for _, w := range t.Words() {
+			if w.Value == "" {
+				*exact = append(*exact, trie.Word{Value: prefix, Count: w.Count})
+			} else {
+				*longer = append(*longer, trie.Word{Value: prefix + w.Value, Count: w.Count})
+			}
+		}
+		return
+	}
+
+	for k, v := range t.Children() {
+		if t9Keys[unicode.ToLower(k)] == digits[0] {
+			t9dfs(v, digits[1:], prefix+string(k), exact, longer)
+		}
+	}
+}
+
+// Suggestions for a digit sequence typed in T9 mode
+func t9Candidates(t *trie.Stack, digits string) []Candidate {
+	var result []Candidate
+	for _, word := range t9Words(t, digits) {
+		result = append(result, Candidate{word: word, label: "T9 " + digits, source: "t9"})
+	}
+	return result
+}
@@ -0,0 +1,389 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/b0tShaman/autocomplete-cli/trie"
+)
+
+const (
+	teamFile       = "team.txt"        // the team dictionary pulled by members, in the data directory
+	teamMemberFile = "team-member.txt" // random id of this profile, so the server can replace its last contribution
+	teamStoreFile  = "team.json"       // contributions kept by the serve mode daemon
+	teamMinUses    = 2                 // words and phrases used less often are never sent
+	teamMaxEntries = 100000            // words plus phrases accepted per contribution
+)
+
+// Opt-in team mode. Members send how often they used words and phrases, never
+// what they typed, to a serve mode daemon which adds them up into the team dictionary
+type TeamConfig struct {
+	Server    string `toml:"server"`    // serve mode daemon of the team, Eg:- "http://team-host:7878"
+	Token     string `toml:"token"`     // bearer token of that daemon, if it wants one
+	Subscribe bool   `toml:"subscribe"` // suggest from the pulled team dictionary after everything else
+}
+
+// What a member sends: use counts of its words and phrases
+type TeamContribution struct {
+	Words   map[string]int `json:"words"`
+	Phrases map[string]int `json:"phrases"`
+	Time    time.Time      `json:"time"` // set by the server
+}
+
+// What members pull: the totals of everything used by enough members
+type TeamDictionary struct {
+	Members int            `json:"members"`
+	Words   map[string]int `json:"words"`
+	Phrases map[string]int `json:"phrases"`
+}
+
+// Counts of the profile worth sharing: learned words and repeated phrases used at
+// least teamMinUses times, without forgotten words and tokens which are not learned
+func localContribution(cfg Config, p DataPaths) (TeamContribution, error) {
+	c := TeamContribution{Words: make(map[string]int), Phrases: make(map[string]int)}
+	tombstones, err := LoadTombstones(p.tombstones)
+	if err != nil {
+		return c, err
+	}
+	counts, err := LoadSnapshot(p.snapshot)
+	if err != nil {
+		return c, err
+	}
+	history, err := ReadLearnLog(p.learnLog)
+	if err != nil {
+		return c, err
+	}
+	for _, lw := range history {
+		counts.Add(lw.word, 1, lw.at)
+	}
+	for word, u := range counts {
+		if u.count >= teamMinUses && !tombstones.Buried(word, u.last) && cfg.Tokens.Policy(word) == tokenLearn {
+			c.Words[word] = u.count
+		}
+	}
+
+	phraseCounts, err := LoadPhraseCounts(p.phrases)
+	if err != nil {
+		return c, err
+	}
+	for _, pc := range NewPhrases(phraseCounts, history).Frequent(teamMinUses) {
+		if !buriedPhrase(tombstones, pc.phrase) {
+			c.Phrases[pc.phrase] = pc.count
+		}
+	}
+	return c, nil
+}
+
+// Reports whether phrase contains a forgotten word
+func buriedPhrase(tombstones Tombstones, phrase string) bool {
+	for _, word := range strings.Fields(phrase) {
+		if _, ok := tombstones[word]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Contributions of the members, kept by the serve mode daemon
+type teamStore struct {
+	mu         sync.Mutex
+	path       string
+	minMembers int
+	members    map[string]TeamContribution // by member id
+}
+
+func openTeamStore(path string, minMembers int) (*teamStore, error) {
+	t := &teamStore{path: path, minMembers: minMembers, members: make(map[string]TeamContribution)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return t, nil
+	} else if err != nil {
+		return t, err
+	}
+	return t, json.Unmarshal(data, &t.members)
+}
+
+// Replaces the last contribution of member
+func (t *teamStore) contribute(member string, c TeamContribution) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.members[member] = c
+	data, err := json.Marshal(t.members)
+	if err != nil {
+		return err
+	}
+	tmp := t.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, t.path)
+}
+
+// Adds up the contributions. Words and phrases used by fewer than minMembers
+// members are left out, so nothing points back to a single member
+func (t *teamStore) dictionary() TeamDictionary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	d := TeamDictionary{Members: len(t.members), Words: make(map[string]int), Phrases: make(map[string]int)}
+	add := func(totals map[string]int, counts func(TeamContribution) map[string]int) {
+		users := make(map[string]int)
+		for _, c := range t.members {
+			for key, n := range counts(c) {
+				totals[key] += n
+				users[key]++
+			}
+		}
+		for key := range totals {
+			if users[key] < t.minMembers {
+				delete(totals, key)
+			}
+		}
+	}
+	add(d.Words, func(c TeamContribution) map[string]int { return c.Words })
+	add(d.Phrases, func(c TeamContribution) map[string]int { return c.Phrases })
+	return d
+}
+
+// POST /team/contribute {"member": "<id>", "words": {...}, "phrases": {...}}
+// GET /team/dictionary
+func (t *teamStore) handle(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/team/dictionary" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, t.dictionary())
+	case r.URL.Path == "/team/contribute" && r.Method == http.MethodPost:
+		var req struct {
+			Member string `json:"member"`
+			TeamContribution
+		}
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 16<<20)).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		if !clientID.MatchString(req.Member) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "member must be 1-64 letters, digits, _ or -"})
+			return
+		}
+		if len(req.Words)+len(req.Phrases) > teamMaxEntries {
+			writeJSON(w, http.StatusRequestEntityTooLarge, map[string]string{"error": "too many words and phrases"})
+			return
+		}
+		req.Time = time.Now().UTC()
+		if err := t.contribute(req.Member, req.TeamContribution); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]int{"words": len(req.Words), "phrases": len(req.Phrases)})
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "use POST /team/contribute or GET /team/dictionary"})
+	}
+}
+
+// Words and phrases of the pulled team dictionary, suggested after everything else
+type TeamWords struct {
+	trie    *trie.Trie
+	phrases map[string]int
+}
+
+// Reads the team dictionary, "count<TAB>word or phrase" per line. nil when it
+// was never pulled
+func LoadTeamWords(path string) (*TeamWords, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	t := &TeamWords{trie: trie.New(), phrases: make(map[string]int)}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		n, entry, ok := strings.Cut(scanner.Text(), "\t")
+		count, err := strconv.Atoi(n)
+		if !ok || err != nil || count < 1 || entry == "" {
+			continue
+		}
+		if strings.Contains(entry, " ") {
+			t.phrases[entry] = count
+		} else {
+			t.trie.InsertCount(entry, count)
+		}
+	}
+	return t, scanner.Err()
+}
+
+// The pulled team dictionary when subscribed to it
+func subscribeTeam(cfg TeamConfig) (*TeamWords, []string) {
+	if !cfg.Subscribe {
+		return nil, nil
+	}
+	t, err := LoadTeamWords(inDataDir(teamFile))
+	if err != nil {
+		return nil, []string{fmt.Sprintf("team dictionary unavailable: %v", err)}
+	}
+	return t, nil
+}
+
+// Team words completing word, starting with the ones continuing a team phrase
+// ending in the previous words. Words suggested already are left out
+func (t *TeamWords) Candidates(score trie.Scorer, previous []string, word string, suggested []Candidate) []Candidate {
+	var result []Candidate
+	if t == nil || word == "" {
+		return result
+	}
+	seen := make(map[string]bool)
+	for _, c := range suggested {
+		seen[c.word] = true
+	}
+	add := func(w string) {
+		if !seen[w] && w != word {
+			seen[w] = true
+			result = append(result, Candidate{word: w, label: "team", source: "team"})
+		}
+	}
+
+	var next []PhraseCount
+	for phrase, count := range t.phrases {
+		words := strings.Fields(phrase)
+		last := words[len(words)-1]
+		before := words[:len(words)-1]
+		if len(before) <= len(previous) && strings.Join(before, " ") == strings.Join(previous[len(previous)-len(before):], " ") && strings.HasPrefix(last, word) {
+			next = append(next, PhraseCount{last, count})
+		}
+	}
+	sort.Slice(next, func(i, j int) bool { return next[i].count > next[j].count })
+	for _, pc := range next {
+		add(pc.phrase)
+	}
+	for _, w := range t.trie.AutofillScored(word, score) {
+		add(word + w)
+	}
+	return result
+}
+
+// The random id this profile contributes under, created on first use
+func teamMember(p DataPaths) (string, error) {
+	path := filepath.Join(p.dir, teamMemberFile)
+	if data, err := os.ReadFile(path); err == nil && clientID.MatchString(strings.TrimSpace(string(data))) {
+		return strings.TrimSpace(string(data)), nil
+	}
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	id := hex.EncodeToString(b)
+	return id, os.WriteFile(path, []byte(id+"\n"), 0644)
+}
+
+// Sends a request to the team server and decodes the answer into v
+func teamRequest(cfg TeamConfig, method, path string, body, v any) error {
+	if cfg.Server == "" {
+		return fmt.Errorf("team mode is off, set team.server in the config")
+	}
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, strings.TrimSuffix(cfg.Server, "/")+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+cfg.Token)
+	}
+	resp, err := (&http.Client{Timeout: 30 * time.Second}).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	out, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(out)))
+	}
+	return json.Unmarshal(out, v)
+}
+
+// autocomplete team preview|push|pull
+// Shows or sends the counts of this profile, or downloads the team dictionary
+func teamCommand(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: team preview|push|pull")
+	}
+	cfg, err := LoadConfig(configPath())
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "preview":
+		c, err := localContribution(cfg, paths)
+		if err != nil {
+			return err
+		}
+		for _, counts := range []map[string]int{c.Words, c.Phrases} {
+			var keys []string
+			for key := range counts {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				fmt.Printf("%6d  %s\n", counts[key], key)
+			}
+		}
+		fmt.Printf("%d words and %d phrases would be sent\n", len(c.Words), len(c.Phrases))
+	case "push":
+		c, err := localContribution(cfg, paths)
+		if err != nil {
+			return err
+		}
+		member, err := teamMember(paths)
+		if err != nil {
+			return err
+		}
+		body := struct {
+			Member string `json:"member"`
+			TeamContribution
+		}{member, c}
+		var sent map[string]int
+		if err := teamRequest(cfg.Team, http.MethodPost, "/team/contribute", body, &sent); err != nil {
+			return err
+		}
+		fmt.Printf("sent %d words and %d phrases\n", sent["words"], sent["phrases"])
+	case "pull":
+		var d TeamDictionary
+		if err := teamRequest(cfg.Team, http.MethodGet, "/team/dictionary", nil, &d); err != nil {
+			return err
+		}
+		var b strings.Builder
+		for _, counts := range []map[string]int{d.Words, d.Phrases} {
+			for key, n := range counts {
+				if !strings.ContainsAny(key, "\t\n") {
+					fmt.Fprintf(&b, "%d\t%s\n", n, key)
+				}
+			}
+		}
+		if err := os.WriteFile(inDataDir(teamFile), []byte(b.String()), 0644); err != nil {
+			return err
+		}
+		fmt.Printf("pulled %d words and %d phrases shared by %d members\n", len(d.Words), len(d.Phrases), d.Members)
+	default:
+		return fmt.Errorf("usage: team preview|push|pull")
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// Writes a team dictionary and loads it
+func loadTeam(t *testing.T, data string) *TeamWords {
+	path := filepath.Join(t.TempDir(), teamFile)
+	os.WriteFile(path, []byte(data), 0644)
+	team, err := LoadTeamWords(path)
+	if err != nil || team == nil {
+		t.Fatalf("team dictionary %v, %v", team, err)
+	}
+	return team
+}
+
+// The words continuing a team phrase come first, then the team words by count,
+// leaving out those suggested already
+func TestTeamCandidates(t *testing.T) {
+	team := loadTeam(t, "10\tkubernetes\n3\tkubectl\n5\tkubelet\n4\trun kubeadm\n2\tkube\nbroken\n")
+	var words []string
+	for _, c := range team.Candidates(usageScore, []string{"please", "run"}, "kube", []Candidate{{word: "kubectl", source: "trie"}}) {
+		if c.source != "team" || c.label != "team" {
+			t.Errorf("%+v not labeled team", c)
+		}
+		words = append(words, c.word)
+	}
+	if want := []string{"kubeadm", "kubernetes", "kubelet"}; !slices.Equal(words, want) {
+		t.Errorf("candidates %v, want %v", words, want)
+	}
+
+	if got := team.Candidates(usageScore, nil, "", nil); len(got) != 0 {
+		t.Errorf("candidates for nothing typed: %v", got)
+	}
+	if got := (*TeamWords)(nil).Candidates(usageScore, nil, "kube", nil); len(got) != 0 {
+		t.Errorf("candidates without a team dictionary: %v", got)
+	}
+}
+
+// In the editor the team words come once, after the local words
+func TestEditorTeam(t *testing.T) {
+	h := newHarness(t, t.TempDir())
+	h.e.team = loadTeam(t, "7\thelmet\n3\thelium\n")
+	var words []string
+	for _, c := range h.e.engineCandidates(nil, "hel") {
+		words = append(words, c.word)
+	}
+	if i := slices.Index(words, "helium"); i != len(words)-1 || slices.Index(words, "helmet") == -1 {
+		t.Fatalf("candidates %v", words)
+	}
+	n := len(words)
+	if slices.Sort(words); len(slices.Compact(words)) != n {
+		t.Errorf("duplicate candidates %v", words)
+	}
+}
+
+// Only what enough members used is in the team dictionary, and the contributions
+// survive a restart
+func TestTeamStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "team.json")
+	store, err := openTeamStore(path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.contribute("ann", TeamContribution{Words: map[string]int{"kubectl": 5, "mine": 9}, Phrases: map[string]int{"run kubectl": 2}})
+	store.contribute("bob", TeamContribution{Words: map[string]int{"kubectl": 3}, Phrases: map[string]int{"run kubectl": 4}})
+	store.contribute("bob", TeamContribution{Words: map[string]int{"kubectl": 2}, Phrases: map[string]int{"run kubectl": 4}}) // replaces the first
+
+	store, err = openTeamStore(path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := store.dictionary()
+	if d.Members != 2 || len(d.Words) != 1 || d.Words["kubectl"] != 7 || d.Phrases["run kubectl"] != 6 {
+		t.Errorf("dictionary %+v", d)
+	}
+}
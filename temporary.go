@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Temporary words, one "word<TAB>uses<TAB>expiry as unix seconds" per line
+const temporaryFile = "temporary.txt"
+
+const defaultTTL = 24 * time.Hour // how long a temporary word lasts after its last use
+
+type TempWord struct {
+	uses    int
+	expires time.Time
+}
+
+// Words kept for a while instead of being learned for good, Eg:- ticket IDs.
+// Each use makes one last the TTL again
+type TempWords map[string]TempWord
+
+// Reads the temporary words which did not expire by now. A missing file means there are none
+func LoadTempWords(path string, now time.Time) (TempWords, error) {
+	words := make(TempWords)
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return words, nil
+	} else if err != nil {
+		return words, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) != 3 || fields[0] == "" {
+			continue
+		}
+		uses, err1 := strconv.Atoi(fields[1])
+		expires, err2 := strconv.ParseInt(fields[2], 10, 64)
+		if err1 == nil && err2 == nil && time.Unix(expires, 0).After(now) {
+			words[fields[0]] = TempWord{uses, time.Unix(expires, 0)}
+		}
+	}
+	return words, scanner.Err()
+}
+
+// Writes the words which did not expire by now
+func (words TempWords) Save(path string, now time.Time) error {
+	var b strings.Builder
+	for _, word := range words.Words(now) {
+		fmt.Fprintf(&b, "%s\t%d\t%d\n", word, words[word].uses, words[word].expires.Unix())
+	}
+	return os.WriteFile(path, []byte(b.String()), 0644)
+}
+
+// Records a use of word, which then expires at expires
+func (words TempWords) Add(word string, expires time.Time) {
+	words[word] = TempWord{words[word].uses + 1, expires}
+}
+
+// The words which did not expire by now, most used first
+func (words TempWords) Words(now time.Time) []string {
+	var list []string
+	for word, t := range words {
+		if t.expires.After(now) {
+			list = append(list, word)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if words[list[i]].uses != words[list[j]].uses {
+			return words[list[i]].uses > words[list[j]].uses
+		}
+		return list[i] < list[j]
+	})
+	return list
+}
+
+// Temporary words which complete prefix, most used first
+func (words TempWords) Candidates(prefix string, now time.Time) []Candidate {
+	var result []Candidate
+	if prefix == "" {
+		return result
+	}
+	for _, word := range words.Words(now) {
+		if strings.HasPrefix(word, prefix) && word != prefix {
+			left := words[word].expires.Sub(now)
+			result = append(result, Candidate{word: word, label: "temporary, expires in " + shortDuration(left), source: "temporary"})
+		}
+	}
+	return result
+}
+
+// Keeps word for the TTL of the tokens config
+func (e *Editor) learnTemporary(word string) {
+	now := time.Now()
+	e.prof.temporary.Add(word, now.Add(e.cfg.Tokens.TTL))
+	if err := e.prof.temporary.Save(e.prof.paths.temporary, now); err != nil {
+		diagnostics.Addf("saving temporary words failed: %v", err)
+	}
+}
+
+// Turns the word learned by the last SPACE into a temporary one. Returns the status to show
+func (e *Editor) makeTemporary() string {
+	word := e.learned
+	if !e.justLearned() {
+		return "only the word just learned can be made temporary"
+	}
+	if err := e.unlearn(word); err != nil {
+		return "could not unlearn " + word + ": " + err.Error()
+	}
+	e.learned = ""
+	e.learnTemporary(word)
+	return fmt.Sprintf("%s is temporary, expires in %s", word, shortDuration(e.cfg.Tokens.TTL))
+}
+
+// d to the minute without the zero units. Eg:- 23h59m30s --> 24h, 1h30m0s --> 1h30m
+func shortDuration(d time.Duration) string {
+	s := d.Round(time.Minute).String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// autocomplete temporary [list|remove <word>]
+// Prints the temporary words with when they expire, or drops one
+func temporaryCommand(args []string) error {
+	now := time.Now()
+	words, err := LoadTempWords(paths.temporary, now)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 || args[0] == "list" {
+		for _, word := range words.Words(now) {
+			fmt.Printf("%-24s %6d  expires %s\n", word, words[word].uses, words[word].expires.Format("2006-01-02 15:04"))
+		}
+		return nil
+	}
+	if len(args) != 2 || args[0] != "remove" {
+		return fmt.Errorf("usage: temporary [list|remove <word>]")
+	}
+	if _, ok := words[args[1]]; !ok {
+		return fmt.Errorf("%s is not a temporary word", args[1])
+	}
+	delete(words, args[1])
+	return words.Save(paths.temporary, now)
+}
@@ -0,0 +1,23 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+
+	"golang.org/x/term"
+)
+
+// Keys sent as a single byte which mean something else on this platform
+var byteKeys = map[byte]rune{}
+
+// Puts the terminal into raw mode so keys arrive as they are typed. Returns
+// what puts it back
+func makeRaw() (restore func(), err error) {
+	fd := int(os.Stdin.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, err
+	}
+	return func() { term.Restore(fd, state) }, nil
+}
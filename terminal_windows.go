@@ -0,0 +1,47 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/term"
+)
+
+const utf8CodePage = 65001
+
+// The console sends BACKSPACE for Backspace and DELETE for Ctrl+Backspace,
+// which deletes a word like it does elsewhere on Windows
+var byteKeys = map[byte]rune{DELETE: keyAltDelete}
+
+// Puts the console into raw mode so keys arrive as they are typed, as escape
+// sequences for the arrows and the like. The output interprets escape sequences
+// too, and both sides use UTF-8. Returns what puts everything back
+func makeRaw() (restore func(), err error) {
+	in, out := windows.Handle(os.Stdin.Fd()), windows.Handle(os.Stdout.Fd())
+	state, err := term.MakeRaw(int(in))
+	if err != nil {
+		return nil, err
+	}
+
+	var outMode uint32
+	if err := windows.GetConsoleMode(out, &outMode); err == nil {
+		windows.SetConsoleMode(out, outMode|windows.ENABLE_PROCESSED_OUTPUT|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING)
+	}
+	inCP, _ := windows.GetConsoleCP()
+	outCP, _ := windows.GetConsoleOutputCP()
+	windows.SetConsoleCP(utf8CodePage)
+	windows.SetConsoleOutputCP(utf8CodePage)
+
+	return func() {
+		if inCP != 0 {
+			windows.SetConsoleCP(inCP)
+		}
+		if outCP != 0 {
+			windows.SetConsoleOutputCP(outCP)
+		}
+		windows.SetConsoleMode(out, outMode)
+		term.Restore(int(in), state)
+	}, nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Splits typed tokens into words, the way suited to what is typed in a profile.
+// A token is what was typed between two spaces
+type Tokenizer interface {
+	// The words of a token to learn. Eg:- (hello), --> [hello]
+	Words(token string) []string
+	// What comes before the word being typed in a token, and the word.
+	// Eg:- (hel --> "(", "hel"
+	Split(token string) (lead, word string)
+}
+
+// Tokenizers by the name the tokenizers config gives, each made for the
+// word_chars config
+var tokenizers = map[string]func(wordChars string) Tokenizer{
+	"text":  func(wordChars string) Tokenizer { return textTokenizer{wordChars} },
+	"code":  func(string) Tokenizer { return codeTokenizer{} },
+	"shell": func(string) Tokenizer { return shellTokenizer{} },
+}
+
+// Words of natural language: letters, digits and the word_chars between them.
+// Eg:- (state-of-the-art), --> [state-of-the-art]
+type textTokenizer struct {
+	wordChars string
+}
+
+func (t textTokenizer) Words(token string) []string { return splitWords(token, t.wordChars) }
+
+func (t textTokenizer) Split(token string) (string, string) { return splitWord(token, t.wordChars) }
+
+// Identifiers: letters, digits and underscores, split at every other
+// punctuation and not starting with a digit. Eg:- self._cache[key]) --> [self _cache key]
+type codeTokenizer struct{}
+
+func isIdentRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+func (codeTokenizer) Words(token string) []string {
+	var words []string
+	for _, f := range strings.FieldsFunc(token, func(r rune) bool { return !isIdentRune(r) }) {
+		if r := []rune(f); !unicode.IsDigit(r[0]) && strings.IndexFunc(f, unicode.IsLetter) >= 0 {
+			words = append(words, f)
+		}
+	}
+	return words
+}
+
+// Eg:- obj.get --> "obj.", "get"
+func (codeTokenizer) Split(token string) (string, string) {
+	r := []rune(token)
+	start := len(r)
+	for start > 0 && isIdentRune(r[start-1]) {
+		start--
+	}
+	return string(r[:start]), string(r[start:])
+}
+
+// Shell command lines: commands, flags and paths are words as they are, split
+// at quotes, operators and assignments. Eg:- "$(git)|less" --> [git less] and
+// --output=./out.json --> [--output ./out.json]
+type shellTokenizer struct{}
+
+func isShellSeparator(r rune) bool {
+	return strings.ContainsRune("\"'`;|&<>(){}$=,", r)
+}
+
+func (shellTokenizer) Words(token string) []string {
+	var words []string
+	for _, f := range strings.FieldsFunc(token, isShellSeparator) {
+		if strings.IndexFunc(f, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) >= 0 {
+			words = append(words, f)
+		}
+	}
+	return words
+}
+
+// Eg:- $(kub --> "$(", "kub" and --out --> "", "--out"
+func (shellTokenizer) Split(token string) (string, string) {
+	start := strings.LastIndexFunc(token, isShellSeparator) + 1 // the separators are all ASCII
+	return token[:start], token[start:]
+}
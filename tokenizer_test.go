@@ -0,0 +1,55 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTokenizers(t *testing.T) {
+	cases := []struct {
+		name, token string
+		words       []string
+		lead, word  string
+	}{
+		{"text", "(state-of-the-art),", []string{"state-of-the-art"}, "(state-of-the-art),", ""},
+		{"text", "(hel", []string{"hel"}, "(", "hel"},
+		{"code", "self._cache[key2])", []string{"self", "_cache", "key2"}, "self._cache[key2])", ""},
+		{"code", "obj.get", []string{"obj", "get"}, "obj.", "get"},
+		{"shell", `"$(git)|less"`, []string{"git", "less"}, `"$(git)|less"`, ""},
+		{"shell", "--output=./out.json", []string{"--output", "./out.json"}, "--output=", "./out.json"},
+		{"shell", "$(kub", []string{"kub"}, "$(", "kub"},
+	}
+	for _, c := range cases {
+		tokenizer := tokenizers[c.name](defaultWordChars)
+		if got := tokenizer.Words(c.token); !slices.Equal(got, c.words) {
+			t.Errorf("%s words of %q = %q, want %q", c.name, c.token, got, c.words)
+		}
+		if lead, word := tokenizer.Split(c.token); lead != c.lead || word != c.word {
+			t.Errorf("%s split of %q = %q, %q, want %q, %q", c.name, c.token, lead, word, c.lead, c.word)
+		}
+	}
+
+	// Each profile learns with its own tokenizer
+	h := newHarness(t, t.TempDir())
+	h.e.cfg.Tokenizers = map[string]string{"ops": "shell"}
+	h.feed([]byte("git --force-with-lease "))
+	if h.e.trie.Count("force-with-lease") != 1 || h.e.trie.Count("--force-with-lease") != 0 {
+		t.Errorf("text learned force-with-lease %d, --force-with-lease %d", h.e.trie.Count("force-with-lease"), h.e.trie.Count("--force-with-lease"))
+	}
+	h.e.cfg.Profile = "ops"
+	h.feed([]byte("git --force-with-lease "))
+	if h.e.trie.Count("--force-with-lease") != 1 {
+		t.Errorf("shell learned --force-with-lease %d", h.e.trie.Count("--force-with-lease"))
+	}
+	h.feed([]byte("$(--fo"))
+	h.pause()
+	if !h.e.triggered || h.e.suggestions[0].word != "$(--force-with-lease" {
+		t.Errorf("suggested %v for $(--fo", h.e.suggestions)
+	}
+
+	cfg := defaultConfig()
+	cfg.Tokenizers = map[string]string{"ops": "bash"}
+	if err := cfg.validate(); err == nil {
+		t.Error("an unknown tokenizer passed validation")
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Policies for tokens which are not words
+const (
+	tokenLearn     = "learn"     // inserted into the trie like any word
+	tokenSuggest   = "suggest"   // only offered again by the recent tokens source
+	tokenIgnore    = "ignore"    // neither learned nor suggested
+	tokenTemporary = "temporary" // learned until the TTL passes without it being used, see TempWords
+)
+
+const (
+	recentTokensLimit = 20 // recent tokens remembered per session
+	minTokenLength    = 4  // shorter tokens are quicker to type than to pick
+)
+
+var (
+	numberToken = regexp.MustCompile(`^[+-]?[0-9]+([.,:/-][0-9]+)*%?$`)                                               // Eg:- 42, 3.14, 1,000, +49-30-1234
+	hexToken    = regexp.MustCompile(`^(0[xX][0-9a-fA-F]+|[0-9a-fA-F]{8,})$`)                                         // Eg:- 0xff, deadbeef42
+	ticketToken = regexp.MustCompile(`^[A-Z][A-Z0-9]+-[0-9]+$`)                                                       // Eg:- PROJ-1234
+	uuidToken   = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`) // Eg:- 123e4567-e89b-12d3-a456-426614174000
+)
+
+// Policy of each token class
+type TokensConfig struct {
+	Number string        `toml:"number"`
+	Hex    string        `toml:"hex"`
+	UUID   string        `toml:"uuid"`
+	Ticket string        `toml:"ticket"`
+	TTL    time.Duration `toml:"ttl"` // of temporary tokens
+}
+
+func (t TokensConfig) validate() error {
+	for class, policy := range map[string]string{"number": t.Number, "hex": t.Hex, "uuid": t.UUID, "ticket": t.Ticket} {
+		if policy != tokenLearn && policy != tokenSuggest && policy != tokenIgnore && policy != tokenTemporary {
+			return fmt.Errorf("tokens.%s must be learn, suggest, ignore or temporary", class)
+		}
+	}
+	if t.TTL <= 0 {
+		return fmt.Errorf("tokens.ttl must be a positive duration")
+	}
+	return nil
+}
+
+// Returns the class of word: "number", "hex", "uuid", "ticket" or "" for ordinary words
+func tokenClass(word string) string {
+	switch {
+	case uuidToken.MatchString(word):
+		return "uuid"
+	case numberToken.MatchString(word):
+		return "number"
+	case ticketToken.MatchString(word):
+		return "ticket"
+	case hexToken.MatchString(word) && strings.ContainsAny(word, "0123456789"):
+		return "hex" // a hex token without digits is most likely a word, Eg:- deadbeef
+	}
+	return ""
+}
+
+// Returns what happens to word when typed
+func (t TokensConfig) Policy(word string) string {
+	switch tokenClass(word) {
+	case "uuid":
+		return t.UUID
+	case "number":
+		return t.Number
+	case "hex":
+		return t.Hex
+	case "ticket":
+		return t.Ticket
+	}
+	return tokenLearn
+}
+
+// Tokens typed this session, most recent first
+type RecentTokens []string
+
+func (r *RecentTokens) Add(token string) {
+	if len([]rune(token)) < minTokenLength {
+		return
+	}
+	tokens := []string{token}
+	for _, t := range *r {
+		if t != token && len(tokens) < recentTokensLimit {
+			tokens = append(tokens, t)
+		}
+	}
+	*r = tokens
+}
+
+// Recent tokens which complete prefix
+func (r RecentTokens) Candidates(prefix string) []Candidate {
+	var result []Candidate
+	if prefix == "" {
+		return result
+	}
+	for _, t := range r {
+		if strings.HasPrefix(t, prefix) && t != prefix {
+			result = append(result, Candidate{word: t, label: "recent " + tokenClass(t), source: "token"})
+		}
+	}
+	return result
+}
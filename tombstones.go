@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Forgotten words, one "word<TAB>unix time" per line
+const tombstonesFile = "tombstones.txt"
+
+// Tombstones older than this are purged by vacuum unless told otherwise
+const tombstoneRetention = 90 * 24 * time.Hour
+
+// Maps a forgotten word to when it was forgotten. Every importer checks the
+// tombstones, so forgotten words stay forgotten even when old corpora contain them
+type Tombstones map[string]time.Time
+
+// Reads the tombstones. A missing file means nothing was forgotten
+func LoadTombstones(path string) (Tombstones, error) {
+	t := make(Tombstones)
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return t, nil
+	} else if err != nil {
+		return t, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		word, ts, ok := strings.Cut(scanner.Text(), "\t")
+		sec, err := strconv.ParseInt(ts, 10, 64)
+		if ok && err == nil && word != "" {
+			t[word] = time.Unix(sec, 0)
+		}
+	}
+	return t, scanner.Err()
+}
+
+func (t Tombstones) Save(path string) error {
+	words := make([]string, 0, len(t))
+	for word := range t {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+
+	var b strings.Builder
+	for _, word := range words {
+		fmt.Fprintf(&b, "%s\t%d\n", word, t[word].Unix())
+	}
+	return os.WriteFile(path, []byte(b.String()), 0644)
+}
+
+// Reports whether a use of word made at time at was forgotten. Uses without a
+// date (dictionaries and imported corpora) pass the zero time and are always buried,
+// while words typed again after being forgotten count as new usage
+func (t Tombstones) Buried(word string, at time.Time) bool {
+	forgotten, ok := t[word]
+	return ok && !at.After(forgotten)
+}
+
+// Removes tombstones created before cutoff and returns how many were removed
+func (t Tombstones) Vacuum(cutoff time.Time) int {
+	var purged int
+	for word, at := range t {
+		if at.Before(cutoff) {
+			delete(t, word)
+			purged++
+		}
+	}
+	return purged
+}
+
+// autocomplete forget <word...>
+func forgetCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: forget <word...>")
+	}
+	t, err := LoadTombstones(paths.tombstones)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, word := range args {
+		t[word] = now
+	}
+	return t.Save(paths.tombstones)
+}
+
+// autocomplete vacuum [max age in days]
+func vacuumCommand(args []string) error {
+	retention := tombstoneRetention
+	if len(args) > 0 {
+		var days int
+		if _, err := fmt.Sscan(args[0], &days); err != nil || days < 0 {
+			return fmt.Errorf("invalid max age %q", args[0])
+		}
+		retention = time.Duration(days) * 24 * time.Hour
+	}
+
+	t, err := LoadTombstones(paths.tombstones)
+	if err != nil {
+		return err
+	}
+	purged := t.Vacuum(time.Now().Add(-retention))
+	if err := t.Save(paths.tombstones); err != nil {
+		return err
+	}
+	fmt.Printf("purged %d tombstones, %d left\n", purged, len(t))
+	return nil
+}
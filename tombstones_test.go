@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTombstonesBuried(t *testing.T) {
+	forgotten := time.Now().Add(-time.Hour)
+	tombstones := Tombstones{"secret": forgotten}
+	for _, tc := range []struct {
+		word   string
+		at     time.Time
+		buried bool
+	}{
+		{"secret", forgotten.Add(-time.Minute), true},
+		{"secret", forgotten, true},
+		{"secret", time.Time{}, true}, // from a dictionary or a corpus
+		{"secret", forgotten.Add(time.Second), false},
+		{"hello", time.Time{}, false},
+	} {
+		if got := tombstones.Buried(tc.word, tc.at); got != tc.buried {
+			t.Errorf("%s used at %v: buried %v, want %v", tc.word, tc.at, got, tc.buried)
+		}
+	}
+}
+
+func TestTombstonesSaveVacuum(t *testing.T) {
+	path := pathsIn(t.TempDir()).tombstones
+	now := time.Now().Truncate(time.Second)
+	if err := (Tombstones{"old": now.Add(-tombstoneRetention - time.Hour), "recent": now}).Save(path); err != nil {
+		t.Fatal(err)
+	}
+	tombstones, err := LoadTombstones(path)
+	if err != nil || len(tombstones) != 2 || !tombstones["recent"].Equal(now) {
+		t.Fatalf("loaded %v, %v", tombstones, err)
+	}
+	if purged := tombstones.Vacuum(now.Add(-tombstoneRetention)); purged != 1 || len(tombstones) != 1 {
+		t.Errorf("purged %d, left %v", purged, tombstones)
+	}
+}
+
+// A forgotten word stays gone through compaction, and comes back once it is
+// typed again
+func TestForgetCompact(t *testing.T) {
+	p := pathsIn(t.TempDir())
+	now := time.Now().Truncate(time.Second)
+	if err := SaveSnapshot(p.snapshot, Snapshot{"secret": {20, now.Add(-time.Hour)}, "hello": {3, now.Add(-time.Hour)}}); err != nil {
+		t.Fatal(err)
+	}
+	writeLearnLog(t, p.learnLog, []LearnedWord{{"secret", now.Add(-time.Minute)}})
+	tombstones := Tombstones{"secret": now}
+
+	compact := func() Snapshot {
+		t.Helper()
+		if _, err := Compact(p.learnLog, p.snapshot, p.phrases, tombstones, defaultPrune, now.Add(time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+		counts, err := LoadSnapshot(p.snapshot)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return counts
+	}
+	if counts := compact(); counts["secret"].count != 0 || counts["hello"].count != 3 {
+		t.Fatalf("after forgetting: %v", counts)
+	}
+	if counts := compact(); counts["secret"].count != 0 {
+		t.Fatalf("came back on the next compaction: %v", counts)
+	}
+
+	writeLearnLog(t, p.learnLog, []LearnedWord{{"secret", now.Add(time.Minute)}})
+	if counts := compact(); counts["secret"].count != 1 {
+		t.Errorf("typed again: %v", counts)
+	}
+}
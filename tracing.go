@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	mathrand "math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Spans of the completion requests of serve mode, sent to an OpenTelemetry
+// collector (serve.trace) as OTLP over HTTP with JSON, so the daemon shows up
+// in the traces of the system around it without the OpenTelemetry SDK. A
+// request with a traceparent header joins the trace of its caller. The typed
+// words are never recorded, only their length
+//
+//	suggest               the whole request
+//	├─ tenant             loading the learned data of the client
+//	├─ lookup             the trie completions
+//	├─ sources            fuzzy, lead, pack and case candidates
+//	├─ rank               ignores, typos, spellings, pins and the guard
+//	└─ encode             the JSON response
+const (
+	traceBatch = 512             // spans sent at once
+	traceEvery = 5 * time.Second // longest a span waits to be sent
+	traceQueue = 4096            // spans waiting, more are dropped rather than slowing requests down
+)
+
+type tracer struct {
+	endpoint string // Eg:- http://localhost:4318/v1/traces
+	sample   float64
+	client   *http.Client
+	queue    chan *span
+	flush    chan chan struct{}
+	once     sync.Once
+}
+
+// One timed step of a request, nil when the request is not traced
+type span struct {
+	tracer  *tracer
+	traceID [16]byte
+	id      [8]byte
+	parent  [8]byte // zero for the first span of the trace
+	name    string
+	server  bool // received the request
+	start   time.Time
+	end     time.Time
+	attrs   map[string]any
+}
+
+// Returns the tracer of the serve config, nil when serve.trace is empty.
+// Spans go to the endpoint's /v1/traces unless it names another path
+func newTracer(c ServeConfig) *tracer {
+	if c.Trace == "" {
+		return nil
+	}
+	endpoint := c.Trace
+	if u, err := url.Parse(c.Trace); err == nil && strings.Trim(u.Path, "/") == "" {
+		endpoint = strings.TrimSuffix(c.Trace, "/") + "/v1/traces"
+	}
+	t := &tracer{endpoint: endpoint, sample: c.TraceSample, client: &http.Client{Timeout: 10 * time.Second},
+		queue: make(chan *span, traceQueue), flush: make(chan chan struct{})}
+	go t.export()
+	return t
+}
+
+// Starts the span of a request received, in the trace of its traceparent
+// header if it has one. Requests whose caller does not sample them, and of the
+// others all but serve.trace_sample, are not traced
+func (t *tracer) Start(r *http.Request, name string) *span {
+	if t == nil {
+		return nil
+	}
+	s := &span{tracer: t, name: name, server: true, start: time.Now(), attrs: make(map[string]any)}
+	if traceID, parent, sampled, ok := parseTraceparent(r.Header.Get("traceparent")); ok {
+		if !sampled {
+			return nil
+		}
+		s.traceID, s.parent = traceID, parent
+	} else if mathrand.Float64() >= t.sample {
+		return nil
+	} else {
+		rand.Read(s.traceID[:])
+	}
+	rand.Read(s.id[:])
+	return s
+}
+
+// Starts a step of s
+func (s *span) Child(name string) *span {
+	if s == nil {
+		return nil
+	}
+	child := &span{tracer: s.tracer, traceID: s.traceID, parent: s.id, name: name, start: time.Now(), attrs: make(map[string]any)}
+	rand.Read(child.id[:])
+	return child
+}
+
+// Records an attribute of the span, a string, int, float64 or bool
+func (s *span) Set(key string, value any) {
+	if s != nil {
+		s.attrs[key] = value
+	}
+}
+
+// Ends the span and queues it to be sent
+func (s *span) End() {
+	if s == nil {
+		return
+	}
+	s.end = time.Now()
+	select {
+	case s.tracer.queue <- s:
+	default: // the collector is not keeping up
+	}
+}
+
+// Sends whatever is queued and stops sending
+func (t *tracer) Close() {
+	if t == nil {
+		return
+	}
+	t.once.Do(func() {
+		done := make(chan struct{})
+		t.flush <- done
+		<-done
+	})
+}
+
+func (t *tracer) export() {
+	ticker := time.NewTicker(traceEvery)
+	defer ticker.Stop()
+	var batch []*span
+	failing := false
+	send := func() {
+		if len(batch) == 0 {
+			return
+		}
+		err := t.send(batch)
+		if err != nil && !failing {
+			fmt.Println("sending traces failed:", err) // once until it works again
+		}
+		failing, batch = err != nil, batch[:0]
+	}
+	for {
+		select {
+		case s := <-t.queue:
+			if batch = append(batch, s); len(batch) >= traceBatch {
+				send()
+			}
+		case <-ticker.C:
+			send()
+		case done := <-t.flush:
+			for len(t.queue) > 0 {
+				batch = append(batch, <-t.queue)
+			}
+			send()
+			close(done)
+			return
+		}
+	}
+}
+
+// Posts the spans as an OTLP ExportTraceServiceRequest
+func (t *tracer) send(spans []*span) error {
+	var out []map[string]any
+	for _, s := range spans {
+		o := map[string]any{
+			"traceId":           hex.EncodeToString(s.traceID[:]),
+			"spanId":            hex.EncodeToString(s.id[:]),
+			"name":              s.name,
+			"kind":              1, // internal
+			"startTimeUnixNano": strconv.FormatInt(s.start.UnixNano(), 10),
+			"endTimeUnixNano":   strconv.FormatInt(s.end.UnixNano(), 10),
+			"attributes":        otlpAttributes(s.attrs),
+		}
+		if s.server {
+			o["kind"] = 2
+		}
+		if s.parent != [8]byte{} {
+			o["parentSpanId"] = hex.EncodeToString(s.parent[:])
+		}
+		out = append(out, o)
+	}
+	body, err := json.Marshal(map[string]any{"resourceSpans": []any{map[string]any{
+		"resource":   map[string]any{"attributes": otlpAttributes(map[string]any{"service.name": "autocomplete"})},
+		"scopeSpans": []any{map[string]any{"scope": map[string]any{"name": "autocomplete/serve"}, "spans": out}},
+	}}})
+	if err != nil {
+		return err
+	}
+	resp, err := t.client.Post(t.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s: %s", t.endpoint, resp.Status)
+	}
+	return nil
+}
+
+// Attributes in the OTLP form, Eg:- {"key": "candidates", "value": {"intValue": "3"}}
+func otlpAttributes(attrs map[string]any) []any {
+	out := []any{}
+	for key, v := range attrs {
+		var value map[string]any
+		switch v := v.(type) {
+		case int:
+			value = map[string]any{"intValue": strconv.Itoa(v)}
+		case float64:
+			value = map[string]any{"doubleValue": v}
+		case bool:
+			value = map[string]any{"boolValue": v}
+		default:
+			value = map[string]any{"stringValue": fmt.Sprint(v)}
+		}
+		out = append(out, map[string]any{"key": key, "value": value})
+	}
+	return out
+}
+
+// Splits a W3C traceparent header, Eg:- 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+func parseTraceparent(header string) (traceID [16]byte, parent [8]byte, sampled, ok bool) {
+	fields := strings.Split(header, "-")
+	if len(fields) < 4 || len(fields[0]) != 2 || fields[0] == "ff" || len(fields[1]) != 32 || len(fields[2]) != 16 || len(fields[3]) != 2 {
+		return traceID, parent, false, false
+	}
+	flags, err := hex.DecodeString(fields[3])
+	if _, err1 := hex.Decode(traceID[:], []byte(fields[1])); err1 != nil || err != nil {
+		return traceID, parent, false, false
+	}
+	if _, err := hex.Decode(parent[:], []byte(fields[2])); err != nil {
+		return traceID, parent, false, false
+	}
+	if traceID == [16]byte{} || parent == [8]byte{} {
+		return traceID, parent, false, false // invalid by the spec
+	}
+	return traceID, parent, flags[0]&1 == 1, true
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Spans go to the collector as OTLP JSON, in the trace of the caller's traceparent
+func TestTracing(t *testing.T) {
+	bodies := make(chan []byte, 4)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			t.Errorf("spans sent to %s", r.URL.Path)
+		}
+		body, _ := io.ReadAll(r.Body)
+		bodies <- body
+	}))
+	defer collector.Close()
+	tr := newTracer(ServeConfig{Trace: collector.URL, TraceSample: 1})
+
+	r := httptest.NewRequest("GET", "/suggest?word=he", nil)
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	sp := tr.Start(r, "suggest")
+	step := sp.Child("lookup")
+	step.Set("candidates", 3)
+	step.End()
+	sp.End()
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	if tr.Start(r, "suggest") != nil {
+		t.Error("traced a request its caller did not sample")
+	}
+	tr.Close()
+
+	var sent struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []struct {
+					TraceID, SpanID, ParentSpanID, Name string
+					Kind                                int
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal(<-bodies, &sent); err != nil {
+		t.Fatal(err)
+	}
+	spans := sent.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 || spans[0].Name != "lookup" || spans[1].Name != "suggest" {
+		t.Fatalf("sent %+v", spans)
+	}
+	if spans[1].TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || spans[1].ParentSpanID != "00f067aa0ba902b7" || spans[1].Kind != 2 {
+		t.Errorf("the request span %+v is not in the caller's trace", spans[1])
+	}
+	if spans[0].TraceID != spans[1].TraceID || spans[0].ParentSpanID != spans[1].SpanID {
+		t.Errorf("the lookup span %+v is not below the request", spans[0])
+	}
+
+	if newTracer(ServeConfig{Trace: collector.URL}).Start(httptest.NewRequest("GET", "/suggest", nil), "suggest") != nil {
+		t.Error("traced a request with trace_sample = 0")
+	}
+	for _, c := range []ServeConfig{{TeamMembers: 1, Trace: "localhost:4318"}, {TeamMembers: 1, TraceSample: 2}} {
+		if c.validate() == nil {
+			t.Errorf("accepted %+v", c)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/b0tShaman/autocomplete-cli/trie"
+)
+
+// autocomplete train [--model] [pattern...]
+// Learns the words of the files matching the patterns into the learned counts,
+// reading each as a stream so a file of any size fits. ** in a pattern matches
+// any number of directories, Eg:- train ~/notes/**/*.md. With --model, or
+// without patterns, it trains the next-word model too, on the files or on the
+// learn log
+func trainCommand(args []string) error {
+	model := false
+	var patterns []string
+	for _, arg := range args {
+		if arg == "--model" {
+			model = true
+		} else {
+			patterns = append(patterns, arg)
+		}
+	}
+	if len(patterns) == 0 {
+		return trainModel(nil)
+	}
+	var files []string
+	for _, pattern := range patterns {
+		matches, err := expandPattern(pattern)
+		if err != nil {
+			return err
+		}
+		files = append(files, matches...)
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no files match %s", strings.Join(patterns, " "))
+	}
+
+	cfg, err := LoadConfig(configPath())
+	if err != nil {
+		return err
+	}
+	prof, problems := openProfile(paths)
+	defer prof.Close()
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", problemStatus(problems))
+	}
+	counts, err := LoadSnapshot(paths.snapshot)
+	if err != nil {
+		return err
+	}
+	// Ctrl+C stops at the file being read, keeping what was learned before it
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	before, words := len(counts), 0
+	for _, file := range files {
+		n, err := learnFile(ctx, cfg, prof.tombstones, counts, file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", file, err)
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		words += n
+	}
+	if err := SaveSnapshot(paths.snapshot, counts); err != nil {
+		return err
+	}
+	fmt.Printf("learned %d words (%d new) from %d files into %s\n", words, len(counts)-before, len(files), paths.snapshot)
+	if model {
+		return trainModel(files)
+	}
+	return nil
+}
+
+// Adds the words of file to counts, used when the file was last modified, and
+// returns how many it learned. Words the token policy does not learn and
+// forgotten ones are left out
+func learnFile(ctx context.Context, cfg Config, tombstones Tombstones, counts Snapshot, file string) (int, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	at := info.ModTime()
+
+	learned := trie.New()
+	tokens := cfg.Tokens
+	p, err := learned.LearnFrom(ctx, f, trie.LearnOptions{
+		WordRune: func(r rune) bool { return isWordRune(r, cfg.WordChars) },
+		Keep: func(word string) bool {
+			return tokens.Policy(word) == tokenLearn && !tombstones.Buried(word, at)
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+	for _, w := range learned.Words() {
+		counts.Add(w.Value, w.Count, at)
+	}
+	return p.Words, nil
+}
+
+// The files matching pattern, sorted. ** matches any number of directories,
+// the rest as in filepath.Match
+func expandPattern(pattern string) ([]string, error) {
+	if rest, ok := strings.CutPrefix(pattern, "~/"); ok { // quoted, so the shell left it
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		pattern = filepath.Join(home, rest)
+	}
+	before, after, ok := strings.Cut(filepath.ToSlash(pattern), "**")
+	if !ok {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		var files []string
+		for _, m := range matches {
+			if info, err := os.Stat(m); err == nil && info.Mode().IsRegular() {
+				files = append(files, m)
+			}
+		}
+		return files, nil
+	}
+
+	root := filepath.FromSlash(strings.TrimSuffix(before, "/"))
+	if root == "" {
+		root = "."
+	}
+	rest := strings.TrimPrefix(after, "/")
+	if _, err := filepath.Match(rest, ""); err != nil {
+		return nil, err
+	}
+	var files []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return nil // unreadable directories are skipped
+		}
+		rel, _ := filepath.Rel(root, path)
+		segments := strings.Split(filepath.ToSlash(rel), "/")
+		for i := range segments {
+			if matched, _ := filepath.Match(rest, strings.Join(segments[i:], "/")); matched || rest == "" {
+				files = append(files, path)
+				break
+			}
+		}
+		return nil
+	})
+	return files, err
+}
+
+// Trains the next-word model on files, by default on the learn log. The files
+// are read one after the other as the model is trained
+func trainModel(files []string) error {
+	corpus := &corpusReader{files: files}
+	defer corpus.Close()
+	var text io.Reader = corpus
+	if len(files) == 0 {
+		history, err := ReadLearnLog(paths.learnLog)
+		if err != nil {
+			return err
+		}
+		var words strings.Builder
+		for _, lw := range history {
+			words.WriteString(lw.word + " ")
+		}
+		text = strings.NewReader(words.String())
+	}
+
+	m, err := TrainModel(text, modelOrder)
+	if err != nil {
+		return err
+	}
+	if m.Runes <= 1 { // the space starting every text
+		return fmt.Errorf("nothing to train on")
+	}
+	if err := m.Save(paths.model); err != nil {
+		return err
+	}
+	fmt.Println("trained", m)
+	return nil
+}
+
+// Reads files one after the other with a space after each, opening each once
+// the one before it is read
+type corpusReader struct {
+	files []string
+	f     *os.File
+}
+
+func (c *corpusReader) Read(p []byte) (int, error) {
+	if c.f == nil {
+		if len(c.files) == 0 {
+			return 0, io.EOF
+		}
+		f, err := os.Open(c.files[0])
+		if err != nil {
+			return 0, err
+		}
+		c.f, c.files = f, c.files[1:]
+	}
+	n, err := c.f.Read(p)
+	if err == io.EOF && n == 0 && len(p) > 0 {
+		c.f.Close()
+		c.f = nil
+		p[0] = ' '
+		return 1, nil
+	}
+	if err == io.EOF {
+		err = nil // the next Read ends the file
+	}
+	return n, err
+}
+
+func (c *corpusReader) Close() {
+	if c.f != nil {
+		c.f.Close()
+	}
+}
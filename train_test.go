@@ -0,0 +1,65 @@
+package main
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// train streams the words of the files matching a pattern into the learned counts
+func TestTrainCommand(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(envPrefix+"_CONFIG", filepath.Join(dir, "config.toml"))
+	old := paths
+	paths = pathsIn(filepath.Join(dir, "profile"))
+	t.Cleanup(func() { paths = old })
+	if err := paths.mkdir(); err != nil {
+		t.Fatal(err)
+	}
+	notes := filepath.Join(dir, "notes")
+	os.MkdirAll(filepath.Join(notes, "2024"), 0755)
+	os.WriteFile(filepath.Join(notes, "2024", "a.md"), []byte("Gophers dig. gophers (0xdeadbeef)\n"), 0644)
+	os.WriteFile(filepath.Join(notes, "b.md"), []byte("dig deeper"), 0644)
+	os.WriteFile(filepath.Join(notes, "2024", "c.txt"), []byte("skipped"), 0644)
+
+	if err := trainCommand([]string{filepath.Join(notes, "**", "*.md")}); err != nil {
+		t.Fatal(err)
+	}
+	counts, err := LoadSnapshot(paths.snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for word, want := range map[string]int{"Gophers": 1, "gophers": 1, "dig": 2, "deeper": 1, "skipped": 0, "0xdeadbeef": 0} {
+		if counts[word].count != want {
+			t.Errorf("%s counts %d, want %d", word, counts[word].count, want)
+		}
+	}
+	if err := trainCommand([]string{filepath.Join(notes, "*.txt")}); err == nil {
+		t.Error("trained on a pattern matching nothing")
+	}
+
+	// The model reads the files as a stream, seeing them as one text
+	if err := trainCommand([]string{"--model", filepath.Join(notes, "**", "*.md")}); err != nil {
+		t.Fatal(err)
+	}
+	text := "Gophers dig. gophers (0xdeadbeef)\n dig deeper"
+	whole, _ := TrainModel(strings.NewReader(text), modelOrder)
+	streamed, _ := TrainModel(iotest.OneByteReader(strings.NewReader(text)), modelOrder)
+	saved, err := LoadModel(paths.model)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tiny, _ := TrainModel(strings.NewReader(" \tab\n"), 2)
+	want := map[string]map[string]int{" ": {"a": 1}, "a": {"b": 1}, "b": {" ": 1}, "": {"a": 1, "b": 1, " ": 1}}
+	if !maps.EqualFunc(tiny.Contexts, want, maps.Equal) {
+		t.Errorf("model of ab %v, want %v", tiny.Contexts, want)
+	}
+	for _, m := range []*Model{streamed, saved} {
+		if m.Runes != len(modelText(text)) || !maps.EqualFunc(m.Contexts, whole.Contexts, maps.Equal) {
+			t.Errorf("model of %d characters and %d contexts, want %d and %d", m.Runes, len(m.Contexts), len(modelText(text)), len(whole.Contexts))
+		}
+	}
+}
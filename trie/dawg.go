@@ -0,0 +1,59 @@
+package trie
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Shares the parts of the Trie which hold the same words, turning it into a
+// DAWG: Eg:- the endings "ed", "ing" and "s" below walk, talk and thousands of
+// other words are kept once, with their counts. Returns root, which must not
+// change from then on since a change would show wherever its part is shared.
+// Clone it for a Trie to change again. Made for a dictionary loaded once, below
+// the words learned in a Stack. A Trie from Map is returned as it is
+func (root *Trie) Minimize() *Trie {
+	if root.image != nil {
+		return root // mapped, the file is shared already
+	}
+	m := minimizer{shared: make(map[string]*Trie), ids: make(map[*Trie]int)}
+	return m.share(root)
+}
+
+type minimizer struct {
+	shared map[string]*Trie // the first node seen with each key
+	ids    map[*Trie]int    // of the shared nodes
+}
+
+// The shared node holding the same words as node, after sharing its children
+func (m *minimizer) share(node *Trie) *Trie {
+	for r, child := range node.children {
+		node.children[r] = m.share(child)
+	}
+	key := m.key(node)
+	if shared, ok := m.shared[key]; ok {
+		return shared
+	}
+	m.ids[node] = len(m.ids)
+	m.shared[key] = node
+	return node
+}
+
+// The edge, count and children of node, which are shared already.
+// Eg:- "ing" 1 [] for the ending of walking
+func (m *minimizer) key(node *Trie) string {
+	var b strings.Builder
+	b.WriteString(node.label)
+	b.WriteByte(0)
+	b.WriteString(strconv.Itoa(node.wordCount))
+	runes := make([]rune, 0, len(node.children))
+	for r := range node.children {
+		runes = append(runes, r)
+	}
+	slices.Sort(runes)
+	for _, r := range runes {
+		b.WriteByte(0)
+		b.WriteString(strconv.Itoa(m.ids[node.children[r]]))
+	}
+	return b.String()
+}
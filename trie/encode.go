@@ -0,0 +1,124 @@
+package trie
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"maps"
+	"slices"
+	"unicode/utf8"
+)
+
+// Longest edge Decode accepts, so a damaged file cannot make it allocate a lot
+const maxLabel = 1 << 16
+
+// Writes the Trie with its counts in a binary form Decode reads back without
+// inserting the words again. Each node is its edge, its count and how many
+// children follow, the children in order of their first letter
+func (root *Trie) Encode(w io.Writer) error {
+	b := bufio.NewWriter(w)
+	root.encode(b)
+	return b.Flush()
+}
+
+func (root *Trie) encode(b *bufio.Writer) {
+	b.Write(binary.AppendUvarint(nil, uint64(len(root.label))))
+	b.WriteString(root.label)
+	b.Write(binary.AppendUvarint(nil, uint64(root.wordCount)))
+	children := sortedEdges(root)
+	b.Write(binary.AppendUvarint(nil, uint64(len(children))))
+	for _, child := range children {
+		child.encode(b)
+	}
+}
+
+// The children of root in order of the first letter of their edge
+func sortedEdges(root *Trie) []*Trie {
+	runes := slices.Sorted(maps.Keys(maps.Collect(root.edges())))
+	children := make([]*Trie, len(runes))
+	for i, r := range runes {
+		children[i] = root.edge(r)
+	}
+	return children
+}
+
+// Reads a Trie written by Encode. The edges share one string read at once
+func Decode(r io.Reader) (*Trie, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	d := decoder{data: string(data)}
+	root, err := d.node(true)
+	if err == nil && d.at != len(d.data) {
+		err = errCorrupt
+	}
+	return root, err
+}
+
+var errCorrupt = errors.New("trie: corrupt encoding")
+
+type decoder struct {
+	data string
+	at   int
+}
+
+func (d *decoder) uvarint() (uint64, error) {
+	var v uint64
+	for shift := 0; shift < 64; shift += 7 {
+		if d.at >= len(d.data) {
+			return 0, io.ErrUnexpectedEOF
+		}
+		b := d.data[d.at]
+		d.at++
+		v |= uint64(b&0x7f) << shift
+		if b < 0x80 {
+			return v, nil
+		}
+	}
+	return 0, errCorrupt
+}
+
+func (d *decoder) node(isRoot bool) (*Trie, error) {
+	size, err := d.uvarint()
+	if err != nil {
+		return nil, err
+	}
+	if size > maxLabel || (size == 0) != isRoot {
+		return nil, errCorrupt
+	}
+	if size > uint64(len(d.data)-d.at) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	label := d.data[d.at : d.at+int(size)]
+	d.at += int(size)
+	if !utf8.ValidString(label) {
+		return nil, errCorrupt
+	}
+	count, err := d.uvarint()
+	if err != nil {
+		return nil, err
+	}
+	children, err := d.uvarint()
+	if err != nil {
+		return nil, err
+	}
+
+	node := &Trie{label: label, wordCount: int(count)}
+	if children > 0 {
+		node.children = make(map[rune]*Trie, min(children, 64))
+	}
+	for range children {
+		child, err := d.node(false)
+		if err != nil {
+			return nil, err
+		}
+		first, _ := utf8.DecodeRuneInString(child.label)
+		if node.children[first] != nil {
+			return nil, errCorrupt
+		}
+		node.children[first] = child
+	}
+	return node, nil
+}
@@ -0,0 +1,110 @@
+package trie
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// Longest word LearnFrom learns, longer runs of letters are left out. Eg:- a
+// base64 blob in a log
+const maxLearned = 64
+
+// Bytes LearnFrom reads between two progress reports when LearnOptions leaves
+// it at 0
+const progressEvery = 64 << 10
+
+// How far LearnFrom got
+type Progress struct {
+	Bytes int64 // read from the stream
+	Words int   // learned, each use counts
+}
+
+// How LearnFrom splits a stream into words and reports on it. The zero value
+// learns every word of letters and digits
+type LearnOptions struct {
+	WordRune      func(r rune) bool      // whether r goes in a word, letters, digits, ' and - when nil. What is not a letter or digit is trimmed off the ends
+	Keep          func(word string) bool // whether to learn word, Eg:- leaving out numbers; every word when nil
+	Progress      func(p Progress)       // called every ProgressEvery bytes and once at the end
+	ProgressEvery int64
+}
+
+func defaultWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '\'' || r == '-'
+}
+
+// Inserts the words of r as they are read, so a stream of any length takes the
+// memory of one word. Eg:- a log being written, documents or chat history.
+// Stops when ctx is done, between two progress reports, keeping what it learned
+// so far; a read which is waited for is not interrupted. Returns how far it got
+// and the error which stopped it, nil at the end of r
+//
+//	p, err := t.LearnFrom(ctx, file, trie.LearnOptions{Progress: func(p trie.Progress) {
+//		fmt.Printf("\r%d words", p.Words)
+//	}})
+func (root *Trie) LearnFrom(ctx context.Context, r io.Reader, opts LearnOptions) (Progress, error) {
+	return learnFrom(ctx, r, opts, root.Insert)
+}
+
+// See Trie.LearnFrom, the words go into layer i
+func (s *Stack) LearnFrom(ctx context.Context, i int, r io.Reader, opts LearnOptions) (Progress, error) {
+	return learnFrom(ctx, r, opts, func(word string) { s.Insert(i, word) })
+}
+
+func learnFrom(ctx context.Context, r io.Reader, opts LearnOptions, insert func(word string)) (Progress, error) {
+	wordRune := opts.WordRune
+	if wordRune == nil {
+		wordRune = defaultWordRune
+	}
+	every := opts.ProgressEvery
+	if every <= 0 {
+		every = progressEvery
+	}
+
+	var p Progress
+	var word []rune
+	learn := func() {
+		w := strings.TrimFunc(string(word), func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+		word = word[:0]
+		if w == "" || len([]rune(w)) > maxLearned || opts.Keep != nil && !opts.Keep(w) {
+			return
+		}
+		insert(w)
+		p.Words++
+	}
+	b := bufio.NewReader(r)
+	next := every
+	for {
+		c, size, err := b.ReadRune()
+		if err != nil {
+			learn()
+			if opts.Progress != nil {
+				opts.Progress(p)
+			}
+			if err == io.EOF {
+				err = nil
+			}
+			return p, err
+		}
+		p.Bytes += int64(size)
+		if !wordRune(c) {
+			if len(word) > 0 {
+				learn()
+			}
+		} else if len(word) <= maxLearned {
+			word = append(word, c) // one too many is enough to leave it out
+		}
+
+		if p.Bytes >= next {
+			next += every
+			if err := ctx.Err(); err != nil {
+				return p, err // the word cut off is left out
+			}
+			if opts.Progress != nil {
+				opts.Progress(p)
+			}
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package trie
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"iter"
+	"math"
+	"runtime"
+	"sort"
+	"unicode/utf8"
+)
+
+// A Trie laid out to be read where it lies, Eg:- in a memory mapped file, so
+// nothing is copied onto the heap and processes mapping the same file share its
+// pages. Little endian, the offset of the root first and then the nodes, each
+//
+//	count, label length, children    uint32 each
+//	label
+//	first letter, offset             uint32 each, per child sorted by letter
+const nodeHeader = 12
+
+// Writes the Trie in the layout Map reads. The children come before their
+// parent, so the offsets are known when the parent is written
+func (root *Trie) EncodeMapped(w io.Writer) error {
+	var b bytes.Buffer
+	b.Write(make([]byte, 4))
+	at, err := encodeMapped(root, &b)
+	if err != nil {
+		return err
+	}
+	data := b.Bytes()
+	binary.LittleEndian.PutUint32(data, uint32(at))
+	_, err = w.Write(data)
+	return err
+}
+
+func encodeMapped(root *Trie, b *bytes.Buffer) (int, error) {
+	children := sortedEdges(root)
+	offsets := make([]int, len(children))
+	for i, child := range children {
+		var err error
+		if offsets[i], err = encodeMapped(child, b); err != nil {
+			return 0, err
+		}
+	}
+	at := b.Len()
+	if uint64(at) > math.MaxUint32 || uint64(root.wordCount) > math.MaxUint32 {
+		return 0, errors.New("trie: too large for the mapped layout")
+	}
+	b.Write(binary.LittleEndian.AppendUint32(nil, uint32(root.wordCount)))
+	b.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(root.label))))
+	b.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(children))))
+	b.WriteString(root.label)
+	for i, child := range children {
+		first, _ := utf8.DecodeRuneInString(child.label)
+		b.Write(binary.LittleEndian.AppendUint32(nil, uint32(first)))
+		b.Write(binary.LittleEndian.AppendUint32(nil, uint32(offsets[i])))
+	}
+	return at, nil
+}
+
+// Returns a read-only Trie over data, written by EncodeMapped, which must not
+// change while the Trie is used. Nodes are read as lookups reach them, a
+// damaged node reads as missing. Insert and Delete must not be used, Clone it
+// for a Trie to change
+func Map(data []byte) (*Trie, error) {
+	if len(data) < 4 {
+		return nil, errCorrupt
+	}
+	img := &image{data: data}
+	root := img.node(int(binary.LittleEndian.Uint32(data)))
+	if root == nil || root.label != "" {
+		return nil, errCorrupt
+	}
+	return root, nil
+}
+
+// Calls release once the data of a Trie from Map is no longer read: the Trie
+// and every node read from it are gone, Eg:- to unmap a file once the Trie was
+// swapped for another one. Reports false and does nothing for other Tries.
+// Called once per Trie from Map
+func (root *Trie) OnRelease(release func()) bool {
+	if root.image == nil {
+		return false
+	}
+	runtime.SetFinalizer(root.image, func(*image) { release() })
+	return true
+}
+
+// The data of a Trie from Map
+type image struct {
+	data []byte
+}
+
+// The node at offset at, nil if it does not fit in the data
+func (img *image) node(at int) *Trie {
+	if at < 4 || at > len(img.data)-nodeHeader {
+		return nil
+	}
+	size, children := img.uint32(at+4), img.uint32(at+8)
+	if size < 0 || children < 0 || size > len(img.data)-at-nodeHeader || // negative past 2GB on 32 bit
+		children > (len(img.data)-at-nodeHeader-size)/8 {
+		return nil
+	}
+	start := at + nodeHeader
+	label := img.data[start : start+size]
+	if !utf8.Valid(label) {
+		return nil
+	}
+	return &Trie{label: string(label), wordCount: img.uint32(at), image: img, at: at}
+}
+
+func (img *image) uint32(at int) int {
+	return int(binary.LittleEndian.Uint32(img.data[at:]))
+}
+
+// Where the children of the node at offset at are listed and how many there are
+func (img *image) table(at int) (int, int) {
+	return at + nodeHeader + img.uint32(at+4), img.uint32(at + 8)
+}
+
+// See Trie.edge
+func (img *image) edge(at int, r rune) *Trie {
+	table, n := img.table(at)
+	i := sort.Search(n, func(i int) bool { return rune(img.uint32(table+8*i)) >= r })
+	if i == n || rune(img.uint32(table+8*i)) != r {
+		return nil
+	}
+	return img.checked(at, img.uint32(table+8*i+4), r)
+}
+
+// See Trie.edges
+func (img *image) edges(at int) iter.Seq2[rune, *Trie] {
+	return func(yield func(rune, *Trie) bool) {
+		table, n := img.table(at)
+		for i := range n {
+			r := rune(img.uint32(table + 8*i))
+			if child := img.checked(at, img.uint32(table+8*i+4), r); child != nil && !yield(r, child) {
+				return
+			}
+		}
+	}
+}
+
+// The node at offset at if its edge starts with r as its parent at offset
+// parent lists it. Children come first, so a damaged file cannot loop
+func (img *image) checked(parent, at int, r rune) *Trie {
+	if at >= parent {
+		return nil
+	}
+	child := img.node(at)
+	if child == nil || child.label == "" {
+		return nil
+	}
+	if first, _ := utf8.DecodeRuneInString(child.label); first != r {
+		return nil
+	}
+	return child
+}
@@ -0,0 +1,264 @@
+package trie
+
+import (
+	"iter"
+	"maps"
+	"slices"
+)
+
+// Several Tries looked up as one, each layer on top of the ones before it. A
+// word is used as many times as in all layers together, so a layer can be left
+// as it is while the words of another one change. Eg:- a dictionary shared by
+// everyone below the words each user learned
+//
+//	s := trie.NewStack(dictionary, learned)
+//	s.Insert(1, "hello") // learned, the dictionary stays untouched
+//	s.Autofill("he")
+type Stack struct {
+	layers []*Trie // the part of each layer below prefix, nil where none is
+	prefix string
+	hidden map[string]bool // words left out of every layer, shared by the whole stack
+}
+
+// Returns a Stack of layers, the first one at the bottom
+func NewStack(layers ...*Trie) *Stack {
+	return &Stack{layers: layers, hidden: make(map[string]bool)}
+}
+
+// Returns layer i, nil below a Node the layer has no words under
+func (s *Stack) Layer(i int) *Trie {
+	return s.layers[i]
+}
+
+// Replaces layer i, Eg:- with the dictionary read again, leaving the others and
+// the hidden words as they are. Clones made before keep the old layer
+func (s *Stack) SetLayer(i int, t *Trie) {
+	s.layers[i] = t
+}
+
+// Insert word into layer i
+func (s *Stack) Insert(i int, word string) {
+	s.InsertCount(i, word, 1)
+}
+
+// Insert word into layer i as if it was inserted count times. A hidden word
+// shows again
+func (s *Stack) InsertCount(i int, word string, count int) {
+	delete(s.hidden, s.prefix+word)
+	s.layers[i].InsertCount(word, count)
+}
+
+// Returns a copy of the Stack to read from another goroutine while this one
+// changes. The layers below shared are not copied, they must not change
+// meanwhile. Eg:- s.Clone(1) shares the dictionary
+func (s *Stack) Clone(shared int) *Stack {
+	clone := &Stack{layers: slices.Clone(s.layers), prefix: s.prefix, hidden: maps.Clone(s.hidden)}
+	for i := shared; i < len(clone.layers); i++ {
+		if clone.layers[i] != nil {
+			clone.layers[i] = clone.layers[i].Clone()
+		}
+	}
+	return clone
+}
+
+// Removes word from layer i, see Trie.Delete
+func (s *Stack) Delete(i int, word string) bool {
+	return s.layers[i].Delete(word)
+}
+
+// Takes one use of word back from layer i, see Trie.Decrement
+func (s *Stack) Decrement(i int, word string) bool {
+	return s.layers[i].Decrement(word)
+}
+
+// Leaves word out of every layer, also those which are not to be changed,
+// until it is inserted again
+func (s *Stack) Hide(word string) {
+	s.hidden[s.prefix+word] = true
+}
+
+// Returns the hidden words, sorted. Below a Node they still have the prefix
+func (s *Stack) Hidden() []string {
+	return slices.Sorted(maps.Keys(s.hidden))
+}
+
+// Returns how many times word was used in all layers together
+func (s *Stack) Count(word string) int {
+	if s.hidden[s.prefix+word] {
+		return 0
+	}
+	count := 0
+	for _, l := range s.layers {
+		if l != nil {
+			count += l.Count(word)
+		}
+	}
+	return count
+}
+
+// Returns the part of the Stack below prefix, nil if no word starts with it
+func (s *Stack) Node(prefix string) *Stack {
+	node := &Stack{layers: make([]*Trie, len(s.layers)), prefix: s.prefix + prefix, hidden: s.hidden}
+	found := false
+	for i, l := range s.layers {
+		if l != nil {
+			node.layers[i] = l.Node(prefix)
+			found = found || node.layers[i] != nil
+		}
+	}
+	if !found {
+		return nil
+	}
+	return node
+}
+
+// Iterates over the next letters and the part of the Stack below each, in no
+// particular order
+func (s *Stack) Children() iter.Seq2[rune, *Stack] {
+	return func(yield func(rune, *Stack) bool) {
+		seen := make(map[rune]bool)
+		for _, l := range s.layers {
+			if l == nil {
+				continue
+			}
+			for r := range l.edges() {
+				if !seen[r] {
+					seen[r] = true
+					if node := s.Node(string(r)); node != nil && !yield(r, node) {
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
+// Returns every word of the Stack with its count in all layers, in no
+// particular order. Below a Node the words lack the prefix
+func (s *Stack) Words() []Word {
+	var only []*Trie
+	for _, l := range s.layers {
+		if l != nil {
+			only = append(only, l)
+		}
+	}
+	if len(only) == 1 && len(s.hidden) == 0 {
+		return only[0].Words() // nothing to merge
+	}
+
+	var output []Word
+	index := make(map[string]int)
+	for _, l := range only {
+		for _, w := range l.Words() {
+			if s.hidden[s.prefix+w.Value] {
+				continue
+			}
+			if i, ok := index[w.Value]; ok {
+				output[i].Count += w.Count
+			} else {
+				index[w.Value] = len(output)
+				output = append(output, w)
+			}
+		}
+	}
+	return output
+}
+
+// See Trie.Autofill
+func (s *Stack) Autofill(word string) []string {
+	return s.AutofillScored(word, ByCount)
+}
+
+// See Trie.AutofillScored
+func (s *Stack) AutofillScored(word string, score Scorer) []string {
+	node := s.Node(word)
+	if len(word) == 0 || node == nil {
+		return nil
+	}
+	return completions(word, node.Words(), score)
+}
+
+// See Trie.AutofillFold
+func (s *Stack) AutofillFold(word string, score Scorer) []string {
+	if len(word) == 0 {
+		return nil
+	}
+	return completions("", s.merge(func(l *Trie) []Word { return l.foldMatches([]rune(word)) }), score)
+}
+
+// See Trie.AutofillFunc
+func (s *Stack) AutofillFunc(word string, fold func(rune) rune, score Scorer) []string {
+	if len(word) == 0 {
+		return nil
+	}
+	return completions("", s.merge(func(l *Trie) []Word { return l.funcMatches([]rune(word), fold) }), score)
+}
+
+// Adds up the words matches finds in each layer, leaving out the hidden ones
+func (s *Stack) merge(matches func(l *Trie) []Word) []Word {
+	var output []Word
+	index := make(map[string]int)
+	for _, l := range s.layers {
+		if l == nil {
+			continue
+		}
+		for _, w := range matches(l) {
+			if s.hidden[s.prefix+w.Value] {
+				continue
+			}
+			if i, ok := index[w.Value]; ok {
+				output[i].Count += w.Count
+			} else {
+				index[w.Value] = len(output)
+				output = append(output, w)
+			}
+		}
+	}
+	return output
+}
+
+// See Trie.AutofillFuzzy
+func (s *Stack) AutofillFuzzy(word string, edits int, score Scorer) []Match {
+	var output []Match
+	index := make(map[string]int)
+	for _, l := range s.layers {
+		if l == nil {
+			continue
+		}
+		for _, m := range l.fuzzyMatches(word, edits) {
+			if s.hidden[s.prefix+m.Value] {
+				continue
+			}
+			if i, ok := index[m.Value]; ok {
+				output[i].Count += m.Count
+				output[i].Edits = min(output[i].Edits, m.Edits)
+			} else {
+				index[m.Value] = len(output)
+				output = append(output, m)
+			}
+		}
+	}
+	return rankMatches(output, score)
+}
+
+// Returns the n highest scoring words
+func (s *Stack) Top(n int, score Scorer) []Word {
+	output := s.Words()
+	Sort(output, score)
+	return output[:min(n, len(output))]
+}
+
+// Words and uses of all layers together, Nodes those of every layer
+func (s *Stack) Stats() Stats {
+	var stats Stats
+	for _, w := range s.Words() {
+		stats.Words++
+		stats.Uses += w.Count
+	}
+	for _, l := range s.layers {
+		if l != nil {
+			stats.Nodes += l.Stats().Nodes
+		}
+	}
+	return stats
+}
@@ -0,0 +1,139 @@
+package trie
+
+import (
+	"container/heap"
+	"sort"
+	"unicode/utf8"
+)
+
+// Like AutofillScored, only the k highest scoring completions. They are kept in
+// a heap while the words below word are walked, instead of collecting and
+// sorting all of them, so short prefixes of a large dictionary stay quick.
+// Eg:- AutofillTop("he", 1, ByCount) --> lp
+func (root *Trie) AutofillTop(word string, k int, score Scorer) []string {
+	node := root.Node(word)
+	if len(word) == 0 || node == nil || k <= 0 {
+		return nil
+	}
+	top := &topWords{k: k}
+	node.walk([]byte(word), func(w []byte, count int) { top.offer(string(w), count, score) })
+	return top.completions(word)
+}
+
+// See Trie.AutofillTop
+func (s *Stack) AutofillTop(word string, k int, score Scorer) []string {
+	node := s.Node(word)
+	if len(word) == 0 || node == nil || k <= 0 {
+		return nil
+	}
+	var layers []*Trie
+	for _, l := range node.layers {
+		if l != nil {
+			layers = append(layers, l)
+		}
+	}
+	top := &topWords{k: k}
+	walkLayers(layers, []byte(word), func(w []byte, count int) {
+		if value := string(w); !s.hidden[s.prefix+value] {
+			top.offer(value, count, score)
+		}
+	})
+	return top.completions(word)
+}
+
+// Calls visit with every word below root, prefix followed by the rest of it,
+// and its count. The word is only valid during the call
+func (root *Trie) walk(prefix []byte, visit func(word []byte, count int)) {
+	if root.wordCount > 0 {
+		visit(prefix, root.wordCount)
+	}
+	for _, child := range root.edges() {
+		child.walk(append(prefix, child.label...), visit)
+	}
+}
+
+// Like Trie.walk over several layers at once, the counts of a word added up
+func walkLayers(layers []*Trie, prefix []byte, visit func(word []byte, count int)) {
+	if len(layers) == 1 {
+		layers[0].walk(prefix, visit) // nothing to add up below
+		return
+	}
+	count := 0
+	for _, l := range layers {
+		count += l.wordCount
+	}
+	if count > 0 {
+		visit(prefix, count)
+	}
+	for i, l := range layers {
+	next:
+		for r, child := range l.edges() {
+			for _, before := range layers[:i] {
+				if before.edge(r) != nil {
+					continue next // walked with that layer already
+				}
+			}
+			// The layers split their edges apart, so they are walked a letter at a time
+			below := []*Trie{child.first()}
+			for _, after := range layers[i+1:] {
+				if after.edge(r) != nil {
+					below = append(below, after.child(r))
+				}
+			}
+			walkLayers(below, utf8.AppendRune(prefix, r), visit)
+		}
+	}
+}
+
+type scoredWord struct {
+	value string
+	score float64
+}
+
+// The k best words offered so far, the worst of them first as a heap
+type topWords struct {
+	k     int
+	words []scoredWord
+}
+
+// Keeps word if it is among the k best so far, ranked like Sort does
+func (t *topWords) offer(word string, count int, score Scorer) {
+	s := score(word, count)
+	switch {
+	case s <= 0:
+	case len(t.words) < t.k:
+		heap.Push(t, scoredWord{word, s})
+	case worse(t.words[0], scoredWord{word, s}):
+		t.words[0] = scoredWord{word, s}
+		heap.Fix(t, 0)
+	}
+}
+
+// The words kept, best first, with what follows word in each
+func (t *topWords) completions(word string) []string {
+	sort.Slice(t.words, func(i, j int) bool { return worse(t.words[j], t.words[i]) })
+	result := make([]string, len(t.words))
+	for i, w := range t.words {
+		result[i] = w.value[len(word):]
+	}
+	return result
+}
+
+// Reports whether a ranks below b: it scores less, or the same and comes later
+// alphabetically
+func worse(a, b scoredWord) bool {
+	if a.score != b.score {
+		return a.score < b.score
+	}
+	return a.value > b.value
+}
+
+func (t *topWords) Len() int           { return len(t.words) }
+func (t *topWords) Less(i, j int) bool { return worse(t.words[i], t.words[j]) }
+func (t *topWords) Swap(i, j int)      { t.words[i], t.words[j] = t.words[j], t.words[i] }
+func (t *topWords) Push(x any)         { t.words = append(t.words, x.(scoredWord)) }
+func (t *topWords) Pop() any {
+	w := t.words[len(t.words)-1]
+	t.words = t.words[:len(t.words)-1]
+	return w
+}
@@ -0,0 +1,516 @@
+// Package trie stores words along with how many times they were used and
+// completes prefixes with them, most used first.
+//
+//	t := trie.New()
+//	t.Insert("hello")
+//	t.InsertCount("help", 3)
+//	t.Autofill("he") // ["lp", "llo"]
+//
+// It is a radix tree: a run of letters no other word branches off from is kept
+// on one edge, so a large dictionary takes a fraction of the nodes of one per
+// letter. A Trie is not safe for concurrent use, callers which share one guard
+// it themselves
+package trie
+
+import (
+	"iter"
+	"maps"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// The core data structure
+type Trie struct {
+	label     string         // letters of the edge from the parent, Eg:- "llo" below "he" for hello
+	children  map[rune]*Trie // by the first letter of their label, nil for none
+	wordCount int
+	image     *image // holds the children instead of the map in a Trie from Map
+	at        int    // of the node in image
+}
+
+// Descibes a word and how many times its been used
+type Word struct {
+	Value string
+	Count int
+}
+
+// Ranks a word used count times, higher first. Autofill leaves out the words
+// scoring 0 or less
+type Scorer func(word string, count int) float64
+
+// Ranks words by their count alone
+func ByCount(word string, count int) float64 {
+	return float64(count)
+}
+
+// Returns an empty Trie
+func New() *Trie {
+	return &Trie{
+		children:  make(map[rune]*Trie),
+		wordCount: 0,
+	}
+}
+
+// Insert word into the Trie
+func (root *Trie) Insert(word string) {
+	root.InsertCount(word, 1)
+}
+
+// Insert word into the Trie as if it was inserted count times
+func (root *Trie) InsertCount(word string, count int) {
+	for word != "" {
+		r, _ := utf8.DecodeRuneInString(word)
+		child := root.children[r]
+		if child == nil {
+			if root.children == nil {
+				root.children = make(map[rune]*Trie)
+			}
+			root.children[r] = &Trie{label: word, wordCount: count}
+			return
+		}
+		// The edge splits where word branches off it, Eg:- help into hel, p and lo
+		if n := commonPrefix(child.label, word); n < len(child.label) {
+			rest, _ := utf8.DecodeRuneInString(child.label[n:])
+			split := &Trie{label: child.label[:n], children: map[rune]*Trie{rest: child}}
+			child.label = child.label[n:]
+			root.children[r] = split
+			child = split
+		}
+		word = word[len(child.label):]
+		root = child
+	}
+	root.wordCount += count
+}
+
+// Bytes of the letters a and b start with alike
+func commonPrefix(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) {
+		r, size := utf8.DecodeRuneInString(a[n:])
+		if s, _ := utf8.DecodeRuneInString(b[n:]); s != r {
+			break
+		}
+		n += size
+	}
+	return n
+}
+
+// Removes word from the Trie, pruning the nodes nothing else uses.
+// Returns false if word was not in the Trie
+func (root *Trie) Delete(word string) bool {
+	if word == "" {
+		return false
+	}
+	r, _ := utf8.DecodeRuneInString(word)
+	child := root.children[r]
+	if child == nil || !strings.HasPrefix(word, child.label) {
+		return false
+	}
+
+	if rest := word[len(child.label):]; rest == "" {
+		if child.wordCount == 0 {
+			return false
+		}
+		child.wordCount = 0
+	} else if !child.Delete(rest) {
+		return false
+	}
+
+	if child.wordCount == 0 {
+		switch len(child.children) {
+		case 0:
+			delete(root.children, r)
+		case 1: // the edges join again
+			for _, only := range child.children {
+				child.label += only.label
+				child.children, child.wordCount = only.children, only.wordCount
+			}
+		}
+	}
+	return true
+}
+
+// Takes one use of word back, removing word once it has none left. Returns
+// false if word was not in the Trie
+func (root *Trie) Decrement(word string) bool {
+	switch root.Count(word) {
+	case 0:
+		return false
+	case 1:
+		return root.Delete(word)
+	}
+	root.InsertCount(word, -1)
+	return true
+}
+
+// Returns a copy of the Trie which changes independently of it
+func (root *Trie) Clone() *Trie {
+	clone := &Trie{label: root.label, wordCount: root.wordCount}
+	for k, v := range root.edges() {
+		if clone.children == nil {
+			clone.children = make(map[rune]*Trie)
+		}
+		clone.children[k] = v.Clone()
+	}
+	return clone
+}
+
+// Returns how many times word was used, 0 if it is not in the Trie
+func (root *Trie) Count(word string) int {
+	for word != "" {
+		r, _ := utf8.DecodeRuneInString(word)
+		if root = root.edge(r); root == nil || !strings.HasPrefix(word, root.label) {
+			return 0
+		}
+		word = word[len(root.label):]
+	}
+	return root.wordCount
+}
+
+// Returns the part of the Trie below prefix, nil if no word starts with it.
+// Within an edge it is a copy to read from, words go into the Trie itself
+func (root *Trie) Node(prefix string) *Trie {
+	for prefix != "" {
+		r, _ := utf8.DecodeRuneInString(prefix)
+		child := root.edge(r)
+		switch {
+		case child == nil:
+			return nil
+		case strings.HasPrefix(prefix, child.label):
+			prefix = prefix[len(child.label):]
+			root = child
+		case strings.HasPrefix(child.label, prefix):
+			return child.within(len(prefix))
+		default:
+			return nil
+		}
+	}
+	return root
+}
+
+// The part of the Trie n bytes into the edge of root, Eg:- "l" into "llo"
+func (root *Trie) within(n int) *Trie {
+	rest := *root
+	rest.label = root.label[n:]
+	r, _ := utf8.DecodeRuneInString(rest.label)
+	return &Trie{label: root.label[:n], children: map[rune]*Trie{r: &rest}}
+}
+
+// The child whose edge starts with r, nil if there is none
+func (root *Trie) edge(r rune) *Trie {
+	if root.image != nil {
+		return root.image.edge(root.at, r)
+	}
+	return root.children[r]
+}
+
+// Iterates over the first letters of the edges below root and their children,
+// in no particular order
+func (root *Trie) edges() iter.Seq2[rune, *Trie] {
+	if root.image != nil {
+		return root.image.edges(root.at)
+	}
+	return maps.All(root.children)
+}
+
+// The part of the Trie below the letter r, nil if no word goes on with it
+func (root *Trie) child(r rune) *Trie {
+	child := root.edge(r)
+	if child == nil {
+		return nil
+	}
+	return child.first()
+}
+
+// The part of child below the first letter of its edge
+func (child *Trie) first() *Trie {
+	if _, size := utf8.DecodeRuneInString(child.label); size < len(child.label) {
+		return child.within(size)
+	}
+	return child
+}
+
+// Iterates over the next letters and the part of the Trie below each, in no
+// particular order
+func (root *Trie) Children() iter.Seq2[rune, *Trie] {
+	return func(yield func(rune, *Trie) bool) {
+		for k, child := range root.edges() {
+			if !yield(k, child.first()) {
+				return
+			}
+		}
+	}
+}
+
+// Returns every word in the Trie with its count, in no particular order. Below
+// a Node the words lack the prefix
+func (root *Trie) Words() []Word {
+	var output []Word
+	dfs(root, "", &output)
+	return output
+}
+
+// Returns list of suggestions for auto-completion: what follows word in every
+// word of the Trie starting with it, sorted in order of usage. Eg:- he --> llo, lp
+// (and "" when he is a word itself)
+func (root *Trie) Autofill(word string) []string {
+	return root.AutofillScored(word, ByCount)
+}
+
+// Like Autofill, sorted by score instead. score is given the whole word
+func (root *Trie) AutofillScored(word string, score Scorer) []string {
+	var result []string
+
+	if len(word) == 0 {
+		return result
+	}
+	if root = root.Node(word); root == nil {
+		return result
+	}
+	return completions(word, root.Words(), score)
+}
+
+// Ranks the words below word by score, returning what follows word in each
+func completions(word string, output []Word, score Scorer) []string {
+	ranked := output[:0]
+	for _, w := range output {
+		w.Value = word + w.Value
+		if score(w.Value, w.Count) > 0 {
+			ranked = append(ranked, w)
+		}
+	}
+	Sort(ranked, score)
+
+	result := make([]string, len(ranked))
+	for i, w := range ranked {
+		result[i] = w.Value[len(word):]
+	}
+	return result
+}
+
+// Like AutofillScored, ignoring the case of word. Returns the whole words
+// since they may be cased differently. Eg:- hel --> Hello, help
+func (root *Trie) AutofillFold(word string, score Scorer) []string {
+	if len(word) == 0 {
+		return nil
+	}
+	return completions("", root.foldMatches([]rune(word)), score)
+}
+
+// The words which start with word in any case, with their counts
+func (root *Trie) foldMatches(word []rune) []Word {
+	var output []Word
+	var walk func(node *Trie, prefix string, rest []rune)
+	walk = func(node *Trie, prefix string, rest []rune) {
+		if len(rest) == 0 {
+			for _, w := range node.Words() {
+				output = append(output, Word{prefix + w.Value, w.Count})
+			}
+			return
+		}
+		// Every rune which is the same letter in another case, Eg:- k, K and the Kelvin sign
+		for r := rest[0]; ; {
+			if child := node.child(r); child != nil {
+				walk(child, prefix+string(r), rest[1:])
+			}
+			if r = unicode.SimpleFold(r); r == rest[0] {
+				break
+			}
+		}
+	}
+	walk(root, "", word)
+	return output
+}
+
+// Like AutofillFold, comparing the runes of word and of the words as fold maps
+// them instead and leaving out combining marks. Eg:- with a fold dropping the
+// accents, cafe --> café and café --> cafe
+func (root *Trie) AutofillFunc(word string, fold func(rune) rune, score Scorer) []string {
+	if len(word) == 0 {
+		return nil
+	}
+	return completions("", root.funcMatches([]rune(word), fold), score)
+}
+
+// The words which start with word once both are folded, with their counts
+func (root *Trie) funcMatches(word []rune, fold func(rune) rune) []Word {
+	var typed []rune
+	for _, r := range word {
+		if !unicode.Is(unicode.Mn, r) {
+			typed = append(typed, fold(r))
+		}
+	}
+	var output []Word
+	var walk func(node *Trie, prefix string, rest []rune)
+	walk = func(node *Trie, prefix string, rest []rune) {
+		if len(rest) == 0 {
+			for _, w := range node.Words() {
+				output = append(output, Word{prefix + w.Value, w.Count})
+			}
+			return
+		}
+		for k, child := range node.edges() {
+			if child = child.first(); unicode.Is(unicode.Mn, k) {
+				walk(child, prefix+string(k), rest) // Eg:- the accent of e◌́
+			} else if fold(k) == rest[0] {
+				walk(child, prefix+string(k), rest[1:])
+			}
+		}
+	}
+	walk(root, "", typed)
+	return output
+}
+
+// A word close to the one typed, see AutofillFuzzy
+type Match struct {
+	Word
+	Edits int // typos between the typed word and the start of Value
+}
+
+// Like AutofillScored, tolerating up to edits typos in word. A typo is a letter
+// inserted, deleted or replaced, or two neighbouring letters swapped. Returns the
+// whole words since they may not start with word, fewest typos first and then
+// sorted by score. Eg:- teh --> the, then, tea (1 typo each)
+func (root *Trie) AutofillFuzzy(word string, edits int, score Scorer) []Match {
+	return rankMatches(root.fuzzyMatches(word, edits), score)
+}
+
+// The words within edits typos of word, unsorted
+func (root *Trie) fuzzyMatches(word string, edits int) []Match {
+	typed := []rune(word)
+	if len(typed) == 0 {
+		return nil
+	}
+	row := make([]int, len(typed)+1)
+	for i := range row {
+		row[i] = i
+	}
+
+	var output []Match
+	for r, child := range root.edges() {
+		child.first().fuzzy(typed, []rune{r}, nil, row, len(typed), edits, &output)
+	}
+	return output
+}
+
+// Sorts output, leaving out the matches scoring 0 or less, see AutofillFuzzy
+func rankMatches(output []Match, score Scorer) []Match {
+	matches := output[:0]
+	for _, m := range output {
+		if score(m.Value, m.Count) > 0 {
+			matches = append(matches, m)
+		}
+	}
+
+	scores := make(map[string]float64, len(matches))
+	for _, m := range matches {
+		scores[m.Value] = score(m.Value, m.Count)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		mi, mj := matches[i], matches[j]
+		if mi.Edits != mj.Edits {
+			return mi.Edits < mj.Edits
+		}
+		if si, sj := scores[mi.Value], scores[mj.Value]; si != sj {
+			return si > sj
+		}
+		return mi.Value < mj.Value
+	})
+	return matches
+}
+
+// Collects the words below root, which is reached by prefix, starting within
+// edits typos of typed. prev and row hold the typos between typed and prefix
+// without its last letter (and without the one before, for swaps), best the
+// fewest typos of any shorter prefix
+func (root *Trie) fuzzy(typed, prefix []rune, prev, row []int, best, edits int, output *[]Match) {
+	r := prefix[len(prefix)-1]
+	next := make([]int, len(row))
+	next[0] = row[0] + 1
+	closest := next[0]
+	for j := 1; j < len(row); j++ {
+		cost := 1
+		if typed[j-1] == r {
+			cost = 0
+		}
+		next[j] = min(row[j]+1, next[j-1]+1, row[j-1]+cost)
+		if prev != nil && j > 1 && typed[j-1] == prefix[len(prefix)-2] && typed[j-2] == r {
+			next[j] = min(next[j], prev[j-2]+1)
+		}
+		closest = min(closest, next[j])
+	}
+	best = min(best, next[len(next)-1])
+
+	if closest > edits {
+		// Longer prefixes only get further away from typed
+		if best <= edits {
+			var words []Word
+			dfs(root, string(prefix), &words)
+			for _, w := range words {
+				*output = append(*output, Match{w, best})
+			}
+		}
+		return
+	}
+	if root.wordCount > 0 && best <= edits {
+		*output = append(*output, Match{Word{string(prefix), root.wordCount}, best})
+	}
+	for k, child := range root.edges() {
+		child.first().fuzzy(typed, append(prefix[:len(prefix):len(prefix)], k), row, next, best, edits, output)
+	}
+}
+
+// Returns the n highest scoring words
+func (root *Trie) Top(n int, score Scorer) []Word {
+	output := root.Words()
+	Sort(output, score)
+	return output[:min(n, len(output))]
+}
+
+// Sorts words by score, highest first, and alphabetically when they score the same
+func Sort(words []Word, score Scorer) {
+	scores := make(map[string]float64, len(words))
+	for _, w := range words {
+		scores[w.Value] = score(w.Value, w.Count)
+	}
+	sort.Slice(words, func(i, j int) bool {
+		si, sj := scores[words[i].Value], scores[words[j].Value]
+		if si != sj {
+			return si > sj
+		}
+		return words[i].Value < words[j].Value
+	})
+}
+
+// Size of the Trie
+type Stats struct {
+	Words int // distinct words
+	Nodes int // trie nodes, including the root
+	Uses  int // sum of all counts
+}
+
+func (root *Trie) Stats() Stats {
+	stats := Stats{Nodes: 1, Uses: root.wordCount}
+	if root.wordCount > 0 {
+		stats.Words++
+	}
+	for _, child := range root.edges() {
+		s := child.Stats()
+		stats.Words += s.Words
+		stats.Nodes += s.Nodes
+		stats.Uses += s.Uses
+	}
+	return stats
+}
+
+func dfs(root *Trie, prefix string, output *[]Word) {
+	if root.wordCount > 0 {
+		*output = append(*output, Word{prefix, root.wordCount})
+	}
+
+	for _, child := range root.edges() {
+		dfs(child, prefix+child.label, output)
+	}
+}
@@ -0,0 +1,531 @@
+package trie_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"maps"
+	"math"
+	"math/rand"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/b0tShaman/autocomplete-cli/trie"
+)
+
+func Example() {
+	t := trie.New()
+	t.Insert("hello")
+	t.InsertCount("help", 3)
+	t.Insert("golang")
+
+	fmt.Println(t.Autofill("he"))
+	fmt.Println(t.Count("help"), t.Count("he"))
+	// Output:
+	// [lp llo]
+	// 3 0
+}
+
+func ExampleTrie_AutofillScored() {
+	t := trie.New()
+	t.InsertCount("hello", 5000)
+	t.InsertCount("help", 900)
+	t.Insert("helium")
+
+	// Counts capped at 1000, so a pasted word does not beat everything forever
+	capped := func(word string, count int) float64 { return math.Min(float64(count), 1000) }
+	fmt.Println(t.AutofillScored("hel", capped))
+	// Output: [lo p ium]
+}
+
+func TestDelete(t *testing.T) {
+	tr := trie.New()
+	tr.Insert("help")
+	tr.Insert("helper")
+	if !tr.Delete("help") || tr.Delete("help") || tr.Delete("hel") {
+		t.Fatal("Delete did not report what it removed")
+	}
+	if got := tr.Autofill("he"); !slices.Equal(got, []string{"lper"}) {
+		t.Fatalf("Autofill after Delete = %q", got)
+	}
+	if !tr.Delete("helper") || tr.Stats() != (trie.Stats{Nodes: 1}) {
+		t.Fatalf("nodes left after deleting everything: %+v", tr.Stats())
+	}
+}
+
+// Edges split as words branch off them and join again as they are deleted,
+// the words stay what a map of counts holds
+func TestRadix(t *testing.T) {
+	tr, want := trie.New(), make(map[string]int)
+	parts := []string{"h", "e", "l", "lo", "p", "é", "日本"}
+	for i := range 3000 {
+		word := ""
+		for j := i; len(word) < 1 || j%3 != 0; j /= 2 {
+			word += parts[(i*31+j)%len(parts)]
+			if len(word) > 12 {
+				break
+			}
+		}
+		switch i % 5 {
+		case 3:
+			if tr.Delete(word) != (want[word] > 0) {
+				t.Fatalf("Delete(%s) with count %d", word, want[word])
+			}
+			delete(want, word)
+		case 4:
+			if tr.Decrement(word) != (want[word] > 0) {
+				t.Fatalf("Decrement(%s) with count %d", word, want[word])
+			}
+			if want[word]--; want[word] <= 0 {
+				delete(want, word)
+			}
+		default:
+			tr.InsertCount(word, i%3+1)
+			want[word] += i%3 + 1
+		}
+	}
+	got := make(map[string]int)
+	for _, w := range tr.Words() {
+		got[w.Value] = w.Count
+	}
+	if !maps.Equal(got, want) {
+		t.Fatalf("words %v, want %v", got, want)
+	}
+	for word, count := range want {
+		if tr.Count(word) != count {
+			t.Fatalf("Count(%s) = %d, want %d", word, tr.Count(word), count)
+		}
+		for i := range word {
+			if node := tr.Node(word[:i]); node == nil || node.Count(word[i:]) != count {
+				t.Fatalf("Node(%s) has no %s", word[:i], word[i:])
+			}
+		}
+	}
+	if stats := tr.Stats(); stats.Words != len(want) || stats.Nodes > 2*len(want) {
+		t.Fatalf("stats %+v for %d words", stats, len(want))
+	}
+}
+
+func TestDecrement(t *testing.T) {
+	tr := trie.New()
+	tr.InsertCount("teh", 2)
+	tr.Insert("tehran")
+	if !tr.Decrement("teh") || tr.Count("teh") != 1 {
+		t.Fatalf("teh counts %d after one decrement", tr.Count("teh"))
+	}
+	if !tr.Decrement("teh") || tr.Decrement("teh") || tr.Decrement("te") {
+		t.Fatal("Decrement did not report what it took back")
+	}
+	if got := tr.Autofill("te"); !slices.Equal(got, []string{"hran"}) {
+		t.Fatalf("Autofill after Decrement = %q", got)
+	}
+}
+
+func TestAutofillScored(t *testing.T) {
+	tr := trie.New()
+	for _, w := range []string{"go", "gopher", "golang", "gone"} {
+		tr.Insert(w)
+	}
+	// Ties are broken alphabetically, words scoring 0 are left out and the
+	// prefix itself completes with ""
+	score := func(word string, count int) float64 {
+		if word == "gone" {
+			return 0
+		}
+		return 1
+	}
+	if got := tr.AutofillScored("go", score); !slices.Equal(got, []string{"", "lang", "pher"}) {
+		t.Fatalf("AutofillScored = %q", got)
+	}
+	if got := tr.Autofill(""); len(got) != 0 {
+		t.Fatalf("Autofill of nothing = %q", got)
+	}
+}
+
+func TestTop(t *testing.T) {
+	tr := trie.New()
+	tr.InsertCount("b", 2)
+	tr.InsertCount("a", 2)
+	tr.InsertCount("c", 5)
+	want := []trie.Word{{"c", 5}, {"a", 2}}
+	if got := tr.Top(2, trie.ByCount); !slices.Equal(got, want) {
+		t.Fatalf("Top = %v, want %v", got, want)
+	}
+	if got := tr.Node("c").Words(); !slices.Equal(got, []trie.Word{{"", 5}}) {
+		t.Fatalf("Words below c = %v", got)
+	}
+}
+
+// AutofillTop keeps the first k of what AutofillScored ranks, also across the
+// layers of a Stack and without its hidden words
+func TestAutofillTop(t *testing.T) {
+	base, learned := trie.New(), trie.New()
+	for i := range 300 {
+		word := fmt.Sprintf("w%x", i*7919%4096)
+		base.InsertCount(word, i%13)
+		if i%3 == 0 {
+			learned.InsertCount(word, i%5+1)
+			learned.Insert(word + "é")
+		}
+	}
+	s := trie.NewStack(base, learned)
+	s.Hide("w1f")
+	score := func(word string, count int) float64 { return float64(count % 4) } // ties and zeros
+	for _, prefix := range []string{"w", "w1", "w1f", "x"} {
+		for _, k := range []int{1, 5, 1000} {
+			all := base.AutofillScored(prefix, score)
+			if got, want := base.AutofillTop(prefix, k, score), all[:min(k, len(all))]; !slices.Equal(got, want) {
+				t.Errorf("Trie.AutofillTop(%s, %d) = %q, want %q", prefix, k, got, want)
+			}
+			all = s.AutofillScored(prefix, score)
+			if got, want := s.AutofillTop(prefix, k, score), all[:min(k, len(all))]; !slices.Equal(got, want) {
+				t.Errorf("Stack.AutofillTop(%s, %d) = %q, want %q", prefix, k, got, want)
+			}
+		}
+	}
+	if got := s.AutofillTop("w", 0, score); len(got) != 0 {
+		t.Errorf("AutofillTop(w, 0) = %q", got)
+	}
+}
+
+func TestAutofillFold(t *testing.T) {
+	tr := trie.New()
+	tr.InsertCount("hello", 3)
+	tr.InsertCount("Hello", 2)
+	tr.Insert("HELP")
+	tr.Insert("world")
+	if got := tr.AutofillFold("hEl", trie.ByCount); !slices.Equal(got, []string{"hello", "Hello", "HELP"}) {
+		t.Fatalf("AutofillFold(hEl) = %q", got)
+	}
+	if got := tr.AutofillFold("Wo", trie.ByCount); !slices.Equal(got, []string{"world"}) {
+		t.Fatalf("AutofillFold(Wo) = %q", got)
+	}
+	if got := tr.AutofillFold("x", trie.ByCount); len(got) != 0 {
+		t.Fatalf("AutofillFold(x) = %q", got)
+	}
+}
+
+func TestAutofillFunc(t *testing.T) {
+	tr := trie.New()
+	tr.InsertCount("café", 2)
+	tr.Insert("cafe\u0301s") // the accent as a combining mark
+	tr.Insert("cafeteria")
+	plain := func(r rune) rune {
+		if r == 'é' {
+			return 'e'
+		}
+		return r
+	}
+	if got := tr.AutofillFunc("cafe", plain, trie.ByCount); !slices.Equal(got, []string{"café", "cafeteria", "cafe\u0301s"}) {
+		t.Fatalf("AutofillFunc(cafe) = %q", got)
+	}
+	if got := tr.AutofillFunc("café", plain, trie.ByCount); !slices.Equal(got, []string{"café", "cafeteria", "cafe\u0301s"}) {
+		t.Fatalf("AutofillFunc(café) = %q", got)
+	}
+	if got := tr.AutofillFunc("cafes", plain, trie.ByCount); !slices.Equal(got, []string{"cafe\u0301s"}) {
+		t.Fatalf("AutofillFunc(cafes) = %q", got)
+	}
+}
+
+func TestAutofillFuzzy(t *testing.T) {
+	tr := trie.New()
+	tr.InsertCount("the", 50)
+	tr.InsertCount("then", 10)
+	tr.InsertCount("tea", 5)
+	tr.Insert("teh")
+	tr.InsertCount("other", 80)
+	tr.InsertCount("hello", 3)
+
+	var got []string
+	for _, m := range tr.AutofillFuzzy("teh", 1, trie.ByCount) {
+		got = append(got, fmt.Sprintf("%s/%d", m.Value, m.Edits))
+	}
+	// The exact prefix first, then swapped letters and a dropped one by count
+	if want := []string{"teh/0", "the/1", "then/1", "tea/1"}; !slices.Equal(got, want) {
+		t.Fatalf("AutofillFuzzy(teh, 1) = %q, want %q", got, want)
+	}
+	if m := tr.AutofillFuzzy("helol", 2, trie.ByCount); len(m) != 1 || m[0].Value != "hello" || m[0].Edits != 1 {
+		t.Fatalf("AutofillFuzzy(helol, 2) = %v", m)
+	}
+	if m := tr.AutofillFuzzy("xyz", 2, trie.ByCount); len(m) != 0 {
+		t.Fatalf("AutofillFuzzy(xyz, 2) = %v", m)
+	}
+}
+
+func ExampleStack() {
+	dictionary := trie.New()
+	dictionary.Insert("hello")
+	dictionary.Insert("help")
+
+	s := trie.NewStack(dictionary, trie.New())
+	s.InsertCount(1, "help", 2)
+	s.Insert(1, "helmet")
+	fmt.Println(s.Autofill("hel"), s.Count("help"), dictionary.Count("help"))
+	// Output: [p lo met] 3 1
+}
+
+func TestStack(t *testing.T) {
+	base, learned := trie.New(), trie.New()
+	base.Insert("golang")
+	base.Insert("gone")
+	s := trie.NewStack(base, learned)
+	s.InsertCount(1, "golang", 2)
+	s.Insert(1, "gopher")
+
+	s.Hide("gone")
+	if s.Count("gone") != 0 || base.Count("gone") != 1 {
+		t.Fatalf("hidden word counts %d, %d in the base", s.Count("gone"), base.Count("gone"))
+	}
+	if got := s.Node("go").Hidden(); !slices.Equal(got, []string{"gone"}) {
+		t.Fatalf("hidden below go %q", got)
+	}
+	if got := s.AutofillFuzzy("goen", 1, trie.ByCount); len(got) != 0 {
+		t.Fatalf("fuzzy matches of a hidden word %v", got)
+	}
+	node := s.Node("go")
+	if node.Count("lang") != 3 || node.Layer(1).Count("pher") != 1 {
+		t.Fatalf("below go: lang %d, pher %d", node.Count("lang"), node.Layer(1).Count("pher"))
+	}
+	var next []rune
+	for r := range node.Children() {
+		next = append(next, r)
+	}
+	if slices.Sort(next); string(next) != "lnp" {
+		t.Fatalf("children of go %q", string(next))
+	}
+	if stats := s.Stats(); stats.Words != 2 || stats.Uses != 4 {
+		t.Fatalf("stats %+v", stats)
+	}
+
+	if got := s.AutofillFold("GO", trie.ByCount); !slices.Equal(got, []string{"golang", "gopher"}) {
+		t.Fatalf("AutofillFold(GO) = %q", got)
+	}
+	clone := s.Clone(1)
+	s.Insert(1, "gone")
+	if got := s.Autofill("go"); !slices.Equal(got, []string{"lang", "ne", "pher"}) {
+		t.Fatalf("after learning the hidden word again %q", got)
+	}
+	if len(s.Hidden()) != 0 {
+		t.Fatalf("still hidden %q", s.Hidden())
+	}
+	if clone.Count("gone") != 0 || clone.Layer(0) != base || clone.Count("golang") != 3 {
+		t.Fatalf("the clone changed with the stack: gone %d, golang %d", clone.Count("gone"), clone.Count("golang"))
+	}
+
+	reread := trie.New()
+	reread.Insert("gopher")
+	s.SetLayer(0, reread)
+	if s.Count("golang") != 2 || s.Count("gopher") != 2 || clone.Layer(0) != base {
+		t.Fatalf("with the base replaced: golang %d, gopher %d", s.Count("golang"), s.Count("gopher"))
+	}
+}
+
+// LearnFrom learns the words of a stream as it reads it and stops when asked to
+func TestLearnFrom(t *testing.T) {
+	tr := trie.New()
+	var reports []trie.Progress
+	text := "Hello, world! don't 'quoted' state-of-the-art " + strings.Repeat("x", 100) + " 42\nhello"
+	p, err := tr.LearnFrom(context.Background(), strings.NewReader(text), trie.LearnOptions{
+		Keep:          func(word string) bool { return word != "42" },
+		Progress:      func(p trie.Progress) { reports = append(reports, p) },
+		ProgressEvery: 16,
+	})
+	if err != nil || p.Bytes != int64(len(text)) || p.Words != 6 {
+		t.Fatalf("learned %+v, %v", p, err)
+	}
+	for word, want := range map[string]int{"Hello": 1, "hello": 1, "don't": 1, "quoted": 1, "state-of-the-art": 1, "42": 0} {
+		if got := tr.Count(word); got != want {
+			t.Errorf("%s counts %d, want %d", word, got, want)
+		}
+	}
+	if len(reports) != len(text)/16+1 || reports[len(reports)-1] != p {
+		t.Errorf("progress reports %v", reports)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := trie.NewStack(trie.New(), trie.New())
+	s.LearnFrom(ctx, 1, strings.NewReader("one two"), trie.LearnOptions{})
+	if s.Layer(1).Count("two") != 1 || s.Layer(0).Count("two") != 0 {
+		t.Fatal("the words did not go into layer 1")
+	}
+	cancel()
+	p, err = s.LearnFrom(ctx, 1, strings.NewReader("three four five"), trie.LearnOptions{ProgressEvery: 8})
+	if err != context.Canceled || p.Words != 1 || s.Count("four") != 0 {
+		t.Fatalf("cancelled after %+v, %v", p, err)
+	}
+}
+
+// A minimized Trie holds the same words, and its clone changes on its own
+func TestMinimize(t *testing.T) {
+	tr, want := trie.New(), trie.New()
+	for _, stem := range []string{"walk", "talk", "jump", "wal", "ta"} {
+		for _, ending := range []string{"", "ed", "ing", "s"} {
+			tr.Insert(stem + ending)
+			want.Insert(stem + ending)
+		}
+	}
+	tr.InsertCount("talks", 2)
+	want.InsertCount("talks", 2)
+	tr = tr.Minimize()
+	words := func(t *trie.Trie) []trie.Word {
+		w := t.Words()
+		trie.Sort(w, trie.ByCount)
+		return w
+	}
+	if got := words(tr); !slices.Equal(got, words(want)) {
+		t.Fatalf("words %v, want %v", got, words(want))
+	}
+	if got := tr.Autofill("wa"); !slices.Equal(got, want.Autofill("wa")) || tr.Count("talks") != 3 || tr.Count("walks") != 1 {
+		t.Fatalf("Autofill(wa) = %q, talks %d, walks %d", got, tr.Count("talks"), tr.Count("walks"))
+	}
+	clone := tr.Clone()
+	clone.Insert("walked")
+	clone.Delete("jumps")
+	if tr.Count("talked") != 1 || tr.Count("walked") != 1 || tr.Count("jumps") != 1 || clone.Count("walked") != 2 {
+		t.Fatalf("the clone changed the minimized Trie: talked %d, walked %d, jumps %d", tr.Count("talked"), tr.Count("walked"), tr.Count("jumps"))
+	}
+}
+
+// A decoded Trie has the words and counts of the encoded one
+func TestEncode(t *testing.T) {
+	tr := trie.New()
+	for _, word := range []string{"hello", "help", "he", "ünïcode", "日本語", "日本"} {
+		tr.Insert(word)
+	}
+	tr.InsertCount("help", 4)
+	var b bytes.Buffer
+	if err := tr.Encode(&b); err != nil {
+		t.Fatal(err)
+	}
+	encoded := b.Bytes()
+	got, err := trie.Decode(bytes.NewReader(encoded))
+	if err != nil {
+		t.Fatal(err)
+	}
+	words := func(tr *trie.Trie) []trie.Word {
+		w := tr.Words()
+		slices.SortFunc(w, func(a, b trie.Word) int { return strings.Compare(a.Value, b.Value) })
+		return w
+	}
+	if !slices.Equal(words(got), words(tr)) {
+		t.Fatalf("decoded %v, want %v", words(got), words(tr))
+	}
+	if c := got.AutofillScored("hel", trie.ByCount); !slices.Equal(c, []string{"p", "lo"}) {
+		t.Fatalf("decoded Autofill(hel) = %q", c)
+	}
+	got.Insert("helium")
+	if got.Count("helium") != 1 || got.Count("help") != 5 {
+		t.Fatalf("decoded trie does not take new words")
+	}
+
+	for _, n := range []int{0, 1, len(encoded) / 2, len(encoded) - 1} {
+		if _, err := trie.Decode(bytes.NewReader(encoded[:n])); err == nil {
+			t.Errorf("decoded %d of %d bytes without an error", n, len(encoded))
+		}
+	}
+}
+
+// A mapped Trie reads like the one it was written from, changes go to a clone
+// and damaged data reads as fewer words rather than failing
+func TestMap(t *testing.T) {
+	tr := trie.New()
+	for _, word := range []string{"hello", "help", "he", "helium", "ünïcode", "日本語", "日本"} {
+		tr.Insert(word)
+	}
+	tr.InsertCount("help", 4)
+	var b bytes.Buffer
+	if err := tr.EncodeMapped(&b); err != nil {
+		t.Fatal(err)
+	}
+	data := b.Bytes()
+	mapped, err := trie.Map(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	words := func(tr *trie.Trie) []trie.Word {
+		w := tr.Words()
+		slices.SortFunc(w, func(a, b trie.Word) int { return strings.Compare(a.Value, b.Value) })
+		return w
+	}
+	if !slices.Equal(words(mapped), words(tr)) {
+		t.Fatalf("mapped %v, want %v", words(mapped), words(tr))
+	}
+	for _, prefix := range []string{"h", "hel", "日", "x"} {
+		if got, want := mapped.AutofillTop(prefix, 10, trie.ByCount), tr.AutofillTop(prefix, 10, trie.ByCount); !slices.Equal(got, want) {
+			t.Errorf("mapped AutofillTop(%s) = %q, want %q", prefix, got, want)
+		}
+	}
+	if got := mapped.AutofillFuzzy("hlep", 1, trie.ByCount); len(got) == 0 || got[0].Value != "help" {
+		t.Errorf("mapped AutofillFuzzy(hlep) = %v", got)
+	}
+	if mapped.Count("help") != 5 || mapped.Count("hel") != 0 || mapped.Stats() != tr.Stats() {
+		t.Errorf("mapped counts help %d, hel %d, stats %+v", mapped.Count("help"), mapped.Count("hel"), mapped.Stats())
+	}
+	s := trie.NewStack(mapped, trie.New())
+	s.Insert(1, "helmet")
+	if got := s.Autofill("helm"); !slices.Equal(got, []string{"et"}) || s.Count("help") != 5 {
+		t.Errorf("stack over a mapped trie %q", got)
+	}
+	clone := mapped.Clone()
+	clone.Insert("helmet")
+	if clone.Count("helmet") != 1 || mapped.Count("helmet") != 0 {
+		t.Errorf("the clone shares words with the mapped trie")
+	}
+
+	if _, err := trie.Map(data[:3]); err == nil {
+		t.Error("mapped 3 bytes")
+	}
+	r := rand.New(rand.NewSource(1))
+	for range 500 {
+		damaged := slices.Clone(data)
+		for range 1 + r.Intn(4) {
+			damaged[r.Intn(len(damaged))] = byte(r.Intn(256))
+		}
+		if m, err := trie.Map(damaged); err == nil {
+			m.Words()
+			m.AutofillFuzzy("hel", 2, trie.ByCount)
+			m.Count("hello")
+		}
+	}
+}
+
+// The data of a mapped Trie is released once nothing reads it
+func TestMapRelease(t *testing.T) {
+	tr := trie.New()
+	tr.Insert("hello")
+	var b bytes.Buffer
+	tr.EncodeMapped(&b)
+	released := make(chan bool, 1)
+	mapped, _ := trie.Map(b.Bytes())
+	if !mapped.OnRelease(func() { released <- true }) || tr.OnRelease(func() {}) {
+		t.Fatal("OnRelease is not for mapped Tries only")
+	}
+	s := trie.NewStack(mapped, trie.New())
+	node := mapped.Node("he")
+	runtime.GC()
+	select {
+	case <-released:
+		t.Fatal("released while in use")
+	case <-time.After(10 * time.Millisecond):
+	}
+	if node.Count("llo") != 1 {
+		t.Error("node of the mapped Trie lost its words")
+	}
+
+	s.SetLayer(0, trie.New())
+	deadline := time.After(5 * time.Second)
+	for done := false; !done; {
+		runtime.GC()
+		select {
+		case <-released:
+			done = true
+		case <-deadline:
+			t.Fatal("not released once replaced")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	s.Count("hello")
+}
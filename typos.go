@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Learned typos, one "typo<TAB>correction<TAB>times" per line
+const typosFile = "typos.txt"
+
+// Maps a typo to the words it was corrected to and how many times.
+// Eg:- teh --> the: 3
+type Typos map[string]map[string]int
+
+// Reads the learned typos. A missing file means none were learned yet
+func LoadTypos(path string) (Typos, error) {
+	typos := make(Typos)
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return typos, nil
+	} else if err != nil {
+		return typos, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) != 3 || fields[0] == "" || fields[1] == "" {
+			continue
+		}
+		if times, err := strconv.Atoi(fields[2]); err == nil && times > 0 {
+			typos.add(fields[0], fields[1], times)
+		}
+	}
+	return typos, scanner.Err()
+}
+
+func (typos Typos) Save(path string) error {
+	var b strings.Builder
+	for _, typo := range typos.typos() {
+		for _, fix := range typos.Fixes(typo) {
+			fmt.Fprintf(&b, "%s\t%s\t%d\n", typo, fix, typos[typo][fix])
+		}
+	}
+	return os.WriteFile(path, []byte(b.String()), 0644)
+}
+
+func (typos Typos) typos() []string {
+	var list []string
+	for typo := range typos {
+		list = append(list, typo)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// Records that typo was corrected to fix once more
+func (typos Typos) Add(typo, fix string) {
+	typos.add(typo, fix, 1)
+}
+
+func (typos Typos) add(typo, fix string, times int) {
+	if typos[typo] == nil {
+		typos[typo] = make(map[string]int)
+	}
+	typos[typo][fix] += times
+}
+
+// Forgets a typo, returns false if it was never learned
+func (typos Typos) Remove(typo string) bool {
+	if _, ok := typos[typo]; !ok {
+		return false
+	}
+	delete(typos, typo)
+	return true
+}
+
+// The words typo was corrected to, most often first
+func (typos Typos) Fixes(typo string) []string {
+	var fixes []string
+	for fix := range typos[typo] {
+		fixes = append(fixes, fix)
+	}
+	sort.Slice(fixes, func(i, j int) bool {
+		if typos[typo][fixes[i]] != typos[typo][fixes[j]] {
+			return typos[typo][fixes[i]] > typos[typo][fixes[j]]
+		}
+		return fixes[i] < fixes[j]
+	})
+	return fixes
+}
+
+// Puts the corrections learned for word ahead of candidates, dropping them
+// further down
+func (typos Typos) Correct(word string, candidates []Candidate) []Candidate {
+	fixes := typos.Fixes(word)
+	if len(fixes) == 0 {
+		return candidates
+	}
+	var result []Candidate
+	for _, fix := range fixes {
+		result = append(result, Candidate{word: fix, label: fmt.Sprintf("corrected %s to %s before (%dx)", word, fix, typos[word][fix]), source: "typo"})
+	}
+	for _, c := range candidates {
+		if typos[word][c.word] == 0 {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// Reports whether fix looks like a correction of the typo typed instead: a
+// different word at most a few typos away, one per two letters after the first
+// like fuzzy matching. Eg:- teh, the --> true but at, it --> false
+func isTypo(typo, fix string) bool {
+	a, b := []rune(typo), []rune(fix)
+	edits := min(maxTypos, (len(b)-1)/2, (len(a)-1)/2)
+	return typo != fix && edits > 0 && typoDistance(a, b) <= edits
+}
+
+// Edits between a and b, swapping two neighbouring letters counts as one
+func typoDistance(a, b []rune) int {
+	rows := make([][]int, len(a)+1)
+	for i := range rows {
+		rows[i] = make([]int, len(b)+1)
+		rows[i][0] = i
+	}
+	for j := range rows[0] {
+		rows[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			rows[i][j] = min(rows[i-1][j]+1, rows[i][j-1]+1, rows[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				rows[i][j] = min(rows[i][j], rows[i-2][j-2]+1)
+			}
+		}
+	}
+	return rows[len(a)][len(b)]
+}
+
+// autocomplete typos [list|remove <typo>]
+// Prints the learned typos or forgets one
+func typosCommand(args []string) error {
+	typos, err := LoadTypos(paths.typos)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 || args[0] == "list" {
+		for _, typo := range typos.typos() {
+			for _, fix := range typos.Fixes(typo) {
+				fmt.Printf("%-16s %-16s %d\n", typo, fix, typos[typo][fix])
+			}
+		}
+		return nil
+	}
+	if len(args) != 2 || args[0] != "remove" {
+		return fmt.Errorf("usage: typos [list|remove <typo>]")
+	}
+	if !typos.Remove(args[1]) {
+		return fmt.Errorf("%s is not a learned typo", args[1])
+	}
+	return typos.Save(paths.typos)
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// System word lists offered as dictionaries (american-english, ngerman, ...)
+const systemDictGlob = "/usr/share/dict/*"
+
+// Status line themes offered by the wizard
+var wizardThemes = []struct{ name, sgr string }{
+	{"dim", "2"},
+	{"cyan", "36"},
+	{"bold yellow", "1;33"},
+	{"plain", ""},
+}
+
+// A dictionary the wizard can set up
+type dictChoice struct {
+	name string
+	path string
+	copy bool // copied into the data directory instead of referenced
+}
+
+func dictionaryChoices() []dictChoice {
+	var choices []dictChoice
+	if info, err := os.Stat("words.txt"); err == nil && !info.IsDir() {
+		choices = append(choices, dictChoice{"words.txt from the current directory", "words.txt", true})
+	}
+	files, _ := filepath.Glob(systemDictGlob)
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil && info.Mode().IsRegular() && !strings.HasPrefix(filepath.Base(file), "README") {
+			choices = append(choices, dictChoice{filepath.Base(file) + " (system word list)", file, false})
+		}
+	}
+	return choices
+}
+
+// Interactive first-run setup: picks a dictionary, optionally imports the shell
+// history, chooses a theme and writes the config and data directories
+func runWizard(in io.Reader, out io.Writer) error {
+	r := bufio.NewReader(in)
+	fmt.Fprintln(out, "Welcome to autocomplete-cli! Let's set things up (press ENTER to accept the default).")
+
+	// Dictionary
+	choices := dictionaryChoices()
+	fmt.Fprintln(out, "\nWhich dictionary should suggestions start from?")
+	for i, c := range choices {
+		fmt.Fprintf(out, "  %d) %s\n", i+1, c.name)
+	}
+	fmt.Fprintf(out, "  %d) another file\n", len(choices)+1)
+	fmt.Fprintf(out, "  %d) none, learn everything from typing\n", len(choices)+2)
+	def := len(choices) + 2
+	if len(choices) > 0 {
+		def = 1
+	}
+	n := askChoice(r, out, len(choices)+2, def)
+
+	var dictionary string // none
+	switch {
+	case n <= len(choices) && choices[n-1].copy:
+		dictionary = "words.txt"
+		if err := copyFile(choices[n-1].path, inDataDir(dictionary)); err != nil {
+			return err
+		}
+	case n <= len(choices):
+		dictionary = choices[n-1].path
+	case n == len(choices)+1:
+		path := ask(r, out, "Path of the dictionary", "")
+		if abs, err := filepath.Abs(path); err == nil && path != "" {
+			dictionary = abs
+		}
+	}
+
+	// Shell history
+	if strings.HasPrefix(strings.ToLower(ask(r, out, "\nImport words from your shell history? [y/N]", "n")), "y") {
+		imported, err := importShellHistory()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Imported %d words\n", imported)
+	}
+
+	// Theme
+	fmt.Fprintln(out, "\nHow should the status line look?")
+	for i, t := range wizardThemes {
+		fmt.Fprintf(out, "  %d) \033[%sm%s\033[0m\n", i+1, t.sgr, t.name)
+	}
+	theme := wizardThemes[askChoice(r, out, len(wizardThemes), 1)-1]
+
+	config := fmt.Sprintf("# Written by the first-run setup, see the README for all options\ndictionary = %q\n", dictionary)
+	if dictionary == "" {
+		config += "builtin_words = false\n" // not even the built-in words
+	}
+	config += fmt.Sprintf("\n[theme]\nstatus = %q\n", theme.sgr)
+	if err := os.MkdirAll(filepath.Dir(configPath()), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(configPath(), []byte(config), 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "\nWrote %s, learned data goes to %s\n\n", configPath(), paths.dir)
+	return nil
+}
+
+// Asks question and returns the trimmed answer, or def for an empty answer
+func ask(r *bufio.Reader, out io.Writer, question string, def string) string {
+	fmt.Fprintf(out, "%s: ", question)
+	answer, _ := r.ReadString('\n')
+	if answer = strings.TrimSpace(answer); answer == "" {
+		return def
+	}
+	return answer
+}
+
+// Asks until a number between 1 and max is entered
+func askChoice(r *bufio.Reader, out io.Writer, count int, def int) int {
+	for {
+		answer := ask(r, out, fmt.Sprintf("Choice [%d]", def), strconv.Itoa(def))
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= count {
+			return n
+		}
+		fmt.Fprintf(out, "Please enter a number between 1 and %d\n", count)
+	}
+}
+
+func copyFile(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, 0644)
+}
+
+// Adds the words found in the bash, zsh and fish histories to the learned counts.
+// Returns how many words were imported
+func importShellHistory() (int, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return 0, err
+	}
+	counts, err := LoadSnapshot(paths.snapshot)
+	if err != nil {
+		return 0, err
+	}
+	tombstones, err := LoadTombstones(paths.tombstones)
+	if err != nil {
+		return 0, err
+	}
+
+	var imported int
+	for _, file := range []string{".bash_history", ".zsh_history", ".local/share/fish/fish_history"} {
+		data, err := os.ReadFile(filepath.Join(home, file))
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			for _, word := range strings.Fields(historyCommand(line)) {
+				if isHistoryWord(word) && !tombstones.Buried(word, time.Time{}) {
+					counts.Add(word, 1, time.Time{})
+					imported++
+				}
+			}
+		}
+	}
+	return imported, SaveSnapshot(paths.snapshot, counts)
+}
+
+// Strips the zsh and fish metadata from a history line
+// Eg:- ": 1700000000:0;git status" --> "git status", "- cmd: git status" --> "git status"
+func historyCommand(line string) string {
+	if strings.HasPrefix(line, ": ") {
+		if _, cmd, ok := strings.Cut(line, ";"); ok {
+			return cmd
+		}
+	}
+	if cmd, ok := strings.CutPrefix(line, "- cmd: "); ok {
+		return cmd
+	}
+	if strings.HasPrefix(line, "  when: ") {
+		return ""
+	}
+	return line
+}
+
+// Keeps words worth suggesting, leaving out flags, paths and short tokens
+func isHistoryWord(word string) bool {
+	if len(word) < 3 {
+		return false
+	}
+	for _, r := range word {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return word[0] != '-'
+}
+
+// autocomplete setup: runs the first-run setup again, overwriting the config
+func setupCommand(args []string) error {
+	return runWizard(os.Stdin, os.Stdout)
+}
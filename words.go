@@ -0,0 +1,67 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/b0tShaman/autocomplete-cli/trie"
+)
+
+// Punctuation which is part of a word between its letters, by default
+// Eg:- state-of-the-art, don't, snake_case
+const defaultWordChars = "-'’_"
+
+// Reports whether r can be part of a word
+func isWordRune(r rune, wordChars string) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r) || strings.ContainsRune(wordChars, r)
+}
+
+// Words start and end with a letter or digit
+func isWordEdge(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// Splits the typed token into what comes before the word being typed and the
+// word, which starts after the last punctuation that is not part of words.
+// Eg:- (hel --> "(", "hel" and and/o --> "and/", "o" but state-of- --> "", "state-of-"
+func splitWord(token, wordChars string) (lead, word string) {
+	r := []rune(token)
+	start := len(r)
+	for start > 0 && isWordRune(r[start-1], wordChars) {
+		start--
+	}
+	for start < len(r) && !isWordEdge(r[start]) {
+		start++
+	}
+	return string(r[:start]), string(r[start:])
+}
+
+// The words of a typed token, leaving out the punctuation around and between
+// them which is not part of words. Eg:- (state-of-the-art), --> [state-of-the-art]
+// and and/or --> [and or]
+func splitWords(token, wordChars string) []string {
+	var words []string
+	for _, f := range strings.FieldsFunc(token, func(r rune) bool { return !isWordRune(r, wordChars) }) {
+		if f = strings.TrimFunc(f, func(r rune) bool { return !isWordEdge(r) }); f != "" {
+			words = append(words, f)
+		}
+	}
+	return words
+}
+
+// Completions of the word being typed after punctuation, with that punctuation
+// kept in front, which are not among candidates. Eg:- (hel --> (hello
+func leadCandidates(t *trie.Stack, score trie.Scorer, token string, tokenizer Tokenizer, candidates []Candidate) []Candidate {
+	lead, word := tokenizer.Split(token)
+	if lead == "" || word == "" {
+		return nil
+	}
+	var result []Candidate
+	for _, suffix := range t.AutofillScored(word, score) {
+		if w := lead + word + suffix; !slices.ContainsFunc(candidates, func(c Candidate) bool { return c.word == w }) {
+			result = append(result, Candidate{word: w, source: "lead"})
+		}
+	}
+	return result
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// Name of the per-application directory inside the config, data and cache base directories
+const appName = "autocomplete-cli"
+
+// Directory of the config file: $XDG_CONFIG_HOME/autocomplete-cli, falling back to
+// ~/.config on Unix, ~/Library/Application Support on macOS and %AppData% on Windows
+func configDir() string {
+	return baseDir("XDG_CONFIG_HOME", os.UserConfigDir)
+}
+
+// Directory of the dictionaries and learned data: $XDG_DATA_HOME/autocomplete-cli, falling
+// back to ~/.local/share on Unix, ~/Library/Application Support on macOS and %LocalAppData% on Windows
+func dataDir() string {
+	return baseDir("XDG_DATA_HOME", userDataDir)
+}
+
+// Directory of compiled artifacts which can be rebuilt at any time: $XDG_CACHE_HOME/autocomplete-cli,
+// falling back to ~/.cache on Unix, ~/Library/Caches on macOS and %LocalAppData% on Windows
+func cacheDir() string {
+	return baseDir("XDG_CACHE_HOME", os.UserCacheDir)
+}
+
+func baseDir(env string, fallback func() (string, error)) string {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return filepath.Join(dir, appName)
+	}
+	dir, err := fallback()
+	if err != nil {
+		// No home directory, keep everything next to the binary's working directory
+		return "."
+	}
+	return filepath.Join(dir, appName)
+}
+
+// The standard library has no equivalent of os.UserConfigDir for application data
+func userDataDir() (string, error) {
+	switch runtime.GOOS {
+	case "windows":
+		if dir := os.Getenv("LocalAppData"); dir != "" {
+			return dir, nil
+		}
+		return os.UserConfigDir()
+	case "darwin", "ios":
+		return os.UserConfigDir() // ~/Library/Application Support
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share"), nil
+}
+
+// Makes relative paths relative to the data directory
+func inDataDir(path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dataDir(), path)
+}